@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/arwoosa/notifaction/service/mail/factory"
 	"github.com/spf13/cobra"
@@ -16,16 +17,30 @@ var applyTplCmd = &cobra.Command{
 	Short: "Apply an email template from a YAML file to AWS SES",
 	Long: `Reads a specified YAML file, validates the email template, 
 and applies it to AWS SES. If the template already exists, it will be updated; 
-otherwise, a new template will be created.`,
+otherwise, a new template will be created. A multi-language file (locales:) is applied
+as one template per language, after checking the languages of mail.lang.required.
+
+When mail.template.workflow.mongo.uri is set the file is stored as a draft named draft-<event>_<lang>
+instead, see approveTpl and publishTpl.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("applyTpl called")
 		file, err := cmd.Flags().GetString("file")
 		errorHandler(err)
 		mailTpl, err := factory.NewTemplate()
 		errorHandler(err)
-		err = mailTpl.Apply(file)
+		wf, err := factory.NewWorkflow(mailTpl)
+		errorHandler(err)
+		if wf == nil {
+			errorHandler(mailTpl.Apply(file))
+			fmt.Println("success")
+			return
+		}
+		user, err := getUser(cmd)
+		errorHandler(err)
+		input, err := factory.ReadTemplateFile(file)
+		errorHandler(err)
+		change, err := wf.Draft(user, input)
 		errorHandler(err)
-		fmt.Println("success")
+		fmt.Printf("drafted %s, approve it with approveTpl -e %s\n", strings.Join(change.DraftNames(), ", "), change.Event)
 	},
 }
 
@@ -42,4 +57,5 @@ func init() {
 	// is called directly, e.g.:
 	// createTplCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	applyTplCmd.Flags().StringP("file", "f", "", "template file (YAML)")
+	addUserFlag(applyTplCmd)
 }
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var approveTplCmd = &cobra.Command{
+	Use:   "approveTpl",
+	Short: "Approve the draft of an email template",
+	Long: `Approves the draft of an event created by applyTpl or syncTpl. The user is the identity of the
+session of --session-token, it has to be one of mail.template.workflow.approvers and can not be the
+user who drafted it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		event, err := cmd.Flags().GetString("event")
+		errorHandler(err)
+		user, err := getUser(cmd)
+		errorHandler(err)
+		wf := newWorkflow()
+		change, err := wf.Approve(user, event)
+		errorHandler(err)
+		fmt.Printf("%s approved by %s, publish it with publishTpl -e %s\n", change.Event, change.ApprovedBy, change.Event)
+	},
+}
+
+func init() {
+	mailCmd.AddCommand(approveTplCmd)
+
+	approveTplCmd.Flags().StringP("event", "e", "", "event of the draft")
+	addUserFlag(approveTplCmd)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/spf13/cobra"
+)
+
+var copyTplCmd = &cobra.Command{
+	Use:   "copyTpl",
+	Short: "Copy email templates between SES regions or accounts",
+	Long: `Copies the templates of one SES to another. Profiles are the ones of the credentials file of
+aws.ses.credentails.filename and regions default to aws.ses.region, so that templates can be moved
+to another region or a staging account.
+
+Templates that exist in the target with different content are skipped unless --overwrite is set,
+templates with the same content are always skipped. --dry-run prints the plan without writing.
+
+When mail.template.workflow.mongo.uri is set the target is the configured template source and the
+copies are drafted per event instead, to be approved with approveTpl and published with publishTpl.
+
+Example:
+  # Plan moving every template from Tokyo to Singapore
+  notifaction mail copyTpl --from-region ap-northeast-1 --to-region ap-southeast-1 --dry-run
+
+  # Copy the welcome templates to the staging account, replacing the changed ones
+  notifaction mail copyTpl --from-profile prod --to-profile staging --filter welcome_ --overwrite
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := getOutputFormat(cmd)
+		errorHandler(err)
+		fromProfile, _ := cmd.Flags().GetString("from-profile")
+		toProfile, _ := cmd.Flags().GetString("to-profile")
+		region, _ := cmd.Flags().GetString("region")
+		fromRegion, _ := cmd.Flags().GetString("from-region")
+		toRegion, _ := cmd.Flags().GetString("to-region")
+		prefix, _ := cmd.Flags().GetString("filter")
+		overwrite, _ := cmd.Flags().GetBool("overwrite")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		mailTpl, err := factory.NewTemplate()
+		errorHandler(err)
+		wf, err := factory.NewWorkflow(mailTpl)
+		errorHandler(err)
+		if wf != nil && (toProfile != "" || toRegion != "" || region != "") {
+			errorHandler(errors.New("the template workflow drafts copies into the configured templates, --to-profile, --to-region and --region can not be set"))
+		}
+		if fromRegion == "" {
+			fromRegion = region
+		}
+		if toRegion == "" {
+			toRegion = region
+		}
+		if fromProfile == toProfile && fromRegion == toRegion {
+			errorHandler(errors.New("source and target are the same, set different profiles or regions"))
+		}
+
+		from, err := factory.NewAwsTemplateStore(fromProfile, fromRegion)
+		errorHandler(err)
+		var to mail.TemplateStore
+		var draft func(event string, tpls []*dao.Template) error
+		if wf != nil {
+			to, err = factory.NewTemplateStore()
+			errorHandler(err)
+			if !dryRun {
+				user, err := getUser(cmd)
+				errorHandler(err)
+				draft = func(event string, tpls []*dao.Template) error {
+					_, err := wf.DraftTemplates(user, event, tpls)
+					return err
+				}
+			}
+		} else {
+			to, err = factory.NewAwsTemplateStore(toProfile, toRegion)
+			errorHandler(err)
+		}
+		items, err := mail.CopyTemplates(from, to,
+			mail.WithCopyPrefix(prefix),
+			mail.WithCopyOverwrite(overwrite),
+			mail.WithCopyDryRun(dryRun),
+			mail.WithCopyDraft(draft),
+		)
+		errorHandler(err)
+
+		failed := 0
+		for _, item := range items {
+			if item.Action == mail.CopyFailed {
+				failed++
+			}
+		}
+		errorHandler(printOutput(format, items, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "Template\tAction\tReason")
+			for _, item := range items {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", item.Name, item.Action, item.Reason)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			if dryRun {
+				fmt.Fprintln(w, "dry run, nothing was copied")
+			}
+			return nil
+		}))
+		if failed > 0 {
+			logf("%d template(s) failed", failed)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	mailCmd.AddCommand(copyTplCmd)
+
+	copyTplCmd.Flags().String("from-profile", "", "credentials profile of the source (default aws.ses.credentails.profile)")
+	copyTplCmd.Flags().String("to-profile", "", "credentials profile of the target (default aws.ses.credentails.profile)")
+	copyTplCmd.Flags().String("region", "", "region of both source and target (default aws.ses.region)")
+	copyTplCmd.Flags().String("from-region", "", "region of the source, overrides --region")
+	copyTplCmd.Flags().String("to-region", "", "region of the target, overrides --region")
+	copyTplCmd.Flags().String("filter", "", "only templates whose name starts with the prefix")
+	copyTplCmd.Flags().Bool("overwrite", false, "replace templates of the target whose content differs")
+	copyTplCmd.Flags().Bool("dry-run", false, "print the plan without copying")
+	addUserFlag(copyTplCmd)
+	addOutputFlag(copyTplCmd)
+}
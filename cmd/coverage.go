@@ -0,0 +1,77 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/coverage"
+	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mail/workflow"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// coverageCmd represents the coverage command
+var coverageCmd = &cobra.Command{
+	Use:   "coverage",
+	Short: "Show which events have templates in which languages",
+	Long: `Lists every stored template, following the next token of each page, and prints
+an event x language matrix where ✗ marks a missing template.
+Languages of --require-langs (mail.lang.required by default) are marked with * and
+always shown. The command exits with a non-zero code when an event misses a required
+language, so CI can block releases with missing translations.
+Drafts and the templates of other channels (<event>-<channel>, see notification.channels)
+are not counted as events.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireLangs, err := cmd.Flags().GetStringSlice("require-langs")
+		errorHandler(err)
+		if !cmd.Flags().Changed("require-langs") {
+			requireLangs = viper.GetStringSlice("mail.lang.required")
+		}
+
+		mailTpl, err := factory.NewTemplate()
+		errorHandler(err)
+		tpls, err := mail.ListAll(mailTpl)
+		errorHandler(err)
+		names := make([]string, 0, len(tpls))
+		for _, t := range tpls {
+			// drafts are not used by senders, so they do not cover an event
+			if workflow.IsDraftName(t.Name) {
+				continue
+			}
+			// a channel variant would show as an event missing every language it is not sent in
+			if event, _, err := service.ParseTemplateName(t.Name); err == nil {
+				if _, variant := channel.BaseEvent(event); variant {
+					continue
+				}
+			}
+			names = append(names, t.Name)
+		}
+
+		matrix := coverage.NewMatrix(names, requireLangs)
+		errorHandler(matrix.Write(os.Stdout))
+		if len(matrix.Invalid) > 0 {
+			fmt.Printf("\ntemplates not named <event>_<lang>: %s\n", strings.Join(matrix.Invalid, ", "))
+		}
+		missing := matrix.MissingRequired()
+		fmt.Printf("\n%d event(s), %d language(s), %d missing, %d missing required\n",
+			len(matrix.Events), len(matrix.Langs), len(matrix.Missing()), len(missing))
+		if len(missing) > 0 {
+			fmt.Printf("missing required: %s\n", strings.Join(missing, ", "))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	mailCmd.AddCommand(coverageCmd)
+
+	coverageCmd.Flags().StringSlice("require-langs", []string{}, "languages every event must have, defaults to mail.lang.required")
+}
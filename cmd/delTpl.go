@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/arwoosa/notifaction/service/mail/factory"
 	"github.com/spf13/cobra"
@@ -15,7 +16,10 @@ var delTplCmd = &cobra.Command{
 	Use:   "delTpl",
 	Short: "Delete an email template from AWS SES",
 	Long: `Deletes a specified email template from AWS SES.
-This command checks if the given template exists; if so, it removes it.`,
+This command checks if the given template exists; if so, it removes it.
+
+When mail.template.workflow.mongo.uri is set the deletion is drafted instead, the template
+is deleted once the deletion is approved with approveTpl and published with publishTpl.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("delTpl called")
 		name, err := cmd.Flags().GetString("name")
@@ -26,6 +30,16 @@ This command checks if the given template exists; if so, it removes it.`,
 		}
 		mailTpl, err := factory.NewTemplate()
 		errorHandler(err)
+		wf, err := factory.NewWorkflow(mailTpl)
+		errorHandler(err)
+		if wf != nil {
+			user, err := getUser(cmd)
+			errorHandler(err)
+			change, err := wf.DraftDelete(user, name)
+			errorHandler(err)
+			fmt.Printf("drafted the deletion of %s, approve it with approveTpl -e %s\n", strings.Join(change.Deletes, ", "), change.Event)
+			return
+		}
 		err = mailTpl.Delete(name)
 		errorHandler(err)
 		fmt.Println("success")
@@ -45,4 +59,5 @@ func init() {
 	// is called directly, e.g.:
 	// createTplCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	delTplCmd.Flags().StringP("name", "n", "", "name")
+	addUserFlag(delTplCmd)
 }
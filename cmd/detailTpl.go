@@ -5,30 +5,46 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/arwoosa/notifaction/service/mail/factory"
 	"github.com/spf13/cobra"
 )
 
+type detailTplOutput struct {
+	Name    string `json:"name" yaml:"name"`
+	Subject string `json:"subject" yaml:"subject"`
+	Html    string `json:"html" yaml:"html"`
+	Plaint  string `json:"plaint" yaml:"plaint"`
+}
+
 // createTplCmd represents the createTpl command
 var detailTplCmd = &cobra.Command{
 	Use:   "detailTpl",
 	Short: "Display detailed information about a mail template",
 	Long:  `Display detailed information about a mail template, including its title, subject, HTML body, and plain text body.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("listTpl called")
-		fmt.Println()
+		format, err := getOutputFormat(cmd)
+		errorHandler(err)
 		tplName, err := cmd.Flags().GetString("name")
 		errorHandler(err)
 		mailTpl, err := factory.NewTemplate()
 		errorHandler(err)
 		result, err := mailTpl.Detail(tplName)
 		errorHandler(err)
-		fmt.Println("Template Name: ", result.Title)
-		fmt.Println("Template Subject: ", result.Subject)
-		fmt.Println("Template Body (HTML): \n", result.Body.Html)
-		fmt.Println("Template Body (PLAIN): \n", result.Body.Plaint)
-
+		output := &detailTplOutput{
+			Name:    result.Title,
+			Subject: result.Subject,
+			Html:    result.Body.Html,
+			Plaint:  result.Body.Plaint,
+		}
+		errorHandler(printOutput(format, output, func(w io.Writer) error {
+			fmt.Fprintln(w, "Template Name: ", output.Name)
+			fmt.Fprintln(w, "Template Subject: ", output.Subject)
+			fmt.Fprintln(w, "Template Body (HTML): \n", output.Html)
+			fmt.Fprintln(w, "Template Body (PLAIN): \n", output.Plaint)
+			return nil
+		}))
 	},
 }
 
@@ -45,4 +61,5 @@ func init() {
 	// is called directly, e.g.:
 	// createTplCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	detailTplCmd.Flags().StringP("name", "n", "", "template name")
+	addOutputFlag(detailTplCmd)
 }
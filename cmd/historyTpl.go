@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arwoosa/notifaction/service/mail/workflow"
+	"github.com/spf13/cobra"
+)
+
+type historyTplEntry struct {
+	Action string    `json:"action" yaml:"action"`
+	User   string    `json:"user" yaml:"user"`
+	Time   time.Time `json:"time" yaml:"time"`
+}
+
+type historyTplItem struct {
+	Event      string             `json:"event" yaml:"event"`
+	Status     string             `json:"status" yaml:"status"`
+	DraftedBy  string             `json:"drafted_by" yaml:"drafted_by"`
+	ApprovedBy string             `json:"approved_by,omitempty" yaml:"approved_by,omitempty"`
+	History    []*historyTplEntry `json:"history" yaml:"history"`
+}
+
+var historyTplCmd = &cobra.Command{
+	Use:   "historyTpl",
+	Short: "Show the status and audit trail of template drafts",
+	Long: `Shows the status of the latest draft of every event, or of --event only, with the audit
+trail of who drafted, approved and published each change.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := getOutputFormat(cmd)
+		errorHandler(err)
+		event, err := cmd.Flags().GetString("event")
+		errorHandler(err)
+		wf := newWorkflow()
+		var changes []*workflow.Change
+		if event != "" {
+			change, err := wf.Get(event)
+			errorHandler(err)
+			changes = []*workflow.Change{change}
+		} else {
+			changes, err = wf.List()
+			errorHandler(err)
+		}
+
+		output := []*historyTplItem{}
+		for _, c := range changes {
+			item := &historyTplItem{
+				Event:      c.Event,
+				Status:     string(c.Status),
+				DraftedBy:  c.DraftedBy,
+				ApprovedBy: c.ApprovedBy,
+				History:    []*historyTplEntry{},
+			}
+			for _, h := range c.History {
+				item.History = append(item.History, &historyTplEntry{Action: h.Action, User: h.User, Time: h.Time})
+			}
+			output = append(output, item)
+		}
+		errorHandler(printOutput(format, output, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "Event\tStatus\tAction\tUser\tTime")
+			for _, item := range output {
+				for _, h := range item.History {
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", item.Event, item.Status, h.Action, h.User, h.Time.Format(time.RFC3339))
+				}
+			}
+			return tw.Flush()
+		}))
+	},
+}
+
+func init() {
+	mailCmd.AddCommand(historyTplCmd)
+
+	historyTplCmd.Flags().StringP("event", "e", "", "only the draft of the event")
+	addOutputFlag(historyTplCmd)
+}
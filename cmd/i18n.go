@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var i18nCmd = &cobra.Command{
+	Use:   "i18n",
+	Short: "Exchange template translations with translators as XLIFF or PO files",
+	Long: `Exports the translatable text of template files, subject, plain text lines and HTML text nodes,
+as XLIFF 1.2 or gettext PO files for translation tools (export), and rebuilds the template
+files of the target language from the translated files (import).
+Placeholders like {{NAME}} and partials like {{> footer}} are protected tokens, a translation
+that misses or changes one fails the import.`,
+}
+
+func init() {
+	mailCmd.AddCommand(i18nCmd)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/arwoosa/notifaction/service/mail/i18n"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var i18nExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the translatable segments of template files",
+	Long: `Extracts the segments of the templates of --source-lang found in the template files of --dir,
+including the locales of multi-language files, and writes them as XLIFF or PO.
+The format defaults to the extension of --out, then to xliff.
+
+Example:
+  notifaction mail i18n export --dir templates --source-lang zh-TW --target-lang en --out en.xlf
+  notifaction mail i18n export --dir templates --source-lang zh-TW --format po > ja.po`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		sourceLang, _ := cmd.Flags().GetString("source-lang")
+		targetLang, _ := cmd.Flags().GetString("target-lang")
+		format, _ := cmd.Flags().GetString("format")
+		out, _ := cmd.Flags().GetString("out")
+		if dir == "" {
+			errorHandler(errors.New("--dir is required"))
+		}
+		if sourceLang == "" {
+			sourceLang = viper.GetString("mail.lang.default")
+		}
+		if format == "" {
+			format = i18n.FormatOf(out)
+		}
+		if format == "" {
+			format = i18n.FormatXliff
+		}
+
+		tpls, err := i18n.LoadTemplates(dir, sourceLang)
+		errorHandler(err)
+		if len(tpls) == 0 {
+			errorHandler(errors.New("no template of " + sourceLang + " found in " + dir))
+		}
+		catalog, err := i18n.Export(tpls, sourceLang, targetLang)
+		errorHandler(err)
+
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.Create(out)
+			errorHandler(err)
+			defer f.Close()
+			w = f
+		}
+		errorHandler(i18n.Write(w, format, catalog))
+		logf("%d segment(s) of %d template(s) exported", len(catalog.Units), len(tpls))
+	},
+}
+
+func init() {
+	i18nCmd.AddCommand(i18nExportCmd)
+
+	i18nExportCmd.Flags().StringP("dir", "d", "", "directory of template files (YAML)")
+	i18nExportCmd.Flags().String("source-lang", "", "language to translate from (default mail.lang.default)")
+	i18nExportCmd.Flags().String("target-lang", "", "language to translate to, written in the file for the import")
+	i18nExportCmd.Flags().String("format", "", "xliff or po")
+	i18nExportCmd.Flags().String("out", "", "output file (default stdout)")
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arwoosa/notifaction/service/mail/i18n"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var i18nImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Rebuild template files of a language from a translated file",
+	Long: `Reads a translated XLIFF or PO file written by export and rebuilds, for every event of the file,
+the template of the target language from the source template in --dir: markup, layout, variables
+and options are kept and the segments are replaced by their translations.
+Templates are written to --out-dir as <event>_<lang>.yaml, replacing existing files, nothing is
+written when a segment is untranslated, its source changed since the export or a placeholder
+is missing or changed.
+
+Example:
+  notifaction mail i18n import --dir templates --file en.xlf
+  notifaction mail i18n import --dir templates --file ja.po --target-lang ja --out-dir translated`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		file, _ := cmd.Flags().GetString("file")
+		format, _ := cmd.Flags().GetString("format")
+		targetLang, _ := cmd.Flags().GetString("target-lang")
+		outDir, _ := cmd.Flags().GetString("out-dir")
+		if dir == "" || file == "" {
+			errorHandler(errors.New("--dir and --file are required"))
+		}
+		if format == "" {
+			format = i18n.FormatOf(file)
+		}
+		if outDir == "" {
+			outDir = dir
+		}
+
+		f, err := os.Open(file)
+		errorHandler(err)
+		defer f.Close()
+		catalog, err := i18n.Read(f, format)
+		errorHandler(err)
+		if targetLang == "" {
+			targetLang = catalog.TargetLang
+		}
+		if targetLang == "" {
+			errorHandler(errors.New("--target-lang is required, the file has no target language"))
+		}
+		if catalog.SourceLang == "" {
+			errorHandler(errors.New("the file has no source language"))
+		}
+
+		sources, err := i18n.LoadTemplates(dir, catalog.SourceLang)
+		errorHandler(err)
+		tpls, err := i18n.Import(catalog, sources, targetLang)
+		errorHandler(err)
+
+		errorHandler(os.MkdirAll(outDir, 0o750))
+		for _, tpl := range tpls {
+			data, err := yaml.Marshal(tpl)
+			errorHandler(err)
+			path := filepath.Join(outDir, tpl.GetName()+".yaml")
+			errorHandler(os.WriteFile(path, data, 0o600))
+			fmt.Println(path)
+		}
+	},
+}
+
+func init() {
+	i18nCmd.AddCommand(i18nImportCmd)
+
+	i18nImportCmd.Flags().StringP("dir", "d", "", "directory of the source template files (YAML)")
+	i18nImportCmd.Flags().StringP("file", "f", "", "translated XLIFF or PO file")
+	i18nImportCmd.Flags().String("format", "", "xliff or po (default by the file extension)")
+	i18nImportCmd.Flags().String("target-lang", "", "language of the translations (default the one of the file)")
+	i18nImportCmd.Flags().String("out-dir", "", "directory of the rebuilt template files (default --dir)")
+}
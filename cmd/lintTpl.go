@@ -0,0 +1,107 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mail/layout"
+	"github.com/arwoosa/notifaction/service/mail/lint"
+	"github.com/arwoosa/notifaction/service/mail/transform"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// lintTplCmd represents the lintTpl command
+var lintTplCmd = &cobra.Command{
+	Use:   "lintTpl",
+	Short: "Check email template YAML files for common mistakes",
+	Long: `Checks template YAML files without applying them: unbalanced {{ }}, placeholders
+used only in the HTML or only in the plain body, malformed HTML, non-https links,
+images without an alt attribute, long subjects and messages over Gmail's clipping limit.
+Layouts and partials of mail.template.layouts.dir are resolved before checking, and
+multi-language files must provide every language of mail.lang.required.
+The same checks run in applyTpl, where errors block the apply.
+Exits with a non-zero code when any error is found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		files, err := cmd.Flags().GetStringSlice("file")
+		errorHandler(err)
+		dir, err := cmd.Flags().GetString("dir")
+		errorHandler(err)
+		if dir != "" {
+			dirFiles, err := factory.ListTemplateFiles(dir)
+			errorHandler(err)
+			files = append(files, dirFiles...)
+		}
+		if len(files) == 0 {
+			fmt.Println("file or dir is required")
+			os.Exit(1)
+		}
+
+		layouts, err := factory.NewLayouts()
+		errorHandler(err)
+
+		errCount, warnCount := 0, 0
+		for _, file := range files {
+			findings := lintFile(file, layouts)
+			if len(findings) == 0 {
+				continue
+			}
+			fmt.Println(file)
+			for _, f := range findings {
+				fmt.Println("  ", f)
+			}
+			errCount += len(findings.Errors())
+			warnCount += len(findings.Warnings())
+		}
+		fmt.Printf("%d file(s), %d error(s), %d warning(s)\n", len(files), errCount, warnCount)
+		if errCount > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func lintFile(file string, layouts *layout.Set) lint.Findings {
+	tpl, err := factory.ReadTemplateFile(file)
+	if err != nil {
+		return lint.Findings{{Severity: lint.SeverityError, Rule: "invalid", Message: err.Error()}}
+	}
+	if err := tpl.Validate(); err != nil {
+		return lint.Findings{{Severity: lint.SeverityError, Rule: "invalid", Message: err.Error()}}
+	}
+	findings := lint.Findings{}
+	if len(tpl.Locales) > 0 {
+		if missing := tpl.MissingLangs(viper.GetStringSlice("mail.lang.required")); len(missing) > 0 {
+			findings = append(findings, &lint.Finding{
+				Severity: lint.SeverityError,
+				Rule:     "missing-locale",
+				Message:  "missing required languages: " + strings.Join(missing, ", "),
+			})
+		}
+	}
+	for _, locale := range tpl.Expand() {
+		built, err := transform.Build(locale, layouts)
+		if err != nil {
+			findings = append(findings, &lint.Finding{Severity: lint.SeverityError, Rule: "invalid", Message: err.Error()})
+			continue
+		}
+		for _, f := range lint.Lint(built) {
+			if len(tpl.Locales) > 0 {
+				f.Message = locale.Lang + ": " + f.Message
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+func init() {
+	mailCmd.AddCommand(lintTplCmd)
+
+	lintTplCmd.Flags().StringSliceP("file", "f", []string{}, "template file (YAML), can be specified multiple times")
+	lintTplCmd.Flags().StringP("dir", "d", "", "directory of template files (YAML)")
+}
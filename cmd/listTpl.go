@@ -5,39 +5,114 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"text/tabwriter"
 	"time"
 
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
 	"github.com/arwoosa/notifaction/service/mail/factory"
 	"github.com/spf13/cobra"
 )
 
+type listTplItem struct {
+	Name      string     `json:"name" yaml:"name"`
+	Event     string     `json:"event" yaml:"event"`
+	Lang      string     `json:"lang" yaml:"lang"`
+	CreatedAt time.Time  `json:"created_at" yaml:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+}
+
+type listTplOutput struct {
+	Templates []*listTplItem `json:"templates" yaml:"templates"`
+	NextToken *string        `json:"next_token,omitempty" yaml:"next_token,omitempty"`
+}
+
 // createTplCmd represents the createTpl command
 var listTplCmd = &cobra.Command{
 	Use:   "listTpl",
 	Short: "List email templates from AWS SES",
 	Long: `Lists all email templates stored in AWS SES with their creation times.
 Supports pagination via the --next-token flag, which allows retrieval of subsequent results
-if the number of templates exceeds a single page.`,
+if the number of templates exceeds a single page, or --all to follow every page.
+Templates can be filtered by event prefix, lang and created or updated time,
+times are RFC 3339 or dates like 2025-01-31.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("listTpl called")
-		fmt.Println()
+		format, err := getOutputFormat(cmd)
+		errorHandler(err)
 		nextToken, err := cmd.Flags().GetString("next-token")
 		errorHandler(err)
-		mailTpl, err := factory.NewTemplate()
+		all, err := cmd.Flags().GetBool("all")
 		errorHandler(err)
-		result, err := mailTpl.List(nextToken)
+		filter, err := listFilter(cmd)
 		errorHandler(err)
-		fmt.Println("Template Name                     Created Time")
-		fmt.Println("========================================================")
-		for _, t := range result.Templates {
-			fmt.Printf("%-30s    %s\n", t.Name, t.CreateTime.Format(time.RFC3339))
+
+		mailTpl, err := factory.NewTemplate()
+		errorHandler(err)
+		var tpls []*dao.ListTemplate
+		var next *string
+		if all {
+			tpls, err = mail.ListAll(mailTpl)
+			errorHandler(err)
+		} else {
+			result, err := mailTpl.List(nextToken)
+			errorHandler(err)
+			tpls, next = result.Templates, result.NextToken
 		}
-		if result.NextToken != nil {
-			fmt.Printf("next token: %s\n", *result.NextToken)
+
+		output := &listTplOutput{Templates: []*listTplItem{}, NextToken: next}
+		for _, t := range filter.Filter(tpls) {
+			event, lang, _ := service.ParseTemplateName(t.Name)
+			output.Templates = append(output.Templates, &listTplItem{
+				Name:      t.Name,
+				Event:     event,
+				Lang:      lang,
+				CreatedAt: t.CreateTime,
+				UpdatedAt: t.UpdateTime,
+			})
 		}
+		errorHandler(printOutput(format, output, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 0, 4, 4, ' ', 0)
+			fmt.Fprintln(tw, "Template Name\tCreated Time")
+			for _, t := range output.Templates {
+				fmt.Fprintf(tw, "%s\t%s\n", t.Name, t.CreatedAt.Format(time.RFC3339))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			if output.NextToken != nil {
+				fmt.Fprintf(w, "next token: %s\n", *output.NextToken)
+			}
+			return nil
+		}))
 	},
 }
 
+func listFilter(cmd *cobra.Command) (*mail.ListFilter, error) {
+	var err error
+	filter := &mail.ListFilter{}
+	if filter.EventPrefix, err = cmd.Flags().GetString("event-prefix"); err != nil {
+		return nil, err
+	}
+	if filter.Lang, err = cmd.Flags().GetString("lang"); err != nil {
+		return nil, err
+	}
+	if filter.CreatedAfter, err = parseTimeFlag(cmd, "created-after"); err != nil {
+		return nil, err
+	}
+	if filter.CreatedBefore, err = parseTimeFlag(cmd, "created-before"); err != nil {
+		return nil, err
+	}
+	if filter.UpdatedAfter, err = parseTimeFlag(cmd, "updated-after"); err != nil {
+		return nil, err
+	}
+	if filter.UpdatedBefore, err = parseTimeFlag(cmd, "updated-before"); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
 func init() {
 	mailCmd.AddCommand(listTplCmd)
 
@@ -51,4 +126,12 @@ func init() {
 	// is called directly, e.g.:
 	// createTplCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	listTplCmd.Flags().StringP("next-token", "t", "", "next token")
+	listTplCmd.Flags().Bool("all", false, "follow the next token and list every page")
+	listTplCmd.Flags().String("event-prefix", "", "only templates whose event starts with the prefix")
+	listTplCmd.Flags().String("lang", "", "only templates of the lang")
+	listTplCmd.Flags().String("created-after", "", "only templates created at or after the time")
+	listTplCmd.Flags().String("created-before", "", "only templates created before the time")
+	listTplCmd.Flags().String("updated-after", "", "only templates updated at or after the time")
+	listTplCmd.Flags().String("updated-before", "", "only templates updated before the time")
+	addOutputFlag(listTplCmd)
 }
@@ -14,8 +14,13 @@ var mailCmd = &cobra.Command{
 	Use:   "mail",
 	Short: "Manage AWS SES email templates",
 	Long: `The mail command provides a set of subcommands to manage email templates in AWS SES.
-It supports applying templates from a YAML file (applyTpl), deleting existing templates (delTpl),
-and listing all stored templates with pagination support (listTpl). Use these subcommands to seamlessly
+It supports applying templates from a YAML file (applyTpl) or a whole directory (syncTpl),
+deleting existing templates (delTpl),
+listing all stored templates with pagination support (listTpl), checking template files
+for common mistakes before applying them (lintTpl), previewing them locally (previewTpl)
+showing the languages each event is translated to (coverage), approving and publishing drafts
+(approveTpl, publishTpl, historyTpl), copying templates between SES regions or accounts (copyTpl)
+and exchanging translations as XLIFF or PO files (i18n). Use these subcommands to seamlessly
 create, update, remove, or query email templates in your AWS SES environment.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("mail called")
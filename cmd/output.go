@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputTable = "table"
+	outputJson  = "json"
+	outputYaml  = "yaml"
+)
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", outputTable, "output format: table, json or yaml")
+}
+
+func getOutputFormat(cmd *cobra.Command) (string, error) {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case outputTable, outputJson, outputYaml:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid output format: %s", format)
+	}
+}
+
+// printOutput writes data to stdout in the format of the output flag, table is used for the table format.
+func printOutput(format string, data any, table func(w io.Writer) error) error {
+	switch format {
+	case outputJson:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case outputYaml:
+		out, err := yaml.Marshal(data)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	default:
+		return table(os.Stdout)
+	}
+}
+
+// logf writes diagnostic messages to stderr so that stdout only holds the output.
+func logf(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+}
+
+// parseTimeFlag accepts RFC 3339 times and dates like 2006-01-02, an empty value is the zero time.
+func parseTimeFlag(cmd *cobra.Command, name string) (time.Time, error) {
+	value, err := cmd.Flags().GetString(name)
+	if err != nil || value == "" {
+		return time.Time{}, err
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s: %s", name, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/arwoosa/notifaction/service/mail/preview"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// previewTplCmd represents the previewTpl command
+var previewTplCmd = &cobra.Command{
+	Use:   "previewTpl",
+	Short: "Serve a local preview of email template YAML files",
+	Long: `Serves a web page listing every event and language of the template files of a directory.
+The page of an event shows the subject, HTML and plain body of each language side by side,
+rendered with the sample data of --data the same way the SMTP sender renders them.
+Layouts and partials of mail.template.layouts.dir are resolved, and pages reload when
+a template, layout or the data file changes. Nothing is pushed to AWS SES.
+
+Templates are rendered with their sample: block, overridden by the data file.
+The data file is a JSON or YAML object, scalar values are used for every event and
+object values keyed by an event name are used for that event only:
+  {"CREATOR_NAME": "Peter", "EVENT_JOIN_DENIED": {"EVENT_NAME": "Hiking"}}`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := cmd.Flags().GetString("dir")
+		errorHandler(err)
+		data, err := cmd.Flags().GetString("data")
+		errorHandler(err)
+		addr, err := cmd.Flags().GetString("addr")
+		errorHandler(err)
+
+		server, err := preview.NewServer(dir,
+			preview.WithDataFile(data),
+			preview.WithLayoutDir(viper.GetString("mail.template.layouts.dir")),
+		)
+		errorHandler(err)
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			if err := server.Watch(done); err != nil {
+				log.Printf("reload disabled: %v", err)
+			}
+		}()
+
+		fmt.Printf("preview server listening on http://%s\n", addr)
+		errorHandler(http.ListenAndServe(addr, server))
+	},
+}
+
+func init() {
+	mailCmd.AddCommand(previewTplCmd)
+
+	previewTplCmd.Flags().StringP("dir", "d", "", "directory of template files (YAML)")
+	previewTplCmd.Flags().String("data", "", "sample data file (JSON or YAML)")
+	previewTplCmd.Flags().String("addr", "localhost:8089", "listen address")
+}
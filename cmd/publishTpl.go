@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mail/workflow"
+	"github.com/spf13/cobra"
+)
+
+var publishTplCmd = &cobra.Command{
+	Use:   "publishTpl",
+	Short: "Publish the approved draft of an email template",
+	Long: `Stores the rendered templates of the approved draft of an event as the templates senders
+use, exactly as they were approved, deletes the templates of an approved deletion, then deletes
+the draft templates.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		event, err := cmd.Flags().GetString("event")
+		errorHandler(err)
+		user, err := getUser(cmd)
+		errorHandler(err)
+		wf := newWorkflow()
+		change, err := wf.Publish(user, event)
+		errorHandler(err)
+		if names := change.TemplateNames(); len(names) > 0 {
+			fmt.Printf("published %s\n", strings.Join(names, ", "))
+		}
+		if len(change.Deletes) > 0 {
+			fmt.Printf("deleted %s\n", strings.Join(change.Deletes, ", "))
+		}
+	},
+}
+
+// newWorkflow returns the configured workflow, exiting when it is not enabled.
+func newWorkflow() *workflow.Workflow {
+	mailTpl, err := factory.NewTemplate()
+	errorHandler(err)
+	wf, err := factory.NewWorkflow(mailTpl)
+	errorHandler(err)
+	if wf == nil {
+		errorHandler(errors.New("mail.template.workflow.mongo.uri is not set"))
+	}
+	return wf
+}
+
+func init() {
+	mailCmd.AddCommand(publishTplCmd)
+
+	publishTplCmd.Flags().StringP("event", "e", "", "event of the approved draft")
+	addUserFlag(publishTplCmd)
+}
@@ -2,10 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
 	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mail/preview"
 	"github.com/spf13/cobra"
 )
 
@@ -13,81 +20,267 @@ var sendCmd = &cobra.Command{
 	Use:   "send",
 	Short: "Send an email notification",
 	Long: `Send an email notification using the configured mail provider.
+The rendered subject is printed to stderr before each email is sent, and the sent
+emails are printed to stdout in the format of -o.
+
+With --tpl-file the file is built with the configured layouts and its content is sent
+instead of the stored template, so that a template can be tried before it is applied.
+-n selects the language of a multi-language file.
+
+Template data is merged in this order, later ones win:
+the sample: block of --tpl-file, --data-file (JSON or YAML), then --data.
 
 Example:
   # Send an email with template "welcome_email" in English
-  notifaction mail send --event welcome_email --lang en --to user@example.com --data "name=John"
+  notifaction mail send -n welcome_email_en --to user@example.com --data "name=John"
 
   # Send an email with multiple recipients and data
-  notifaction mail send --event welcome_email --lang en --to user1@example.com --to user2@example.com --data "name=John&age=30"
+  notifaction mail send -n welcome_email_en --to user1@example.com --to user2@example.com --data "name=John&age=30"
+
+  # Send a YAML template file to yourself with its sample data and a data file
+  notifaction mail send --tpl-file templates/welcome_email_en.yaml --to me@example.com --data-file data.yaml
+
+  # Resolve recipients and their languages through the identity service like the HTTP API
+  notifaction mail send -n welcome_email_en --from <sub> --sub <sub> --sub <sub>
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
 		tplname, _ := cmd.Flags().GetString("n")
+		tplFile, _ := cmd.Flags().GetString("tpl-file")
 		to, _ := cmd.Flags().GetStringSlice("to")
+		subs, _ := cmd.Flags().GetStringSlice("sub")
+		from, _ := cmd.Flags().GetString("from")
 		data, _ := cmd.Flags().GetString("data")
-
-		if tplname == "" {
-			return fmt.Errorf("-n is required")
+		dataFile, _ := cmd.Flags().GetString("data-file")
+		if len(subs) > 0 && from == "" {
+			return fmt.Errorf("--from is required with --sub")
 		}
 
-		splitIdx := strings.LastIndex(tplname, "_")
-		if splitIdx == -1 {
-			return fmt.Errorf("invalid template name: %s", tplname)
+		var event, tplLang string
+		var fileTpls *mail.TemplateSet
+		notifyData := map[string]string{}
+		if tplFile != "" {
+			input, err := factory.ReadTemplateFile(tplFile)
+			if err != nil {
+				return err
+			}
+			if fileTpls, err = factory.BuildTemplates(input); err != nil {
+				return err
+			}
+			event = input.Event
+			// a multi-language file only gives the lang when it has a single locale, otherwise -n selects it
+			tpls := input.Expand()
+			if len(tpls) == 1 {
+				tplLang = tpls[0].Lang
+			}
+			sample := input.Sample
+			for _, tpl := range tpls {
+				if tplname == "" || tpl.GetName() == tplname {
+					sample = tpl.Sample
+					break
+				}
+			}
+			mergeData(notifyData, sample)
 		}
-
-		event := tplname[:splitIdx]
-		lang := tplname[splitIdx+1:]
-
-		notification := &service.Notification{
-			Event:  event,
-			Lang:   lang,
-			SendTo: make([]*service.Info, len(to)),
-			Data:   make(map[string]string),
+		if tplname != "" {
+			var err error
+			event, tplLang, err = service.ParseTemplateName(tplname)
+			if err != nil {
+				return err
+			}
 		}
-
-		if len(to) == 0 {
-			return fmt.Errorf("--to is required")
+		if event == "" {
+			return fmt.Errorf("-n or --tpl-file is required")
 		}
 
+		if dataFile != "" {
+			fileData, err := preview.ReadFlatDataFile(dataFile)
+			if err != nil {
+				return err
+			}
+			mergeData(notifyData, fileData)
+		}
 		// Parse data string into map
 		if data != "" {
 			pairs := strings.Split(data, "&")
 			for _, pair := range pairs {
 				keyValue := strings.SplitN(pair, "=", 2)
 				if len(keyValue) == 2 {
-					notification.Data[keyValue[0]] = keyValue[1]
+					mergeData(notifyData, map[string]string{keyValue[0]: keyValue[1]})
 				}
 			}
 		}
 
-		// Create Info objects for recipients
-		for i, email := range to {
-			notification.SendTo[i] = &service.Info{
-				Email: email,
+		cl, err := recipients(event, tplLang, to, from, subs)
+		if err != nil {
+			return err
+		}
+		if cl.From != nil {
+			notifyData["FROM"] = cl.From.Name
+		}
+
+		variables, err := sendVariables(event, fileTpls)
+		if err != nil {
+			return err
+		}
+		if variables != nil {
+			if missing := variables.Missing(notifyData, "TO"); len(missing) > 0 {
+				logf("Warning: missing data: %s", strings.Join(missing, ", "))
 			}
+			variables.ApplyDefaults(notifyData)
 		}
 
-		sender, err := factory.NewApiSender()
+		// the templates of --tpl-file are rendered and sent as they are, not the stored ones
+		var sender mail.ApiSender
+		var mailTpl mail.TemplateReader
+		if fileTpls != nil {
+			sender, err = factory.NewContentSender(fileTpls)
+			mailTpl = fileTpls
+		} else {
+			sender, err = factory.NewApiSender()
+		}
 		if err != nil {
 			return fmt.Errorf("failed to create mail sender: %w", err)
 		}
+		if mailTpl == nil {
+			if mailTpl, err = factory.NewTemplate(); err != nil {
+				return err
+			}
+		}
+
+		results := []*sendResult{}
+		for _, l := range cl.GetLangs() {
+			for _, info := range cl.GetInfos(l) {
+				if info.Sub != "" {
+					notifyData["TO"] = info.Name
+				}
+				notification := &service.Notification{
+					Event:  event,
+					Lang:   l,
+					From:   cl.From,
+					SendTo: []*service.Info{info},
+					Data:   notifyData,
+				}
+				subject, err := renderSubject(mailTpl, notification)
+				if err != nil {
+					return err
+				}
+				logf("To: %s, Subject: %s", info.Email, subject)
+
+				messageId, err := sender.Send(notification)
+				if err != nil {
+					return fmt.Errorf("failed to send email: %w", err)
+				}
+				logf("Email sent successfully. Template: %s, Message ID: %s", notification.TemplateUsed, messageId)
+				results = append(results, &sendResult{
+					To:        info.Email,
+					Lang:      l,
+					Template:  notification.TemplateUsed,
+					Subject:   subject,
+					MessageId: messageId,
+				})
+			}
+		}
+		return printOutput(format, results, func(w io.Writer) error {
+			tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "To\tTemplate\tMessage ID\tSubject")
+			for _, r := range results {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.To, r.Template, r.MessageId, r.Subject)
+			}
+			return tw.Flush()
+		})
+	},
+}
 
-		messageId, err := sender.Send(notification)
+type sendResult struct {
+	To        string `json:"to" yaml:"to"`
+	Lang      string `json:"lang" yaml:"lang"`
+	Template  string `json:"template" yaml:"template"`
+	Subject   string `json:"subject" yaml:"subject"`
+	MessageId string `json:"message_id" yaml:"message_id"`
+}
+
+// mergeData copies src into dst, keys are upper-cased so that later sources override earlier ones.
+func mergeData(dst, src map[string]string) {
+	for k, v := range src {
+		dst[strings.ToUpper(k)] = v
+	}
+}
+
+// recipients returns the recipients grouped by language, resolved through the identity service
+// when subs are given, otherwise the email addresses of to in the language of the template.
+func recipients(event, tplLang string, to []string, from string, subs []string) (*identity.ClassificationLang, error) {
+	if len(subs) > 0 {
+		if len(to) > 0 {
+			return nil, fmt.Errorf("--to and --sub can not be used together")
+		}
+		idService, err := identity.NewIdentity()
+		if err != nil {
+			return nil, err
+		}
+		cl, err := idService.SubToInfo(from, subs)
 		if err != nil {
-			return fmt.Errorf("failed to send email: %w", err)
+			return nil, err
+		}
+		if len(cl.GetLangs()) == 0 {
+			return nil, fmt.Errorf("no recipients found for %s", strings.Join(subs, ", "))
 		}
+		return cl, nil
+	}
+	if len(to) == 0 {
+		return nil, fmt.Errorf("--to or --sub is required")
+	}
+	if tplLang == "" {
+		return nil, fmt.Errorf("-n is required to choose the language of %s", event)
+	}
+	infos := map[string][]*service.Info{}
+	for _, email := range to {
+		infos[tplLang] = append(infos[tplLang], &service.Info{Email: email})
+	}
+	return identity.NewClassificationLang(
+		identity.WithClassificationLang(infos),
+		identity.WithClassificationLangKeys([]string{tplLang}),
+	), nil
+}
 
-		fmt.Printf("Email sent successfully. Message ID: %s\n", messageId)
-		return nil
-	},
+// sendVariables returns the variables of the templates of a file, otherwise those of the variable store if any.
+func sendVariables(event string, fileTpls *mail.TemplateSet) (dao.Variables, error) {
+	if fileTpls != nil {
+		return fileTpls.Variables(), nil
+	}
+	varStore, err := factory.NewVariableStore()
+	if err != nil || varStore == nil {
+		return nil, err
+	}
+	return varStore.Get(event)
+}
+
+// renderSubject renders the subject of the template the sender will use, including the language fallback.
+func renderSubject(tpl mail.TemplateReader, notify *service.Notification) (string, error) {
+	name, _, err := mail.ResolveTemplate(lang.NewResolver(), tpl.IsTemplateExist, notify.Event, notify.Lang)
+	if err != nil {
+		return "", err
+	}
+	detail, err := tpl.Detail(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get template detail: %w", err)
+	}
+	mail.Render(detail, notify.Data)
+	return detail.Subject, nil
 }
 
 func init() {
 	mailCmd.AddCommand(sendCmd)
 
 	sendCmd.Flags().String("n", "", "Template name")
+	sendCmd.Flags().String("tpl-file", "", "Template file (YAML) to render and send instead of the stored template, its sample data is used")
 	sendCmd.Flags().StringSlice("to", []string{}, "Recipient email address (can be specified multiple times)")
+	sendCmd.Flags().StringSlice("sub", []string{}, "Recipient identity id, resolved through the identity service (can be specified multiple times)")
+	sendCmd.Flags().String("from", "", "Sender identity id, used with --sub")
 	sendCmd.Flags().String("data", "", "Template data in key=value format (multiple values can be separated by &)\nExample: name=John&age=30")
-	sendCmd.MarkFlagRequired("n")
-	sendCmd.MarkFlagRequired("to")
+	sendCmd.Flags().String("data-file", "", "Template data file (JSON or YAML)")
+	addOutputFlag(sendCmd)
 }
@@ -9,6 +9,8 @@ import (
 
 	"github.com/94peter/microservice"
 	"github.com/arwoosa/notifaction/router"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/mail/factory"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -23,6 +25,22 @@ Additionally, it can run a test API for local development to simulate API reques
 	Run: func(cmd *cobra.Command, args []string) {
 		showInfo()
 		fmt.Println("serve called", viper.GetString("service"))
+		if viper.GetBool("mail.template.cache.warmup") {
+			// a failed warm-up only costs the first sends their lookups, so the service still starts
+			n, err := factory.WarmTemplateCache()
+			if err != nil {
+				log.Printf("failed to warm up the template cache: %v", err)
+			} else {
+				log.Printf("template cache warmed up with %d template(s)", n)
+			}
+		}
+		// deliveries retried by a process that stopped would otherwise stay pending forever
+		n, err := channelFactory.ResumeWebhookDeliveries()
+		if err != nil {
+			log.Printf("failed to resume webhook deliveries: %v", err)
+		} else if n > 0 {
+			log.Printf("resumed %d webhook delivery(ies)", n)
+		}
 		apiServ, err := microservice.NewApiWithViper(microservice.WithAPI(router.GetApis()...))
 		if err != nil {
 			log.Fatal(err)
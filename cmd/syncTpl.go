@@ -0,0 +1,88 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// syncTplCmd represents the syncTpl command
+var syncTplCmd = &cobra.Command{
+	Use:   "syncTpl",
+	Short: "Apply every email template YAML file of a directory",
+	Long: `Applies every template YAML file found under a directory, the same way applyTpl
+applies a single file. Layouts and partials of mail.template.layouts.dir are resolved
+before the templates are pushed, files inside that directory are skipped.
+Failed files are reported and the command exits with a non-zero code once all files are processed.
+When mail.template.workflow.mongo.uri is set every file is stored as a draft, see applyTpl.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := cmd.Flags().GetString("dir")
+		errorHandler(err)
+		if dir == "" {
+			fmt.Println("dir is required")
+			os.Exit(1)
+		}
+		files, err := factory.ListTemplateFiles(dir)
+		errorHandler(err)
+		mailTpl, err := factory.NewTemplate()
+		errorHandler(err)
+		wf, err := factory.NewWorkflow(mailTpl)
+		errorHandler(err)
+		apply := mailTpl.Apply
+		if wf != nil {
+			user, err := getUser(cmd)
+			errorHandler(err)
+			apply = func(file string) error {
+				input, err := factory.ReadTemplateFile(file)
+				if err != nil {
+					return err
+				}
+				_, err = wf.Draft(user, input)
+				return err
+			}
+		}
+
+		layoutDir := viper.GetString("mail.template.layouts.dir")
+		if layoutDir != "" {
+			layoutDir, err = filepath.Abs(layoutDir)
+			errorHandler(err)
+		}
+		failed := 0
+		for _, file := range files {
+			absFile, err := filepath.Abs(file)
+			errorHandler(err)
+			if layoutDir != "" && strings.HasPrefix(absFile, layoutDir+string(filepath.Separator)) {
+				continue
+			}
+			if err := apply(file); err != nil {
+				failed++
+				fmt.Printf("%s: %v\n", file, err)
+				continue
+			}
+			if wf != nil {
+				fmt.Printf("%s: drafted\n", file)
+				continue
+			}
+			fmt.Printf("%s: applied\n", file)
+		}
+		fmt.Printf("%d file(s), %d failed\n", len(files), failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	mailCmd.AddCommand(syncTplCmd)
+
+	syncTplCmd.Flags().StringP("dir", "d", "", "directory of template files (YAML)")
+	addUserFlag(syncTplCmd)
+}
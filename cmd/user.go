@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/mail/workflow"
+	"github.com/spf13/cobra"
+)
+
+// sessionTokenEnv holds the identity session token when --session-token is not set.
+const sessionTokenEnv = "NOTIFACTION_SESSION_TOKEN"
+
+// addUserFlag adds the --session-token flag, the identity session of who drafts, approves or publishes a template.
+func addUserFlag(cmd *cobra.Command) {
+	cmd.Flags().String("session-token", "", "identity session token of the user recorded in the audit trail (default $"+sessionTokenEnv+")")
+}
+
+// getUser returns the user of the identity session of --session-token, see workflow.UserOf.
+func getUser(cmd *cobra.Command) (string, error) {
+	token, err := cmd.Flags().GetString("session-token")
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		token = os.Getenv(sessionTokenEnv)
+	}
+	if token == "" {
+		return "", errors.New("a session token is required, set --session-token or " + sessionTokenEnv)
+	}
+	id, err := identity.NewIdentity()
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{}
+	header.Set("X-Session-Token", token)
+	info, err := id.Whoami(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate the session: %w", err)
+	}
+	return workflow.UserOf(info)
+}
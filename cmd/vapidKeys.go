@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/arwoosa/notifaction/service/webpush"
+	"github.com/spf13/cobra"
+)
+
+type vapidKeysOutput struct {
+	PrivateKey string `json:"private_key" yaml:"private_key"`
+	PublicKey  string `json:"public_key" yaml:"public_key"`
+}
+
+var vapidKeysCmd = &cobra.Command{
+	Use:   "vapidKeys",
+	Short: "Generate the VAPID key pair of web push",
+	Long: `Generates a P-256 key pair identifying the service to browser push services.
+The private key goes to webpush.vapid.private_key, browsers subscribe with the public key,
+which /push/public-key also serves. Changing the keys invalidates every subscription.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := getOutputFormat(cmd)
+		errorHandler(err)
+		private, public, err := webpush.GenerateVapidKeys()
+		errorHandler(err)
+		output := &vapidKeysOutput{PrivateKey: private, PublicKey: public}
+		errorHandler(printOutput(format, output, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "private key: %s\npublic key:  %s\n", output.PrivateKey, output.PublicKey)
+			return err
+		}))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(vapidKeysCmd)
+	addOutputFlag(vapidKeysCmd)
+}
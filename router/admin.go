@@ -0,0 +1,37 @@
+package router
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/94peter/microservice/apitool/err"
+	"github.com/gin-gonic/gin"
+)
+
+// adminAuth lets requests with one of the bearer tokens of api.admin.tokens through to handler.
+func adminAuth(h *err.CommonErrorHandler, tokens []string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || !isAdminToken(tokens, token) {
+			h.GinErrorWithStatusHandler(c, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+		handler(c)
+	}
+}
+
+func isAdminToken(tokens []string, token string) bool {
+	if token == "" {
+		return false
+	}
+	valid := false
+	for _, t := range tokens {
+		// every token is compared so that the response time does not tell which one matched
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
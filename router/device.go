@@ -0,0 +1,113 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/94peter/microservice/apitool"
+	"github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/router/request"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/mobilepush"
+	"github.com/gin-gonic/gin"
+)
+
+// deviceApi registers the mobile push tokens of the user of the identity session of the request.
+type deviceApi struct {
+	err.CommonErrorHandler
+}
+
+func (m *deviceApi) GetHandlers() []*apitool.GinHandler {
+	return []*apitool.GinHandler{
+		{
+			Path:    "/push/devices",
+			Method:  "POST",
+			Handler: m.register,
+		},
+		{
+			Path:    "/push/devices",
+			Method:  "DELETE",
+			Handler: m.unregister,
+		},
+	}
+}
+
+func (m *deviceApi) store(c *gin.Context) (mobilepush.Store, bool) {
+	store, err := channelFactory.NewDeviceStore()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, false
+	}
+	if store == nil {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, errors.New("mobilepush is not enabled"))
+		return nil, false
+	}
+	return store, true
+}
+
+// register adds the device, apps call it on every launch so that last_seen stays current.
+func (m *deviceApi) register(c *gin.Context) {
+	sub, ok := sessionSub(c, &m.CommonErrorHandler)
+	if !ok {
+		return
+	}
+	var body request.RegisterDevice
+	if err := c.BindJSON(&body); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	now := time.Now()
+	device := &mobilepush.Device{
+		Sub:        sub,
+		Platform:   body.Platform,
+		Token:      body.Token,
+		AppVersion: body.AppVersion,
+		LastSeen:   now,
+		CreatedAt:  now,
+	}
+	if err := device.Validate(); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, err)
+		return
+	}
+	store, ok := m.store(c)
+	if !ok {
+		return
+	}
+	if err := store.Register(device); err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"platform": device.Platform, "token": device.Token})
+}
+
+func (m *deviceApi) unregister(c *gin.Context) {
+	sub, ok := sessionSub(c, &m.CommonErrorHandler)
+	if !ok {
+		return
+	}
+	var body request.DeleteDevice
+	if err := c.BindJSON(&body); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if err := body.Validate(); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, err)
+		return
+	}
+	store, ok := m.store(c)
+	if !ok {
+		return
+	}
+	err := store.Delete(sub, body.Platform, body.Token)
+	if errors.Is(err, mobilepush.ErrNotFound) {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
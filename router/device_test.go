@@ -0,0 +1,136 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiErr "github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/service"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/mobilepush"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDevice() *gin.Engine {
+	m := &deviceApi{}
+	m.SetErrorHandler(func(c *gin.Context, err error) {
+		status := http.StatusInternalServerError
+		if apiErr, ok := err.(apiErr.ApiError); ok {
+			status = apiErr.GetStatus()
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+	})
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	for _, h := range m.GetHandlers() {
+		engine.Handle(h.Method, h.Path, h.Handler)
+	}
+	return engine
+}
+
+func TestPushDevices(t *testing.T) {
+	defer identity.ResetMock()
+	defer channelFactory.ResetMockDeviceStore()
+
+	identity.SetMockWhoamiFunc(func(header http.Header) (*service.Info, error) {
+		if header.Get("Cookie") == "" {
+			return nil, identity.ErrUnauthorized
+		}
+		return &service.Info{Sub: header.Get("Cookie")}, nil
+	})
+	store := mobilepush.NewMockStore()
+	channelFactory.SetMockDeviceStore(store)
+	engine := newTestDevice()
+
+	steps := []struct {
+		name       string
+		method     string
+		user       string
+		body       string
+		statusCode int
+		contains   string
+		check      func(t *testing.T)
+	}{
+		{
+			name:       "no session",
+			method:     "POST",
+			body:       `{"platform":"ios","token":"t1"}`,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid body",
+			method:     "POST",
+			user:       "bob",
+			body:       `{`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown platform",
+			method:     "POST",
+			user:       "bob",
+			body:       `{"platform":"web","token":"t1"}`,
+			statusCode: http.StatusBadRequest,
+			contains:   "invalid platform: web",
+		},
+		{
+			name:       "register",
+			method:     "POST",
+			user:       "bob",
+			body:       `{"platform":"ios","token":"t1","app_version":"2.3.0"}`,
+			statusCode: http.StatusCreated,
+			check: func(t *testing.T) {
+				devices, _ := store.List("bob")
+				assert.Len(t, devices, 1)
+				assert.Equal(t, "2.3.0", devices[0].AppVersion)
+				assert.False(t, devices[0].LastSeen.IsZero())
+			},
+		},
+		{
+			name:       "unregister of another user",
+			method:     "DELETE",
+			user:       "alice",
+			body:       `{"platform":"ios","token":"t1"}`,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "unregister without token",
+			method:     "DELETE",
+			user:       "bob",
+			body:       `{"platform":"ios"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "unregister",
+			method:     "DELETE",
+			user:       "bob",
+			body:       `{"platform":"ios","token":"t1"}`,
+			statusCode: http.StatusNoContent,
+			check: func(t *testing.T) {
+				devices, _ := store.List("bob")
+				assert.Empty(t, devices)
+			},
+		},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(step.method, "/push/devices", strings.NewReader(step.body))
+			req.Header.Set("Content-Type", "application/json")
+			if step.user != "" {
+				req.Header.Set("Cookie", step.user)
+			}
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, step.statusCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), step.contains)
+			if step.check != nil {
+				step.check(t)
+			}
+		})
+	}
+}
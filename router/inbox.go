@@ -0,0 +1,167 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/94peter/microservice/apitool"
+	"github.com/94peter/microservice/apitool/err"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/inbox"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// inboxApi serves the in-app notifications of the user of the identity session of the request.
+type inboxApi struct {
+	err.CommonErrorHandler
+	heartbeat      time.Duration
+	allowedOrigins []string
+}
+
+func newInboxApi() *inboxApi {
+	return &inboxApi{
+		heartbeat:      viper.GetDuration("inbox.stream.heartbeat"),
+		allowedOrigins: viper.GetStringSlice("inbox.stream.allowed_origins"),
+	}
+}
+
+func (m *inboxApi) GetHandlers() []*apitool.GinHandler {
+	return []*apitool.GinHandler{
+		{
+			Path:    "/inbox",
+			Method:  "GET",
+			Handler: m.auth(m.listInbox),
+		},
+		{
+			Path:    "/inbox/unread",
+			Method:  "GET",
+			Handler: m.auth(m.unreadCount),
+		},
+		{
+			Path:    "/inbox/stream",
+			Method:  "GET",
+			Handler: m.auth(m.streamSSE),
+		},
+		{
+			Path:    "/inbox/ws",
+			Method:  "GET",
+			Handler: m.auth(m.streamWebSocket),
+		},
+		{
+			Path:    "/inbox/read",
+			Method:  "POST",
+			Handler: m.auth(m.markAllRead),
+		},
+		{
+			Path:    "/inbox/:id/read",
+			Method:  "POST",
+			Handler: m.auth(m.markRead),
+		},
+		{
+			Path:    "/inbox/:id",
+			Method:  "DELETE",
+			Handler: m.auth(m.deleteNotification),
+		},
+	}
+}
+
+type inboxHandler func(c *gin.Context, store inbox.Store, sub string)
+
+// auth resolves the sub of the caller and the inbox store before calling handler.
+func (m *inboxApi) auth(handler inboxHandler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sub, ok := sessionSub(c, &m.CommonErrorHandler)
+		if !ok {
+			return
+		}
+		store, err := channelFactory.NewInboxStore()
+		if err != nil {
+			m.GinErrorHandler(c, err)
+			return
+		}
+		if store == nil {
+			m.GinErrorWithStatusHandler(c, http.StatusNotFound, errors.New("inbox is not enabled"))
+			return
+		}
+		handler(c, store, sub)
+	}
+}
+
+func (m *inboxApi) storeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, inbox.ErrNotFound):
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, err)
+	case errors.Is(err, inbox.ErrInvalidCursor):
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, err)
+	default:
+		m.GinErrorHandler(c, err)
+	}
+}
+
+// listInbox returns a page of notifications, newest first, and the unread count.
+// The cursor query is the next_cursor of the previous page.
+func (m *inboxApi) listInbox(c *gin.Context, store inbox.Store, sub string) {
+	limit := 0
+	if l := c.Query("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil {
+			m.GinErrorWithStatusHandler(c, http.StatusBadRequest, errors.New("invalid limit: "+l))
+			return
+		}
+	}
+	page, err := store.List(sub, c.Query("cursor"), limit)
+	if err != nil {
+		m.storeError(c, err)
+		return
+	}
+	unread, err := store.Unread(sub)
+	if err != nil {
+		m.storeError(c, err)
+		return
+	}
+	resp := gin.H{
+		"notifications": page.Messages,
+		"unread":        unread,
+	}
+	if page.NextCursor != "" {
+		resp["next_cursor"] = page.NextCursor
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (m *inboxApi) unreadCount(c *gin.Context, store inbox.Store, sub string) {
+	unread, err := store.Unread(sub)
+	if err != nil {
+		m.storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": unread})
+}
+
+func (m *inboxApi) markAllRead(c *gin.Context, store inbox.Store, sub string) {
+	updated, err := store.MarkAllRead(sub)
+	if err != nil {
+		m.storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+func (m *inboxApi) markRead(c *gin.Context, store inbox.Store, sub string) {
+	if err := store.MarkRead(sub, c.Param("id")); err != nil {
+		m.storeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (m *inboxApi) deleteNotification(c *gin.Context, store inbox.Store, sub string) {
+	if err := store.Delete(sub, c.Param("id")); err != nil {
+		m.storeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,207 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/inbox"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	streamEvent      = "notification"
+	defaultHeartbeat = 25 * time.Second
+)
+
+// inboxStream forwards the messages published to a sub, after replaying the stored messages
+// newer than the last event id of a resuming client.
+type inboxStream struct {
+	store        inbox.Store
+	sub          string
+	subscription *inbox.Subscription
+	replay       []*inbox.Message
+	// replayed messages may be published again while they are replayed
+	replayed map[primitive.ObjectID]bool
+}
+
+// openStream subscribes before reading the replay, so that no message is missed in between.
+func openStream(store inbox.Store, sub, lastId string) (*inboxStream, error) {
+	broker, err := channelFactory.NewInboxBroker()
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := broker.Subscribe(sub)
+	if err != nil {
+		return nil, err
+	}
+	s := &inboxStream{store: store, sub: sub, subscription: subscription, replayed: map[primitive.ObjectID]bool{}}
+	if lastId != "" {
+		if s.replay, err = store.ListAfter(sub, lastId, inbox.MaxLimit); err != nil {
+			subscription.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *inboxStream) Close() {
+	s.subscription.Close()
+}
+
+// run sends messages until ctx is done, sending fails or the subscription falls behind,
+// in which case the client resumes with its last event id.
+func (s *inboxStream) run(ctx context.Context, heartbeat time.Duration, send func(*inbox.Message) error, ping func() error) error {
+	for len(s.replay) > 0 {
+		for _, msg := range s.replay {
+			if err := send(msg); err != nil {
+				return err
+			}
+			s.replayed[msg.ID] = true
+		}
+		if len(s.replay) < inbox.MaxLimit {
+			break
+		}
+		var err error
+		if s.replay, err = s.store.ListAfter(s.sub, s.replay[len(s.replay)-1].ID.Hex(), inbox.MaxLimit); err != nil {
+			return err
+		}
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-s.subscription.C:
+			if !ok {
+				return errors.New("subscription closed")
+			}
+			if s.replayed[msg.ID] {
+				continue
+			}
+			if err := send(msg); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamSSE streams the inbox as server-sent events whose id is the notification id,
+// EventSource resumes with the Last-Event-ID header, other clients may use the last_event_id query.
+func (m *inboxApi) streamSSE(c *gin.Context, store inbox.Store, sub string) {
+	lastId := c.GetHeader("Last-Event-ID")
+	if lastId == "" {
+		lastId = c.Query("last_event_id")
+	}
+	stream, err := openStream(store, sub, lastId)
+	if err != nil {
+		m.storeError(c, err)
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// disables the buffering of nginx
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	w := c.Writer
+	_ = stream.run(c.Request.Context(), m.getHeartbeat(), func(msg *inbox.Message) error {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", msg.ID.Hex(), streamEvent, data); err != nil {
+			return err
+		}
+		w.Flush()
+		return nil
+	}, func() error {
+		if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+			return err
+		}
+		w.Flush()
+		return nil
+	})
+}
+
+type wsFrame struct {
+	Type         string         `json:"type"`
+	ID           string         `json:"id,omitempty"`
+	Notification *inbox.Message `json:"notification,omitempty"`
+}
+
+// streamWebSocket streams the inbox as json frames of type notification or ping,
+// clients resume with the last_event_id query.
+func (m *inboxApi) streamWebSocket(c *gin.Context, store inbox.Store, sub string) {
+	stream, err := openStream(store, sub, c.Query("last_event_id"))
+	if err != nil {
+		m.storeError(c, err)
+		return
+	}
+	defer stream.Close()
+
+	server := websocket.Server{
+		Handshake: m.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+			go func() {
+				// the client sends nothing, reading tells when it goes away
+				_, _ = io.Copy(io.Discard, ws)
+				cancel()
+			}()
+			_ = stream.run(ctx, m.getHeartbeat(), func(msg *inbox.Message) error {
+				return websocket.JSON.Send(ws, &wsFrame{Type: streamEvent, ID: msg.ID.Hex(), Notification: msg})
+			}, func() error {
+				return websocket.JSON.Send(ws, &wsFrame{Type: "ping"})
+			})
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkOrigin refuses cross-site websockets, which would otherwise be authenticated by the session
+// cookie of the browser. Origins are those of inbox.stream.allowed_origins or the host of the api.
+func (m *inboxApi) checkOrigin(config *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		// not a browser
+		return nil
+	}
+	if len(m.allowedOrigins) > 0 {
+		if slices.Contains(m.allowedOrigins, origin) {
+			return nil
+		}
+		return fmt.Errorf("origin not allowed: %s", origin)
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != req.Host {
+		return fmt.Errorf("origin not allowed: %s", origin)
+	}
+	return nil
+}
+
+func (m *inboxApi) getHeartbeat() time.Duration {
+	if m.heartbeat <= 0 {
+		return defaultHeartbeat
+	}
+	return m.heartbeat
+}
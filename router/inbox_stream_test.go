@@ -0,0 +1,175 @@
+package router
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/inbox"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+func setupInboxStream(t *testing.T, heartbeat time.Duration) (*httptest.Server, inbox.Store, []string) {
+	identity.SetMockWhoamiFunc(func(header http.Header) (*service.Info, error) {
+		if header.Get("Cookie") == "" {
+			return nil, identity.ErrUnauthorized
+		}
+		return &service.Info{Sub: header.Get("Cookie")}, nil
+	})
+	store := inbox.NewMockStore(nil)
+	ids := []string{}
+	for _, title := range []string{"first", "second", "third"} {
+		msg := &inbox.Message{Sub: "alice", Title: title}
+		assert.NoError(t, store.Insert(msg))
+		ids = append(ids, msg.ID.Hex())
+	}
+	channelFactory.SetMockInboxStore(store)
+	channelFactory.ResetInboxBroker()
+
+	server := httptest.NewServer(newTestInbox(&inboxApi{heartbeat: heartbeat}))
+	t.Cleanup(func() {
+		server.Close()
+		identity.ResetMock()
+		channelFactory.ResetMockInboxStore()
+		channelFactory.ResetInboxBroker()
+	})
+	return server, store, ids
+}
+
+// publish stores and publishes a message like the inbox sender.
+func publish(t *testing.T, store inbox.Store, msg *inbox.Message) {
+	assert.NoError(t, store.Insert(msg))
+	broker, err := channelFactory.NewInboxBroker()
+	assert.NoError(t, err)
+	assert.NoError(t, broker.Publish(msg))
+}
+
+type sseEvent struct {
+	id, event, data, comment string
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) *sseEvent {
+	e := &sseEvent{}
+	for {
+		line, err := r.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return e
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return e
+		case strings.HasPrefix(line, ":"):
+			e.comment = strings.TrimSpace(line[1:])
+		case strings.HasPrefix(line, "id: "):
+			e.id = line[len("id: "):]
+		case strings.HasPrefix(line, "event: "):
+			e.event = line[len("event: "):]
+		case strings.HasPrefix(line, "data: "):
+			e.data = line[len("data: "):]
+		}
+	}
+}
+
+func TestInboxSSE(t *testing.T) {
+	server, store, ids := setupInboxStream(t, time.Hour)
+
+	req, _ := http.NewRequest("GET", server.URL+"/inbox/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req.Header.Set("Cookie", "alice")
+	req.Header.Set("Last-Event-ID", "nope")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// resumes after the first message, then receives new ones
+	req.Header.Set("Last-Event-ID", ids[0])
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+
+	for _, id := range ids[1:] {
+		e := readEvent(t, r)
+		assert.Equal(t, id, e.id)
+		assert.Equal(t, "notification", e.event)
+	}
+	publish(t, store, &inbox.Message{Sub: "bob", Title: "not alice's"})
+	publish(t, store, &inbox.Message{Sub: "alice", Title: "live"})
+	e := readEvent(t, r)
+	msg := &inbox.Message{}
+	assert.NoError(t, json.Unmarshal([]byte(e.data), msg))
+	assert.Equal(t, "live", msg.Title)
+	assert.Equal(t, msg.ID.Hex(), e.id)
+}
+
+func TestInboxSSEHeartbeat(t *testing.T) {
+	server, _, _ := setupInboxStream(t, 10*time.Millisecond)
+	req, _ := http.NewRequest("GET", server.URL+"/inbox/stream", nil)
+	req.Header.Set("Cookie", "alice")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	e := readEvent(t, bufio.NewReader(resp.Body))
+	assert.Equal(t, "ping", e.comment)
+}
+
+func dialInbox(server *httptest.Server, query, origin, user string) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(strings.Replace(server.URL, "http", "ws", 1)+"/inbox/ws"+query, origin)
+	if err != nil {
+		return nil, err
+	}
+	config.Header.Set("Cookie", user)
+	return websocket.DialConfig(config)
+}
+
+func TestInboxWebSocket(t *testing.T) {
+	server, store, ids := setupInboxStream(t, time.Hour)
+
+	_, err := dialInbox(server, "", "https://evil.example.com", "alice")
+	assert.Error(t, err)
+	_, err = dialInbox(server, "", server.URL, "")
+	assert.Error(t, err)
+
+	ws, err := dialInbox(server, "?last_event_id="+ids[1], server.URL, "alice")
+	assert.NoError(t, err)
+	defer ws.Close()
+
+	frame := &wsFrame{}
+	assert.NoError(t, websocket.JSON.Receive(ws, frame))
+	assert.Equal(t, "notification", frame.Type)
+	assert.Equal(t, ids[2], frame.ID)
+	assert.Equal(t, "third", frame.Notification.Title)
+
+	publish(t, store, &inbox.Message{Sub: "alice", Title: "live"})
+	assert.NoError(t, websocket.JSON.Receive(ws, frame))
+	assert.Equal(t, "live", frame.Notification.Title)
+}
+
+func TestInboxWebSocketAllowedOrigins(t *testing.T) {
+	m := &inboxApi{allowedOrigins: []string{"https://app.example.com"}}
+	req := httptest.NewRequest("GET", "http://api.example.com/inbox/ws", nil)
+	assert.NoError(t, m.checkOrigin(nil, req))
+	req.Header.Set("Origin", "https://app.example.com")
+	assert.NoError(t, m.checkOrigin(nil, req))
+	req.Header.Set("Origin", "http://api.example.com")
+	assert.EqualError(t, m.checkOrigin(nil, req), "origin not allowed: http://api.example.com")
+
+	m = &inboxApi{}
+	assert.NoError(t, m.checkOrigin(nil, req))
+}
@@ -0,0 +1,247 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apiErr "github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/service"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/inbox"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestInbox(m *inboxApi) *gin.Engine {
+	m.SetErrorHandler(func(c *gin.Context, err error) {
+		status := http.StatusInternalServerError
+		if apiErr, ok := err.(apiErr.ApiError); ok {
+			status = apiErr.GetStatus()
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+	})
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	for _, h := range m.GetHandlers() {
+		engine.Handle(h.Method, h.Path, h.Handler)
+	}
+	return engine
+}
+
+func TestInbox(t *testing.T) {
+	defer identity.ResetMock()
+	defer channelFactory.ResetMockInboxStore()
+
+	identity.SetMockWhoamiFunc(func(header http.Header) (*service.Info, error) {
+		if header.Get("Cookie") == "" {
+			return nil, identity.ErrUnauthorized
+		}
+		return &service.Info{Sub: header.Get("Cookie")}, nil
+	})
+	store := inbox.NewMockStore(func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) })
+	ids := []string{}
+	for _, title := range []string{"first", "second", "third"} {
+		msg := &inbox.Message{Sub: "alice", Event: "EVENT_JOIN", Title: title}
+		assert.NoError(t, store.Insert(msg))
+		ids = append(ids, msg.ID.Hex())
+	}
+	assert.NoError(t, store.Insert(&inbox.Message{Sub: "bob", Title: "bob's"}))
+	channelFactory.SetMockInboxStore(store)
+	engine := newTestInbox(&inboxApi{})
+
+	type page struct {
+		Notifications []*inbox.Message `json:"notifications"`
+		NextCursor    string           `json:"next_cursor"`
+		Unread        int64            `json:"unread"`
+	}
+	titles := func(p *page) []string {
+		result := []string{}
+		for _, m := range p.Notifications {
+			result = append(result, m.Title)
+		}
+		return result
+	}
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		user       string
+		statusCode int
+		check      func(t *testing.T, body []byte)
+	}{
+		{
+			name:       "no session",
+			method:     "GET",
+			path:       "/inbox",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "first page",
+			method:     "GET",
+			path:       "/inbox?limit=2",
+			user:       "alice",
+			statusCode: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				p := &page{}
+				assert.NoError(t, json.Unmarshal(body, p))
+				assert.Equal(t, []string{"third", "second"}, titles(p))
+				assert.Equal(t, ids[1], p.NextCursor)
+				assert.Equal(t, int64(3), p.Unread)
+			},
+		},
+		{
+			name:       "last page",
+			method:     "GET",
+			path:       "/inbox?limit=2&cursor=" + ids[1],
+			user:       "alice",
+			statusCode: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				p := &page{}
+				assert.NoError(t, json.Unmarshal(body, p))
+				assert.Equal(t, []string{"first"}, titles(p))
+				assert.Empty(t, p.NextCursor)
+			},
+		},
+		{
+			name:       "invalid cursor",
+			method:     "GET",
+			path:       "/inbox?cursor=nope",
+			user:       "alice",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid limit",
+			method:     "GET",
+			path:       "/inbox?limit=ten",
+			user:       "alice",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "mark read",
+			method:     "POST",
+			path:       "/inbox/" + ids[0] + "/read",
+			user:       "alice",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "mark read of another user",
+			method:     "POST",
+			path:       "/inbox/" + ids[1] + "/read",
+			user:       "bob",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "unread after mark read",
+			method:     "GET",
+			path:       "/inbox/unread",
+			user:       "alice",
+			statusCode: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				assert.JSONEq(t, `{"unread":2}`, string(body))
+			},
+		},
+		{
+			name:       "mark all read",
+			method:     "POST",
+			path:       "/inbox/read",
+			user:       "alice",
+			statusCode: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				assert.JSONEq(t, `{"updated":2}`, string(body))
+			},
+		},
+		{
+			name:       "read_at is set",
+			method:     "GET",
+			path:       "/inbox",
+			user:       "alice",
+			statusCode: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				p := &page{}
+				assert.NoError(t, json.Unmarshal(body, p))
+				assert.Equal(t, int64(0), p.Unread)
+				for _, m := range p.Notifications {
+					assert.NotNil(t, m.ReadAt)
+				}
+			},
+		},
+		{
+			name:       "delete",
+			method:     "DELETE",
+			path:       "/inbox/" + ids[2],
+			user:       "alice",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "delete again",
+			method:     "DELETE",
+			path:       "/inbox/" + ids[2],
+			user:       "alice",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "delete invalid id",
+			method:     "DELETE",
+			path:       "/inbox/nope",
+			user:       "alice",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "other inbox is untouched",
+			method:     "GET",
+			path:       "/inbox",
+			user:       "bob",
+			statusCode: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				p := &page{}
+				assert.NoError(t, json.Unmarshal(body, p))
+				assert.Equal(t, []string{"bob's"}, titles(p))
+				assert.Equal(t, int64(1), p.Unread)
+			},
+		},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(step.method, step.path, nil)
+			if step.user != "" {
+				req.Header.Set("Cookie", step.user)
+			}
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, step.statusCode, w.Code, w.Body.String())
+			if step.check != nil {
+				step.check(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestInboxErrors(t *testing.T) {
+	defer identity.ResetMock()
+	defer channelFactory.ResetMockInboxStore()
+	engine := newTestInbox(&inboxApi{})
+
+	identity.SetMockWhoamiFunc(func(header http.Header) (*service.Info, error) {
+		return nil, errors.New("kratos down")
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/inbox", nil)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	identity.SetMockWhoamiFunc(func(header http.Header) (*service.Info, error) {
+		return &service.Info{Sub: "alice"}, nil
+	})
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"inbox is not enabled"}`, w.Body.String())
+}
@@ -0,0 +1,204 @@
+package router
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/94peter/microservice/apitool"
+	"github.com/94peter/microservice/apitool/err"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/line"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// lineStateCookie binds the state of a LINE Login redirect to the browser that started it.
+const lineStateCookie = "line_link_state"
+
+// lineApi links the user of the identity session of the request to a LINE user with LINE Login,
+// and receives the webhook events of the official account.
+type lineApi struct {
+	err.CommonErrorHandler
+	channelSecret string
+	doneUrl       string
+}
+
+func newLineApi() *lineApi {
+	return &lineApi{
+		channelSecret: viper.GetString("line.messaging.channel_secret"),
+		doneUrl:       viper.GetString("line.login.done_url"),
+	}
+}
+
+func (m *lineApi) GetHandlers() []*apitool.GinHandler {
+	return []*apitool.GinHandler{
+		{
+			Path:    "/line/link",
+			Method:  "GET",
+			Handler: m.link,
+		},
+		{
+			Path:    "/line/link",
+			Method:  "DELETE",
+			Handler: m.unlink,
+		},
+		{
+			Path:    "/line/callback",
+			Method:  "GET",
+			Handler: m.callback,
+		},
+		{
+			Path:    "/line/webhook",
+			Method:  "POST",
+			Handler: m.webhook,
+		},
+	}
+}
+
+func (m *lineApi) store(c *gin.Context) (line.Store, bool) {
+	store, err := channelFactory.NewLineStore()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, false
+	}
+	if store == nil {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, errors.New("line link is not enabled"))
+		return nil, false
+	}
+	return store, true
+}
+
+func (m *lineApi) login(c *gin.Context) (*line.Login, bool) {
+	login, err := channelFactory.NewLineLogin()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, false
+	}
+	if login == nil {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, errors.New("line login is not enabled"))
+		return nil, false
+	}
+	return login, true
+}
+
+// link redirects the browser to LINE Login, the callback links the LINE user who consents.
+func (m *lineApi) link(c *gin.Context) {
+	if _, ok := sessionSub(c, &m.CommonErrorHandler); !ok {
+		return
+	}
+	if _, ok := m.store(c); !ok {
+		return
+	}
+	login, ok := m.login(c)
+	if !ok {
+		return
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	state := base64.RawURLEncoding.EncodeToString(b)
+	// lax, the callback is a top level navigation from access.line.me
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(lineStateCookie, state, int((10 * time.Minute).Seconds()), "/line", "", true, true)
+	c.Redirect(http.StatusFound, login.AuthorizeUrl(state))
+}
+
+func (m *lineApi) callback(c *gin.Context) {
+	state, _ := c.Cookie(lineStateCookie)
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, errors.New("invalid state"))
+		return
+	}
+	c.SetCookie(lineStateCookie, "", -1, "/line", "", true, true)
+	if e := c.Query("error"); e != "" {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("line login failed: %s %s", e, c.Query("error_description")))
+		return
+	}
+	sub, ok := sessionSub(c, &m.CommonErrorHandler)
+	if !ok {
+		return
+	}
+	store, ok := m.store(c)
+	if !ok {
+		return
+	}
+	login, ok := m.login(c)
+	if !ok {
+		return
+	}
+	userId, err := login.UserId(c.Query("code"))
+	if err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadGateway, err)
+		return
+	}
+	if err := store.Link(&line.Link{Sub: sub, UserId: userId, CreatedAt: time.Now()}); err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	if m.doneUrl != "" {
+		c.Redirect(http.StatusFound, m.doneUrl)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": userId})
+}
+
+func (m *lineApi) unlink(c *gin.Context) {
+	sub, ok := sessionSub(c, &m.CommonErrorHandler)
+	if !ok {
+		return
+	}
+	store, ok := m.store(c)
+	if !ok {
+		return
+	}
+	err := store.Unlink(sub)
+	if errors.Is(err, line.ErrNotFound) {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// webhook applies the follow and unfollow events of the official account to the links,
+// so that users who block it are skipped.
+func (m *lineApi) webhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if !line.VerifySignature(m.channelSecret, body, c.GetHeader("X-Line-Signature")) {
+		m.GinErrorWithStatusHandler(c, http.StatusUnauthorized, errors.New("invalid signature"))
+		return
+	}
+	events, err := line.ParseEvents(body)
+	if err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	store, err := channelFactory.NewLineStore()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	// without links the blocks are only seen when pushes fail
+	if store != nil {
+		if err := line.ApplyEvents(store, events); err != nil {
+			m.GinErrorHandler(c, err)
+			return
+		}
+	}
+	c.Status(http.StatusOK)
+}
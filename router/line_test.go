@@ -0,0 +1,158 @@
+package router
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	apiErr "github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/service"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/line"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLine(m *lineApi) *gin.Engine {
+	m.SetErrorHandler(func(c *gin.Context, err error) {
+		status := http.StatusInternalServerError
+		if apiErr, ok := err.(apiErr.ApiError); ok {
+			status = apiErr.GetStatus()
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+	})
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	for _, h := range m.GetHandlers() {
+		engine.Handle(h.Method, h.Path, h.Handler)
+	}
+	return engine
+}
+
+func TestLineLink(t *testing.T) {
+	defer viper.Reset()
+	defer identity.ResetMock()
+	defer channelFactory.ResetMockLineStore()
+
+	loginServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/v2.1/token":
+			_, _ = io.WriteString(w, `{"access_token":"access-1"}`)
+		case "/v2/profile":
+			_, _ = io.WriteString(w, `{"userId":"U-bob"}`)
+		}
+	}))
+	defer loginServer.Close()
+	viper.Set("line.login.channel_id", "1234")
+	viper.Set("line.login.channel_secret", "secret")
+	viper.Set("line.login.redirect_url", "https://api.oosa.life/line/callback")
+	viper.Set("line.login.api_url", loginServer.URL)
+	identity.SetMockWhoamiFunc(func(header http.Header) (*service.Info, error) {
+		if header.Get("X-Session-Token") == "" {
+			return nil, identity.ErrUnauthorized
+		}
+		return &service.Info{Sub: header.Get("X-Session-Token")}, nil
+	})
+	store := line.NewMockStore()
+	channelFactory.SetMockLineStore(store)
+	engine := newTestLine(newLineApi())
+
+	serve := func(method, path, user string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if user != "" {
+			req.Header.Set("X-Session-Token", user)
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("GET", "/line/link", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serve("GET", "/line/link", "bob")
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "access.line.me", location.Host)
+	state := location.Query().Get("state")
+	assert.NotEmpty(t, state)
+	cookie := w.Result().Cookies()[0]
+	assert.Equal(t, lineStateCookie, cookie.Name)
+	assert.Equal(t, state, cookie.Value)
+	assert.True(t, cookie.HttpOnly)
+
+	w = serve("GET", "/line/callback?code=c&state=forged", "bob", cookie)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid state")
+	w = serve("GET", "/line/callback?code=c&state="+state, "bob")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve("GET", "/line/callback?error=access_denied&state="+state, "bob", cookie)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "line login failed: access_denied")
+
+	w = serve("GET", "/line/callback?code=c&state="+state, "bob", cookie)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"user_id":"U-bob"}`, w.Body.String())
+	link, err := store.Get("bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "U-bob", link.UserId)
+
+	w = serve("DELETE", "/line/link", "alice")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serve("DELETE", "/line/link", "bob")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	viper.Set("line.login.channel_id", "")
+	w = serve("GET", "/line/link", "bob")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "line login is not enabled")
+}
+
+func TestLineWebhook(t *testing.T) {
+	defer viper.Reset()
+	defer channelFactory.ResetMockLineStore()
+
+	viper.Set("line.messaging.channel_secret", "secret")
+	store := line.NewMockStore()
+	assert.NoError(t, store.Link(&line.Link{Sub: "bob", UserId: "U-bob"}))
+	channelFactory.SetMockLineStore(store)
+	engine := newTestLine(newLineApi())
+
+	body := `{"destination":"U0","events":[{"type":"unfollow","source":{"type":"user","userId":"U-bob"}}]}`
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(body))
+	tests := []struct {
+		name       string
+		signature  string
+		statusCode int
+		blocked    bool
+	}{
+		{name: "invalid signature", signature: "c2lnbmF0dXJl", statusCode: http.StatusUnauthorized},
+		{name: "unfollow", signature: base64.StdEncoding.EncodeToString(mac.Sum(nil)), statusCode: http.StatusOK, blocked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/line/webhook", strings.NewReader(body))
+			req.Header.Set("X-Line-Signature", tt.signature)
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code, w.Body.String())
+			link, _ := store.Get("bob")
+			assert.Equal(t, tt.blocked, link.Blocked)
+		})
+	}
+}
@@ -3,12 +3,14 @@ package router
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/94peter/microservice/apitool"
 	"github.com/94peter/microservice/apitool/err"
 	"github.com/arwoosa/notifaction/router/request"
 	"github.com/arwoosa/notifaction/service"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
 	"github.com/arwoosa/notifaction/service/identity"
 	"github.com/arwoosa/notifaction/service/mail/factory"
 	"github.com/gin-gonic/gin"
@@ -47,7 +49,31 @@ func (m *notification) createNotification(c *gin.Context) {
 		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, err)
 		return
 	}
-	sender, err := factory.NewApiSender()
+	varStore, err := factory.NewVariableStore()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	if varStore != nil {
+		variables, err := varStore.Get(requestBody.Event)
+		if err != nil {
+			m.GinErrorHandler(c, err)
+			return
+		}
+		// FROM, TO and forwarded headers are filled by the service itself
+		missing := variables.Missing(requestBody.Data, append([]string{"FROM", "TO"}, header2data...)...)
+		if len(missing) > 0 {
+			m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("missing required data: %s", strings.Join(missing, ", ")))
+			return
+		}
+		variables.ApplyDefaults(requestBody.Data)
+	}
+	registry, err := channelFactory.NewRegistry()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	dispatcher, err := registry.Dispatcher(requestBody.Event)
 	if err != nil {
 		m.GinErrorHandler(c, err)
 		return
@@ -73,62 +99,76 @@ func (m *notification) createNotification(c *gin.Context) {
 	}
 	var errSends []sendError
 	successResp := make([]gin.H, 0)
+	skippedResp := make([]gin.H, 0)
 	for _, lang := range cl.GetLangs() {
 		for i, info := range cl.GetInfos(lang) {
 			if i > 0 {
 				time.Sleep(200 * time.Microsecond)
 			}
 			requestBody.Data["TO"] = info.Name
-			mid, err := sender.Send(&service.Notification{
+			notify := &service.Notification{
 				Event:  requestBody.Event,
 				Lang:   lang,
 				From:   cl.From,
 				SendTo: []*service.Info{info},
 				Data:   requestBody.Data,
-			})
-			if err != nil {
-				errSends = append(errSends, sendError{err: err, info: info})
-			} else {
-				successResp = append(successResp, gin.H{
-					"send_to": info.Name,
-					"mid":     mid,
-					"lang":    lang,
-					"from":    cl.From.Name,
-					"event":   requestBody.Event,
-				})
+			}
+			for _, result := range dispatcher.Send(notify) {
+				switch {
+				case result.Skipped():
+					skippedResp = append(skippedResp, gin.H{
+						"channel": result.Channel,
+						"send_to": info.Name,
+						"reason":  result.Err.Error(),
+					})
+				case result.Err != nil:
+					errSends = append(errSends, sendError{err: result.Err, info: info, channel: result.Channel})
+				default:
+					successResp = append(successResp, gin.H{
+						"channel":  result.Channel,
+						"send_to":  info.Name,
+						"mid":      result.MessageId,
+						"lang":     lang,
+						"template": result.Template,
+						"from":     cl.From.Name,
+						"event":    requestBody.Event,
+					})
+				}
 			}
 		}
 	}
 
-	if len(errSends) == 0 {
-		c.JSON(http.StatusAccepted, gin.H{
-			"success": successResp,
-		})
-		return
-	}
-
-	if len(errSends) == len(requestBody.To) {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": errSends[0].err.Error(),
-		})
-		return
-	}
-
 	errorResp := make([]gin.H, len(errSends))
 	for i, e := range errSends {
 		errorResp[i] = e.output()
 	}
-	c.JSON(http.StatusPartialContent, errorResp)
+	resp := gin.H{
+		"success": successResp,
+		"skipped": skippedResp,
+		"errors":  errorResp,
+	}
+	switch {
+	case len(errSends) == 0:
+		c.JSON(http.StatusAccepted, resp)
+	case len(successResp) == 0:
+		// error is the first one, as answered before errors were listed
+		resp["error"] = errSends[0].err.Error()
+		c.JSON(http.StatusInternalServerError, resp)
+	default:
+		c.JSON(http.StatusPartialContent, resp)
+	}
 }
 
 type sendError struct {
-	err  error
-	info *service.Info
+	err     error
+	info    *service.Info
+	channel string
 }
 
 func (s *sendError) output() gin.H {
 	return gin.H{
-		"error": s.err.Error(),
-		"email": s.info.Name,
+		"error":   s.err.Error(),
+		"email":   s.info.Name,
+		"channel": s.channel,
 	}
 }
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/94peter/microservice/apitool"
+	"github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/router/request"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/preference"
+	"github.com/gin-gonic/gin"
+)
+
+// preferenceApi lets the user of the identity session of the request choose what they receive.
+type preferenceApi struct {
+	err.CommonErrorHandler
+}
+
+func (m *preferenceApi) GetHandlers() []*apitool.GinHandler {
+	return []*apitool.GinHandler{
+		{
+			Path:    "/preferences",
+			Method:  "GET",
+			Handler: m.get,
+		},
+		{
+			Path:    "/preferences",
+			Method:  "PUT",
+			Handler: m.update,
+		},
+	}
+}
+
+func (m *preferenceApi) store(c *gin.Context) (preference.Store, *preference.Categories, bool) {
+	store, err := channelFactory.NewPreferenceStore()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, nil, false
+	}
+	if store == nil {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, errors.New("preference is not enabled"))
+		return nil, nil, false
+	}
+	categories, err := channelFactory.NewPreferenceCategories()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, nil, false
+	}
+	return store, categories, true
+}
+
+// get returns the channels of every event category with the defaults applied.
+func (m *preferenceApi) get(c *gin.Context) {
+	sub, ok := sessionSub(c, &m.CommonErrorHandler)
+	if !ok {
+		return
+	}
+	store, categories, ok := m.store(c)
+	if !ok {
+		return
+	}
+	p, err := store.Get(sub)
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toPreferenceResponse(p, categories))
+}
+
+func (m *preferenceApi) update(c *gin.Context) {
+	sub, ok := sessionSub(c, &m.CommonErrorHandler)
+	if !ok {
+		return
+	}
+	var body request.UpdatePreferences
+	if err := c.BindJSON(&body); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	store, categories, ok := m.store(c)
+	if !ok {
+		return
+	}
+	p := &preference.Preference{
+		Sub:       sub,
+		Muted:     body.Muted,
+		Channels:  body.Categories,
+		UpdatedAt: time.Now(),
+	}
+	if err := p.Validate(categories); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := store.Save(p); err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toPreferenceResponse(p, categories))
+}
+
+func toPreferenceResponse(p *preference.Preference, categories *preference.Categories) gin.H {
+	return gin.H{
+		"muted":      p.Muted,
+		"categories": p.Choices(categories),
+	}
+}
@@ -0,0 +1,199 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiErr "github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/router/request"
+	"github.com/arwoosa/notifaction/service"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/preference"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPreference() *gin.Engine {
+	m := &preferenceApi{}
+	m.SetErrorHandler(func(c *gin.Context, err error) {
+		status := http.StatusInternalServerError
+		if apiErr, ok := err.(apiErr.ApiError); ok {
+			status = apiErr.GetStatus()
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+	})
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	for _, h := range m.GetHandlers() {
+		engine.Handle(h.Method, h.Path, h.Handler)
+	}
+	return engine
+}
+
+func setTestPreferenceCategories() {
+	viper.Set("preference.categories", []map[string]any{
+		{"name": "social", "events": []string{"EVENT_JOIN"}, "defaults": map[string]bool{"sms": false}},
+	})
+}
+
+func TestPreferences(t *testing.T) {
+	defer identity.ResetMock()
+	defer channelFactory.ResetMockPreferenceStore()
+	defer viper.Reset()
+
+	identity.SetMockWhoamiFunc(func(header http.Header) (*service.Info, error) {
+		if header.Get("Cookie") == "" {
+			return nil, identity.ErrUnauthorized
+		}
+		return &service.Info{Sub: header.Get("Cookie")}, nil
+	})
+	store := preference.NewMockStore()
+	channelFactory.SetMockPreferenceStore(store)
+	setTestPreferenceCategories()
+	engine := newTestPreference()
+
+	steps := []struct {
+		name       string
+		method     string
+		user       string
+		body       string
+		statusCode int
+		contains   string
+		check      func(t *testing.T, body []byte)
+	}{
+		{
+			name:       "no session",
+			method:     "GET",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "defaults",
+			method:     "GET",
+			user:       "bob",
+			statusCode: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				assert.JSONEq(t, `{"muted":false,"categories":[
+					{"name":"social","events":["EVENT_JOIN"],"channels":{"email":true,"inbox":true,"webpush":true,"mobilepush":true,"line":true,"sms":false}},
+					{"name":"general","events":null,"channels":{"email":true,"inbox":true,"webpush":true,"mobilepush":true,"line":true,"sms":true}}
+				]}`, string(body))
+			},
+		},
+		{
+			name:       "unknown category",
+			method:     "PUT",
+			user:       "bob",
+			body:       `{"categories":{"news":{"email":false}}}`,
+			statusCode: http.StatusBadRequest,
+			contains:   "unknown category: news",
+		},
+		{
+			name:       "update",
+			method:     "PUT",
+			user:       "bob",
+			body:       `{"muted":true,"categories":{"social":{"email":false,"sms":true}}}`,
+			statusCode: http.StatusOK,
+			contains:   `"muted":true`,
+			check: func(t *testing.T, body []byte) {
+				p, _ := store.Get("bob")
+				assert.True(t, p.Muted)
+				assert.Equal(t, map[string]map[string]bool{"social": {"email": false, "sms": true}}, p.Channels)
+				assert.False(t, p.UpdatedAt.IsZero())
+			},
+		},
+		{
+			name:       "updated",
+			method:     "GET",
+			user:       "bob",
+			statusCode: http.StatusOK,
+			contains:   `{"name":"social","events":["EVENT_JOIN"],"channels":{"email":false,"inbox":true,"line":true,"mobilepush":true,"sms":true,"webpush":true}}`,
+		},
+		{
+			name:       "other user",
+			method:     "GET",
+			user:       "alice",
+			statusCode: http.StatusOK,
+			contains:   `"muted":false`,
+		},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(step.method, "/preferences", strings.NewReader(step.body))
+			req.Header.Set("Content-Type", "application/json")
+			if step.user != "" {
+				req.Header.Set("Cookie", step.user)
+			}
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, step.statusCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), step.contains)
+			if step.check != nil {
+				step.check(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestCreateNotificationPreferences(t *testing.T) {
+	defer identity.ResetMock()
+	defer factory.ResetMockSender()
+	defer channelFactory.ResetMockPreferenceStore()
+	defer viper.Reset()
+	gin.SetMode(gin.TestMode)
+
+	store := preference.NewMockStore()
+	assert.NoError(t, store.Save(&preference.Preference{Sub: "muted", Muted: true}))
+	assert.NoError(t, store.Save(&preference.Preference{Sub: "opted-out", Channels: map[string]map[string]bool{"social": {"email": false}}}))
+	channelFactory.SetMockPreferenceStore(store)
+	setTestPreferenceCategories()
+	identity.SetMockSubToInfoFunc(func(from string, to []string) (*identity.ClassificationLang, error) {
+		tos := make([]*service.Info, len(to))
+		for i, t := range to {
+			tos[i] = &service.Info{Sub: t, Name: t}
+		}
+		return identity.NewClassificationLang(
+			identity.WithClassificationLangKeys([]string{"en"}),
+			identity.WithClassificationLangFrom(&service.Info{Sub: "alice", Name: "alice"}),
+			identity.WithClassificationLangFromLang("en"),
+			identity.WithClassificationLang(map[string][]*service.Info{"en": tos}),
+		), nil
+	})
+	var sent []string
+	factory.SetMockSender(func(t *testing.T, msg *service.Notification) (string, error) {
+		sent = append(sent, msg.SendTo[0].Sub)
+		return "mid", nil
+	})
+
+	data, _ := json.Marshal(&request.CreateNotification{
+		To:    []string{"bob", "muted", "opted-out"},
+		From:  "alice",
+		Event: "EVENT_JOIN",
+		Data:  map[string]string{},
+	})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/notification", bytes.NewBuffer(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	newNotification().createNotification(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Equal(t, []string{"bob"}, sent)
+	resp := struct {
+		Success []map[string]any `json:"success"`
+		Skipped []map[string]any `json:"skipped"`
+	}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Success, 1)
+	assert.Equal(t, []map[string]any{
+		{"channel": "email", "send_to": "muted", "reason": "skipped: preference"},
+		{"channel": "email", "send_to": "opted-out", "reason": "skipped: preference"},
+	}, resp.Skipped)
+}
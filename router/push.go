@@ -0,0 +1,124 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/94peter/microservice/apitool"
+	"github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/router/request"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/webpush"
+	"github.com/gin-gonic/gin"
+)
+
+// pushApi registers the web push subscriptions of the user of the identity session of the request.
+type pushApi struct {
+	err.CommonErrorHandler
+}
+
+func (m *pushApi) GetHandlers() []*apitool.GinHandler {
+	return []*apitool.GinHandler{
+		{
+			Path:    "/push/public-key",
+			Method:  "GET",
+			Handler: m.publicKey,
+		},
+		{
+			Path:    "/push/subscriptions",
+			Method:  "POST",
+			Handler: m.subscribe,
+		},
+		{
+			Path:    "/push/subscriptions",
+			Method:  "DELETE",
+			Handler: m.unsubscribe,
+		},
+	}
+}
+
+// publicKey returns the applicationServerKey of pushManager.subscribe.
+func (m *pushApi) publicKey(c *gin.Context) {
+	vapid, err := channelFactory.NewVapid()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"public_key": vapid.PublicKey()})
+}
+
+func (m *pushApi) store(c *gin.Context) (webpush.Store, bool) {
+	store, err := channelFactory.NewPushSubscriptionStore()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, false
+	}
+	if store == nil {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, errors.New("webpush is not enabled"))
+		return nil, false
+	}
+	return store, true
+}
+
+func (m *pushApi) subscribe(c *gin.Context) {
+	sub, ok := sessionSub(c, &m.CommonErrorHandler)
+	if !ok {
+		return
+	}
+	var body request.PushSubscription
+	if err := c.BindJSON(&body); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	subscription := &webpush.Subscription{
+		Sub:       sub,
+		Endpoint:  body.Endpoint,
+		Keys:      body.Keys,
+		CreatedAt: time.Now(),
+	}
+	if err := subscription.Validate(channelFactory.PushAllowedHosts()); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, err)
+		return
+	}
+	store, ok := m.store(c)
+	if !ok {
+		return
+	}
+	if err := store.Save(subscription); err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"endpoint": subscription.Endpoint})
+}
+
+func (m *pushApi) unsubscribe(c *gin.Context) {
+	sub, ok := sessionSub(c, &m.CommonErrorHandler)
+	if !ok {
+		return
+	}
+	var body request.DeletePushSubscription
+	if err := c.BindJSON(&body); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if err := body.Validate(); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, err)
+		return
+	}
+	store, ok := m.store(c)
+	if !ok {
+		return
+	}
+	err := store.Delete(sub, body.Endpoint)
+	if errors.Is(err, webpush.ErrNotFound) {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,159 @@
+package router
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiErr "github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/service"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/webpush"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPush() *gin.Engine {
+	m := &pushApi{}
+	m.SetErrorHandler(func(c *gin.Context, err error) {
+		status := http.StatusInternalServerError
+		if apiErr, ok := err.(apiErr.ApiError); ok {
+			status = apiErr.GetStatus()
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+	})
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	for _, h := range m.GetHandlers() {
+		engine.Handle(h.Method, h.Path, h.Handler)
+	}
+	return engine
+}
+
+func TestPushSubscriptions(t *testing.T) {
+	defer viper.Reset()
+	defer identity.ResetMock()
+	defer channelFactory.ResetMockPushSubscriptionStore()
+
+	private, public, err := webpush.GenerateVapidKeys()
+	assert.NoError(t, err)
+	viper.Set("webpush.vapid.private_key", private)
+	viper.Set("webpush.vapid.subject", "mailto:dev@example.com")
+	identity.SetMockWhoamiFunc(func(header http.Header) (*service.Info, error) {
+		if header.Get("Cookie") == "" {
+			return nil, identity.ErrUnauthorized
+		}
+		return &service.Info{Sub: header.Get("Cookie")}, nil
+	})
+	store := webpush.NewMockStore()
+	channelFactory.SetMockPushSubscriptionStore(store)
+	engine := newTestPush()
+
+	key, _ := ecdh.P256().GenerateKey(rand.Reader)
+	p256dh := webpush.EncodeKey(key.PublicKey().Bytes())
+	auth := webpush.EncodeKey([]byte("0123456789abcdef"))
+	subscription := `{"endpoint":"https://fcm.googleapis.com/fcm/send/abc","expirationTime":null,"keys":{"p256dh":"` + p256dh + `","auth":"` + auth + `"}}`
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		user       string
+		body       string
+		statusCode int
+		contains   string
+		check      func(t *testing.T)
+	}{
+		{
+			name:       "public key",
+			method:     "GET",
+			path:       "/push/public-key",
+			statusCode: http.StatusOK,
+			contains:   public,
+		},
+		{
+			name:       "no session",
+			method:     "POST",
+			path:       "/push/subscriptions",
+			body:       subscription,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid body",
+			method:     "POST",
+			path:       "/push/subscriptions",
+			user:       "bob",
+			body:       `{`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "not a push service",
+			method:     "POST",
+			path:       "/push/subscriptions",
+			user:       "bob",
+			body:       strings.Replace(subscription, "fcm.googleapis.com", "localhost", 1),
+			statusCode: http.StatusBadRequest,
+			contains:   "endpoint host is not a known push service: localhost",
+		},
+		{
+			name:       "subscribe",
+			method:     "POST",
+			path:       "/push/subscriptions",
+			user:       "bob",
+			body:       subscription,
+			statusCode: http.StatusCreated,
+			check: func(t *testing.T) {
+				subs, _ := store.List("bob")
+				assert.Len(t, subs, 1)
+				assert.Equal(t, p256dh, subs[0].Keys.P256dh)
+			},
+		},
+		{
+			name:       "unsubscribe of another user",
+			method:     "DELETE",
+			path:       "/push/subscriptions",
+			user:       "alice",
+			body:       `{"endpoint":"https://fcm.googleapis.com/fcm/send/abc"}`,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "unsubscribe without endpoint",
+			method:     "DELETE",
+			path:       "/push/subscriptions",
+			user:       "bob",
+			body:       `{}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "unsubscribe",
+			method:     "DELETE",
+			path:       "/push/subscriptions",
+			user:       "bob",
+			body:       `{"endpoint":"https://fcm.googleapis.com/fcm/send/abc"}`,
+			statusCode: http.StatusNoContent,
+		},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(step.method, step.path, strings.NewReader(step.body))
+			req.Header.Set("Content-Type", "application/json")
+			if step.user != "" {
+				req.Header.Set("Cookie", step.user)
+			}
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, step.statusCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), step.contains)
+			if step.check != nil {
+				step.check(t)
+			}
+		})
+	}
+}
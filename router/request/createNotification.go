@@ -1,6 +1,11 @@
 package request
 
-import "errors"
+import (
+	"errors"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service/mail/workflow"
+)
 
 type CreateNotification struct {
 	To    []string          `json:"to"`
@@ -22,6 +27,9 @@ func (r *CreateNotification) Validate() error {
 	if r.Event == "" {
 		return errors.New("empty event")
 	}
+	if strings.HasPrefix(r.Event, workflow.DraftPrefix) {
+		return errors.New("draft events can not be sent")
+	}
 	if r.Data == nil {
 		return errors.New("empty data")
 	}
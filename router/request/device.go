@@ -0,0 +1,24 @@
+package request
+
+import (
+	"errors"
+)
+
+// RegisterDevice is the push token an app registers for its installation.
+type RegisterDevice struct {
+	Platform   string `json:"platform"`
+	Token      string `json:"token"`
+	AppVersion string `json:"app_version"`
+}
+
+type DeleteDevice struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+}
+
+func (r *DeleteDevice) Validate() error {
+	if r.Platform == "" || r.Token == "" {
+		return errors.New("platform and token are required")
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package request
+
+// UpdatePreferences replaces the preferences of a user: the channels chosen per event category,
+// the defaults of the category apply to the channels left out.
+type UpdatePreferences struct {
+	Muted      bool                       `json:"muted"`
+	Categories map[string]map[string]bool `json:"categories"`
+}
@@ -0,0 +1,24 @@
+package request
+
+import (
+	"errors"
+
+	"github.com/arwoosa/notifaction/service/webpush"
+)
+
+// PushSubscription is the json of a browser PushSubscription.
+type PushSubscription struct {
+	Endpoint string       `json:"endpoint"`
+	Keys     webpush.Keys `json:"keys"`
+}
+
+type DeletePushSubscription struct {
+	Endpoint string `json:"endpoint"`
+}
+
+func (r *DeletePushSubscription) Validate() error {
+	if r.Endpoint == "" {
+		return errors.New("empty endpoint")
+	}
+	return nil
+}
@@ -50,6 +50,16 @@ func TestCreateNotificationValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "draft event",
+			notify: &CreateNotification{
+				To:    []string{"test"},
+				From:  "test",
+				Event: "draft-test",
+				Data:  map[string]string{},
+			},
+			wantErr: true,
+		},
 		{
 			name: "valid notification",
 			notify: &CreateNotification{
@@ -75,3 +85,48 @@ func TestCreateNotificationValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantSubject string
+		wantErr     bool
+	}{
+		{
+			name:        "json",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"event":"welcome","lang":"en","subject":"Hi","body":{"html":"<p>Hi</p>"},"options":{"generate_plaint":true}}`,
+			wantSubject: "Hi",
+		},
+		{
+			name:        "yaml",
+			contentType: "application/yaml",
+			body:        "event: welcome\nlang: en\nsubject: Hello\n",
+			wantSubject: "Hello",
+		},
+		{
+			name:        "empty body",
+			contentType: "application/json",
+			wantErr:     true,
+		},
+		{
+			name:        "invalid json",
+			contentType: "application/json",
+			body:        "event: welcome",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := ParseTemplate(tt.contentType, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tpl.Subject != tt.wantSubject {
+				t.Errorf("ParseTemplate() subject = %v, want %v", tpl.Subject, tt.wantSubject)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"gopkg.in/yaml.v2"
+)
+
+// ParseTemplate decodes a template in the format of the template files, the body is YAML
+// when the content type is YAML and JSON otherwise.
+func ParseTemplate(contentType string, body []byte) (*dao.ApplyTemplateInput, error) {
+	if len(body) == 0 {
+		return nil, errors.New("empty body")
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+	default:
+		// the template fields only have yaml tags, so JSON is converted to YAML first
+		var raw map[string]any
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal json: %w", err)
+		}
+		var err error
+		if body, err = yaml.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("failed to convert json: %w", err)
+		}
+	}
+	var tpl dao.ApplyTemplateInput
+	if err := yaml.Unmarshal(body, &tpl); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+	}
+	return &tpl, nil
+}
+
+type PreviewTemplate struct {
+	Data map[string]string `json:"data"`
+}
@@ -0,0 +1,10 @@
+package request
+
+// CreateWebhook is the endpoint a partner service receives the notifications of events at,
+// the secret signs the posts.
+type CreateWebhook struct {
+	Name   string   `json:"name"`
+	Url    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"github.com/94peter/microservice/apitool"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
 	"github.com/spf13/viper"
 )
 
@@ -13,5 +14,28 @@ func GetApis() []apitool.GinAPI {
 	if viper.GetBool("api.test") {
 		apis = append(apis, &test{})
 	}
+	// the template admin API is only served when it can be authenticated
+	if len(viper.GetStringSlice("api.admin.tokens")) > 0 {
+		apis = append(apis, newTemplateAdmin())
+	}
+	if channelFactory.InboxEnabled() {
+		apis = append(apis, newInboxApi())
+	}
+	if channelFactory.WebPushEnabled() {
+		apis = append(apis, &pushApi{})
+	}
+	if channelFactory.MobilePushEnabled() {
+		apis = append(apis, &deviceApi{})
+	}
+	if channelFactory.LineEnabled() {
+		apis = append(apis, newLineApi())
+	}
+	if channelFactory.PreferenceEnabled() {
+		apis = append(apis, &preferenceApi{})
+	}
+	// webhook subscriptions are managed by admins on behalf of the partner services
+	if channelFactory.WebhookEnabled() && len(viper.GetStringSlice("api.admin.tokens")) > 0 {
+		apis = append(apis, newWebhookApi())
+	}
 	return apis
 }
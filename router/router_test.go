@@ -46,6 +46,85 @@ func TestGetApis(t *testing.T) {
 				viper.Set("api.test", true)
 			},
 		},
+		{
+			name: "test GetApis with template admin api",
+			want: []apitool.GinAPI{
+				&notification{},
+				&health{},
+				&templateAdmin{},
+			},
+			prefunc: func() {
+				viper.Set("api.admin.tokens", []string{"secret"})
+			},
+		},
+		{
+			name: "test GetApis with inbox api",
+			want: []apitool.GinAPI{
+				&notification{},
+				&health{},
+				&inboxApi{},
+			},
+			prefunc: func() {
+				viper.Set("inbox.mongo.uri", "mongodb://localhost:27017")
+			},
+		},
+		{
+			name: "test GetApis with push api",
+			want: []apitool.GinAPI{
+				&notification{},
+				&health{},
+				&pushApi{},
+			},
+			prefunc: func() {
+				viper.Set("webpush.mongo.uri", "mongodb://localhost:27017")
+			},
+		},
+		{
+			name: "test GetApis with device api",
+			want: []apitool.GinAPI{
+				&notification{},
+				&health{},
+				&deviceApi{},
+			},
+			prefunc: func() {
+				viper.Set("mobilepush.mongo.uri", "mongodb://localhost:27017")
+			},
+		},
+		{
+			name: "test GetApis with line api",
+			want: []apitool.GinAPI{
+				&notification{},
+				&health{},
+				&lineApi{},
+			},
+			prefunc: func() {
+				viper.Set("line.messaging.access_token", "token")
+			},
+		},
+		{
+			name: "test GetApis with preference api",
+			want: []apitool.GinAPI{
+				&notification{},
+				&health{},
+				&preferenceApi{},
+			},
+			prefunc: func() {
+				viper.Set("preference.mongo.uri", "mongodb://localhost:27017")
+			},
+		},
+		{
+			name: "test GetApis with webhook api",
+			want: []apitool.GinAPI{
+				&notification{},
+				&health{},
+				&templateAdmin{},
+				&webhookApi{},
+			},
+			prefunc: func() {
+				viper.Set("api.admin.tokens", []string{"secret"})
+				viper.Set("webhook.mongo.uri", "mongodb://localhost:27017")
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -221,7 +300,10 @@ func TestCreateNotification(t *testing.T) {
 		mockSender          func(t *testing.T, msg *service.Notification) (messageId string, err error)
 		mockNewIdentityErr  error
 		mockSubToInfo       func(from string, to []string) (*identity.ClassificationLang, error)
+		mockGetVariables    func(event string) (dao.Variables, error)
+		channels            []string
 		statusCode          int
+		contains            []string
 	}{
 		{
 			name:        "bind error",
@@ -235,6 +317,76 @@ func TestCreateNotification(t *testing.T) {
 			},
 			statusCode: http.StatusBadRequest,
 		},
+		{
+			name: "draft event",
+			requestBody: &request.CreateNotification{
+				To:    []string{"valid"},
+				From:  "fff",
+				Event: "draft-event",
+				Data:  map[string]string{},
+			},
+			mockNewIdentityErr: errors.New("identity should not be called"),
+			statusCode:         http.StatusBadRequest,
+			contains:           []string{"draft events can not be sent"},
+		},
+		{
+			name: "missing required data",
+			requestBody: &request.CreateNotification{
+				To:    []string{"valid"},
+				From:  "fff",
+				Event: "event",
+				Data:  map[string]string{"creator_name": "john"},
+			},
+			mockGetVariables: func(event string) (dao.Variables, error) {
+				return dao.Variables{
+					{Name: "CREATOR_NAME", Required: true},
+					{Name: "EVENT_NAME", Required: true},
+					{Name: "TO", Required: true},
+				}, nil
+			},
+			mockNewIdentityErr: errors.New("identity should not be called"),
+			statusCode:         http.StatusBadRequest,
+		},
+		{
+			name: "get variables error",
+			requestBody: &request.CreateNotification{
+				To:    []string{"valid"},
+				From:  "fff",
+				Event: "event",
+				Data:  map[string]string{},
+			},
+			mockGetVariables: func(event string) (dao.Variables, error) {
+				return nil, errors.New("get variables error")
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "required data with default",
+			requestBody: &request.CreateNotification{
+				To:    []string{"valid"},
+				From:  "fff",
+				Event: "event",
+				Data:  map[string]string{},
+			},
+			mockGetVariables: func(event string) (dao.Variables, error) {
+				return dao.Variables{{Name: "HOST", Required: true, Default: "oosa.life"}}, nil
+			},
+			mockSubToInfo: func(from string, to []string) (*identity.ClassificationLang, error) {
+				return identity.NewClassificationLang(
+					identity.WithClassificationLangKeys([]string{"en"}),
+					identity.WithClassificationLangFrom(&service.Info{Sub: "valid"}),
+					identity.WithClassificationLangFromLang("en"),
+					identity.WithClassificationLang(map[string][]*service.Info{"en": {{Sub: "valid"}}}),
+				), nil
+			},
+			mockSender: func(t *testing.T, msg *service.Notification) (messageId string, err error) {
+				if msg.Data["HOST"] != "oosa.life" {
+					return "", errors.New("default not applied")
+				}
+				return "", nil
+			},
+			statusCode: http.StatusAccepted,
+		},
 		{
 			name: "NewApiSender error",
 			requestBody: &request.CreateNotification{
@@ -246,6 +398,17 @@ func TestCreateNotification(t *testing.T) {
 			mockSenderException: errors.New("new sender error"),
 			statusCode:          http.StatusInternalServerError,
 		},
+		{
+			name: "unknown channel",
+			requestBody: &request.CreateNotification{
+				To:    []string{"valid"},
+				From:  "fff",
+				Event: "event",
+				Data:  map[string]string{},
+			},
+			channels:   []string{"email", "fax"},
+			statusCode: http.StatusInternalServerError,
+		},
 		{
 			name: "NewIdentity error",
 			requestBody: &request.CreateNotification{
@@ -290,6 +453,10 @@ func TestCreateNotification(t *testing.T) {
 				return "", errors.New("send error")
 			},
 			statusCode: http.StatusInternalServerError,
+			contains: []string{
+				`"error":"send error","errors":[{"channel":"email","email":"","error":"send error"}]`,
+				`"skipped":[],"success":[]`,
+			},
 		},
 		{
 			name: "partial send error",
@@ -318,6 +485,11 @@ func TestCreateNotification(t *testing.T) {
 				return "", nil
 			},
 			statusCode: http.StatusPartialContent,
+			contains: []string{
+				`"errors":[{"channel":"email","email":"","error":"send error"}]`,
+				`"skipped":[]`,
+				`"success":[{"channel":"email"`,
+			},
 		},
 		{
 			name: "successful send",
@@ -344,6 +516,7 @@ func TestCreateNotification(t *testing.T) {
 			identity.ResetMock()
 			factory.ResetMockSender()
 			factory.ResetMockTemplate()
+			factory.ResetMockVariableStore()
 		}()
 		t.Run(test.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
@@ -361,9 +534,15 @@ func TestCreateNotification(t *testing.T) {
 
 			factory.SetMockSender(test.mockSender)
 			factory.SetMockNewSenderException(test.mockSenderException)
+			viper.Set("notification.channels.default", test.channels)
+			defer viper.Set("notification.channels.default", nil)
 
 			identity.SetNewException(test.mockNewIdentityErr)
 			identity.SetMockSubToInfoFunc(test.mockSubToInfo)
+			factory.ResetMockVariableStore()
+			if test.mockGetVariables != nil {
+				factory.SetMockGetVariables(test.mockGetVariables)
+			}
 
 			notification := &notification{}
 			notification.SetErrorHandler(func(c *gin.Context, err error) {
@@ -379,6 +558,9 @@ func TestCreateNotification(t *testing.T) {
 			notification.createNotification(c)
 
 			assert.Equal(t, test.statusCode, w.Code)
+			for _, contains := range test.contains {
+				assert.Contains(t, w.Body.String(), contains)
+			}
 		})
 	}
 }
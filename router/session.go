@@ -0,0 +1,66 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/mail/workflow"
+	"github.com/gin-gonic/gin"
+)
+
+// sessionTokenHeader carries the identity session of clients without cookies.
+const sessionTokenHeader = "X-Session-Token"
+
+// sessionSub returns the sub of the identity session of the request. Without a session it
+// responds 401 with h and returns false.
+func sessionSub(c *gin.Context, h *err.CommonErrorHandler) (string, bool) {
+	id, e := identity.NewIdentity()
+	if e != nil {
+		h.GinErrorHandler(c, e)
+		return "", false
+	}
+	user, e := id.Whoami(c.Request.Header)
+	if errors.Is(e, identity.ErrUnauthorized) {
+		h.GinErrorWithStatusHandler(c, http.StatusUnauthorized, e)
+		return "", false
+	}
+	if e != nil {
+		h.GinErrorHandler(c, e)
+		return "", false
+	}
+	return user.Sub, true
+}
+
+// sessionUser returns the workflow user of the identity session of the request, see workflow.UserOf.
+// Only the session cookie and token are read, the Authorization header holds the admin token.
+// Without an active session it responds 401 with h and returns false.
+func sessionUser(c *gin.Context, h *err.CommonErrorHandler) (string, bool) {
+	id, e := identity.NewIdentity()
+	if e != nil {
+		h.GinErrorHandler(c, e)
+		return "", false
+	}
+	header := http.Header{}
+	for _, name := range []string{"Cookie", sessionTokenHeader} {
+		if v := c.GetHeader(name); v != "" {
+			header.Set(name, v)
+		}
+	}
+	info, e := id.Whoami(header)
+	if errors.Is(e, identity.ErrUnauthorized) {
+		h.GinErrorWithStatusHandler(c, http.StatusUnauthorized, e)
+		return "", false
+	}
+	if e != nil {
+		h.GinErrorHandler(c, e)
+		return "", false
+	}
+	user, e := workflow.UserOf(info)
+	if e != nil {
+		h.GinErrorWithStatusHandler(c, http.StatusUnauthorized, e)
+		return "", false
+	}
+	return user, true
+}
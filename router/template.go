@@ -0,0 +1,304 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/94peter/microservice/apitool"
+	"github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/router/request"
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mail/workflow"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// templateAdmin manages mail templates over HTTP, every request needs one of the
+// bearer tokens of api.admin.tokens. With the template workflow enabled, changes also need
+// the identity session of the user drafting them.
+type templateAdmin struct {
+	err.CommonErrorHandler
+	tokens []string
+}
+
+func newTemplateAdmin() *templateAdmin {
+	return &templateAdmin{
+		tokens: viper.GetStringSlice("api.admin.tokens"),
+	}
+}
+
+func (m *templateAdmin) GetHandlers() []*apitool.GinHandler {
+	return []*apitool.GinHandler{
+		{
+			Path:    "/admin/templates",
+			Method:  "GET",
+			Handler: m.auth(m.listTemplates),
+		},
+		{
+			Path:    "/admin/templates",
+			Method:  "PUT",
+			Handler: m.auth(m.applyTemplate),
+		},
+		{
+			Path:    "/admin/templates/:name",
+			Method:  "GET",
+			Handler: m.auth(m.detailTemplate),
+		},
+		{
+			Path:    "/admin/templates/:name",
+			Method:  "DELETE",
+			Handler: m.auth(m.deleteTemplate),
+		},
+		{
+			Path:    "/admin/templates/:name/preview",
+			Method:  "POST",
+			Handler: m.auth(m.previewTemplate),
+		},
+	}
+}
+
+func (m *templateAdmin) auth(handler gin.HandlerFunc) gin.HandlerFunc {
+	return adminAuth(&m.CommonErrorHandler, m.tokens, handler)
+}
+
+type templateListItem struct {
+	Name       string  `json:"name"`
+	CreateTime string  `json:"create_time"`
+	UpdateTime *string `json:"update_time,omitempty"`
+}
+
+// listTemplates returns a page of templates, or every template with all=true.
+// The event_prefix and lang query filters apply to the returned templates.
+func (m *templateAdmin) listTemplates(c *gin.Context) {
+	mailTpl, err := factory.NewTemplate()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	resp := gin.H{}
+	var tpls []*templateListItem
+	filter := &mail.ListFilter{
+		EventPrefix: c.Query("event_prefix"),
+		Lang:        c.Query("lang"),
+	}
+	if c.Query("all") == "true" {
+		all, err := mail.ListAll(mailTpl)
+		if err != nil {
+			m.GinErrorHandler(c, err)
+			return
+		}
+		tpls = toTemplateListItems(filter.Filter(all))
+	} else {
+		page, err := mailTpl.List(c.Query("next_token"))
+		if err != nil {
+			m.GinErrorHandler(c, err)
+			return
+		}
+		if page == nil {
+			page = &dao.ListTemplateResponse{}
+		}
+		tpls = toTemplateListItems(filter.Filter(page.Templates))
+		if page.NextToken != nil && *page.NextToken != "" {
+			resp["next_token"] = *page.NextToken
+		}
+	}
+	resp["templates"] = tpls
+	c.JSON(http.StatusOK, resp)
+}
+
+func toTemplateListItems(tpls []*dao.ListTemplate) []*templateListItem {
+	items := make([]*templateListItem, 0, len(tpls))
+	for _, tpl := range tpls {
+		item := &templateListItem{
+			Name:       tpl.Name,
+			CreateTime: tpl.CreateTime.Format(time.RFC3339),
+		}
+		if tpl.UpdateTime != nil {
+			updated := tpl.UpdateTime.Format(time.RFC3339)
+			item.UpdateTime = &updated
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (m *templateAdmin) detailTemplate(c *gin.Context) {
+	mailTpl, ok := m.existingTemplate(c)
+	if !ok {
+		return
+	}
+	detail, err := mailTpl.Detail(c.Param("name"))
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toTemplateDetail(detail))
+}
+
+// applyTemplate creates or updates the templates of a JSON or YAML body in the format of the template files,
+// or drafts them when the template workflow is enabled.
+func (m *templateAdmin) applyTemplate(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	input, err := request.ParseTemplate(c.ContentType(), body)
+	if err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if err := input.Validate(); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, err)
+		return
+	}
+	mailTpl, err := factory.NewTemplate()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	wf, err := factory.NewWorkflow(mailTpl)
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	if wf != nil {
+		// with the workflow enabled the body becomes a draft of the user of the identity session
+		user, ok := sessionUser(c, &m.CommonErrorHandler)
+		if !ok {
+			return
+		}
+		change, err := wf.Draft(user, input)
+		if err != nil {
+			m.GinErrorWithStatusHandler(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":    change.Status,
+			"templates": change.DraftNames(),
+		})
+		return
+	}
+	if err := mailTpl.ApplyTemplate(input); err != nil {
+		// the input is valid, so the errors left are the ones of building and linting it
+		m.GinErrorWithStatusHandler(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	names := []string{}
+	for _, tpl := range input.Expand() {
+		names = append(names, tpl.GetName())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":    workflow.StatusPublished,
+		"templates": names,
+	})
+}
+
+// deleteTemplate deletes a template, or drafts its deletion when the template workflow is enabled.
+func (m *templateAdmin) deleteTemplate(c *gin.Context) {
+	mailTpl, ok := m.existingTemplate(c)
+	if !ok {
+		return
+	}
+	wf, err := factory.NewWorkflow(mailTpl)
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	if wf != nil {
+		user, ok := sessionUser(c, &m.CommonErrorHandler)
+		if !ok {
+			return
+		}
+		change, err := wf.DraftDelete(user, c.Param("name"))
+		if err != nil {
+			m.GinErrorWithStatusHandler(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":  change.Status,
+			"deletes": change.Deletes,
+		})
+		return
+	}
+	if err := mailTpl.Delete(c.Param("name")); err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// previewTemplate renders a stored template with the data of the body the way the senders do.
+func (m *templateAdmin) previewTemplate(c *gin.Context) {
+	var requestBody request.PreviewTemplate
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&requestBody); err != nil {
+			m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+	if requestBody.Data == nil {
+		requestBody.Data = map[string]string{}
+	}
+	mailTpl, ok := m.existingTemplate(c)
+	if !ok {
+		return
+	}
+	name := c.Param("name")
+	detail, err := mailTpl.Detail(name)
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	if event, _, err := service.ParseTemplateName(name); err == nil {
+		varStore, err := factory.NewVariableStore()
+		if err != nil {
+			m.GinErrorHandler(c, err)
+			return
+		}
+		if varStore != nil {
+			variables, err := varStore.Get(event)
+			if err != nil {
+				m.GinErrorHandler(c, err)
+				return
+			}
+			variables.ApplyDefaults(requestBody.Data)
+		}
+	}
+	mail.Render(detail, requestBody.Data)
+	c.JSON(http.StatusOK, toTemplateDetail(detail))
+}
+
+// existingTemplate returns the template store, responding 404 when the template of the name param does not exist.
+func (m *templateAdmin) existingTemplate(c *gin.Context) (mail.Template, bool) {
+	mailTpl, err := factory.NewTemplate()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, false
+	}
+	name := c.Param("name")
+	exist, err := mailTpl.IsTemplateExist(name)
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, false
+	}
+	if !exist {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, fmt.Errorf("template %s does not exist", name))
+		return nil, false
+	}
+	return mailTpl, true
+}
+
+func toTemplateDetail(detail *dao.DetailTemplateResponse) gin.H {
+	return gin.H{
+		"name":    detail.Title,
+		"subject": detail.Subject,
+		"html":    detail.Body.Html,
+		"plaint":  detail.Body.Plaint,
+	}
+}
@@ -0,0 +1,326 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiErr "github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/identity"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mail/workflow"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTemplateAdmin() (*gin.Engine, *templateAdmin) {
+	m := &templateAdmin{tokens: []string{"secret"}}
+	m.SetErrorHandler(func(c *gin.Context, err error) {
+		status := http.StatusInternalServerError
+		if apiErr, ok := err.(apiErr.ApiError); ok {
+			status = apiErr.GetStatus()
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+	})
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	for _, h := range m.GetHandlers() {
+		engine.Handle(h.Method, h.Path, h.Handler)
+	}
+	return engine, m
+}
+
+func mockDetail(name string) (*dao.DetailTemplateResponse, error) {
+	detail := &dao.DetailTemplateResponse{Title: name, Subject: "Hi {{NAME}}"}
+	detail.Body.Html = "<p>Hi {{NAME}}</p>"
+	detail.Body.Plaint = "Hi {{NAME}}"
+	return detail, nil
+}
+
+func TestTemplateAdmin(t *testing.T) {
+	nextToken := "next"
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		token       string
+		session     string
+		contentType string
+		body        string
+		prefunc     func()
+		statusCode  int
+		contains    string
+	}{
+		{
+			name:       "missing token",
+			method:     "GET",
+			path:       "/admin/templates",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid token",
+			method:     "GET",
+			path:       "/admin/templates",
+			token:      "wrong",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "list",
+			method: "GET",
+			path:   "/admin/templates?lang=en",
+			token:  "secret",
+			prefunc: func() {
+				factory.SetMockList(func(string) (*dao.ListTemplateResponse, error) {
+					return &dao.ListTemplateResponse{
+						NextToken: &nextToken,
+						Templates: []*dao.ListTemplate{{Name: "welcome_en"}, {Name: "welcome_ja"}},
+					}, nil
+				})
+			},
+			statusCode: http.StatusOK,
+			contains:   `"next_token":"next","templates":[{"name":"welcome_en","create_time":"0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			name:   "list error",
+			method: "GET",
+			path:   "/admin/templates",
+			token:  "secret",
+			prefunc: func() {
+				factory.SetMockList(func(string) (*dao.ListTemplateResponse, error) {
+					return nil, errors.New("list failed")
+				})
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:   "detail",
+			method: "GET",
+			path:   "/admin/templates/welcome_en",
+			token:  "secret",
+			prefunc: func() {
+				factory.SetMockDetail(mockDetail)
+			},
+			statusCode: http.StatusOK,
+			contains:   `"subject":"Hi {{NAME}}"`,
+		},
+		{
+			name:        "apply json",
+			method:      "PUT",
+			path:        "/admin/templates",
+			token:       "secret",
+			contentType: "application/json",
+			body:        `{"event":"welcome","lang":"en","subject":"Hi","body":{"html":"<p>Hi</p>"}}`,
+			prefunc: func() {
+				factory.SetMockApplyTemplate(func(input *dao.ApplyTemplateInput) error {
+					if input.Body.Html != "<p>Hi</p>" {
+						return errors.New("unexpected html")
+					}
+					return nil
+				})
+			},
+			statusCode: http.StatusOK,
+			contains:   `"status":"published","templates":["welcome_en"]`,
+		},
+		{
+			name:        "apply yaml",
+			method:      "PUT",
+			path:        "/admin/templates",
+			token:       "secret",
+			contentType: "application/yaml",
+			body:        "event: welcome\nsubject: Hi\nbody:\n  html: <p>Hi</p>\nlocales:\n  en: {}\n  ja:\n    subject: こんにちは\n",
+			prefunc: func() {
+				factory.SetMockApplyTemplate(func(input *dao.ApplyTemplateInput) error { return nil })
+			},
+			statusCode: http.StatusOK,
+			contains:   `"templates":["welcome_en","welcome_ja"]`,
+		},
+		{
+			name:        "draft",
+			method:      "PUT",
+			path:        "/admin/templates",
+			token:       "secret",
+			session:     "alice",
+			contentType: "application/json",
+			body:        `{"event":"welcome","lang":"en","subject":"Hi","body":{"html":"<p>Hi</p>"}}`,
+			prefunc: func() {
+				factory.SetMockWorkflowStore(workflow.NewMockStore())
+				factory.SetMockApplyTemplate(func(input *dao.ApplyTemplateInput) error {
+					if input.Event != "draft-welcome" {
+						return errors.New("unexpected event")
+					}
+					return nil
+				})
+				factory.SetMockDetail(mockDetail)
+			},
+			statusCode: http.StatusOK,
+			contains:   `"status":"draft","templates":["draft-welcome_en"]`,
+		},
+		{
+			name:        "draft of inactive identity",
+			method:      "PUT",
+			path:        "/admin/templates",
+			token:       "secret",
+			session:     "carol",
+			contentType: "application/json",
+			body:        `{"event":"welcome","lang":"en","subject":"Hi","body":{"html":"<p>Hi</p>"}}`,
+			prefunc: func() {
+				factory.SetMockWorkflowStore(workflow.NewMockStore())
+				factory.SetMockApplyTemplate(func(input *dao.ApplyTemplateInput) error {
+					return errors.New("drafted by inactive identity")
+				})
+			},
+			statusCode: http.StatusUnauthorized,
+			contains:   "identity is not active",
+		},
+		{
+			name:        "draft without session",
+			method:      "PUT",
+			path:        "/admin/templates",
+			token:       "secret",
+			contentType: "application/json",
+			body:        `{"event":"welcome","lang":"en","subject":"Hi","body":{"html":"<p>Hi</p>"}}`,
+			prefunc: func() {
+				factory.SetMockWorkflowStore(workflow.NewMockStore())
+				factory.SetMockApplyTemplate(func(input *dao.ApplyTemplateInput) error {
+					return errors.New("drafted without session")
+				})
+			},
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:        "apply invalid body",
+			method:      "PUT",
+			path:        "/admin/templates",
+			token:       "secret",
+			contentType: "application/json",
+			body:        `{"event":`,
+			statusCode:  http.StatusBadRequest,
+		},
+		{
+			name:        "apply invalid template",
+			method:      "PUT",
+			path:        "/admin/templates",
+			token:       "secret",
+			contentType: "application/json",
+			body:        `{"event":"welcome"}`,
+			statusCode:  http.StatusBadRequest,
+		},
+		{
+			name:        "apply failed",
+			method:      "PUT",
+			path:        "/admin/templates",
+			token:       "secret",
+			contentType: "application/json",
+			body:        `{"event":"welcome","lang":"en","subject":"Hi","body":{"html":"<p>Hi</p>"}}`,
+			prefunc: func() {
+				factory.SetMockApplyTemplate(func(input *dao.ApplyTemplateInput) error {
+					return errors.New("lint failed")
+				})
+			},
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "delete",
+			method: "DELETE",
+			path:   "/admin/templates/welcome_en",
+			token:  "secret",
+			prefunc: func() {
+				factory.SetMockDelete(func(name string) error { return nil })
+			},
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:    "draft delete",
+			method:  "DELETE",
+			path:    "/admin/templates/welcome_en",
+			token:   "secret",
+			session: "alice",
+			prefunc: func() {
+				factory.SetMockWorkflowStore(workflow.NewMockStore())
+				factory.SetMockDelete(func(name string) error { return errors.New("deleted without approval") })
+			},
+			statusCode: http.StatusOK,
+			contains:   `"deletes":["welcome_en"],"status":"draft"`,
+		},
+		{
+			name:   "draft delete without session",
+			method: "DELETE",
+			path:   "/admin/templates/welcome_en",
+			token:  "secret",
+			prefunc: func() {
+				factory.SetMockWorkflowStore(workflow.NewMockStore())
+				factory.SetMockDelete(func(name string) error { return errors.New("deleted without approval") })
+			},
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:        "preview",
+			method:      "POST",
+			path:        "/admin/templates/welcome_en/preview",
+			token:       "secret",
+			contentType: "application/json",
+			body:        `{"data":{"name":"Peter"}}`,
+			prefunc: func() {
+				factory.SetMockDetail(mockDetail)
+			},
+			statusCode: http.StatusOK,
+			contains:   `"name":"welcome_en","plaint":"Hi Peter","subject":"Hi Peter"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				factory.ResetMockTemplate()
+				factory.ResetMockVariableStore()
+				factory.ResetMockWorkflowStore()
+				identity.ResetMock()
+				viper.Reset()
+			}()
+			identity.SetMockWhoamiFunc(func(header http.Header) (*service.Info, error) {
+				switch header.Get("X-Session-Token") {
+				case "":
+					return nil, identity.ErrUnauthorized
+				case "carol":
+					return &service.Info{Sub: "carol", Email: "carol@oosa.life"}, nil
+				}
+				if header.Get("Authorization") != "" {
+					return nil, errors.New("admin token sent to identity")
+				}
+				return &service.Info{Sub: header.Get("X-Session-Token"), Email: header.Get("X-Session-Token") + "@oosa.life", Enable: true}, nil
+			})
+			if tt.prefunc != nil {
+				tt.prefunc()
+			}
+			engine, _ := newTestTemplateAdmin()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.session != "" {
+				req.Header.Set("X-Session-Token", tt.session)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code, w.Body.String())
+			if tt.contains != "" {
+				assert.Contains(t, w.Body.String(), tt.contains)
+			}
+			if w.Code != http.StatusNoContent {
+				assert.True(t, json.Valid(w.Body.Bytes()))
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/94peter/microservice/apitool"
+	"github.com/94peter/microservice/apitool/err"
+	"github.com/arwoosa/notifaction/router/request"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// webhookApi manages the webhook subscriptions of partner services, every request needs one of
+// the bearer tokens of api.admin.tokens.
+type webhookApi struct {
+	err.CommonErrorHandler
+	tokens []string
+}
+
+func newWebhookApi() *webhookApi {
+	return &webhookApi{
+		tokens: viper.GetStringSlice("api.admin.tokens"),
+	}
+}
+
+func (m *webhookApi) GetHandlers() []*apitool.GinHandler {
+	return []*apitool.GinHandler{
+		{
+			Path:    "/admin/webhooks",
+			Method:  "GET",
+			Handler: m.auth(m.list),
+		},
+		{
+			Path:    "/admin/webhooks",
+			Method:  "POST",
+			Handler: m.auth(m.create),
+		},
+		{
+			Path:    "/admin/webhooks/:id",
+			Method:  "GET",
+			Handler: m.auth(m.get),
+		},
+		{
+			Path:    "/admin/webhooks/:id",
+			Method:  "DELETE",
+			Handler: m.auth(m.delete),
+		},
+		{
+			Path:    "/admin/webhooks/:id/deliveries",
+			Method:  "GET",
+			Handler: m.auth(m.deliveries),
+		},
+		{
+			Path:    "/admin/webhooks/:id/ping",
+			Method:  "POST",
+			Handler: m.auth(m.ping),
+		},
+	}
+}
+
+func (m *webhookApi) auth(handler gin.HandlerFunc) gin.HandlerFunc {
+	return adminAuth(&m.CommonErrorHandler, m.tokens, handler)
+}
+
+func (m *webhookApi) stores(c *gin.Context) (webhook.Store, webhook.DeliveryStore, bool) {
+	store, deliveries, err := channelFactory.NewWebhookStores()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, nil, false
+	}
+	if store == nil {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, errors.New("webhook is not enabled"))
+		return nil, nil, false
+	}
+	return store, deliveries, true
+}
+
+// subscription returns the subscription of the id param, responding 404 when it does not exist.
+func (m *webhookApi) subscription(c *gin.Context, store webhook.Store) (*webhook.Subscription, bool) {
+	sub, err := store.Get(c.Param("id"))
+	if errors.Is(err, webhook.ErrNotFound) {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, err)
+		return nil, false
+	}
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return nil, false
+	}
+	return sub, true
+}
+
+func (m *webhookApi) list(c *gin.Context) {
+	store, _, ok := m.stores(c)
+	if !ok {
+		return
+	}
+	subs, err := store.List()
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": subs})
+}
+
+func (m *webhookApi) create(c *gin.Context) {
+	var body request.CreateWebhook
+	if err := c.BindJSON(&body); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	sub := &webhook.Subscription{
+		Name:      body.Name,
+		Url:       body.Url,
+		Secret:    body.Secret,
+		Events:    body.Events,
+		CreatedAt: time.Now(),
+	}
+	if err := sub.Validate(); err != nil {
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, err)
+		return
+	}
+	store, _, ok := m.stores(c)
+	if !ok {
+		return
+	}
+	if err := store.Create(sub); err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, sub)
+}
+
+func (m *webhookApi) get(c *gin.Context) {
+	store, _, ok := m.stores(c)
+	if !ok {
+		return
+	}
+	sub, ok := m.subscription(c, store)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+func (m *webhookApi) delete(c *gin.Context) {
+	store, _, ok := m.stores(c)
+	if !ok {
+		return
+	}
+	err := store.Delete(c.Param("id"))
+	if errors.Is(err, webhook.ErrNotFound) {
+		m.GinErrorWithStatusHandler(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// deliveries returns the latest deliveries of a subscription with their attempts, newest first.
+// The status query keeps the ones of pending, succeeded or failed, limit defaults to 50.
+func (m *webhookApi) deliveries(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", webhook.StatusPending, webhook.StatusSucceeded, webhook.StatusFailed:
+	default:
+		m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("invalid status: %s", status))
+		return
+	}
+	limit := defaultDeliveryLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxDeliveryLimit {
+			m.GinErrorWithStatusHandler(c, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxDeliveryLimit))
+			return
+		}
+		limit = n
+	}
+	store, deliveries, ok := m.stores(c)
+	if !ok {
+		return
+	}
+	sub, ok := m.subscription(c, store)
+	if !ok {
+		return
+	}
+	result, err := deliveries.List(sub.Id, status, limit)
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": result})
+}
+
+// ping posts a test ping to the subscription and returns its delivery, the outcome of the post
+// is in the attempt of the delivery.
+func (m *webhookApi) ping(c *gin.Context) {
+	store, deliveries, ok := m.stores(c)
+	if !ok {
+		return
+	}
+	sub, ok := m.subscription(c, store)
+	if !ok {
+		return
+	}
+	deliverer, err := channelFactory.NewWebhookDeliverer(deliveries)
+	if err != nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	delivery, err := deliverer.Ping(sub)
+	if delivery == nil {
+		m.GinErrorHandler(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
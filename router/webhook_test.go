@@ -0,0 +1,190 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	apiErr "github.com/94peter/microservice/apitool/err"
+	channelFactory "github.com/arwoosa/notifaction/service/channel/factory"
+	"github.com/arwoosa/notifaction/service/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestWebhookApi() *gin.Engine {
+	m := &webhookApi{tokens: []string{"secret"}}
+	m.SetErrorHandler(func(c *gin.Context, err error) {
+		status := http.StatusInternalServerError
+		if apiErr, ok := err.(apiErr.ApiError); ok {
+			status = apiErr.GetStatus()
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+	})
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	for _, h := range m.GetHandlers() {
+		engine.Handle(h.Method, h.Path, h.Handler)
+	}
+	return engine
+}
+
+func TestWebhookApi(t *testing.T) {
+	defer channelFactory.ResetMockWebhookStores()
+	store := webhook.NewMockStore()
+	deliveries := webhook.NewMockDeliveryStore()
+	channelFactory.SetMockWebhookStores(store, deliveries)
+	engine := newTestWebhookApi()
+
+	var signature string
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(webhook.SignatureHeader)
+		if err := webhook.Verify("whsec_0123456789abcdef", signature, body, time.Minute, time.Now()); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer partner.Close()
+	pinged := &webhook.Subscription{Name: "local", Url: partner.URL, Secret: "whsec_0123456789abcdef", Events: []string{"EVENT_JOIN"}}
+	assert.NoError(t, store.Create(pinged))
+
+	var created string
+	steps := []struct {
+		name       string
+		method     string
+		path       func() string
+		token      string
+		body       string
+		statusCode int
+		contains   string
+		check      func(t *testing.T, body []byte)
+	}{
+		{
+			name:       "missing token",
+			method:     "GET",
+			path:       func() string { return "/admin/webhooks" },
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "insecure url",
+			method:     "POST",
+			path:       func() string { return "/admin/webhooks" },
+			token:      "secret",
+			body:       `{"name":"partner","url":"http://partner.example.com/hooks","secret":"whsec_fedcba9876543210","events":["EVENT_JOIN"]}`,
+			statusCode: http.StatusBadRequest,
+			contains:   "url must be https",
+		},
+		{
+			name:       "create",
+			method:     "POST",
+			path:       func() string { return "/admin/webhooks" },
+			token:      "secret",
+			body:       `{"name":"partner","url":"https://partner.example.com/hooks","secret":"whsec_fedcba9876543210","events":["EVENT_JOIN","EVENT_CANCEL"]}`,
+			statusCode: http.StatusCreated,
+			check: func(t *testing.T, body []byte) {
+				sub := map[string]any{}
+				assert.NoError(t, json.Unmarshal(body, &sub))
+				assert.NotContains(t, sub, "secret")
+				created = sub["id"].(string)
+				subs, _ := store.ListByEvent("EVENT_CANCEL")
+				assert.Len(t, subs, 1)
+				assert.Equal(t, "whsec_fedcba9876543210", subs[0].Secret)
+			},
+		},
+		{
+			name:       "list",
+			method:     "GET",
+			path:       func() string { return "/admin/webhooks" },
+			token:      "secret",
+			statusCode: http.StatusOK,
+			contains:   `"url":"https://partner.example.com/hooks"`,
+			check: func(t *testing.T, body []byte) {
+				assert.NotContains(t, string(body), "whsec_")
+			},
+		},
+		{
+			name:       "get unknown",
+			method:     "GET",
+			path:       func() string { return "/admin/webhooks/unknown" },
+			token:      "secret",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "ping",
+			method:     "POST",
+			path:       func() string { return "/admin/webhooks/" + pinged.Id + "/ping" },
+			token:      "secret",
+			statusCode: http.StatusOK,
+			contains:   `"status":"succeeded"`,
+			check: func(t *testing.T, body []byte) {
+				assert.NotEmpty(t, signature)
+				assert.Contains(t, string(body), `"status_code":204`)
+			},
+		},
+		{
+			name:       "invalid delivery status",
+			method:     "GET",
+			path:       func() string { return "/admin/webhooks/" + pinged.Id + "/deliveries?status=done" },
+			token:      "secret",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "deliveries",
+			method:     "GET",
+			path:       func() string { return "/admin/webhooks/" + pinged.Id + "/deliveries?status=succeeded&limit=10" },
+			token:      "secret",
+			statusCode: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				result := struct {
+					Deliveries []*webhook.Delivery `json:"deliveries"`
+				}{}
+				assert.NoError(t, json.Unmarshal(body, &result))
+				assert.Len(t, result.Deliveries, 1)
+				assert.Equal(t, webhook.PingEvent, result.Deliveries[0].Event)
+				assert.Len(t, result.Deliveries[0].Attempts, 1)
+			},
+		},
+		{
+			name:       "delete",
+			method:     "DELETE",
+			path:       func() string { return "/admin/webhooks/" + created },
+			token:      "secret",
+			statusCode: http.StatusNoContent,
+			check: func(t *testing.T, body []byte) {
+				subs, _ := store.List()
+				assert.Len(t, subs, 1)
+			},
+		},
+		{
+			name:       "delete again",
+			method:     "DELETE",
+			path:       func() string { return "/admin/webhooks/" + created },
+			token:      "secret",
+			statusCode: http.StatusNotFound,
+		},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(step.method, step.path(), strings.NewReader(step.body))
+			req.Header.Set("Content-Type", "application/json")
+			if step.token != "" {
+				req.Header.Set("Authorization", "Bearer "+step.token)
+			}
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, step.statusCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), step.contains)
+			if step.check != nil {
+				step.check(t, w.Body.Bytes())
+			}
+		})
+	}
+}
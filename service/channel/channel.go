@@ -0,0 +1,256 @@
+package channel
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service"
+)
+
+const (
+	Email      = "email"
+	Inbox      = "inbox"
+	MobilePush = "mobilepush"
+	WebPush    = "webpush"
+	ChatOps    = "chatops"
+	Line       = "line"
+	SMS        = "sms"
+	Webhook    = "webhook"
+)
+
+// VariantEvent returns the event whose templates a channel sends. Email uses the templates of
+// the event itself, other channels the ones of <event>-<channel>, e.g. EVENT_JOIN-mobilepush_en.
+func VariantEvent(channel, event string) string {
+	if channel == Email {
+		return event
+	}
+	return event + "-" + channel
+}
+
+// BaseEvent returns the event of a variant of VariantEvent, including the <event>-chatops-<platform>
+// variants of chatops, and whether event is a variant at all.
+func BaseEvent(event string) (string, bool) {
+	if i := strings.LastIndex(event, "-"+ChatOps+"-"); i > 0 {
+		return event[:i], true
+	}
+	for _, ch := range []string{Inbox, MobilePush, WebPush, ChatOps, Line, SMS, Webhook} {
+		if base, ok := strings.CutSuffix(event, "-"+ch); ok && base != "" {
+			return base, true
+		}
+	}
+	return event, false
+}
+
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return "skipped: " + e.reason
+}
+
+// Skip returns the error of a sender that does not send to a recipient on purpose, e.g. a
+// recipient without a device for push. Skipped sends are neither successes nor failures.
+func Skip(format string, a ...any) error {
+	return &skipError{reason: fmt.Sprintf(format, a...)}
+}
+
+func IsSkip(err error) bool {
+	var s *skipError
+	return errors.As(err, &s)
+}
+
+// Broadcaster is a sender that posts an event once whatever its recipients, e.g. to a team chat.
+// A dispatcher sends it the notification of the first recipient only.
+type Broadcaster interface {
+	service.Sender
+	Broadcast()
+}
+
+// Filter decides whether the recipient of a notification receives it through a channel, e.g. by
+// the preferences of the recipient. Broadcasters have no recipient and are not filtered.
+type Filter interface {
+	Allow(channel string, notify *service.Notification) (bool, error)
+}
+
+// NewSenderFunc creates the sender of a channel, it is called once per dispatch.
+type NewSenderFunc func() (service.Sender, error)
+
+type registryOpt func(*Registry)
+
+func WithSender(channel string, newSender NewSenderFunc) registryOpt {
+	return func(r *Registry) {
+		r.senders[channel] = newSender
+	}
+}
+
+// WithSenders registers every sender of senders, keyed by channel.
+func WithSenders(senders map[string]NewSenderFunc) registryOpt {
+	return func(r *Registry) {
+		for channel, newSender := range senders {
+			r.senders[channel] = newSender
+		}
+	}
+}
+
+// WithRoutes sets the channels of events, events without a route use the default channels.
+func WithRoutes(routes map[string][]string) registryOpt {
+	return func(r *Registry) {
+		r.routes = routes
+	}
+}
+
+// WithFilter makes dispatchers skip the channels f does not allow with the reason "preference",
+// nothing is filtered when f is nil.
+func WithFilter(f Filter) registryOpt {
+	return func(r *Registry) {
+		r.filter = f
+	}
+}
+
+func WithDefaultChannels(channels ...string) registryOpt {
+	return func(r *Registry) {
+		r.defaults = channels
+	}
+}
+
+// NewRegistry returns the registry of channel senders and the channels each event is routed to.
+// Every routed channel must have a sender.
+func NewRegistry(opts ...registryOpt) (*Registry, error) {
+	r := &Registry{
+		senders:  map[string]NewSenderFunc{},
+		routes:   map[string][]string{},
+		defaults: []string{Email},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if len(r.defaults) == 0 {
+		return nil, errors.New("no default channel")
+	}
+	if err := r.checkChannels("default", r.defaults); err != nil {
+		return nil, err
+	}
+	for event, channels := range r.routes {
+		if len(channels) == 0 {
+			return nil, fmt.Errorf("event %s has no channel", event)
+		}
+		if err := r.checkChannels("event "+event, channels); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+type Registry struct {
+	senders  map[string]NewSenderFunc
+	routes   map[string][]string
+	defaults []string
+	filter   Filter
+}
+
+func (r *Registry) checkChannels(name string, channels []string) error {
+	seen := map[string]bool{}
+	for _, c := range channels {
+		if _, ok := r.senders[c]; !ok {
+			return fmt.Errorf("%s: unknown channel %s, registered channels: %v", name, c, r.Names())
+		}
+		if seen[c] {
+			return fmt.Errorf("%s: channel %s is repeated", name, c)
+		}
+		seen[c] = true
+	}
+	return nil
+}
+
+// Names returns the registered channels, sorted.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.senders))
+	for name := range r.senders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Channels returns the channels of event in the configured order.
+func (r *Registry) Channels(event string) []string {
+	if channels, ok := r.routes[event]; ok {
+		return channels
+	}
+	return r.defaults
+}
+
+// Dispatcher creates the senders of the channels of event.
+func (r *Registry) Dispatcher(event string) (*Dispatcher, error) {
+	d := &Dispatcher{filter: r.filter}
+	for _, name := range r.Channels(event) {
+		sender, err := r.senders[name]()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s sender: %w", name, err)
+		}
+		if sender == nil {
+			return nil, fmt.Errorf("failed to create %s sender", name)
+		}
+		_, broadcast := sender.(Broadcaster)
+		d.channels = append(d.channels, &channelSender{name: name, sender: sender, broadcast: broadcast})
+	}
+	return d, nil
+}
+
+type channelSender struct {
+	name      string
+	sender    service.Sender
+	broadcast bool
+	sent      bool
+}
+
+type Dispatcher struct {
+	channels []*channelSender
+	filter   Filter
+}
+
+// Result is the outcome of sending a notification through one channel.
+type Result struct {
+	Channel   string
+	MessageId string
+	// Template is the template the sender used.
+	Template string
+	Err      error
+}
+
+func (r *Result) Skipped() bool {
+	return IsSkip(r.Err)
+}
+
+// Send sends notify through every channel, a failing channel does not stop the others.
+// Broadcasters are left out once they were sent a notification.
+func (d *Dispatcher) Send(notify *service.Notification) []*Result {
+	results := make([]*Result, 0, len(d.channels))
+	for _, c := range d.channels {
+		if c.broadcast && c.sent {
+			continue
+		}
+		if !c.broadcast && d.filter != nil {
+			allowed, err := d.filter.Allow(c.name, notify)
+			if err != nil {
+				results = append(results, &Result{Channel: c.name, Err: fmt.Errorf("failed to check preferences: %w", err)})
+				continue
+			}
+			if !allowed {
+				results = append(results, &Result{Channel: c.name, Err: Skip("preference")})
+				continue
+			}
+		}
+		c.sent = true
+		// senders set TemplateUsed and may keep the data, so each channel gets its own copy
+		n := *notify
+		n.Data = maps.Clone(notify.Data)
+		mid, err := c.sender.Send(&n)
+		results = append(results, &Result{Channel: c.name, MessageId: mid, Template: n.TemplateUsed, Err: err})
+	}
+	return results
+}
@@ -0,0 +1,297 @@
+package channel
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSender struct {
+	send func(notify *service.Notification) (string, error)
+}
+
+func (f *fakeSender) Send(notify *service.Notification) (string, error) {
+	return f.send(notify)
+}
+
+type fakeBroadcaster struct {
+	fakeSender
+}
+
+func (f *fakeBroadcaster) Broadcast() {}
+
+func newFake(send func(notify *service.Notification) (string, error)) NewSenderFunc {
+	return func() (service.Sender, error) {
+		return &fakeSender{send: send}, nil
+	}
+}
+
+func TestVariantEvent(t *testing.T) {
+	assert.Equal(t, "EVENT_JOIN", VariantEvent(Email, "EVENT_JOIN"))
+	assert.Equal(t, "EVENT_JOIN-mobilepush", VariantEvent(MobilePush, "EVENT_JOIN"))
+}
+
+func TestSkip(t *testing.T) {
+	err := Skip("no device for %s", "sub1")
+	assert.EqualError(t, err, "skipped: no device for sub1")
+	assert.True(t, IsSkip(err))
+	assert.True(t, IsSkip(errors.Join(errors.New("other"), err)))
+	assert.False(t, IsSkip(errors.New("skipped: no device")))
+	assert.False(t, IsSkip(nil))
+}
+
+func TestNewRegistry(t *testing.T) {
+	ok := newFake(nil)
+	tests := []struct {
+		name     string
+		opts     []registryOpt
+		expErr   string
+		event    string
+		expected []string
+	}{
+		{
+			name:     "default email",
+			opts:     []registryOpt{WithSender(Email, ok)},
+			event:    "EVENT_JOIN",
+			expected: []string{Email},
+		},
+		{
+			name: "routed event",
+			opts: []registryOpt{
+				WithSender(Email, ok), WithSender(MobilePush, ok),
+				WithRoutes(map[string][]string{"EVENT_JOIN": {MobilePush, Email}}),
+			},
+			event:    "EVENT_JOIN",
+			expected: []string{MobilePush, Email},
+		},
+		{
+			name: "event without route",
+			opts: []registryOpt{
+				WithSender(Email, ok), WithSender(MobilePush, ok),
+				WithRoutes(map[string][]string{"EVENT_JOIN": {MobilePush}}),
+				WithDefaultChannels(Email, MobilePush),
+			},
+			event:    "EVENT_LEAVE",
+			expected: []string{Email, MobilePush},
+		},
+		{
+			name:   "no sender of default",
+			opts:   nil,
+			expErr: "default: unknown channel email, registered channels: []",
+		},
+		{
+			name:   "no default",
+			opts:   []registryOpt{WithSender(Email, ok), WithDefaultChannels()},
+			expErr: "no default channel",
+		},
+		{
+			name: "unknown routed channel",
+			opts: []registryOpt{
+				WithSender(Email, ok), WithSender(MobilePush, ok),
+				WithRoutes(map[string][]string{"EVENT_JOIN": {ChatOps}}),
+			},
+			expErr: "event EVENT_JOIN: unknown channel chatops, registered channels: [email mobilepush]",
+		},
+		{
+			name: "repeated channel",
+			opts: []registryOpt{
+				WithSender(Email, ok),
+				WithRoutes(map[string][]string{"EVENT_JOIN": {Email, Email}}),
+			},
+			expErr: "event EVENT_JOIN: channel email is repeated",
+		},
+		{
+			name: "route without channel",
+			opts: []registryOpt{
+				WithSender(Email, ok),
+				WithRoutes(map[string][]string{"EVENT_JOIN": {}}),
+			},
+			expErr: "event EVENT_JOIN has no channel",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewRegistry(test.opts...)
+			if test.expErr != "" {
+				assert.EqualError(t, err, test.expErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, r.Channels(test.event))
+		})
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	r, err := NewRegistry(
+		WithSender(Email, newFake(func(notify *service.Notification) (string, error) {
+			notify.TemplateUsed = notify.Event + "_" + notify.Lang
+			return "mail-id", nil
+		})),
+		WithSender(MobilePush, newFake(func(notify *service.Notification) (string, error) {
+			return "", Skip("no device")
+		})),
+		WithSender(ChatOps, newFake(func(notify *service.Notification) (string, error) {
+			return "", errors.New("chatops down")
+		})),
+		WithDefaultChannels(Email, MobilePush, ChatOps),
+	)
+	assert.NoError(t, err)
+	d, err := r.Dispatcher("EVENT_JOIN")
+	assert.NoError(t, err)
+
+	notify := &service.Notification{Event: "EVENT_JOIN", Lang: "en"}
+	results := d.Send(notify)
+	assert.Len(t, results, 3)
+	assert.Equal(t, &Result{Channel: Email, MessageId: "mail-id", Template: "EVENT_JOIN_en"}, results[0])
+	assert.Equal(t, MobilePush, results[1].Channel)
+	assert.True(t, results[1].Skipped())
+	assert.Equal(t, ChatOps, results[2].Channel)
+	assert.EqualError(t, results[2].Err, "chatops down")
+	assert.False(t, results[2].Skipped())
+	// senders get copies of the notification
+	assert.Empty(t, notify.TemplateUsed)
+
+	r, err = NewRegistry(WithSender(Email, func() (service.Sender, error) {
+		return nil, errors.New("not ready")
+	}))
+	assert.NoError(t, err)
+	_, err = r.Dispatcher("EVENT_JOIN")
+	assert.EqualError(t, err, "failed to create email sender: not ready")
+}
+
+func TestDispatcherCopiesData(t *testing.T) {
+	var kept map[string]string
+	r, err := NewRegistry(WithSender(Email, newFake(func(notify *service.Notification) (string, error) {
+		kept = notify.Data
+		return "mail-id", nil
+	})))
+	assert.NoError(t, err)
+	d, err := r.Dispatcher("EVENT_JOIN")
+	assert.NoError(t, err)
+
+	data := map[string]string{"TO": "bob"}
+	d.Send(&service.Notification{Event: "EVENT_JOIN", Data: data})
+	// callers reuse the data for the next recipient
+	data["TO"] = "carol"
+	assert.Equal(t, map[string]string{"TO": "bob"}, kept)
+}
+
+func TestDispatcherBroadcast(t *testing.T) {
+	sent := 0
+	r, err := NewRegistry(
+		WithSender(Email, newFake(func(notify *service.Notification) (string, error) {
+			return "mail-" + notify.SendTo[0].Sub, nil
+		})),
+		WithSender(ChatOps, func() (service.Sender, error) {
+			return &fakeBroadcaster{fakeSender{send: func(notify *service.Notification) (string, error) {
+				sent++
+				return "posted", nil
+			}}}, nil
+		}),
+		WithDefaultChannels(Email, ChatOps),
+	)
+	assert.NoError(t, err)
+	d, err := r.Dispatcher("EVENT_REPORT")
+	assert.NoError(t, err)
+
+	results := d.Send(&service.Notification{Event: "EVENT_REPORT", SendTo: []*service.Info{{Sub: "bob"}}})
+	assert.Len(t, results, 2)
+	assert.Equal(t, ChatOps, results[1].Channel)
+	// the event is posted once for every recipient
+	results = d.Send(&service.Notification{Event: "EVENT_REPORT", SendTo: []*service.Info{{Sub: "carol"}}})
+	assert.Equal(t, []*Result{{Channel: Email, MessageId: "mail-carol"}}, results)
+	assert.Equal(t, 1, sent)
+}
+
+type fakeFilter func(channel string, notify *service.Notification) (bool, error)
+
+func (f fakeFilter) Allow(channel string, notify *service.Notification) (bool, error) {
+	return f(channel, notify)
+}
+
+func TestDispatcherFilter(t *testing.T) {
+	send := func(notify *service.Notification) (string, error) {
+		return "sent-" + notify.SendTo[0].Sub, nil
+	}
+	r, err := NewRegistry(
+		WithSender(Email, newFake(send)),
+		WithSender(SMS, newFake(send)),
+		WithSender(ChatOps, func() (service.Sender, error) {
+			return &fakeBroadcaster{fakeSender{send: func(notify *service.Notification) (string, error) {
+				return "posted", nil
+			}}}, nil
+		}),
+		WithDefaultChannels(Email, SMS, ChatOps),
+		WithFilter(fakeFilter(func(channel string, notify *service.Notification) (bool, error) {
+			switch notify.SendTo[0].Sub {
+			case "muted":
+				return false, nil
+			case "broken":
+				return false, errors.New("store is down")
+			}
+			return channel != SMS, nil
+		})),
+	)
+	assert.NoError(t, err)
+	d, err := r.Dispatcher("EVENT_JOIN")
+	assert.NoError(t, err)
+
+	// broadcasters have no recipient to filter
+	results := d.Send(&service.Notification{Event: "EVENT_JOIN", SendTo: []*service.Info{{Sub: "muted"}}})
+	assert.Len(t, results, 3)
+	assert.EqualError(t, results[0].Err, "skipped: preference")
+	assert.True(t, results[1].Skipped())
+	assert.Equal(t, &Result{Channel: ChatOps, MessageId: "posted"}, results[2])
+
+	results = d.Send(&service.Notification{Event: "EVENT_JOIN", SendTo: []*service.Info{{Sub: "bob"}}})
+	assert.Len(t, results, 2)
+	assert.Equal(t, &Result{Channel: Email, MessageId: "sent-bob"}, results[0])
+	assert.True(t, results[1].Skipped())
+
+	results = d.Send(&service.Notification{Event: "EVENT_JOIN", SendTo: []*service.Info{{Sub: "broken"}}})
+	assert.EqualError(t, results[0].Err, "failed to check preferences: store is down")
+	assert.False(t, results[0].Skipped())
+}
+
+func TestEscaped(t *testing.T) {
+	data := map[string]string{"TITLE": `<b>"Tom & Jerry"</b>`}
+	assert.Equal(t, map[string]string{"TITLE": `\u003cb\u003e\"Tom \u0026 Jerry\"\u003c/b\u003e`}, JSONEscaped(data))
+	assert.Equal(t, map[string]string{"TITLE": `&lt;b&gt;&#34;Tom &amp; Jerry&#34;&lt;/b&gt;`}, HTMLEscaped(data))
+}
+
+func TestResolveDraft(t *testing.T) {
+	tpls := mail.NewTemplateSet(dao.NewTemplate("draft-EVENT_JOIN", "en", "subject", "plain", "html"))
+	notify := &service.Notification{Event: "draft-EVENT_JOIN", Lang: "en"}
+	_, err := Resolve(tpls, lang.NewResolver(), Email, notify)
+	assert.ErrorIs(t, err, mail.ErrDraftEvent)
+	assert.Empty(t, notify.TemplateUsed)
+}
+
+func TestBaseEvent(t *testing.T) {
+	tests := []struct {
+		event   string
+		want    string
+		variant bool
+	}{
+		{event: "EVENT_JOIN", want: "EVENT_JOIN"},
+		{event: "EVENT_JOIN-sms", want: "EVENT_JOIN", variant: true},
+		{event: "EVENT_JOIN-mobilepush", want: "EVENT_JOIN", variant: true},
+		{event: "EVENT_REPORT-chatops-slack", want: "EVENT_REPORT", variant: true},
+		{event: "EVENT_REPORT-chatops", want: "EVENT_REPORT", variant: true},
+		{event: "-sms", want: "-sms"},
+		{event: "EVENT-other", want: "EVENT-other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			base, variant := BaseEvent(tt.event)
+			assert.Equal(t, tt.want, base)
+			assert.Equal(t, tt.variant, variant)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package factory
+
+import (
+	"fmt"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/chatops"
+	mailFactory "github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/spf13/viper"
+)
+
+func ChatOpsEnabled() bool {
+	return viper.IsSet("chatops.destinations")
+}
+
+// NewChatOpsRoutes reads chatops.routes as a list because viper lowercases map keys.
+func NewChatOpsRoutes() (map[string][]*chatops.Destination, error) {
+	var destinations []*chatops.Destination
+	if err := viper.UnmarshalKey("chatops.destinations", &destinations); err != nil {
+		return nil, fmt.Errorf("invalid chatops.destinations: %w", err)
+	}
+	var routes []*chatops.Route
+	if err := viper.UnmarshalKey("chatops.routes", &routes); err != nil {
+		return nil, fmt.Errorf("invalid chatops.routes: %w", err)
+	}
+	result, err := chatops.NewRoutes(destinations, routes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chatops: %w", err)
+	}
+	return result, nil
+}
+
+func newChatOpsSender() (service.Sender, error) {
+	routes, err := NewChatOpsRoutes()
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := mailFactory.NewTemplate()
+	if err != nil {
+		return nil, err
+	}
+	maxRetries := -1
+	if viper.IsSet("chatops.max_retries") {
+		maxRetries = viper.GetInt("chatops.max_retries")
+	}
+	return chatops.NewSender(routes, tpl,
+		chatops.WithMaxRetries(maxRetries),
+		chatops.WithMaxRetryWait(viper.GetDuration("chatops.max_retry_wait")),
+	)
+}
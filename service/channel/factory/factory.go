@@ -0,0 +1,76 @@
+package factory
+
+import (
+	"fmt"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	mailFactory "github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/spf13/viper"
+)
+
+type route struct {
+	Event    string   `mapstructure:"event"`
+	Channels []string `mapstructure:"channels"`
+}
+
+// NewRegistry returns the registry of every channel the service sends through, routed by
+// notification.channels. Routes are a list because viper lowercases map keys and events are upper case.
+func NewRegistry() (*channel.Registry, error) {
+	var routes []route
+	if err := viper.UnmarshalKey("notification.channels.routes", &routes); err != nil {
+		return nil, fmt.Errorf("invalid notification.channels.routes: %w", err)
+	}
+	routeMap := map[string][]string{}
+	for _, r := range routes {
+		if r.Event == "" {
+			return nil, fmt.Errorf("invalid notification.channels.routes: event is empty")
+		}
+		if _, ok := routeMap[r.Event]; ok {
+			return nil, fmt.Errorf("invalid notification.channels.routes: event %s is repeated", r.Event)
+		}
+		routeMap[r.Event] = r.Channels
+	}
+	defaults := viper.GetStringSlice("notification.channels.default")
+	if len(defaults) == 0 {
+		defaults = []string{channel.Email}
+	}
+	senders := map[string]channel.NewSenderFunc{
+		channel.Email: newEmailSender,
+	}
+	if InboxEnabled() {
+		senders[channel.Inbox] = newInboxSender
+	}
+	if WebPushEnabled() {
+		senders[channel.WebPush] = newWebPushSender
+	}
+	if MobilePushEnabled() {
+		senders[channel.MobilePush] = newMobilePushSender
+	}
+	if LineEnabled() {
+		senders[channel.Line] = newLineSender
+	}
+	if SMSEnabled() {
+		senders[channel.SMS] = newSMSSender
+	}
+	if ChatOpsEnabled() {
+		senders[channel.ChatOps] = newChatOpsSender
+	}
+	if WebhookEnabled() {
+		senders[channel.Webhook] = newWebhookSender
+	}
+	filter, err := newPreferenceFilter()
+	if err != nil {
+		return nil, err
+	}
+	return channel.NewRegistry(
+		channel.WithSenders(senders),
+		channel.WithRoutes(routeMap),
+		channel.WithDefaultChannels(defaults...),
+		channel.WithFilter(filter),
+	)
+}
+
+func newEmailSender() (service.Sender, error) {
+	return mailFactory.NewApiSender()
+}
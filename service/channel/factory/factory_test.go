@@ -0,0 +1,334 @@
+package factory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/inbox"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	mailFactory "github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mobilepush"
+	"github.com/arwoosa/notifaction/service/preference"
+	"github.com/arwoosa/notifaction/service/webhook"
+	"github.com/arwoosa/notifaction/service/webpush"
+	"github.com/spf13/viper"
+)
+
+func TestNewRegistry(t *testing.T) {
+	defer viper.Reset()
+
+	tests := []struct {
+		name     string
+		setup    func()
+		routes   []map[string]any
+		event    string
+		expected []string
+		expErr   string
+	}{
+		{
+			name:     "default email channel",
+			event:    "EVENT_JOIN",
+			expected: []string{"email"},
+		},
+		{
+			name:     "routed email channel",
+			routes:   []map[string]any{{"event": "EVENT_JOIN", "channels": []string{"email"}}},
+			event:    "EVENT_JOIN",
+			expected: []string{"email"},
+		},
+		{
+			name:     "inbox",
+			setup:    func() { SetMockInboxStore(inbox.NewMockStore(nil)) },
+			routes:   []map[string]any{{"event": "EVENT_JOIN", "channels": []string{"email", "inbox"}}},
+			event:    "EVENT_JOIN",
+			expected: []string{"email", "inbox"},
+		},
+		{
+			name:     "webpush",
+			setup:    func() { SetMockPushSubscriptionStore(webpush.NewMockStore()) },
+			routes:   []map[string]any{{"event": "EVENT_JOIN", "channels": []string{"webpush"}}},
+			event:    "EVENT_JOIN",
+			expected: []string{"webpush"},
+		},
+		{
+			name:     "mobilepush",
+			setup:    func() { SetMockDeviceStore(mobilepush.NewMockStore()) },
+			routes:   []map[string]any{{"event": "EVENT_JOIN", "channels": []string{"email", "mobilepush"}}},
+			event:    "EVENT_JOIN",
+			expected: []string{"email", "mobilepush"},
+		},
+		{
+			name:     "line",
+			setup:    func() { viper.Set("line.messaging.access_token", "token") },
+			routes:   []map[string]any{{"event": "EVENT_JOIN", "channels": []string{"line"}}},
+			event:    "EVENT_JOIN",
+			expected: []string{"line"},
+		},
+		{
+			name:     "sms",
+			setup:    func() { viper.Set("sms.provider", "twilio") },
+			routes:   []map[string]any{{"event": "EVENT_CANCEL", "channels": []string{"email", "sms"}}},
+			event:    "EVENT_CANCEL",
+			expected: []string{"email", "sms"},
+		},
+		{
+			name: "chatops",
+			setup: func() {
+				viper.Set("chatops.destinations", []map[string]any{
+					{"name": "ops", "platform": "slack", "url": "https://hooks.slack.com/services/T/B/X"},
+				})
+			},
+			routes:   []map[string]any{{"event": "EVENT_REPORT", "channels": []string{"chatops"}}},
+			event:    "EVENT_REPORT",
+			expected: []string{"chatops"},
+		},
+		{
+			name:     "webhook",
+			setup:    func() { SetMockWebhookStores(webhook.NewMockStore(), webhook.NewMockDeliveryStore()) },
+			routes:   []map[string]any{{"event": "EVENT_REPORT", "channels": []string{"email", "webhook"}}},
+			event:    "EVENT_REPORT",
+			expected: []string{"email", "webhook"},
+		},
+		{
+			name: "invalid preference category",
+			setup: func() {
+				SetMockPreferenceStore(preference.NewMockStore())
+				viper.Set("preference.categories", []map[string]any{{"name": "social", "defaults": map[string]bool{"fax": false}}})
+			},
+			expErr: "invalid preference.categories: category social: invalid channel fax, must be one of [email inbox webpush mobilepush line sms]",
+		},
+		{
+			name:   "empty event",
+			routes: []map[string]any{{"channels": []string{"email"}}},
+			expErr: "invalid notification.channels.routes: event is empty",
+		},
+		{
+			name: "repeated event",
+			routes: []map[string]any{
+				{"event": "EVENT_JOIN", "channels": []string{"email"}},
+				{"event": "EVENT_JOIN", "channels": []string{"email"}},
+			},
+			expErr: "invalid notification.channels.routes: event EVENT_JOIN is repeated",
+		},
+		{
+			name:   "unknown channel",
+			routes: []map[string]any{{"event": "EVENT_JOIN", "channels": []string{"fax"}}},
+			expErr: "event EVENT_JOIN: unknown channel fax, registered channels: [email]",
+		},
+		{
+			name:   "inbox is not enabled",
+			routes: []map[string]any{{"event": "EVENT_JOIN", "channels": []string{"inbox"}}},
+			expErr: "event EVENT_JOIN: unknown channel inbox, registered channels: [email]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Reset()
+			defer resetMocks()
+			if test.setup != nil {
+				test.setup()
+			}
+			viper.Set("notification.channels.routes", test.routes)
+			r, err := NewRegistry()
+			if test.expErr != "" {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("expected error %q, got %v", test.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := r.Channels(test.event); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected channels %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func resetMocks() {
+	ResetMockInboxStore()
+	ResetMockPushSubscriptionStore()
+	ResetMockDeviceStore()
+	ResetMockWebhookStores()
+	ResetMockPreferenceStore()
+}
+
+func TestNewInboxBroker(t *testing.T) {
+	defer viper.Reset()
+	defer ResetInboxBroker()
+
+	ResetInboxBroker()
+	broker, err := NewInboxBroker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, _ := NewInboxBroker(); again != broker {
+		t.Errorf("expected the broker to be shared")
+	}
+
+	ResetInboxBroker()
+	viper.Set("inbox.stream.broker", "redis")
+	if _, err := NewInboxBroker(); err == nil || err.Error() != "invalid inbox.stream.broker: redis" {
+		t.Errorf("expected invalid broker error, got %v", err)
+	}
+}
+
+func TestPushAllowedHosts(t *testing.T) {
+	defer viper.Reset()
+
+	tests := []struct {
+		name     string
+		hosts    []string
+		expected []string
+	}{
+		{name: "default hosts", expected: webpush.DefaultAllowedHosts},
+		{name: "configured hosts", hosts: []string{"push.example.com"}, expected: []string{"push.example.com"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Set("webpush.allowed_hosts", test.hosts)
+			if got := PushAllowedHosts(); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected hosts %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewMobilePushSender(t *testing.T) {
+	defer viper.Reset()
+	SetMockDeviceStore(mobilepush.NewMockStore())
+	defer ResetMockDeviceStore()
+	mailFactory.SetMockDetail(func(name string) (*dao.DetailTemplateResponse, error) {
+		return &dao.DetailTemplateResponse{}, nil
+	})
+	defer mailFactory.ResetMockTemplate()
+
+	if _, err := newMobilePushSender(); err == nil || err.Error() != "at least one push provider is required" {
+		t.Errorf("expected missing provider error, got %v", err)
+	}
+	viper.Set("mobilepush.apns.key_file", "missing.p8")
+	if _, err := newMobilePushSender(); err == nil || !strings.HasPrefix(err.Error(), "failed to read apns key") {
+		t.Errorf("expected apns key error, got %v", err)
+	}
+}
+
+func TestNewLineClient(t *testing.T) {
+	defer viper.Reset()
+	defer ResetLineClient()
+
+	ResetLineClient()
+	if _, err := NewLineClient(); err == nil || err.Error() != "line channel access token is required" {
+		t.Errorf("expected missing token error, got %v", err)
+	}
+	viper.Set("line.messaging.access_token", "token")
+	client, err := NewLineClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, _ := NewLineClient(); again != client {
+		t.Errorf("expected the client to be shared")
+	}
+	if login, err := NewLineLogin(); login != nil || err != nil {
+		t.Errorf("expected no login without line.login.channel_id, got %v, %v", login, err)
+	}
+}
+
+func TestNewSMSProvider(t *testing.T) {
+	defer viper.Reset()
+
+	tests := []struct {
+		name   string
+		config map[string]any
+		expErr string
+	}{
+		{
+			name:   "twilio",
+			config: map[string]any{"sms.provider": "twilio", "sms.twilio.account_sid": "AC1", "sms.twilio.auth_token": "t", "sms.twilio.from": "+15005550006"},
+		},
+		{
+			name:   "twilio without credentials",
+			config: map[string]any{"sms.provider": "twilio"},
+			expErr: "twilio needs account sid, auth token and from",
+		},
+		{
+			name:   "sns without aws session",
+			config: map[string]any{"sms.provider": "sns", "sms.sns.region": "ap-northeast-1"},
+			expErr: "aws.ses.credentails.filename is empty",
+		},
+		{
+			name:   "unknown provider",
+			config: map[string]any{"sms.provider": "fax"},
+			expErr: "invalid sms.provider: fax",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Reset()
+			for k, v := range test.config {
+				viper.Set(k, v)
+			}
+			_, err := NewSMSProvider()
+			if test.expErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if test.expErr != "" && (err == nil || err.Error() != test.expErr) {
+				t.Errorf("expected error %q, got %v", test.expErr, err)
+			}
+		})
+	}
+}
+
+func TestNewChatOpsRoutes(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("chatops.destinations", []map[string]any{
+		{"name": "ops-slack", "platform": "slack", "url": "https://hooks.slack.com/services/T/B/X"},
+		{"name": "ops-telegram", "platform": "telegram", "bot_token": "123:abc", "chat_id": "-100", "lang": "en"},
+	})
+
+	tests := []struct {
+		name         string
+		destinations []string
+		expected     []string
+		expErr       string
+	}{
+		{
+			name:         "routed destinations",
+			destinations: []string{"ops-slack", "ops-telegram"},
+			expected:     []string{"ops-slack", "ops-telegram"},
+		},
+		{
+			name:         "unknown destination",
+			destinations: []string{"ops-discord"},
+			expErr:       "invalid chatops: event EVENT_REPORT: unknown destination ops-discord",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Set("chatops.routes", []map[string]any{
+				{"event": "EVENT_REPORT", "destinations": test.destinations},
+			})
+			routes, err := NewChatOpsRoutes()
+			if test.expErr != "" {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("expected error %q, got %v", test.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := []string{}
+			for _, d := range routes["EVENT_REPORT"] {
+				got = append(got, d.Name)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected destinations %v, got %v", test.expected, got)
+			}
+			if telegram := routes["EVENT_REPORT"][1]; telegram.ChatId != "-100" || telegram.Lang != "en" {
+				t.Errorf("unexpected telegram destination: %v", telegram)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package factory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/inbox"
+	mailFactory "github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mongodb"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	inboxMu         sync.Mutex
+	inboxCollection *mongo.Collection
+	inboxStore      inbox.Store
+	inboxBroker     inbox.Broker
+)
+
+func InboxEnabled() bool {
+	return mockInboxStore != nil || viper.GetString("inbox.mongo.uri") != ""
+}
+
+func NewInboxStore() (inbox.Store, error) {
+	if mockInboxStore != nil {
+		return mockInboxStore, nil
+	}
+	if viper.GetString("inbox.mongo.uri") == "" {
+		return nil, nil
+	}
+	inboxMu.Lock()
+	defer inboxMu.Unlock()
+	if inboxStore != nil {
+		return inboxStore, nil
+	}
+	coll, err := getInboxCollection()
+	if err != nil {
+		return nil, err
+	}
+	inboxStore = inbox.NewMongoStore(coll)
+	return inboxStore, nil
+}
+
+// NewInboxBroker defaults to memory, which only reaches the subscribers of this replica.
+func NewInboxBroker() (inbox.Broker, error) {
+	inboxMu.Lock()
+	defer inboxMu.Unlock()
+	if inboxBroker != nil {
+		return inboxBroker, nil
+	}
+	switch broker := viper.GetString("inbox.stream.broker"); broker {
+	case "", "memory":
+		inboxBroker = inbox.NewMemoryBroker()
+	case "mongo":
+		coll, err := getInboxCollection()
+		if err != nil {
+			return nil, err
+		}
+		inboxBroker = inbox.NewMongoBroker(coll)
+	default:
+		return nil, fmt.Errorf("invalid inbox.stream.broker: %s", broker)
+	}
+	return inboxBroker, nil
+}
+
+func ResetInboxBroker() {
+	inboxMu.Lock()
+	defer inboxMu.Unlock()
+	inboxBroker = nil
+}
+
+// getInboxCollection is called with inboxMu held.
+func getInboxCollection() (*mongo.Collection, error) {
+	if inboxCollection != nil {
+		return inboxCollection, nil
+	}
+	coll, err := mongodb.Collection("inbox", "inbox")
+	if err != nil {
+		return nil, err
+	}
+	if err := inbox.EnsureIndexes(coll); err != nil {
+		return nil, err
+	}
+	inboxCollection = coll
+	return inboxCollection, nil
+}
+
+func newInboxSender() (service.Sender, error) {
+	store, err := NewInboxStore()
+	if err != nil {
+		return nil, err
+	}
+	broker, err := NewInboxBroker()
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := mailFactory.NewTemplate()
+	if err != nil {
+		return nil, err
+	}
+	return inbox.NewSender(store, tpl, inbox.WithBroker(broker))
+}
@@ -0,0 +1,98 @@
+package factory
+
+import (
+	"sync"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/line"
+	mailFactory "github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mongodb"
+	"github.com/spf13/viper"
+)
+
+var (
+	lineMu     sync.Mutex
+	lineStore  line.Store
+	lineClient *line.Client
+)
+
+func LineEnabled() bool {
+	return mockLineStore != nil || viper.GetString("line.messaging.access_token") != ""
+}
+
+// NewLineStore returns nil without line.mongo, LINE users are then read from identity traits.
+func NewLineStore() (line.Store, error) {
+	if mockLineStore != nil {
+		return mockLineStore, nil
+	}
+	if viper.GetString("line.mongo.uri") == "" {
+		return nil, nil
+	}
+	lineMu.Lock()
+	defer lineMu.Unlock()
+	if lineStore != nil {
+		return lineStore, nil
+	}
+	coll, err := mongodb.Collection("line", "line_links")
+	if err != nil {
+		return nil, err
+	}
+	if err := line.EnsureIndexes(coll); err != nil {
+		return nil, err
+	}
+	lineStore = line.NewMongoStore(coll)
+	return lineStore, nil
+}
+
+// NewLineClient is shared so that every push counts against one quota.
+func NewLineClient() (*line.Client, error) {
+	lineMu.Lock()
+	defer lineMu.Unlock()
+	if lineClient != nil {
+		return lineClient, nil
+	}
+	client, err := line.NewClient(viper.GetString("line.messaging.access_token"),
+		line.WithApiUrl(viper.GetString("line.messaging.api_url")),
+		line.WithQuotaRefresh(viper.GetDuration("line.messaging.quota_refresh")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	lineClient = client
+	return lineClient, nil
+}
+
+func ResetLineClient() {
+	lineMu.Lock()
+	defer lineMu.Unlock()
+	lineClient = nil
+}
+
+func NewLineLogin() (*line.Login, error) {
+	if viper.GetString("line.login.channel_id") == "" {
+		return nil, nil
+	}
+	return line.NewLogin(
+		viper.GetString("line.login.channel_id"),
+		viper.GetString("line.login.channel_secret"),
+		viper.GetString("line.login.redirect_url"),
+		line.WithLoginApiUrl(viper.GetString("line.login.api_url")),
+		line.WithLoginAuthorizeUrl(viper.GetString("line.login.authorize_url")),
+	)
+}
+
+func newLineSender() (service.Sender, error) {
+	store, err := NewLineStore()
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewLineClient()
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := mailFactory.NewTemplate()
+	if err != nil {
+		return nil, err
+	}
+	return line.NewSender(client, tpl, line.WithStore(store))
+}
@@ -0,0 +1,102 @@
+package factory
+
+import (
+	"sync"
+
+	"github.com/arwoosa/notifaction/service"
+	mailFactory "github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mobilepush"
+	"github.com/arwoosa/notifaction/service/mongodb"
+	"github.com/spf13/viper"
+)
+
+var (
+	mobilePushMu sync.Mutex
+	deviceStore  mobilepush.Store
+)
+
+func MobilePushEnabled() bool {
+	return mockDeviceStore != nil || viper.GetString("mobilepush.mongo.uri") != ""
+}
+
+func NewDeviceStore() (mobilepush.Store, error) {
+	if mockDeviceStore != nil {
+		return mockDeviceStore, nil
+	}
+	if viper.GetString("mobilepush.mongo.uri") == "" {
+		return nil, nil
+	}
+	mobilePushMu.Lock()
+	defer mobilePushMu.Unlock()
+	if deviceStore != nil {
+		return deviceStore, nil
+	}
+	coll, err := mongodb.Collection("mobilepush", "devices")
+	if err != nil {
+		return nil, err
+	}
+	if err := mobilepush.EnsureIndexes(coll); err != nil {
+		return nil, err
+	}
+	deviceStore = mobilepush.NewMongoStore(coll)
+	return deviceStore, nil
+}
+
+// newMobilePushSender leaves out the platforms without credentials.
+func newMobilePushSender() (service.Sender, error) {
+	store, err := NewDeviceStore()
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := mailFactory.NewTemplate()
+	if err != nil {
+		return nil, err
+	}
+	fcm, err := newFcmProvider()
+	if err != nil {
+		return nil, err
+	}
+	apns, err := newApnsProvider()
+	if err != nil {
+		return nil, err
+	}
+	return mobilepush.NewSender(store, tpl,
+		mobilepush.WithProvider(mobilepush.Android, fcm),
+		mobilepush.WithProvider(mobilepush.IOS, apns),
+	)
+}
+
+func newFcmProvider() (mobilepush.Provider, error) {
+	file := viper.GetString("mobilepush.fcm.credentials_file")
+	if file == "" {
+		return nil, nil
+	}
+	account, err := mobilepush.LoadServiceAccount(file)
+	if err != nil {
+		return nil, err
+	}
+	if project := viper.GetString("mobilepush.fcm.project_id"); project != "" {
+		account.ProjectId = project
+	}
+	return mobilepush.NewFCM(account,
+		mobilepush.WithFcmEndpoint(viper.GetString("mobilepush.fcm.endpoint")),
+		mobilepush.WithFcmTokenUrl(viper.GetString("mobilepush.fcm.token_url")),
+	)
+}
+
+func newApnsProvider() (mobilepush.Provider, error) {
+	file := viper.GetString("mobilepush.apns.key_file")
+	if file == "" {
+		return nil, nil
+	}
+	key, err := mobilepush.LoadApnsKey(file)
+	if err != nil {
+		return nil, err
+	}
+	return mobilepush.NewAPNs(key,
+		viper.GetString("mobilepush.apns.key_id"),
+		viper.GetString("mobilepush.apns.team_id"),
+		viper.GetString("mobilepush.apns.topic"),
+		mobilepush.WithApnsEndpoint(viper.GetString("mobilepush.apns.endpoint")),
+	)
+}
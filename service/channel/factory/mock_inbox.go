@@ -0,0 +1,13 @@
+package factory
+
+import "github.com/arwoosa/notifaction/service/inbox"
+
+var mockInboxStore inbox.Store
+
+func SetMockInboxStore(store inbox.Store) {
+	mockInboxStore = store
+}
+
+func ResetMockInboxStore() {
+	mockInboxStore = nil
+}
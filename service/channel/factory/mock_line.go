@@ -0,0 +1,13 @@
+package factory
+
+import "github.com/arwoosa/notifaction/service/line"
+
+var mockLineStore line.Store
+
+func SetMockLineStore(store line.Store) {
+	mockLineStore = store
+}
+
+func ResetMockLineStore() {
+	mockLineStore = nil
+}
@@ -0,0 +1,13 @@
+package factory
+
+import "github.com/arwoosa/notifaction/service/mobilepush"
+
+var mockDeviceStore mobilepush.Store
+
+func SetMockDeviceStore(store mobilepush.Store) {
+	mockDeviceStore = store
+}
+
+func ResetMockDeviceStore() {
+	mockDeviceStore = nil
+}
@@ -0,0 +1,13 @@
+package factory
+
+import "github.com/arwoosa/notifaction/service/preference"
+
+var mockPreferenceStore preference.Store
+
+func SetMockPreferenceStore(store preference.Store) {
+	mockPreferenceStore = store
+}
+
+func ResetMockPreferenceStore() {
+	mockPreferenceStore = nil
+}
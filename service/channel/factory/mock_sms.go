@@ -0,0 +1,13 @@
+package factory
+
+import "github.com/arwoosa/notifaction/service/sms"
+
+var mockSMSProvider sms.Provider
+
+func SetMockSMSProvider(provider sms.Provider) {
+	mockSMSProvider = provider
+}
+
+func ResetMockSMSProvider() {
+	mockSMSProvider = nil
+}
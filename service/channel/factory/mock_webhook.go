@@ -0,0 +1,18 @@
+package factory
+
+import "github.com/arwoosa/notifaction/service/webhook"
+
+var (
+	mockWebhookStore         webhook.Store
+	mockWebhookDeliveryStore webhook.DeliveryStore
+)
+
+func SetMockWebhookStores(store webhook.Store, deliveries webhook.DeliveryStore) {
+	mockWebhookStore = store
+	mockWebhookDeliveryStore = deliveries
+}
+
+func ResetMockWebhookStores() {
+	mockWebhookStore = nil
+	mockWebhookDeliveryStore = nil
+}
@@ -0,0 +1,13 @@
+package factory
+
+import "github.com/arwoosa/notifaction/service/webpush"
+
+var mockPushStore webpush.Store
+
+func SetMockPushSubscriptionStore(store webpush.Store) {
+	mockPushStore = store
+}
+
+func ResetMockPushSubscriptionStore() {
+	mockPushStore = nil
+}
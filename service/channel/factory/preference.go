@@ -0,0 +1,67 @@
+package factory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/mongodb"
+	"github.com/arwoosa/notifaction/service/preference"
+	"github.com/spf13/viper"
+)
+
+var (
+	preferenceMu    sync.Mutex
+	preferenceStore preference.Store
+)
+
+func PreferenceEnabled() bool {
+	return mockPreferenceStore != nil || viper.GetString("preference.mongo.uri") != ""
+}
+
+func NewPreferenceStore() (preference.Store, error) {
+	if mockPreferenceStore != nil {
+		return mockPreferenceStore, nil
+	}
+	if viper.GetString("preference.mongo.uri") == "" {
+		return nil, nil
+	}
+	preferenceMu.Lock()
+	defer preferenceMu.Unlock()
+	if preferenceStore != nil {
+		return preferenceStore, nil
+	}
+	coll, err := mongodb.Collection("preference", "preferences")
+	if err != nil {
+		return nil, err
+	}
+	preferenceStore = preference.NewMongoStore(coll)
+	return preferenceStore, nil
+}
+
+func NewPreferenceCategories() (*preference.Categories, error) {
+	var categories []*preference.Category
+	if err := viper.UnmarshalKey("preference.categories", &categories); err != nil {
+		return nil, fmt.Errorf("invalid preference.categories: %w", err)
+	}
+	result, err := preference.NewCategories(categories)
+	if err != nil {
+		return nil, fmt.Errorf("invalid preference.categories: %w", err)
+	}
+	return result, nil
+}
+
+func newPreferenceFilter() (channel.Filter, error) {
+	if !PreferenceEnabled() {
+		return nil, nil
+	}
+	store, err := NewPreferenceStore()
+	if err != nil {
+		return nil, err
+	}
+	categories, err := NewPreferenceCategories()
+	if err != nil {
+		return nil, err
+	}
+	return preference.NewFilter(store, categories), nil
+}
@@ -0,0 +1,100 @@
+package factory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	mailAws "github.com/arwoosa/notifaction/service/mail/aws"
+	mailFactory "github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mongodb"
+	"github.com/arwoosa/notifaction/service/sms"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/spf13/viper"
+)
+
+var (
+	smsMu      sync.Mutex
+	smsCounter sms.Counter
+)
+
+func SMSEnabled() bool {
+	return mockSMSProvider != nil || viper.GetString("sms.provider") != ""
+}
+
+// NewSMSProvider reuses the aws.ses session for SNS.
+func NewSMSProvider() (sms.Provider, error) {
+	if mockSMSProvider != nil {
+		return mockSMSProvider, nil
+	}
+	switch provider := viper.GetString("sms.provider"); provider {
+	case "twilio":
+		return sms.NewTwilio(
+			viper.GetString("sms.twilio.account_sid"),
+			viper.GetString("sms.twilio.auth_token"),
+			viper.GetString("sms.twilio.from"),
+			sms.WithTwilioApiUrl(viper.GetString("sms.twilio.api_url")),
+		)
+	case "sns":
+		cfg := mailAws.ConfigFromViper()
+		if region := viper.GetString("sms.sns.region"); region != "" {
+			cfg.Region = region
+		}
+		sess, err := mailAws.NewSession(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sms.NewSNS(sns.New(sess), sms.WithSenderId(viper.GetString("sms.sns.sender_id")))
+	default:
+		return nil, fmt.Errorf("invalid sms.provider: %s", provider)
+	}
+}
+
+// newSMSCounter counts in sms.mongo when it is set, so that the daily cap holds across replicas.
+func newSMSCounter() (sms.Counter, error) {
+	smsMu.Lock()
+	defer smsMu.Unlock()
+	if smsCounter != nil {
+		return smsCounter, nil
+	}
+	if viper.GetString("sms.mongo.uri") == "" {
+		smsCounter = sms.NewMemoryCounter()
+		return smsCounter, nil
+	}
+	coll, err := mongodb.Collection("sms", "sms_counts")
+	if err != nil {
+		return nil, err
+	}
+	if err := sms.EnsureCounterIndexes(coll); err != nil {
+		return nil, err
+	}
+	smsCounter = sms.NewMongoCounter(coll)
+	return smsCounter, nil
+}
+
+func newSMSSender() (service.Sender, error) {
+	provider, err := NewSMSProvider()
+	if err != nil {
+		return nil, err
+	}
+	counter, err := newSMSCounter()
+	if err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	if tz := viper.GetString("sms.cap.timezone"); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid sms.cap.timezone: %w", err)
+		}
+	}
+	tpl, err := mailFactory.NewTemplate()
+	if err != nil {
+		return nil, err
+	}
+	return sms.NewSender(provider, tpl,
+		sms.WithDailyCap(viper.GetInt("sms.cap.daily"), counter),
+		sms.WithLocation(loc),
+		sms.WithMaxSegments(viper.GetInt("sms.max_segments")),
+	)
+}
@@ -0,0 +1,85 @@
+package factory
+
+import (
+	"sync"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/mongodb"
+	"github.com/arwoosa/notifaction/service/webhook"
+	"github.com/spf13/viper"
+)
+
+var (
+	webhookMu            sync.Mutex
+	webhookStore         webhook.Store
+	webhookDeliveryStore webhook.DeliveryStore
+)
+
+func WebhookEnabled() bool {
+	return mockWebhookStore != nil || viper.GetString("webhook.mongo.uri") != ""
+}
+
+// NewWebhookStores keeps deliveries for webhook.retention, forever when it is 0.
+func NewWebhookStores() (webhook.Store, webhook.DeliveryStore, error) {
+	if mockWebhookStore != nil {
+		return mockWebhookStore, mockWebhookDeliveryStore, nil
+	}
+	if viper.GetString("webhook.mongo.uri") == "" {
+		return nil, nil, nil
+	}
+	webhookMu.Lock()
+	defer webhookMu.Unlock()
+	if webhookStore != nil {
+		return webhookStore, webhookDeliveryStore, nil
+	}
+	coll, err := mongodb.Collection("webhook", "webhooks")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := webhook.EnsureIndexes(coll); err != nil {
+		return nil, nil, err
+	}
+	deliveriesName := viper.GetString("webhook.mongo.deliveries_collection")
+	if deliveriesName == "" {
+		deliveriesName = "webhook_deliveries"
+	}
+	deliveries := coll.Database().Collection(deliveriesName)
+	if err := webhook.EnsureDeliveryIndexes(deliveries, viper.GetDuration("webhook.retention")); err != nil {
+		return nil, nil, err
+	}
+	webhookStore = webhook.NewMongoStore(coll)
+	webhookDeliveryStore = webhook.NewMongoDeliveryStore(deliveries)
+	return webhookStore, webhookDeliveryStore, nil
+}
+
+func NewWebhookDeliverer(deliveries webhook.DeliveryStore) (*webhook.Deliverer, error) {
+	return webhook.NewDeliverer(deliveries,
+		webhook.WithMaxAttempts(viper.GetInt("webhook.max_attempts")),
+		webhook.WithBackoff(viper.GetDuration("webhook.backoff")),
+	)
+}
+
+// ResumeWebhookDeliveries resumes the deliveries left pending by stopped processes.
+func ResumeWebhookDeliveries() (int, error) {
+	store, deliveries, err := NewWebhookStores()
+	if err != nil || store == nil {
+		return 0, err
+	}
+	deliverer, err := NewWebhookDeliverer(deliveries)
+	if err != nil {
+		return 0, err
+	}
+	return deliverer.Resume(store, nil)
+}
+
+func newWebhookSender() (service.Sender, error) {
+	store, deliveries, err := NewWebhookStores()
+	if err != nil {
+		return nil, err
+	}
+	deliverer, err := NewWebhookDeliverer(deliveries)
+	if err != nil {
+		return nil, err
+	}
+	return webhook.NewSender(store, deliverer)
+}
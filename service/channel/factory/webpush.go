@@ -0,0 +1,71 @@
+package factory
+
+import (
+	"sync"
+
+	"github.com/arwoosa/notifaction/service"
+	mailFactory "github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mongodb"
+	"github.com/arwoosa/notifaction/service/webpush"
+	"github.com/spf13/viper"
+)
+
+var (
+	webPushMu sync.Mutex
+	pushStore webpush.Store
+)
+
+func WebPushEnabled() bool {
+	return mockPushStore != nil || viper.GetString("webpush.mongo.uri") != ""
+}
+
+func NewPushSubscriptionStore() (webpush.Store, error) {
+	if mockPushStore != nil {
+		return mockPushStore, nil
+	}
+	if viper.GetString("webpush.mongo.uri") == "" {
+		return nil, nil
+	}
+	webPushMu.Lock()
+	defer webPushMu.Unlock()
+	if pushStore != nil {
+		return pushStore, nil
+	}
+	coll, err := mongodb.Collection("webpush", "push_subscriptions")
+	if err != nil {
+		return nil, err
+	}
+	if err := webpush.EnsureIndexes(coll); err != nil {
+		return nil, err
+	}
+	pushStore = webpush.NewMongoStore(coll)
+	return pushStore, nil
+}
+
+func NewVapid() (*webpush.Vapid, error) {
+	return webpush.NewVapid(viper.GetString("webpush.vapid.private_key"), viper.GetString("webpush.vapid.subject"))
+}
+
+// PushAllowedHosts defaults to the push services of the major browsers.
+func PushAllowedHosts() []string {
+	if hosts := viper.GetStringSlice("webpush.allowed_hosts"); len(hosts) > 0 {
+		return hosts
+	}
+	return webpush.DefaultAllowedHosts
+}
+
+func newWebPushSender() (service.Sender, error) {
+	store, err := NewPushSubscriptionStore()
+	if err != nil {
+		return nil, err
+	}
+	vapid, err := NewVapid()
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := mailFactory.NewTemplate()
+	if err != nil {
+		return nil, err
+	}
+	return webpush.NewSender(store, vapid, tpl, webpush.WithTTL(viper.GetDuration("webpush.ttl")))
+}
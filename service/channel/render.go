@@ -0,0 +1,72 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+// TemplateReader is the part of a template store channels render from.
+type TemplateReader interface {
+	IsTemplateExist(name string) (bool, error)
+	Detail(name string) (*dao.DetailTemplateResponse, error)
+}
+
+// Render returns the template of the channel variant of the event of notify, in the lang of notify
+// or its fallbacks, rendered with the data of notify. It sets notify.TemplateUsed.
+func Render(tpl TemplateReader, resolver lang.Resolver, channel string, notify *service.Notification) (*dao.DetailTemplateResponse, error) {
+	detail, err := Resolve(tpl, resolver, channel, notify)
+	if err != nil {
+		return nil, err
+	}
+	mail.Render(detail, notify.Data)
+	return detail, nil
+}
+
+// Resolve returns the template Render renders, for channels that render it their own way.
+func Resolve(tpl TemplateReader, resolver lang.Resolver, channel string, notify *service.Notification) (*dao.DetailTemplateResponse, error) {
+	if strings.HasPrefix(notify.Event, mail.DraftPrefix) {
+		return nil, fmt.Errorf("%w: %s", mail.ErrDraftEvent, notify.Event)
+	}
+	event := VariantEvent(channel, notify.Event)
+	name, _, err := mail.ResolveTemplate(resolver, tpl.IsTemplateExist, event, notify.Lang)
+	if err != nil {
+		return nil, err
+	}
+	if want := service.GetTemplateName(event, notify.Lang); name != want {
+		log.Printf("template %s does not exist, fallback to %s", want, name)
+	}
+	notify.TemplateUsed = name
+	detail, err := tpl.Detail(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template detail: %w", err)
+	}
+	return detail, nil
+}
+
+// JSONEscaped returns data escaped for the inside of json strings, for templates whose
+// body is json, e.g. LINE flex messages or Slack blocks.
+func JSONEscaped(data map[string]string) map[string]string {
+	escaped := make(map[string]string, len(data))
+	for k, v := range data {
+		b, _ := json.Marshal(v)
+		escaped[k] = string(b[1 : len(b)-1])
+	}
+	return escaped
+}
+
+// HTMLEscaped returns data escaped for html.
+func HTMLEscaped(data map[string]string) map[string]string {
+	escaped := make(map[string]string, len(data))
+	for k, v := range data {
+		escaped[k] = html.EscapeString(v)
+	}
+	return escaped
+}
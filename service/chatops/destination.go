@@ -0,0 +1,86 @@
+package chatops
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	Slack    = "slack"
+	Discord  = "discord"
+	Telegram = "telegram"
+
+	DefaultTelegramApiUrl = "https://api.telegram.org"
+)
+
+// Destination is a team chat events are posted to.
+type Destination struct {
+	Name     string `mapstructure:"name"`
+	Platform string `mapstructure:"platform"`
+	// Url is the incoming webhook of slack and discord, the bot api of telegram, https://api.telegram.org by default
+	Url string `mapstructure:"url"`
+	// BotToken and ChatId address the chat of a telegram bot
+	BotToken string `mapstructure:"bot_token"`
+	ChatId   string `mapstructure:"chat_id"`
+	// Lang is the lang of the templates posted, the lang of the notification when empty
+	Lang string `mapstructure:"lang"`
+}
+
+func (d *Destination) Validate() error {
+	if d.Name == "" {
+		return errors.New("destination name is empty")
+	}
+	switch d.Platform {
+	case Slack, Discord:
+		if d.Url == "" {
+			return fmt.Errorf("destination %s: webhook url is empty", d.Name)
+		}
+	case Telegram:
+		if d.BotToken == "" || d.ChatId == "" {
+			return fmt.Errorf("destination %s: telegram needs bot_token and chat_id", d.Name)
+		}
+	default:
+		return fmt.Errorf("destination %s: invalid platform %s, must be %s, %s or %s", d.Name, d.Platform, Slack, Discord, Telegram)
+	}
+	return nil
+}
+
+// Route is the destinations of an event.
+type Route struct {
+	Event        string   `mapstructure:"event"`
+	Destinations []string `mapstructure:"destinations"`
+}
+
+// NewRoutes validates destinations and returns the destinations of each event of routes.
+func NewRoutes(destinations []*Destination, routes []*Route) (map[string][]*Destination, error) {
+	byName := map[string]*Destination{}
+	for _, d := range destinations {
+		if err := d.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := byName[d.Name]; ok {
+			return nil, fmt.Errorf("destination %s is repeated", d.Name)
+		}
+		byName[d.Name] = d
+	}
+	result := map[string][]*Destination{}
+	for _, r := range routes {
+		if r.Event == "" {
+			return nil, errors.New("route event is empty")
+		}
+		if _, ok := result[r.Event]; ok {
+			return nil, fmt.Errorf("event %s is repeated", r.Event)
+		}
+		if len(r.Destinations) == 0 {
+			return nil, fmt.Errorf("event %s has no destination", r.Event)
+		}
+		for _, name := range r.Destinations {
+			d, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("event %s: unknown destination %s", r.Event, name)
+			}
+			result[r.Event] = append(result[r.Event], d)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package chatops
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRoutes(t *testing.T) {
+	slack := &Destination{Name: "ops-slack", Platform: Slack, Url: "https://hooks.slack.com/services/T/B/X"}
+	telegram := &Destination{Name: "ops-telegram", Platform: Telegram, BotToken: "123:abc", ChatId: "-100"}
+	routes, err := NewRoutes([]*Destination{slack, telegram}, []*Route{
+		{Event: "EVENT_REPORT", Destinations: []string{"ops-slack", "ops-telegram"}},
+		{Event: "EVENT_PAYMENT_FAILED", Destinations: []string{"ops-telegram"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]*Destination{
+		"EVENT_REPORT":         {slack, telegram},
+		"EVENT_PAYMENT_FAILED": {telegram},
+	}, routes)
+
+	tests := []struct {
+		name         string
+		destinations []*Destination
+		routes       []*Route
+		err          string
+	}{
+		{
+			name:         "invalid platform",
+			destinations: []*Destination{{Name: "ops", Platform: "teams", Url: "https://example.com"}},
+			err:          "destination ops: invalid platform teams, must be slack, discord or telegram",
+		},
+		{
+			name:         "webhook without url",
+			destinations: []*Destination{{Name: "ops", Platform: Discord}},
+			err:          "destination ops: webhook url is empty",
+		},
+		{
+			name:         "telegram without chat",
+			destinations: []*Destination{{Name: "ops", Platform: Telegram, BotToken: "123:abc"}},
+			err:          "destination ops: telegram needs bot_token and chat_id",
+		},
+		{
+			name:         "repeated destination",
+			destinations: []*Destination{slack, slack},
+			err:          "destination ops-slack is repeated",
+		},
+		{
+			name:         "unknown destination",
+			destinations: []*Destination{slack},
+			routes:       []*Route{{Event: "EVENT_REPORT", Destinations: []string{"ops-discord"}}},
+			err:          "event EVENT_REPORT: unknown destination ops-discord",
+		},
+		{
+			name:         "repeated event",
+			destinations: []*Destination{slack},
+			routes: []*Route{
+				{Event: "EVENT_REPORT", Destinations: []string{"ops-slack"}},
+				{Event: "EVENT_REPORT", Destinations: []string{"ops-slack"}},
+			},
+			err: "event EVENT_REPORT is repeated",
+		},
+		{
+			name:   "route without destination",
+			routes: []*Route{{Event: "EVENT_REPORT"}},
+			err:    "event EVENT_REPORT has no destination",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRoutes(tt.destinations, tt.routes)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
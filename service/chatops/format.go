@@ -0,0 +1,93 @@
+package chatops
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+const (
+	maxDiscordContent = 2000
+	maxTelegramText   = 4096
+)
+
+// isJSON reports whether the html of a template is the native json payload of the platform,
+// e.g. slack blocks or discord embeds, instead of html.
+func isJSON(detail *dao.DetailTemplateResponse) bool {
+	return strings.HasPrefix(strings.TrimSpace(detail.Body.Html), "{")
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+// request returns the url and body posting a template to d. A template whose html is json is
+// posted as is, data escaped for json. Otherwise slack and discord get the plain text, telegram
+// the html when there is one.
+func request(d *Destination, detail *dao.DetailTemplateResponse, data map[string]string) (string, []byte, error) {
+	text := *detail
+	mail.Render(&text, data)
+	plain := strings.TrimSpace(text.Body.Plaint)
+	if plain == "" {
+		plain = text.Subject
+	}
+
+	var payload map[string]any
+	if isJSON(detail) {
+		native := *detail
+		mail.Render(&native, channel.JSONEscaped(data))
+		if err := json.Unmarshal([]byte(native.Body.Html), &payload); err != nil {
+			return "", nil, errors.New("template json is not valid")
+		}
+	}
+	target := d.Url
+	switch d.Platform {
+	case Slack:
+		if payload == nil {
+			payload = map[string]any{"text": plain}
+		}
+	case Discord:
+		if payload == nil {
+			payload = map[string]any{"content": truncate(plain, maxDiscordContent)}
+		}
+		// wait makes discord answer with the message it created
+		u, err := url.Parse(d.Url)
+		if err != nil {
+			return "", nil, errors.New("invalid discord webhook url")
+		}
+		query := u.Query()
+		query.Set("wait", "true")
+		u.RawQuery = query.Encode()
+		target = u.String()
+	case Telegram:
+		switch {
+		case payload != nil:
+		case strings.TrimSpace(detail.Body.Html) != "":
+			html := *detail
+			mail.Render(&html, channel.HTMLEscaped(data))
+			payload = map[string]any{"text": strings.TrimSpace(html.Body.Html), "parse_mode": "HTML"}
+		default:
+			payload = map[string]any{"text": truncate(plain, maxTelegramText)}
+		}
+		payload["chat_id"] = d.ChatId
+		apiUrl := d.Url
+		if apiUrl == "" {
+			apiUrl = DefaultTelegramApiUrl
+		}
+		target = strings.TrimRight(apiUrl, "/") + "/bot" + d.BotToken + "/sendMessage"
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+	return target, body, nil
+}
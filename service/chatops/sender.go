@@ -0,0 +1,230 @@
+package chatops
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultMaxRetryWait = 30 * time.Second
+	defaultRetryAfter   = time.Second
+)
+
+type senderOpt func(*sender)
+
+func WithHttpClient(client *http.Client) senderOpt {
+	return func(s *sender) {
+		s.client = client
+	}
+}
+
+// WithMaxRetries sets how often a rate limited post is retried, 3 times when negative.
+func WithMaxRetries(maxRetries int) senderOpt {
+	return func(s *sender) {
+		s.maxRetries = maxRetries
+	}
+}
+
+// WithMaxRetryWait sets the longest Retry-After waited for, 30 seconds when not positive.
+// Posts asked to wait longer fail.
+func WithMaxRetryWait(wait time.Duration) senderOpt {
+	return func(s *sender) {
+		s.maxRetryWait = wait
+	}
+}
+
+func WithLangResolver(resolver lang.Resolver) senderOpt {
+	return func(s *sender) {
+		s.langResolver = resolver
+	}
+}
+
+// NewSender returns the sender of the chatops channel, it posts the template of each destination
+// of the event: <event>-chatops-<platform>, or else <event>-chatops.
+func NewSender(routes map[string][]*Destination, tpl channel.TemplateReader, opts ...senderOpt) (service.Sender, error) {
+	if tpl == nil {
+		return nil, errors.New("template is required")
+	}
+	s := &sender{
+		routes:     routes,
+		tpl:        tpl,
+		maxRetries: -1,
+		sleep:      time.Sleep,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.client == nil {
+		s.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if s.maxRetries < 0 {
+		s.maxRetries = defaultMaxRetries
+	}
+	if s.maxRetryWait <= 0 {
+		s.maxRetryWait = defaultMaxRetryWait
+	}
+	if s.langResolver == nil {
+		s.langResolver = lang.NewResolver()
+	}
+	return s, nil
+}
+
+type sender struct {
+	routes       map[string][]*Destination
+	tpl          channel.TemplateReader
+	client       *http.Client
+	maxRetries   int
+	maxRetryWait time.Duration
+	sleep        func(time.Duration)
+	langResolver lang.Resolver
+}
+
+// Broadcast makes dispatchers post an event once, not once per recipient.
+func (s *sender) Broadcast() {}
+
+// Send returns the message ids of the destinations of the event, events without destinations are skipped.
+func (s *sender) Send(notify *service.Notification) (string, error) {
+	destinations := s.routes[notify.Event]
+	if len(destinations) == 0 {
+		return "", channel.Skip("no chatops destination of %s", notify.Event)
+	}
+	var ids []string
+	var errs []error
+	for _, d := range destinations {
+		n := *notify
+		if d.Lang != "" {
+			n.Lang = d.Lang
+		}
+		detail, err := s.resolve(d, &n)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chatops %s: %w", d.Name, err))
+			continue
+		}
+		if notify.TemplateUsed == "" {
+			notify.TemplateUsed = n.TemplateUsed
+		}
+		target, body, err := request(d, detail, notify.Data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chatops %s: template %s: %w", d.Name, n.TemplateUsed, err))
+			continue
+		}
+		id, err := s.post(d, target, body)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chatops %s: %w", d.Name, err))
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return strings.Join(ids, ","), errors.Join(errs...)
+}
+
+// resolve returns the template of the platform of d, or else the one of the chatops channel.
+func (s *sender) resolve(d *Destination, notify *service.Notification) (*dao.DetailTemplateResponse, error) {
+	detail, err := channel.Resolve(s.tpl, s.langResolver, channel.ChatOps+"-"+d.Platform, notify)
+	if errors.Is(err, mail.ErrTemplateNotFound) {
+		return channel.Resolve(s.tpl, s.langResolver, channel.ChatOps, notify)
+	}
+	return detail, err
+}
+
+// post posts body to target, waiting for the Retry-After of 429 responses. Its errors leave the
+// url out, webhook urls and bot tokens are secrets.
+func (s *sender) post(d *Destination, target string, body []byte) (string, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+		if err != nil {
+			return "", errors.New("failed to create request")
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := s.client.Do(req)
+		if err != nil {
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return "", fmt.Errorf("failed to post: %w", err)
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait := retryAfter(resp.Header, data)
+			if attempt >= s.maxRetries || wait > s.maxRetryWait {
+				return "", fmt.Errorf("rate limited by %s, retry after %s", d.Platform, wait)
+			}
+			s.sleep(wait)
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return messageId(d, data), nil
+		default:
+			return "", fmt.Errorf("%s post failed with status %d: %s", d.Platform, resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+	}
+}
+
+// retryAfter returns how long a 429 response asks to wait: the retry_after of the body of discord
+// and telegram, or the Retry-After header in seconds or as a date.
+func retryAfter(header http.Header, body []byte) time.Duration {
+	result := struct {
+		RetryAfter float64 `json:"retry_after"`
+		Parameters struct {
+			RetryAfter float64 `json:"retry_after"`
+		} `json:"parameters"`
+	}{}
+	_ = json.Unmarshal(body, &result)
+	switch {
+	case result.RetryAfter > 0:
+		return time.Duration(result.RetryAfter * float64(time.Second))
+	case result.Parameters.RetryAfter > 0:
+		return time.Duration(result.Parameters.RetryAfter * float64(time.Second))
+	}
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return defaultRetryAfter
+}
+
+// messageId returns the id of the posted message, slack webhooks do not return one so the
+// destination name stands for it.
+func messageId(d *Destination, body []byte) string {
+	switch d.Platform {
+	case Discord:
+		message := struct {
+			Id string `json:"id"`
+		}{}
+		if json.Unmarshal(body, &message) == nil && message.Id != "" {
+			return message.Id
+		}
+	case Telegram:
+		message := struct {
+			Result struct {
+				MessageId int64 `json:"message_id"`
+			} `json:"result"`
+		}{}
+		if json.Unmarshal(body, &message) == nil && message.Result.MessageId != 0 {
+			return strconv.FormatInt(message.Result.MessageId, 10)
+		}
+	}
+	return d.Name
+}
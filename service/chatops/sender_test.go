@@ -0,0 +1,181 @@
+package chatops
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeChat is a local stand-in for the webhooks of every platform, the first limited
+// requests of a path are answered with 429.
+type fakeChat struct {
+	server  *httptest.Server
+	mu      sync.Mutex
+	limited map[string]int
+	posts   map[string][]map[string]any
+	queries map[string]string
+}
+
+func newFakeChat(t *testing.T) *fakeChat {
+	f := &fakeChat{limited: map[string]int{}, posts: map[string][]map[string]any{}, queries: map[string]string{}}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.limited[r.URL.Path] > 0 {
+			f.limited[r.URL.Path]--
+			switch r.URL.Path {
+			case "/slack":
+				w.Header().Set("Retry-After", "2")
+				w.WriteHeader(http.StatusTooManyRequests)
+			case "/discord":
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = io.WriteString(w, `{"message":"You are being rate limited.","retry_after":0.5,"global":false}`)
+			default:
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = io.WriteString(w, `{"ok":false,"error_code":429,"parameters":{"retry_after":3}}`)
+			}
+			return
+		}
+		body := map[string]any{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		f.posts[r.URL.Path] = append(f.posts[r.URL.Path], body)
+		f.queries[r.URL.Path] = r.URL.RawQuery
+		switch r.URL.Path {
+		case "/slack":
+			_, _ = io.WriteString(w, "ok")
+		case "/discord":
+			_, _ = io.WriteString(w, `{"id":"1100","content":"x"}`)
+		case "/bot123:abc/sendMessage":
+			_, _ = io.WriteString(w, `{"ok":true,"result":{"message_id":42}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"ok":false,"description":"Not Found"}`)
+		}
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func newTestTemplates(templates map[string]*dao.DetailTemplateResponse) channel.TemplateReader {
+	return mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) {
+			_, ok := templates[name]
+			return ok, nil
+		}),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			detail := *templates[name]
+			return &detail, nil
+		}),
+	)
+}
+
+func newTestSender(t *testing.T, routes map[string][]*Destination, tpl channel.TemplateReader) (*sender, *[]time.Duration) {
+	s, err := NewSender(routes, tpl, WithMaxRetryWait(5*time.Second), WithLangResolver(lang.NewResolver(lang.WithDefault("en"))))
+	assert.NoError(t, err)
+	waits := &[]time.Duration{}
+	impl := s.(*sender)
+	impl.sleep = func(d time.Duration) {
+		*waits = append(*waits, d)
+	}
+	return impl, waits
+}
+
+func TestSender(t *testing.T) {
+	generic := &dao.DetailTemplateResponse{Subject: "New report"}
+	generic.Body.Plaint = "{{FROM}} reported {{TITLE}}"
+	generic.Body.Html = "<b>{{FROM}}</b> reported {{TITLE}}"
+	slackBlocks := &dao.DetailTemplateResponse{}
+	slackBlocks.Body.Html = `{"blocks":[{"type":"section","text":{"type":"mrkdwn","text":"*{{FROM}}* reported {{TITLE}}"}}]}`
+	tpl := newTestTemplates(map[string]*dao.DetailTemplateResponse{
+		"EVENT_REPORT-chatops_en":       generic,
+		"EVENT_REPORT-chatops-slack_en": slackBlocks,
+	})
+	fake := newFakeChat(t)
+	fake.limited["/slack"] = 1
+	fake.limited["/discord"] = 1
+	fake.limited["/bot123:abc/sendMessage"] = 1
+	routes, err := NewRoutes([]*Destination{
+		{Name: "ops-slack", Platform: Slack, Url: fake.server.URL + "/slack"},
+		{Name: "ops-discord", Platform: Discord, Url: fake.server.URL + "/discord?thread_id=9"},
+		{Name: "ops-telegram", Platform: Telegram, Url: fake.server.URL, BotToken: "123:abc", ChatId: "-100", Lang: "en"},
+	}, []*Route{{Event: "EVENT_REPORT", Destinations: []string{"ops-slack", "ops-discord", "ops-telegram"}}})
+	assert.NoError(t, err)
+	s, waits := newTestSender(t, routes, tpl)
+
+	notify := &service.Notification{
+		Event: "EVENT_REPORT",
+		Lang:  "zh-TW",
+		Data:  map[string]string{"FROM": "Alice", "TITLE": `"spam" <post>`},
+	}
+	mid, err := s.Send(notify)
+	assert.NoError(t, err)
+	assert.Equal(t, "ops-slack,1100,42", mid)
+	assert.Equal(t, "EVENT_REPORT-chatops-slack_en", notify.TemplateUsed)
+	// each platform's retry after is honored
+	assert.Equal(t, []time.Duration{2 * time.Second, 500 * time.Millisecond, 3 * time.Second}, *waits)
+
+	assert.Equal(t, []map[string]any{{"blocks": []any{map[string]any{"type": "section",
+		"text": map[string]any{"type": "mrkdwn", "text": `*Alice* reported "spam" <post>`}}}}}, fake.posts["/slack"])
+	assert.Equal(t, []map[string]any{{"content": `Alice reported "spam" <post>`}}, fake.posts["/discord"])
+	assert.Equal(t, "thread_id=9&wait=true", fake.queries["/discord"])
+	assert.Equal(t, []map[string]any{{"chat_id": "-100", "parse_mode": "HTML",
+		"text": "<b>Alice</b> reported &#34;spam&#34; &lt;post&gt;"}}, fake.posts["/bot123:abc/sendMessage"])
+
+	_, err = s.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en"})
+	assert.True(t, channel.IsSkip(err), err)
+	assert.EqualError(t, err, "skipped: no chatops destination of EVENT_JOIN")
+}
+
+func TestSenderErrors(t *testing.T) {
+	text := &dao.DetailTemplateResponse{}
+	text.Body.Plaint = "payment failed"
+	tpl := newTestTemplates(map[string]*dao.DetailTemplateResponse{"EVENT_PAYMENT_FAILED-chatops_en": text})
+	fake := newFakeChat(t)
+	fake.limited["/slack"] = 5
+	routes := map[string][]*Destination{"EVENT_PAYMENT_FAILED": {
+		{Name: "ops-slack", Platform: Slack, Url: fake.server.URL + "/slack"},
+		{Name: "ops-telegram", Platform: Telegram, Url: fake.server.URL, BotToken: "999:secret", ChatId: "-100"},
+		{Name: "ops-down", Platform: Discord, Url: "http://127.0.0.1:1/api/webhooks/1/secret-token"},
+	}}
+	s, waits := newTestSender(t, routes, tpl)
+	s.maxRetries = 2
+
+	mid, err := s.Send(&service.Notification{Event: "EVENT_PAYMENT_FAILED", Lang: "en"})
+	assert.Empty(t, mid)
+	assert.Len(t, *waits, 2)
+	assert.ErrorContains(t, err, "chatops ops-slack: rate limited by slack, retry after 2s")
+	assert.ErrorContains(t, err, `chatops ops-telegram: telegram post failed with status 404: {"ok":false,"description":"Not Found"}`)
+	assert.ErrorContains(t, err, "chatops ops-down: failed to post")
+	// the secrets of the urls are not leaked
+	assert.NotContains(t, err.Error(), "secret")
+
+	// retry afters longer than the max wait fail at once
+	fake.limited["/slack"] = 1
+	s.maxRetryWait = time.Second
+	*waits = nil
+	_, err = s.Send(&service.Notification{Event: "EVENT_PAYMENT_FAILED", Lang: "en"})
+	assert.ErrorContains(t, err, "chatops ops-slack: rate limited by slack, retry after 2s")
+	assert.Empty(t, *waits)
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := http.Header{}
+	assert.Equal(t, time.Second, retryAfter(header, nil))
+	header.Set("Retry-After", "7")
+	assert.Equal(t, 7*time.Second, retryAfter(header, nil))
+	assert.Equal(t, 1500*time.Millisecond, retryAfter(header, []byte(`{"retry_after":1.5}`)))
+	header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.Equal(t, time.Duration(0), retryAfter(header, nil))
+}
@@ -9,5 +9,9 @@ type identity struct {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Language string `json:"language"`
+		// LineUserId is the LINE user of the identity when kratos keeps it as a trait
+		LineUserId string `json:"line_user_id"`
+		// Phone is the E.164 number sms are sent to
+		Phone string `json:"phone"`
 	} `json:"traits"`
 }
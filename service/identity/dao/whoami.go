@@ -0,0 +1,6 @@
+package dao
+
+type WhoamiResponse struct {
+	Active   bool      `json:"active"`
+	Identity *identity `json:"identity"`
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/arwoosa/notifaction/service"
 	"github.com/arwoosa/notifaction/service/identity/dao"
+	"github.com/arwoosa/notifaction/service/lang"
 	"github.com/spf13/viper"
 )
 
@@ -121,14 +122,23 @@ func (c *ClassificationLang) add(lang string, info *service.Info) {
 type Identity interface {
 	// return notify info and classification by lang
 	SubToInfo(from string, to []string) (*ClassificationLang, error)
+	// Whoami returns the user of the session in the cookie or token of header,
+	// ErrUnauthorized when there is no active session.
+	Whoami(header http.Header) (*service.Info, error)
 	service.Health
 }
 
+var ErrUnauthorized = errors.New("unauthorized")
+
 const (
 	identityPath = "/admin/identities"
 	healthPath   = "/admin/health/ready"
+	whoamiPath   = "/sessions/whoami"
 )
 
+// session headers forwarded to whoami
+var sessionHeaders = []string{"Cookie", "Authorization", "X-Session-Token"}
+
 type option func(*identityApi)
 
 func WithHttpClient(httpClient myHttpClient) option {
@@ -149,6 +159,10 @@ func NewIdentity(opts ...option) (Identity, error) {
 		identityUri: url + identityPath,
 		heathUri:    url + healthPath,
 	}
+	// sessions are served by the public api, whoami is not available without it
+	if publicUrl := viper.GetString("identity.public_url"); publicUrl != "" {
+		api.whoamiUri = publicUrl + whoamiPath
+	}
 
 	for _, opt := range opts {
 		opt(api)
@@ -167,6 +181,7 @@ type identityApi struct {
 	httpClient  myHttpClient
 	identityUri string
 	heathUri    string
+	whoamiUri   string
 }
 
 func (i *identityApi) SubToInfo(from string, to []string) (*ClassificationLang, error) {
@@ -188,14 +203,16 @@ func (i *identityApi) SubToInfo(from string, to []string) (*ClassificationLang,
 				Email:  r.Traits.Email,
 				Enable: r.State == "active",
 			}
-			cl.FromLang = r.Traits.Language
+			cl.FromLang = lang.Normalize(r.Traits.Language)
 			continue
 		}
-		cl.add(r.Traits.Language, &service.Info{
-			Sub:    r.Id,
-			Name:   r.Traits.Name,
-			Email:  r.Traits.Email,
-			Enable: r.State == "active",
+		cl.add(lang.Normalize(r.Traits.Language), &service.Info{
+			Sub:        r.Id,
+			Name:       r.Traits.Name,
+			Email:      r.Traits.Email,
+			Enable:     r.State == "active",
+			LineUserId: r.Traits.LineUserId,
+			Phone:      r.Traits.Phone,
 		})
 	}
 	return cl, nil
@@ -242,3 +259,44 @@ func (i *identityApi) IsReady() (bool, error) {
 
 	return resp.StatusCode == http.StatusOK, nil
 }
+
+func (i *identityApi) Whoami(header http.Header) (*service.Info, error) {
+	if i.whoamiUri == "" {
+		return nil, errors.New("identity.public_url is empty")
+	}
+	req, err := http.NewRequest("GET", i.whoamiUri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for _, h := range sessionHeaders {
+		if v := header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	default:
+		return nil, fmt.Errorf("whoami failed with status %d", resp.StatusCode)
+	}
+
+	response := dao.WhoamiResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !response.Active || response.Identity == nil {
+		return nil, ErrUnauthorized
+	}
+	return &service.Info{
+		Sub:    response.Identity.Id,
+		Name:   response.Identity.Traits.Name,
+		Email:  response.Identity.Traits.Email,
+		Enable: response.Identity.State == "active",
+	}, nil
+}
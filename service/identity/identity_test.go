@@ -177,7 +177,9 @@ func TestSubToInfo(t *testing.T) {
 						{"id": "2", "state": "active", "traits": {
 							"name": "To2 Name",
 							"email": "to2@example.com",
-							"language": "en"
+							"language": "en",
+							"line_user_id": "U2",
+							"phone": "+886912345678"
 						}},
 						{"id": "3", "state": "active", "traits": {
 							"name": "from3 Name",
@@ -201,10 +203,12 @@ func TestSubToInfo(t *testing.T) {
 							Enable: true,
 						},
 						{
-							Sub:    "2",
-							Name:   "To2 Name",
-							Email:  "to2@example.com",
-							Enable: true,
+							Sub:        "2",
+							Name:       "To2 Name",
+							Email:      "to2@example.com",
+							Enable:     true,
+							LineUserId: "U2",
+							Phone:      "+886912345678",
 						},
 					},
 				},
@@ -289,6 +293,89 @@ func TestSubToInfo(t *testing.T) {
 	}
 }
 
+func TestWhoami(t *testing.T) {
+	defer viper.Reset()
+	respond := func(status int, body string) option {
+		return WithHttpClient(newMockHttpClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.String() != "https://public.example.com/sessions/whoami" {
+				return nil, errors.New("unexpected url " + req.URL.String())
+			}
+			if req.Header.Get("Cookie") != "ory_session=abc" {
+				return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+			}
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}, nil
+		}))
+	}
+	tests := []struct {
+		name         string
+		publicUrl    string
+		opt          option
+		cookie       string
+		want         *service.Info
+		wantErr      error
+		errorContain string
+	}{
+		{
+			name:      "active session",
+			publicUrl: "https://public.example.com",
+			opt: respond(http.StatusOK, `{"active": true, "identity": {"id": "1", "state": "active",
+				"traits": {"name": "To1 Name", "email": "to1@example.com", "language": "en"}}}`),
+			cookie: "ory_session=abc",
+			want:   &service.Info{Sub: "1", Name: "To1 Name", Email: "to1@example.com", Enable: true},
+		},
+		{
+			name:      "no session",
+			publicUrl: "https://public.example.com",
+			opt:       respond(http.StatusOK, `{}`),
+			wantErr:   ErrUnauthorized,
+		},
+		{
+			name:      "inactive session",
+			publicUrl: "https://public.example.com",
+			opt:       respond(http.StatusOK, `{"active": false, "identity": {"id": "1"}}`),
+			cookie:    "ory_session=abc",
+			wantErr:   ErrUnauthorized,
+		},
+		{
+			name:         "server error",
+			publicUrl:    "https://public.example.com",
+			opt:          respond(http.StatusInternalServerError, ``),
+			cookie:       "ory_session=abc",
+			errorContain: "whoami failed with status 500",
+		},
+		{
+			name:         "no public url",
+			opt:          respond(http.StatusOK, `{}`),
+			cookie:       "ory_session=abc",
+			errorContain: "identity.public_url is empty",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Set("identity.url", "https://example.com")
+			viper.Set("identity.public_url", test.publicUrl)
+			i, err := NewIdentity(test.opt)
+			if err != nil {
+				t.Fatalf("NewIdentity() error = %v", err)
+			}
+			header := http.Header{}
+			if test.cookie != "" {
+				header.Set("Cookie", test.cookie)
+			}
+			info, err := i.Whoami(header)
+			if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("Whoami() error = %v, want %v", err, test.wantErr)
+			}
+			if test.errorContain != "" && (err == nil || !strings.Contains(err.Error(), test.errorContain)) {
+				t.Errorf("Whoami() error = %v, should contain %s", err, test.errorContain)
+			}
+			if !reflect.DeepEqual(info, test.want) {
+				t.Errorf("Whoami() got = %v, want %v", info, test.want)
+			}
+		})
+	}
+}
+
 func TestIsEqual(t *testing.T) {
 	tests := []struct {
 		name     string
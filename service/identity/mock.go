@@ -1,5 +1,11 @@
 package identity
 
+import (
+	"net/http"
+
+	"github.com/arwoosa/notifaction/service"
+)
+
 func SetMockHealthFunc(f func() (bool, error)) {
 	mock := getMockIdentity()
 	mock.healthFunc = f
@@ -22,6 +28,12 @@ func SetMockSubToInfoFunc(f func(from string, to []string) (*ClassificationLang,
 	mockIdentity = mock
 }
 
+func SetMockWhoamiFunc(f func(header http.Header) (*service.Info, error)) {
+	mock := getMockIdentity()
+	mock.whoami = f
+	mockIdentity = mock
+}
+
 func SetNewException(e error) {
 	mock := getMockIdentity()
 	mock.newException = e
@@ -50,6 +62,7 @@ type mockIdentityImpl struct {
 	newException error
 	healthFunc   func() (bool, error)
 	subToInfo    func(from string, to []string) (*ClassificationLang, error)
+	whoami       func(header http.Header) (*service.Info, error)
 }
 
 func (m *mockIdentityImpl) IsReady() (bool, error) {
@@ -65,3 +78,10 @@ func (m *mockIdentityImpl) SubToInfo(from string, to []string) (*ClassificationL
 	}
 	return nil, nil
 }
+
+func (m *mockIdentityImpl) Whoami(header http.Header) (*service.Info, error) {
+	if m.whoami != nil {
+		return m.whoami(header)
+	}
+	return nil, ErrUnauthorized
+}
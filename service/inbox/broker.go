@@ -0,0 +1,96 @@
+package inbox
+
+import (
+	"sync"
+)
+
+// Broker fans new messages out to the subscribers of their sub.
+type Broker interface {
+	// Publish delivers msg to the subscribers of msg.Sub, it is called once msg is stored.
+	Publish(msg *Message) error
+	Subscribe(sub string) (*Subscription, error)
+}
+
+// Subscription receives the messages published to a sub. C is closed when the subscription
+// is closed or falls behind, the subscriber then resumes from the store with ListAfter.
+type Subscription struct {
+	C     <-chan *Message
+	close func()
+}
+
+func (s *Subscription) Close() {
+	s.close()
+}
+
+type memoryBrokerOpt func(*memoryBroker)
+
+// WithBuffer sets how many messages a subscription buffers before it is dropped as too slow.
+func WithBuffer(buffer int) memoryBrokerOpt {
+	return func(b *memoryBroker) {
+		b.buffer = buffer
+	}
+}
+
+// NewMemoryBroker returns a broker of the subscribers of this process.
+func NewMemoryBroker(opts ...memoryBrokerOpt) Broker {
+	b := &memoryBroker{
+		buffer: 16,
+		subs:   map[string]map[*subscriber]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+type subscriber struct {
+	c      chan *Message
+	closed bool
+}
+
+type memoryBroker struct {
+	mu     sync.Mutex
+	buffer int
+	subs   map[string]map[*subscriber]struct{}
+}
+
+func (b *memoryBroker) Publish(msg *Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs[msg.Sub] {
+		select {
+		case s.c <- msg:
+		default:
+			// a full buffer drops the subscriber instead of blocking the publisher
+			b.remove(msg.Sub, s)
+		}
+	}
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(sub string) (*Subscription, error) {
+	s := &subscriber{c: make(chan *Message, b.buffer)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[sub] == nil {
+		b.subs[sub] = map[*subscriber]struct{}{}
+	}
+	b.subs[sub][s] = struct{}{}
+	return &Subscription{C: s.c, close: func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(sub, s)
+	}}, nil
+}
+
+func (b *memoryBroker) remove(sub string, s *subscriber) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.c)
+	delete(b.subs[sub], s)
+	if len(b.subs[sub]) == 0 {
+		delete(b.subs, sub)
+	}
+}
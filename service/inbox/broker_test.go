@@ -0,0 +1,45 @@
+package inbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	broker := NewMemoryBroker(WithBuffer(2))
+	bob, err := broker.Subscribe("bob")
+	assert.NoError(t, err)
+	bob2, err := broker.Subscribe("bob")
+	assert.NoError(t, err)
+	alice, err := broker.Subscribe("alice")
+	assert.NoError(t, err)
+
+	msg := &Message{ID: primitive.NewObjectID(), Sub: "bob"}
+	assert.NoError(t, broker.Publish(msg))
+	assert.Equal(t, msg, <-bob.C)
+	assert.Equal(t, msg, <-bob2.C)
+	assert.Empty(t, alice.C)
+
+	// bob2 stops reading and is dropped once its buffer is full
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, broker.Publish(&Message{ID: primitive.NewObjectID(), Sub: "bob"}))
+		<-bob.C
+	}
+	received := 0
+	for range bob2.C {
+		received++
+	}
+	assert.Equal(t, 2, received)
+
+	bob.Close()
+	bob.Close()
+	_, ok := <-bob.C
+	assert.False(t, ok)
+	bob2.Close()
+	assert.NoError(t, broker.Publish(&Message{ID: primitive.NewObjectID(), Sub: "bob"}))
+	assert.Empty(t, broker.(*memoryBroker).subs["bob"])
+	alice.Close()
+	assert.Empty(t, broker.(*memoryBroker).subs)
+}
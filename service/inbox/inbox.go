@@ -0,0 +1,167 @@
+package inbox
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	ErrNotFound      = errors.New("notification not found")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Message is a notification in the inbox of a user.
+type Message struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Sub       string             `json:"-" bson:"sub"`
+	Event     string             `json:"event" bson:"event"`
+	Lang      string             `json:"lang" bson:"lang"`
+	Template  string             `json:"template" bson:"template"`
+	Title     string             `json:"title" bson:"title"`
+	Body      string             `json:"body" bson:"body"`
+	Data      map[string]string  `json:"data" bson:"data"`
+	ReadAt    *time.Time         `json:"read_at" bson:"read_at"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Page is a page of an inbox, newest first. NextCursor is empty on the last page.
+type Page struct {
+	Messages   []*Message `json:"notifications"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// Store keeps the inboxes, every method is scoped to the inbox of sub.
+type Store interface {
+	Insert(msg *Message) error
+	// List returns up to limit messages older than cursor, the NextCursor of the previous page.
+	List(sub, cursor string, limit int) (*Page, error)
+	// ListAfter returns up to limit messages newer than the message id, oldest first.
+	ListAfter(sub, id string, limit int) ([]*Message, error)
+	Unread(sub string) (int64, error)
+	MarkRead(sub, id string) error
+	// MarkAllRead returns the number of messages marked.
+	MarkAllRead(sub string) (int64, error)
+	Delete(sub, id string) error
+}
+
+// NormalizeLimit returns the default limit for values < 1 and caps it at MaxLimit.
+func NormalizeLimit(limit int) int {
+	if limit < 1 {
+		return DefaultLimit
+	}
+	return min(limit, MaxLimit)
+}
+
+func parseCursor(cursor string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(cursor)
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+func parseId(id string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, ErrNotFound
+	}
+	return oid, nil
+}
+
+type senderOpt func(*sender)
+
+func WithLangResolver(resolver lang.Resolver) senderOpt {
+	return func(s *sender) {
+		s.langResolver = resolver
+	}
+}
+
+// WithBroker publishes the stored messages to the subscribers of the recipients.
+func WithBroker(broker Broker) senderOpt {
+	return func(s *sender) {
+		s.broker = broker
+	}
+}
+
+func WithNow(now func() time.Time) senderOpt {
+	return func(s *sender) {
+		s.now = now
+	}
+}
+
+// NewSender returns the sender of the inbox channel, it stores the <event>-inbox template rendered
+// in the lang of the recipient: the subject as title and the plain text as body.
+func NewSender(store Store, tpl channel.TemplateReader, opts ...senderOpt) (service.Sender, error) {
+	if store == nil {
+		return nil, errors.New("inbox store is required")
+	}
+	if tpl == nil {
+		return nil, errors.New("template is required")
+	}
+	s := &sender{store: store, tpl: tpl, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.langResolver == nil {
+		s.langResolver = lang.NewResolver()
+	}
+	return s, nil
+}
+
+type sender struct {
+	store        Store
+	tpl          channel.TemplateReader
+	langResolver lang.Resolver
+	broker       Broker
+	now          func() time.Time
+}
+
+func (s *sender) Send(notify *service.Notification) (string, error) {
+	if len(notify.SendTo) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+	detail, err := channel.Render(s.tpl, s.langResolver, channel.Inbox, notify)
+	if err != nil {
+		return "", err
+	}
+	ids := make([]string, 0, len(notify.SendTo))
+	for _, to := range notify.SendTo {
+		msg := &Message{
+			Sub:      to.Sub,
+			Event:    notify.Event,
+			Lang:     notify.Lang,
+			Template: notify.TemplateUsed,
+			Title:    detail.Subject,
+			Body:     detail.Body.Plaint,
+			// the message outlives the send in the broker, the caller may reuse its data
+			Data:      maps.Clone(notify.Data),
+			CreatedAt: s.now(),
+		}
+		if err := s.store.Insert(msg); err != nil {
+			return strings.Join(ids, ","), fmt.Errorf("failed to store notification of %s: %w", to.Sub, err)
+		}
+		ids = append(ids, msg.ID.Hex())
+		if s.broker == nil {
+			continue
+		}
+		// the message is stored, subscribers missing it get it when they resume
+		if err := s.broker.Publish(msg); err != nil {
+			log.Printf("failed to publish notification %s: %v", msg.ID.Hex(), err)
+		}
+	}
+	return strings.Join(ids, ","), nil
+}
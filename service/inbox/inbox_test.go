@@ -0,0 +1,176 @@
+package inbox
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLimit(t *testing.T) {
+	assert.Equal(t, DefaultLimit, NormalizeLimit(0))
+	assert.Equal(t, DefaultLimit, NormalizeLimit(-1))
+	assert.Equal(t, 5, NormalizeLimit(5))
+	assert.Equal(t, MaxLimit, NormalizeLimit(1000))
+}
+
+func TestSender(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	tpl := mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) {
+			return name == "EVENT_JOIN-inbox_en", nil
+		}),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			detail := &dao.DetailTemplateResponse{Subject: "{{FROM}} joined"}
+			detail.Body.Plaint = "Say hi to {{FROM}}, {{TO}}"
+			detail.Body.Html = "<p>ignored</p>"
+			return detail, nil
+		}),
+	)
+	store := NewMockStore(nil)
+	broker := NewMemoryBroker()
+	subscription, err := broker.Subscribe("carol")
+	assert.NoError(t, err)
+	defer subscription.Close()
+	sender, err := NewSender(store, tpl, WithNow(func() time.Time { return now }),
+		WithLangResolver(lang.NewResolver(lang.WithDefault("en"))), WithBroker(broker))
+	assert.NoError(t, err)
+
+	notify := &service.Notification{
+		Event:  "EVENT_JOIN",
+		Lang:   "ja",
+		Data:   map[string]string{"FROM": "Alice", "TO": "Bob"},
+		SendTo: []*service.Info{{Sub: "bob"}, {Sub: "carol"}},
+	}
+	mid, err := sender.Send(notify)
+	assert.NoError(t, err)
+	assert.Equal(t, "EVENT_JOIN-inbox_en", notify.TemplateUsed)
+
+	page, err := store.List("bob", "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, page.Messages, 1)
+	msg := page.Messages[0]
+	assert.Contains(t, mid, msg.ID.Hex())
+	assert.Equal(t, &Message{
+		ID:        msg.ID,
+		Sub:       "bob",
+		Event:     "EVENT_JOIN",
+		Lang:      "ja",
+		Template:  "EVENT_JOIN-inbox_en",
+		Title:     "Alice joined",
+		Body:      "Say hi to Alice, Bob",
+		Data:      map[string]string{"FROM": "Alice", "TO": "Bob"},
+		CreatedAt: now,
+	}, msg)
+	unread, err := store.Unread("carol")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), unread)
+	published := <-subscription.C
+	assert.Equal(t, "carol", published.Sub)
+	assert.Equal(t, "Alice joined", published.Title)
+
+	_, err = sender.Send(&service.Notification{Event: "EVENT_LEAVE", Lang: "en", SendTo: []*service.Info{{Sub: "bob"}}})
+	assert.ErrorContains(t, err, "template does not exist: EVENT_LEAVE-inbox_en")
+
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en"})
+	assert.EqualError(t, err, "no recipients specified")
+}
+
+type failingStore struct {
+	Store
+}
+
+func (f *failingStore) Insert(msg *Message) error {
+	return errors.New("db down")
+}
+
+func TestSenderStoreError(t *testing.T) {
+	tpl := mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) { return true, nil }),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			return &dao.DetailTemplateResponse{}, nil
+		}),
+	)
+	sender, err := NewSender(&failingStore{}, tpl)
+	assert.NoError(t, err)
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "bob"}}})
+	assert.EqualError(t, err, "failed to store notification of bob: db down")
+
+	_, err = NewSender(nil, tpl)
+	assert.EqualError(t, err, "inbox store is required")
+}
+
+func TestMockStore(t *testing.T) {
+	store := NewMockStore(nil)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, store.Insert(&Message{Sub: "bob"}))
+	}
+	seen := 0
+	cursor := ""
+	for {
+		page, err := store.List("bob", cursor, 2)
+		assert.NoError(t, err)
+		seen += len(page.Messages)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, 5, seen)
+
+	_, err := store.List("bob", "nope", 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	assert.ErrorIs(t, store.MarkRead("bob", "nope"), ErrNotFound)
+	assert.ErrorIs(t, store.Delete("alice", firstId(t, store)), ErrNotFound)
+}
+
+func firstId(t *testing.T, store Store) string {
+	page, err := store.List("bob", "", 1)
+	assert.NoError(t, err)
+	return page.Messages[0].ID.Hex()
+}
+
+// TestSenderStreamedData publishes messages while the data of the notification is reused for the
+// next recipient, run with -race.
+func TestSenderStreamedData(t *testing.T) {
+	tpl := mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) { return true, nil }),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			detail := &dao.DetailTemplateResponse{Subject: "Hi {{TO}}"}
+			detail.Body.Plaint = "Hi {{TO}}"
+			return detail, nil
+		}),
+	)
+	const sends = 20
+	broker := NewMemoryBroker(WithBuffer(sends))
+	subscription, err := broker.Subscribe("bob")
+	assert.NoError(t, err)
+	defer subscription.Close()
+	sender, err := NewSender(NewMockStore(nil), tpl, WithBroker(broker))
+	assert.NoError(t, err)
+
+	streamed := make(chan string, sends)
+	go func() {
+		for i := 0; i < sends; i++ {
+			msg := <-subscription.C
+			data, _ := json.Marshal(msg)
+			streamed <- string(data)
+		}
+	}()
+	data := map[string]string{}
+	for i := 0; i < sends; i++ {
+		data["TO"] = "bob"
+		_, err := sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", Data: data, SendTo: []*service.Info{{Sub: "bob"}}})
+		assert.NoError(t, err)
+		data["TO"] = "carol"
+	}
+	for i := 0; i < sends; i++ {
+		assert.Contains(t, <-streamed, `"data":{"TO":"bob"}`)
+	}
+}
@@ -0,0 +1,134 @@
+package inbox
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMockStore returns a store keeping the inboxes in memory, with the semantics of the mongo store.
+func NewMockStore(now func() time.Time) Store {
+	if now == nil {
+		now = time.Now
+	}
+	return &mockStore{now: now}
+}
+
+type mockStore struct {
+	mu   sync.Mutex
+	msgs []*Message
+	now  func() time.Time
+}
+
+func (m *mockStore) Insert(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if msg.ID.IsZero() {
+		msg.ID = primitive.NewObjectID()
+	}
+	stored := *msg
+	m.msgs = append(m.msgs, &stored)
+	return nil
+}
+
+func (m *mockStore) find(sub string, match func(*Message) bool) []*Message {
+	result := []*Message{}
+	for _, msg := range m.msgs {
+		if msg.Sub == sub && match(msg) {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+func (m *mockStore) List(sub, cursor string, limit int) (*Page, error) {
+	limit = NormalizeLimit(limit)
+	match := func(*Message) bool { return true }
+	if cursor != "" {
+		id, err := parseCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		match = func(msg *Message) bool { return msg.ID.Hex() < id.Hex() }
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := m.find(sub, match)
+	sort.Slice(found, func(i, j int) bool { return found[i].ID.Hex() > found[j].ID.Hex() })
+	msgs := []*Message{}
+	for _, msg := range found[:min(len(found), limit+1)] {
+		c := *msg
+		msgs = append(msgs, &c)
+	}
+	return newPage(msgs, limit), nil
+}
+
+func (m *mockStore) ListAfter(sub, id string, limit int) ([]*Message, error) {
+	oid, err := parseCursor(id)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := m.find(sub, func(msg *Message) bool { return msg.ID.Hex() > oid.Hex() })
+	sort.Slice(found, func(i, j int) bool { return found[i].ID.Hex() < found[j].ID.Hex() })
+	msgs := []*Message{}
+	for _, msg := range found[:min(len(found), NormalizeLimit(limit))] {
+		c := *msg
+		msgs = append(msgs, &c)
+	}
+	return msgs, nil
+}
+
+func (m *mockStore) Unread(sub string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.find(sub, func(msg *Message) bool { return msg.ReadAt == nil }))), nil
+}
+
+func (m *mockStore) MarkRead(sub, id string) error {
+	oid, err := parseId(id)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := m.find(sub, func(msg *Message) bool { return msg.ID == oid })
+	if len(found) == 0 {
+		return ErrNotFound
+	}
+	if found[0].ReadAt == nil {
+		now := m.now()
+		found[0].ReadAt = &now
+	}
+	return nil
+}
+
+func (m *mockStore) MarkAllRead(sub string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := m.find(sub, func(msg *Message) bool { return msg.ReadAt == nil })
+	now := m.now()
+	for _, msg := range found {
+		msg.ReadAt = &now
+	}
+	return int64(len(found)), nil
+}
+
+func (m *mockStore) Delete(sub, id string) error {
+	oid, err := parseId(id)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, msg := range m.msgs {
+		if msg.Sub == sub && msg.ID == oid {
+			m.msgs = append(m.msgs[:i], m.msgs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
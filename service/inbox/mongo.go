@@ -0,0 +1,183 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoStoreOpt func(*mongoStore)
+
+func WithTimeout(timeout time.Duration) mongoStoreOpt {
+	return func(m *mongoStore) {
+		m.timeout = timeout
+	}
+}
+
+func WithStoreNow(now func() time.Time) mongoStoreOpt {
+	return func(m *mongoStore) {
+		m.now = now
+	}
+}
+
+// NewMongoStore returns the store of the inboxes in collection, see EnsureIndexes.
+func NewMongoStore(collection *mongo.Collection, opts ...mongoStoreOpt) Store {
+	m := &mongoStore{collection: collection, timeout: 5 * time.Second, now: time.Now}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// EnsureIndexes creates the indexes of pagination and unread counts, it is a no-op when they exist.
+func EnsureIndexes(collection *mongo.Collection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sub", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "sub", Value: 1}, {Key: "read_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create inbox indexes: %w", err)
+	}
+	return nil
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+	now        func() time.Time
+}
+
+func (m *mongoStore) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), m.timeout)
+}
+
+func (m *mongoStore) Insert(msg *Message) error {
+	if msg.ID.IsZero() {
+		msg.ID = primitive.NewObjectID()
+	}
+	ctx, cancel := m.context()
+	defer cancel()
+	_, err := m.collection.InsertOne(ctx, msg)
+	return err
+}
+
+func (m *mongoStore) List(sub, cursor string, limit int) (*Page, error) {
+	limit = NormalizeLimit(limit)
+	filter := bson.M{"sub": sub}
+	if cursor != "" {
+		id, err := parseCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter["_id"] = bson.M{"$lt": id}
+	}
+	ctx, cancel := m.context()
+	defer cancel()
+	// one more than the limit tells whether there is a next page
+	cur, err := m.collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit+1)))
+	if err != nil {
+		return nil, err
+	}
+	msgs := []*Message{}
+	if err := cur.All(ctx, &msgs); err != nil {
+		return nil, err
+	}
+	return newPage(msgs, limit), nil
+}
+
+func (m *mongoStore) ListAfter(sub, id string, limit int) ([]*Message, error) {
+	oid, err := parseCursor(id)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := m.context()
+	defer cancel()
+	cur, err := m.collection.Find(ctx, bson.M{"sub": sub, "_id": bson.M{"$gt": oid}}, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(NormalizeLimit(limit))))
+	if err != nil {
+		return nil, err
+	}
+	msgs := []*Message{}
+	if err := cur.All(ctx, &msgs); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func newPage(msgs []*Message, limit int) *Page {
+	page := &Page{Messages: msgs}
+	if len(msgs) > limit {
+		page.Messages = msgs[:limit]
+		page.NextCursor = msgs[limit-1].ID.Hex()
+	}
+	return page
+}
+
+func (m *mongoStore) Unread(sub string) (int64, error) {
+	ctx, cancel := m.context()
+	defer cancel()
+	return m.collection.CountDocuments(ctx, bson.M{"sub": sub, "read_at": nil})
+}
+
+func (m *mongoStore) MarkRead(sub, id string) error {
+	oid, err := parseId(id)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := m.context()
+	defer cancel()
+	filter := bson.M{"_id": oid, "sub": sub}
+	// only unread messages are updated so that read_at keeps the first read
+	result, err := m.collection.UpdateOne(ctx, bson.M{"_id": oid, "sub": sub, "read_at": nil},
+		bson.M{"$set": bson.M{"read_at": m.now()}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	err = m.collection.FindOne(ctx, filter).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (m *mongoStore) MarkAllRead(sub string) (int64, error) {
+	ctx, cancel := m.context()
+	defer cancel()
+	result, err := m.collection.UpdateMany(ctx, bson.M{"sub": sub, "read_at": nil},
+		bson.M{"$set": bson.M{"read_at": m.now()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (m *mongoStore) Delete(sub, id string) error {
+	oid, err := parseId(id)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := m.context()
+	defer cancel()
+	result, err := m.collection.DeleteOne(ctx, bson.M{"_id": oid, "sub": sub})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package inbox
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoBroker returns a broker fed by the change stream of the inbox collection, so that
+// subscribers of every replica receive the messages stored by any of them. Change streams
+// need a replica set. Publish is a no-op, storing the message publishes it.
+func NewMongoBroker(collection *mongo.Collection, opts ...memoryBrokerOpt) Broker {
+	return &mongoBroker{
+		collection: collection,
+		local:      NewMemoryBroker(opts...),
+	}
+}
+
+type mongoBroker struct {
+	collection *mongo.Collection
+	local      Broker
+	once       sync.Once
+}
+
+func (b *mongoBroker) Publish(msg *Message) error {
+	return nil
+}
+
+func (b *mongoBroker) Subscribe(sub string) (*Subscription, error) {
+	b.once.Do(func() {
+		go b.watch()
+	})
+	return b.local.Subscribe(sub)
+}
+
+type changeEvent struct {
+	FullDocument *Message `bson:"fullDocument"`
+}
+
+// watch forwards the inserts of the collection to the local subscribers until the process ends,
+// resuming after errors.
+func (b *mongoBroker) watch() {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+	var resumeToken bson.Raw
+	backoff := time.Second
+	for {
+		opts := options.ChangeStream()
+		if resumeToken != nil {
+			opts.SetResumeAfter(resumeToken)
+		}
+		stream, err := b.collection.Watch(context.Background(), pipeline, opts)
+		if err != nil {
+			log.Printf("inbox change stream failed, retry in %s: %v", backoff, err)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, time.Minute)
+			continue
+		}
+		backoff = time.Second
+		for stream.Next(context.Background()) {
+			resumeToken = stream.ResumeToken()
+			event := &changeEvent{}
+			if err := stream.Decode(event); err != nil || event.FullDocument == nil {
+				log.Printf("invalid inbox change event: %v", err)
+				continue
+			}
+			_ = b.local.Publish(event.FullDocument)
+		}
+		log.Printf("inbox change stream closed: %v", stream.Err())
+		_ = stream.Close(context.Background())
+	}
+}
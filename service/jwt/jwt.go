@@ -0,0 +1,76 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func unsigned(header, claims map[string]any) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return encode(h) + "." + encode(c), nil
+}
+
+// SignES256 returns the compact JWT of claims signed with key, header gets alg and typ.
+func SignES256(key *ecdsa.PrivateKey, header, claims map[string]any) (string, error) {
+	if key == nil {
+		return "", errors.New("nil key")
+	}
+	header = withAlg(header, "ES256")
+	data, err := unsigned(header, claims)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(data))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS uses the fixed size r || s encoding, not ASN.1
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return data + "." + encode(sig), nil
+}
+
+// SignRS256 returns the compact JWT of claims signed with key, header gets alg and typ.
+func SignRS256(key *rsa.PrivateKey, header, claims map[string]any) (string, error) {
+	if key == nil {
+		return "", errors.New("nil key")
+	}
+	header = withAlg(header, "RS256")
+	data, err := unsigned(header, claims)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(data))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return data + "." + encode(sig), nil
+}
+
+func withAlg(header map[string]any, alg string) map[string]any {
+	result := map[string]any{"typ": "JWT", "alg": alg}
+	for k, v := range header {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodePart(t *testing.T, part string) map[string]any {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	assert.NoError(t, err)
+	m := map[string]any{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	return m
+}
+
+func TestSignES256(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	token, err := SignES256(key, map[string]any{"kid": "K1"}, map[string]any{"iss": "team"})
+	assert.NoError(t, err)
+	parts := strings.Split(token, ".")
+	assert.Len(t, parts, 3)
+	assert.Equal(t, map[string]any{"typ": "JWT", "alg": "ES256", "kid": "K1"}, decodePart(t, parts[0]))
+	assert.Equal(t, map[string]any{"iss": "team"}, decodePart(t, parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.True(t, ecdsa.Verify(&key.PublicKey, hash[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])))
+
+	_, err = SignES256(nil, nil, nil)
+	assert.EqualError(t, err, "nil key")
+}
+
+func TestSignRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	token, err := SignRS256(key, nil, map[string]any{"iss": "svc"})
+	assert.NoError(t, err)
+	parts := strings.Split(token, ".")
+	assert.Len(t, parts, 3)
+	assert.Equal(t, map[string]any{"typ": "JWT", "alg": "RS256"}, decodePart(t, parts[0]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], sig))
+}
@@ -0,0 +1,125 @@
+package lang
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/text/language"
+)
+
+// Normalize returns the canonical BCP 47 form of lang, e.g. "zh_hant_tw" becomes "zh-Hant-TW".
+// Values that are not well-formed tags are returned trimmed.
+func Normalize(lang string) string {
+	lang = strings.TrimSpace(lang)
+	if lang == "" {
+		return ""
+	}
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return lang
+	}
+	return tag.String()
+}
+
+// parents returns the less specific tags of lang, most specific first:
+// zh-Hant-TW gives zh-TW, zh-Hant and zh.
+func parents(lang string) []string {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return nil
+	}
+	base, script, region := tag.Raw()
+	hasScript := script != language.Script{}
+	hasRegion := region != language.Region{}
+	result := []string{}
+	if hasScript && hasRegion {
+		result = append(result, base.String()+"-"+region.String(), base.String()+"-"+script.String())
+	}
+	if hasScript || hasRegion {
+		result = append(result, base.String())
+	}
+	return result
+}
+
+type Resolver interface {
+	// Candidates returns the languages to try for lang, in order.
+	Candidates(lang string) []string
+}
+
+type resolverOpt func(*resolver)
+
+func WithDefault(lang string) resolverOpt {
+	return func(r *resolver) {
+		r.defaultLang = Normalize(lang)
+	}
+}
+
+// WithFallback sets the fallback chain of lang. Chains also apply to more specific tags,
+// so a chain of "zh" is used for "zh-Hant-HK" unless it has its own.
+func WithFallback(lang string, chain ...string) resolverOpt {
+	return func(r *resolver) {
+		normalized := make([]string, len(chain))
+		for i, c := range chain {
+			normalized[i] = Normalize(c)
+		}
+		r.fallback[strings.ToLower(Normalize(lang))] = normalized
+	}
+}
+
+// NewResolver returns a resolver configured by mail.lang.default and mail.lang.fallback,
+// followed by the given options.
+func NewResolver(opts ...resolverOpt) Resolver {
+	r := &resolver{
+		fallback: map[string][]string{},
+	}
+	if defaultLang := viper.GetString("mail.lang.default"); defaultLang != "" {
+		WithDefault(defaultLang)(r)
+	}
+	for lang, chain := range viper.GetStringMapStringSlice("mail.lang.fallback") {
+		WithFallback(lang, chain...)(r)
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+type resolver struct {
+	defaultLang string
+	fallback    map[string][]string
+}
+
+func (r *resolver) chain(lang string) ([]string, bool) {
+	if chain, ok := r.fallback[strings.ToLower(lang)]; ok {
+		return chain, true
+	}
+	for _, p := range parents(lang) {
+		if chain, ok := r.fallback[strings.ToLower(p)]; ok {
+			return chain, true
+		}
+	}
+	return nil, false
+}
+
+func (r *resolver) Candidates(lang string) []string {
+	lang = Normalize(lang)
+	result := []string{}
+	seen := map[string]bool{}
+	add := func(langs ...string) {
+		for _, l := range langs {
+			if l == "" || seen[l] {
+				continue
+			}
+			seen[l] = true
+			result = append(result, l)
+		}
+	}
+	add(lang)
+	if chain, ok := r.chain(lang); ok {
+		add(chain...)
+	} else if lang != "" {
+		add(parents(lang)...)
+	}
+	add(r.defaultLang)
+	return result
+}
@@ -0,0 +1,88 @@
+package lang
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{lang: "", want: ""},
+		{lang: " zh-TW ", want: "zh-TW"},
+		{lang: "zh-hant-tw", want: "zh-Hant-TW"},
+		{lang: "EN_us", want: "en-US"},
+		{lang: "not a tag", want: "not a tag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			assert.Equal(t, tt.want, Normalize(tt.lang))
+		})
+	}
+}
+
+func TestResolverCandidates(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []resolverOpt
+		lang string
+		want []string
+	}{
+		{
+			name: "no config",
+			lang: "zh-TW",
+			want: []string{"zh-TW", "zh"},
+		},
+		{
+			name: "empty lang uses default",
+			opts: []resolverOpt{WithDefault("en")},
+			lang: "",
+			want: []string{"en"},
+		},
+		{
+			name: "derived chain",
+			opts: []resolverOpt{WithDefault("en")},
+			lang: "zh-hant-tw",
+			want: []string{"zh-Hant-TW", "zh-TW", "zh-Hant", "zh", "en"},
+		},
+		{
+			name: "configured chain",
+			opts: []resolverOpt{WithDefault("en"), WithFallback("zh-Hant-TW", "zh-TW", "en")},
+			lang: "zh-Hant-TW",
+			want: []string{"zh-Hant-TW", "zh-TW", "en"},
+		},
+		{
+			name: "configured chain of parent",
+			opts: []resolverOpt{WithDefault("en"), WithFallback("zh", "zh-TW")},
+			lang: "zh-Hant-HK",
+			want: []string{"zh-Hant-HK", "zh-TW", "en"},
+		},
+		{
+			name: "language without template",
+			opts: []resolverOpt{WithDefault("en")},
+			lang: "ja",
+			want: []string{"ja", "en"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			assert.Equal(t, tt.want, NewResolver(tt.opts...).Candidates(tt.lang))
+		})
+	}
+}
+
+func TestNewResolverWithViper(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("mail.lang.default", "en")
+	viper.Set("mail.lang.fallback", map[string]interface{}{
+		"zh-Hant-TW": []string{"zh-TW", "en"},
+	})
+	assert.Equal(t, []string{"zh-Hant-TW", "zh-TW", "en"}, NewResolver().Candidates("zh-hant-tw"))
+	assert.Equal(t, []string{"en"}, NewResolver().Candidates(""))
+}
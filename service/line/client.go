@@ -0,0 +1,224 @@
+package line
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultApiUrl = "https://api.line.me"
+
+	defaultQuotaRefresh = 5 * time.Minute
+)
+
+var (
+	// ErrQuotaExceeded is returned for pushes over the monthly message quota of the official account.
+	ErrQuotaExceeded = errors.New("line push quota exceeded")
+	// ErrUnreachable is returned for users who blocked the official account or never added it as a friend.
+	ErrUnreachable = errors.New("line user blocked the official account or is not a friend")
+)
+
+type clientOpt func(*Client)
+
+// WithApiUrl replaces https://api.line.me, e.g. by a local fake, when url is not empty.
+func WithApiUrl(url string) clientOpt {
+	return func(c *Client) {
+		if url != "" {
+			c.apiUrl = strings.TrimRight(url, "/")
+		}
+	}
+}
+
+func WithHttpClient(client *http.Client) clientOpt {
+	return func(c *Client) {
+		c.client = client
+	}
+}
+
+// WithQuotaRefresh sets how often the quota is read from the api, 5 minutes when not positive.
+// Pushes in between are counted locally.
+func WithQuotaRefresh(refresh time.Duration) clientOpt {
+	return func(c *Client) {
+		c.quotaRefresh = refresh
+	}
+}
+
+func WithNow(now func() time.Time) clientOpt {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// NewClient returns the Messaging API client of the channel access token of the official account.
+func NewClient(accessToken string, opts ...clientOpt) (*Client, error) {
+	if accessToken == "" {
+		return nil, errors.New("line channel access token is required")
+	}
+	c := &Client{
+		accessToken:  accessToken,
+		apiUrl:       DefaultApiUrl,
+		quotaRefresh: defaultQuotaRefresh,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.client == nil {
+		c.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if c.quotaRefresh <= 0 {
+		c.quotaRefresh = defaultQuotaRefresh
+	}
+	return c, nil
+}
+
+// Client pushes messages and keeps them within the monthly quota, share one per official account.
+type Client struct {
+	accessToken  string
+	apiUrl       string
+	client       *http.Client
+	quotaRefresh time.Duration
+	now          func() time.Time
+
+	mu      sync.Mutex
+	quota   *Quota
+	quotaAt time.Time
+}
+
+// Quota is the monthly push quota of the official account.
+type Quota struct {
+	// Limited is false for plans without a limit
+	Limited bool
+	Limit   int
+	Used    int
+}
+
+func (q *Quota) exceeded() bool {
+	return q.Limited && q.Used >= q.Limit
+}
+
+type apiError struct {
+	Message string `json:"message"`
+}
+
+func (c *Client) do(method, path string, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.apiUrl+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		e := &apiError{}
+		_ = json.Unmarshal(data, e)
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && strings.Contains(e.Message, "monthly limit"):
+			return ErrQuotaExceeded
+		case resp.StatusCode == http.StatusBadRequest && e.Message == "Failed to send messages":
+			return ErrUnreachable
+		}
+		return fmt.Errorf("line %s failed with status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if result != nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("invalid line %s response: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Quota reads the quota and its consumption of the current month.
+func (c *Client) Quota() (*Quota, error) {
+	limit := struct {
+		Type  string `json:"type"`
+		Value int    `json:"value"`
+	}{}
+	if err := c.do(http.MethodGet, "/v2/bot/message/quota", nil, &limit); err != nil {
+		return nil, err
+	}
+	usage := struct {
+		TotalUsage int `json:"totalUsage"`
+	}{}
+	if err := c.do(http.MethodGet, "/v2/bot/message/quota/consumption", nil, &usage); err != nil {
+		return nil, err
+	}
+	return &Quota{Limited: limit.Type == "limited", Limit: limit.Value, Used: usage.TotalUsage}, nil
+}
+
+// reserve counts a push against the quota, reading it again when it is older than the refresh interval.
+func (c *Client) reserve() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if c.quota == nil || now.Sub(c.quotaAt) >= c.quotaRefresh {
+		quota, err := c.Quota()
+		if err != nil {
+			return fmt.Errorf("failed to read line quota: %w", err)
+		}
+		c.quota = quota
+		c.quotaAt = now
+	}
+	if c.quota.exceeded() {
+		return ErrQuotaExceeded
+	}
+	c.quota.Used++
+	return nil
+}
+
+func (c *Client) release(exhausted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.quota == nil {
+		return
+	}
+	if exhausted {
+		c.quota.Used = c.quota.Limit
+		return
+	}
+	c.quota.Used--
+}
+
+// Push sends messages to the LINE user to and returns the id of the first message. A push is
+// one message of the quota whatever the number of messages, up to 5.
+func (c *Client) Push(to string, messages ...any) (string, error) {
+	if err := c.reserve(); err != nil {
+		return "", err
+	}
+	result := struct {
+		SentMessages []struct {
+			Id string `json:"id"`
+		} `json:"sentMessages"`
+	}{}
+	err := c.do(http.MethodPost, "/v2/bot/message/push", map[string]any{"to": to, "messages": messages}, &result)
+	if err != nil {
+		c.release(errors.Is(err, ErrQuotaExceeded))
+		return "", err
+	}
+	if len(result.SentMessages) == 0 {
+		return "", nil
+	}
+	return result.SentMessages[0].Id, nil
+}
@@ -0,0 +1,130 @@
+package line
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLine is a local stand-in for the Messaging API.
+type fakeLine struct {
+	t          *testing.T
+	server     *httptest.Server
+	mu         sync.Mutex
+	limit      int
+	used       int
+	quotaReads int
+	pushes     []map[string]any
+	// unreachable users answer like users who blocked the official account
+	unreachable map[string]bool
+}
+
+func newFakeLine(t *testing.T, limit int) *fakeLine {
+	f := &fakeLine{t: t, limit: limit, unreachable: map[string]bool{}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeLine) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	assert.Equal(f.t, "Bearer token-1", r.Header.Get("Authorization"))
+	switch r.URL.Path {
+	case "/v2/bot/message/quota":
+		f.quotaReads++
+		if f.limit < 0 {
+			_, _ = io.WriteString(w, `{"type":"none"}`)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"type": "limited", "value": f.limit})
+	case "/v2/bot/message/quota/consumption":
+		_ = json.NewEncoder(w).Encode(map[string]any{"totalUsage": f.used})
+	case "/v2/bot/message/push":
+		body := map[string]any{}
+		assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+		to, _ := body["to"].(string)
+		if f.unreachable[to] {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"message":"Failed to send messages"}`)
+			return
+		}
+		if f.limit >= 0 && f.used >= f.limit {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(w, `{"message":"You have reached your monthly limit."}`)
+			return
+		}
+		f.used++
+		f.pushes = append(f.pushes, body)
+		_, _ = io.WriteString(w, `{"sentMessages":[{"id":"m-`+to+`","quoteToken":"q"}]}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"message":"Not found"}`)
+	}
+}
+
+func TestClientQuota(t *testing.T) {
+	fake := newFakeLine(t, 3)
+	fake.used = 1
+	now := time.Unix(1700000000, 0)
+	client, err := NewClient("token-1", WithApiUrl(fake.server.URL), WithNow(func() time.Time { return now }))
+	assert.NoError(t, err)
+
+	quota, err := client.Quota()
+	assert.NoError(t, err)
+	assert.Equal(t, &Quota{Limited: true, Limit: 3, Used: 1}, quota)
+
+	id, err := client.Push("U1", TextMessage("hi"))
+	assert.NoError(t, err)
+	assert.Equal(t, "m-U1", id)
+	assert.Equal(t, map[string]any{"to": "U1", "messages": []any{map[string]any{"type": "text", "text": "hi"}}}, fake.pushes[0])
+	_, err = client.Push("U2", TextMessage("hi"))
+	assert.NoError(t, err)
+	// the quota counted locally is exceeded without asking the api
+	_, err = client.Push("U3", TextMessage("hi"))
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Len(t, fake.pushes, 2)
+	assert.Equal(t, 2, fake.quotaReads)
+
+	// a new month resets the usage, the quota is read again after the refresh interval
+	fake.used = 0
+	now = now.Add(defaultQuotaRefresh)
+	_, err = client.Push("U3", TextMessage("hi"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fake.quotaReads)
+
+	// the api rejects pushes the local count did not see
+	fake.used = 3
+	_, err = client.Push("U4", TextMessage("hi"))
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = client.Push("U4", TextMessage("hi"))
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+}
+
+func TestClientPushErrors(t *testing.T) {
+	fake := newFakeLine(t, -1)
+	fake.unreachable["U9"] = true
+	client, err := NewClient("token-1", WithApiUrl(fake.server.URL+"/"))
+	assert.NoError(t, err)
+
+	_, err = client.Push("U9", TextMessage("hi"))
+	assert.ErrorIs(t, err, ErrUnreachable)
+	for i := 0; i < 3; i++ {
+		_, err = client.Push("U1", TextMessage("hi"))
+		assert.NoError(t, err)
+	}
+
+	client, err = NewClient("token-1", WithApiUrl(fake.server.URL+"/missing"))
+	assert.NoError(t, err)
+	_, err = client.Push("U1", TextMessage("hi"))
+	assert.EqualError(t, err, `failed to read line quota: line /v2/bot/message/quota failed with status 404: {"message":"Not found"}`)
+
+	_, err = NewClient("")
+	assert.EqualError(t, err, "line channel access token is required")
+}
@@ -0,0 +1,27 @@
+package line
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("line link not found")
+
+// Link maps the user sub to the LINE user the LINE Login link flow returned.
+type Link struct {
+	Sub    string `json:"-" bson:"sub"`
+	UserId string `json:"user_id" bson:"user_id"`
+	// Blocked is set while the user blocks the official account, pushes to them are not delivered
+	Blocked   bool      `json:"blocked" bson:"blocked"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Store keeps the links of subs, a sub is linked to one LINE user at a time.
+type Store interface {
+	// Link adds l, or replaces the LINE user of its sub.
+	Link(l *Link) error
+	Get(sub string) (*Link, error)
+	Unlink(sub string) error
+	// SetBlocked marks the links of the LINE user on its follow and unfollow webhook events.
+	SetBlocked(userId string, blocked bool) error
+}
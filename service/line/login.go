@@ -0,0 +1,133 @@
+package line
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultAuthorizeUrl = "https://access.line.me/oauth2/v2.1/authorize"
+
+type loginOpt func(*Login)
+
+// WithLoginApiUrl replaces https://api.line.me of the token and profile endpoints when url is not empty.
+func WithLoginApiUrl(url string) loginOpt {
+	return func(l *Login) {
+		if url != "" {
+			l.apiUrl = strings.TrimRight(url, "/")
+		}
+	}
+}
+
+// WithLoginAuthorizeUrl replaces https://access.line.me/oauth2/v2.1/authorize when url is not empty.
+func WithLoginAuthorizeUrl(url string) loginOpt {
+	return func(l *Login) {
+		if url != "" {
+			l.authorizeUrl = url
+		}
+	}
+}
+
+func WithLoginHttpClient(client *http.Client) loginOpt {
+	return func(l *Login) {
+		l.client = client
+	}
+}
+
+// NewLogin returns the LINE Login flow of the login channel that links users to the official account.
+// The login channel must belong to the provider of the official account for the user ids to match.
+func NewLogin(channelId, channelSecret, redirectUrl string, opts ...loginOpt) (*Login, error) {
+	if channelId == "" || channelSecret == "" || redirectUrl == "" {
+		return nil, errors.New("line login needs channel id, channel secret and redirect url")
+	}
+	l := &Login{
+		channelId:     channelId,
+		channelSecret: channelSecret,
+		redirectUrl:   redirectUrl,
+		authorizeUrl:  DefaultAuthorizeUrl,
+		apiUrl:        DefaultApiUrl,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.client == nil {
+		l.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return l, nil
+}
+
+type Login struct {
+	channelId     string
+	channelSecret string
+	redirectUrl   string
+	authorizeUrl  string
+	apiUrl        string
+	client        *http.Client
+}
+
+// AuthorizeUrl returns the url users are redirected to for consent, it also asks them to add
+// the official account as a friend, which pushes need.
+func (l *Login) AuthorizeUrl(state string) string {
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {l.channelId},
+		"redirect_uri":  {l.redirectUrl},
+		"state":         {state},
+		"scope":         {"profile openid"},
+		"bot_prompt":    {"aggressive"},
+	}
+	return l.authorizeUrl + "?" + query.Encode()
+}
+
+// UserId exchanges the authorization code of the callback for the LINE user who consented.
+func (l *Login) UserId(code string) (string, error) {
+	resp, err := l.client.PostForm(l.apiUrl+"/oauth2/v2.1/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {l.redirectUrl},
+		"client_id":     {l.channelId},
+		"client_secret": {l.channelSecret},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange line login code: %w", err)
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := decodeLoginResponse(resp, &token); err != nil {
+		return "", fmt.Errorf("failed to exchange line login code: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, l.apiUrl+"/v2/profile", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	resp, err = l.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get line profile: %w", err)
+	}
+	profile := struct {
+		UserId string `json:"userId"`
+	}{}
+	if err := decodeLoginResponse(resp, &profile); err != nil {
+		return "", fmt.Errorf("failed to get line profile: %w", err)
+	}
+	if profile.UserId == "" {
+		return "", errors.New("line profile has no user id")
+	}
+	return profile.UserId, nil
+}
+
+func decodeLoginResponse(resp *http.Response, result any) error {
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, result)
+}
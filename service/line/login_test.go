@@ -0,0 +1,56 @@
+package line
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeLogin(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/v2.1/token":
+			assert.Equal(t, "authorization_code", r.FormValue("grant_type"))
+			assert.Equal(t, "secret", r.FormValue("client_secret"))
+			if r.FormValue("code") != "code-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `{"error":"invalid_grant"}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"access_token":"access-1","id_token":"id","expires_in":2592000}`)
+		case "/v2/profile":
+			assert.Equal(t, "Bearer access-1", r.Header.Get("Authorization"))
+			_, _ = io.WriteString(w, `{"userId":"U-bob","displayName":"Bob"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLogin(t *testing.T) {
+	server := newFakeLogin(t)
+	login, err := NewLogin("1234", "secret", "https://api.oosa.life/line/callback", WithLoginApiUrl(server.URL))
+	assert.NoError(t, err)
+
+	authorize, err := url.Parse(login.AuthorizeUrl("state-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "access.line.me", authorize.Host)
+	query := authorize.Query()
+	assert.Equal(t, "1234", query.Get("client_id"))
+	assert.Equal(t, "state-1", query.Get("state"))
+	assert.Equal(t, "https://api.oosa.life/line/callback", query.Get("redirect_uri"))
+	assert.Equal(t, "aggressive", query.Get("bot_prompt"))
+
+	userId, err := login.UserId("code-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "U-bob", userId)
+	_, err = login.UserId("code-2")
+	assert.EqualError(t, err, `failed to exchange line login code: status 400: {"error":"invalid_grant"}`)
+
+	_, err = NewLogin("1234", "", "https://api.oosa.life/line/callback")
+	assert.EqualError(t, err, "line login needs channel id, channel secret and redirect url")
+}
@@ -0,0 +1,65 @@
+package line
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+const (
+	maxTextLength    = 5000
+	maxAltTextLength = 400
+)
+
+// TextMessage returns a text message of text, cut to the 5000 characters LINE accepts.
+func TextMessage(text string) map[string]any {
+	return map[string]any{"type": "text", "text": truncate(text, maxTextLength)}
+}
+
+// FlexMessage returns a flex message of a bubble or carousel container, altText is shown
+// where flex messages are not, e.g. in notifications and the chat list.
+func FlexMessage(altText string, contents json.RawMessage) map[string]any {
+	return map[string]any{"type": "flex", "altText": truncate(altText, maxAltTextLength), "contents": contents}
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+// isFlex reports whether the html of a line template is a flex container instead of html.
+func isFlex(detail *dao.DetailTemplateResponse) bool {
+	return strings.HasPrefix(strings.TrimSpace(detail.Body.Html), "{")
+}
+
+// renderMessage renders a line template into a flex message when its html is the json of a
+// flex container, into a text message of its plain text otherwise. Data is escaped for json
+// before it is put into a flex container.
+func renderMessage(detail *dao.DetailTemplateResponse, data map[string]string) (map[string]any, error) {
+	text := *detail
+	mail.Render(&text, data)
+	if !isFlex(detail) {
+		if text.Body.Plaint == "" {
+			return nil, errors.New("line template has no plain text")
+		}
+		return TextMessage(text.Body.Plaint), nil
+	}
+	flex := *detail
+	mail.Render(&flex, channel.JSONEscaped(data))
+	contents := json.RawMessage(strings.TrimSpace(flex.Body.Html))
+	if !json.Valid(contents) {
+		return nil, errors.New("line template flex message is not valid json")
+	}
+	altText := text.Subject
+	if altText == "" {
+		altText = text.Body.Plaint
+	}
+	return FlexMessage(altText, contents), nil
+}
@@ -0,0 +1,53 @@
+package line
+
+import "sync"
+
+// NewMockStore returns links in memory.
+func NewMockStore() Store {
+	return &mockStore{links: map[string]*Link{}}
+}
+
+type mockStore struct {
+	mu    sync.Mutex
+	links map[string]*Link
+}
+
+func (m *mockStore) Link(l *Link) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := *l
+	m.links[l.Sub] = &c
+	return nil
+}
+
+func (m *mockStore) Get(sub string) (*Link, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.links[sub]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *l
+	return &c, nil
+}
+
+func (m *mockStore) Unlink(sub string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.links[sub]; !ok {
+		return ErrNotFound
+	}
+	delete(m.links, sub)
+	return nil
+}
+
+func (m *mockStore) SetBlocked(userId string, blocked bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.links {
+		if l.UserId == userId {
+			l.Blocked = blocked
+		}
+	}
+	return nil
+}
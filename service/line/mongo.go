@@ -0,0 +1,77 @@
+package line
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoStore returns the links in collection, see EnsureIndexes.
+func NewMongoStore(collection *mongo.Collection) Store {
+	return &mongoStore{collection: collection, timeout: 5 * time.Second}
+}
+
+// EnsureIndexes creates the unique index of subs and the index of LINE users.
+func EnsureIndexes(collection *mongo.Collection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sub", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create line link indexes: %w", err)
+	}
+	return nil
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func (m *mongoStore) Link(l *Link) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.ReplaceOne(ctx, bson.M{"sub": l.Sub}, l, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *mongoStore) Get(sub string) (*Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	l := &Link{}
+	err := m.collection.FindOne(ctx, bson.M{"sub": sub}).Decode(l)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (m *mongoStore) Unlink(sub string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	result, err := m.collection.DeleteOne(ctx, bson.M{"sub": sub})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *mongoStore) SetBlocked(userId string, blocked bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.UpdateMany(ctx, bson.M{"user_id": userId}, bson.M{"$set": bson.M{"blocked": blocked}})
+	return err
+}
@@ -0,0 +1,128 @@
+package line
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+)
+
+type senderOpt func(*sender)
+
+// WithStore resolves the LINE users of recipients from the links of store before their identity trait.
+func WithStore(store Store) senderOpt {
+	return func(s *sender) {
+		s.store = store
+	}
+}
+
+func WithLangResolver(resolver lang.Resolver) senderOpt {
+	return func(s *sender) {
+		s.langResolver = resolver
+	}
+}
+
+// NewSender returns the sender of the line channel, it pushes the <event>-line template rendered
+// in the lang of the recipient to the LINE user of the recipient.
+func NewSender(client *Client, tpl channel.TemplateReader, opts ...senderOpt) (service.Sender, error) {
+	if client == nil {
+		return nil, errors.New("line client is required")
+	}
+	if tpl == nil {
+		return nil, errors.New("template is required")
+	}
+	s := &sender{
+		client: client,
+		tpl:    tpl,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.langResolver == nil {
+		s.langResolver = lang.NewResolver()
+	}
+	return s, nil
+}
+
+type sender struct {
+	client       *Client
+	store        Store
+	tpl          channel.TemplateReader
+	langResolver lang.Resolver
+}
+
+// userId returns the LINE user of to, the one of its link or else the one of its identity trait.
+func (s *sender) userId(to *service.Info) (userId string, blocked bool, err error) {
+	if s.store != nil {
+		link, err := s.store.Get(to.Sub)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil:
+			return "", false, fmt.Errorf("failed to get line link of %s: %w", to.Sub, err)
+		default:
+			return link.UserId, link.Blocked, nil
+		}
+	}
+	return to.LineUserId, false, nil
+}
+
+// Send returns the message ids of the pushes. Recipients without a LINE user, or who blocked the
+// official account, are skipped. Pushes stop once the quota is exceeded.
+func (s *sender) Send(notify *service.Notification) (string, error) {
+	if len(notify.SendTo) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+	var userIds []string
+	blocked := 0
+	for _, to := range notify.SendTo {
+		userId, isBlocked, err := s.userId(to)
+		if err != nil {
+			return "", err
+		}
+		if isBlocked {
+			blocked++
+			continue
+		}
+		if userId != "" {
+			userIds = append(userIds, userId)
+		}
+	}
+	if len(userIds) == 0 {
+		if blocked > 0 {
+			return "", channel.Skip("line users blocked the official account")
+		}
+		return "", channel.Skip("no line user")
+	}
+	detail, err := channel.Resolve(s.tpl, s.langResolver, channel.Line, notify)
+	if err != nil {
+		return "", err
+	}
+	message, err := renderMessage(detail, notify.Data)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %w", notify.TemplateUsed, err)
+	}
+
+	var ids []string
+	var errs []error
+	unreachable := 0
+	for _, userId := range userIds {
+		id, err := s.client.Push(userId, message)
+		switch {
+		case errors.Is(err, ErrUnreachable):
+			unreachable++
+		case errors.Is(err, ErrQuotaExceeded):
+			return strings.Join(ids, ","), errors.Join(append(errs, err)...)
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			ids = append(ids, id)
+		}
+	}
+	if unreachable == len(userIds) {
+		return "", channel.Skip("line users blocked the official account")
+	}
+	return strings.Join(ids, ","), errors.Join(errs...)
+}
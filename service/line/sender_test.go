@@ -0,0 +1,105 @@
+package line
+
+import (
+	"testing"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTemplates(templates map[string]*dao.DetailTemplateResponse) channel.TemplateReader {
+	return mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) {
+			_, ok := templates[name]
+			return ok, nil
+		}),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			detail := *templates[name]
+			return &detail, nil
+		}),
+	)
+}
+
+func TestSender(t *testing.T) {
+	text := &dao.DetailTemplateResponse{Subject: "{{FROM}} joined"}
+	text.Body.Plaint = "Say hi to {{FROM}}"
+	flex := &dao.DetailTemplateResponse{Subject: "{{FROM}} 加入了"}
+	flex.Body.Html = `{"type":"bubble","body":{"type":"box","layout":"vertical","contents":[{"type":"text","text":"{{FROM}}"}]}}`
+	tpl := newTestTemplates(map[string]*dao.DetailTemplateResponse{
+		"EVENT_JOIN-line_en":    text,
+		"EVENT_JOIN-line_zh-TW": flex,
+	})
+	fake := newFakeLine(t, -1)
+	fake.unreachable["U-blocked"] = true
+	client, err := NewClient("token-1", WithApiUrl(fake.server.URL))
+	assert.NoError(t, err)
+	store := NewMockStore()
+	assert.NoError(t, store.Link(&Link{Sub: "bob", UserId: "U-bob"}))
+	assert.NoError(t, store.Link(&Link{Sub: "carol", UserId: "U-carol", Blocked: true}))
+	sender, err := NewSender(client, tpl, WithStore(store), WithLangResolver(lang.NewResolver(lang.WithDefault("en"))))
+	assert.NoError(t, err)
+
+	notify := &service.Notification{
+		Event: "EVENT_JOIN",
+		Lang:  "en",
+		Data:  map[string]string{"FROM": "Alice"},
+		// dave has no link, his identity trait names his LINE user
+		SendTo: []*service.Info{{Sub: "bob"}, {Sub: "dave", LineUserId: "U-dave"}, {Sub: "erin"}},
+	}
+	mid, err := sender.Send(notify)
+	assert.NoError(t, err)
+	assert.Equal(t, "m-U-bob,m-U-dave", mid)
+	assert.Equal(t, "EVENT_JOIN-line_en", notify.TemplateUsed)
+	assert.Equal(t, []any{map[string]any{"type": "text", "text": "Say hi to Alice"}}, fake.pushes[0]["messages"])
+
+	// data is escaped inside flex json
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "zh-TW", Data: map[string]string{"FROM": `"Al"`},
+		SendTo: []*service.Info{{Sub: "bob"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{
+		"type":    "flex",
+		"altText": `"Al" 加入了`,
+		"contents": map[string]any{"type": "bubble", "body": map[string]any{"type": "box", "layout": "vertical",
+			"contents": []any{map[string]any{"type": "text", "text": `"Al"`}}}},
+	}}, fake.pushes[2]["messages"])
+
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "carol"}}})
+	assert.EqualError(t, err, "skipped: line users blocked the official account")
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "frank", LineUserId: "U-blocked"}}})
+	assert.EqualError(t, err, "skipped: line users blocked the official account")
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "erin"}}})
+	assert.True(t, channel.IsSkip(err), err)
+	assert.EqualError(t, err, "skipped: no line user")
+
+	broken := &dao.DetailTemplateResponse{}
+	broken.Body.Html = `{"type":"bubble"`
+	sender, err = NewSender(client, newTestTemplates(map[string]*dao.DetailTemplateResponse{"EVENT_JOIN-line_en": broken}))
+	assert.NoError(t, err)
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "gina", LineUserId: "U-gina"}}})
+	assert.EqualError(t, err, "template EVENT_JOIN-line_en: line template flex message is not valid json")
+}
+
+func TestSenderQuotaExceeded(t *testing.T) {
+	text := &dao.DetailTemplateResponse{}
+	text.Body.Plaint = "hi"
+	fake := newFakeLine(t, 1)
+	client, err := NewClient("token-1", WithApiUrl(fake.server.URL))
+	assert.NoError(t, err)
+	sender, err := NewSender(client, newTestTemplates(map[string]*dao.DetailTemplateResponse{"EVENT_JOIN-line_en": text}))
+	assert.NoError(t, err)
+	mid, err := sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en",
+		SendTo: []*service.Info{{Sub: "a", LineUserId: "U-a"}, {Sub: "b", LineUserId: "U-b"}, {Sub: "c", LineUserId: "U-c"}}})
+	assert.Equal(t, "m-U-a", mid)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Len(t, fake.pushes, 1)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 3))
+	assert.Equal(t, "ab…", truncate("abcd", 3))
+	assert.Equal(t, "你好…", truncate("你好世界", 3))
+}
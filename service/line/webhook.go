@@ -0,0 +1,67 @@
+package line
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+)
+
+const (
+	EventFollow   = "follow"
+	EventUnfollow = "unfollow"
+)
+
+// Event is the part of a webhook event the channel needs.
+type Event struct {
+	Type   string `json:"type"`
+	Source struct {
+		Type   string `json:"type"`
+		UserId string `json:"userId"`
+	} `json:"source"`
+}
+
+// VerifySignature reports whether signature is the X-Line-Signature of body, the base64 of
+// its HMAC-SHA256 with the channel secret of the official account.
+func VerifySignature(channelSecret string, body []byte, signature string) bool {
+	if channelSecret == "" {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(channelSecret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+func ParseEvents(body []byte) ([]*Event, error) {
+	payload := struct {
+		Events []*Event `json:"events"`
+	}{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Events, nil
+}
+
+// ApplyEvents marks the links of users who block the official account, unfollow, or unblock it, follow.
+func ApplyEvents(store Store, events []*Event) error {
+	for _, e := range events {
+		if e.Source.UserId == "" {
+			continue
+		}
+		switch e.Type {
+		case EventUnfollow:
+			if err := store.SetBlocked(e.Source.UserId, true); err != nil {
+				return err
+			}
+		case EventFollow:
+			if err := store.SetBlocked(e.Source.UserId, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package line
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"destination":"U0","events":[]}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, VerifySignature("secret", body, signature))
+	assert.False(t, VerifySignature("other", body, signature))
+	assert.False(t, VerifySignature("secret", []byte(`{}`), signature))
+	assert.False(t, VerifySignature("secret", body, "not base64"))
+	assert.False(t, VerifySignature("", body, signature))
+}
+
+func TestApplyEvents(t *testing.T) {
+	store := NewMockStore()
+	assert.NoError(t, store.Link(&Link{Sub: "bob", UserId: "U-bob"}))
+	events, err := ParseEvents([]byte(`{"events":[
+		{"type":"unfollow","source":{"type":"user","userId":"U-bob"}},
+		{"type":"message","source":{"type":"user","userId":"U-bob"}},
+		{"type":"join","source":{"type":"group","groupId":"G1"}}
+	]}`))
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyEvents(store, events))
+	link, _ := store.Get("bob")
+	assert.True(t, link.Blocked)
+
+	events, _ = ParseEvents([]byte(`{"events":[{"type":"follow","source":{"type":"user","userId":"U-bob"}}]}`))
+	assert.NoError(t, ApplyEvents(store, events))
+	link, _ = store.Get("bob")
+	assert.False(t, link.Blocked)
+
+	assert.ErrorIs(t, store.Unlink("carol"), ErrNotFound)
+	assert.NoError(t, store.Unlink("bob"))
+	_, err = store.Get("bob")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -10,22 +10,40 @@ import (
 	"github.com/spf13/viper"
 )
 
+// SessionConfig selects the SES region and the profile of a shared credentials file.
+type SessionConfig struct {
+	Region          string
+	CredentialsFile string
+	Profile         string
+}
+
+// ConfigFromViper returns the session config of the aws.ses block.
+func ConfigFromViper() SessionConfig {
+	return SessionConfig{
+		Region:          viper.GetString("aws.ses.region"),
+		CredentialsFile: viper.GetString("aws.ses.credentails.filename"),
+		Profile:         viper.GetString("aws.ses.credentails.profile"),
+	}
+}
+
 func newAwsSession() (*session.Session, error) {
-	region := viper.GetString("aws.ses.region")
-	if region == "" {
+	return NewSession(ConfigFromViper())
+}
+
+// NewSession returns the session of cfg, services other than SES, e.g. SNS for sms, share it.
+func NewSession(cfg SessionConfig) (*session.Session, error) {
+	if cfg.Region == "" {
 		return nil, errors.New("aws.ses.region is empty")
 	}
-	filename := viper.GetString("aws.ses.credentails.filename")
-	if filename == "" {
+	if cfg.CredentialsFile == "" {
 		return nil, errors.New("aws.ses.credentails.filename is empty")
 	}
-	profile := viper.GetString("aws.ses.credentails.profile")
-	if profile == "" {
+	if cfg.Profile == "" {
 		return nil, errors.New("aws.ses.credentails.profile is empty")
 	}
 	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: credentials.NewSharedCredentials(filename, profile),
+		Region:      aws.String(cfg.Region),
+		Credentials: credentials.NewSharedCredentials(cfg.CredentialsFile, cfg.Profile),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("new aws session fail: %w", err)
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
 	"github.com/arwoosa/notifaction/service/mail"
 	"github.com/arwoosa/notifaction/service/mail/dao"
 	"github.com/aws/aws-sdk-go/aws"
@@ -422,6 +423,7 @@ func TestSend(t *testing.T) {
 		notify        *service.Notification
 		wantErr       bool
 		expectedMsgId string
+		expectedTpl   string
 	}{
 		{
 			name: "valid notification",
@@ -455,6 +457,83 @@ func TestSend(t *testing.T) {
 			},
 			wantErr:       false,
 			expectedMsgId: "1234",
+			expectedTpl:   "test-event_zh-TW",
+		},
+		{
+			name: "fallback language template",
+			opts: []apiSenderOpt{
+				WithLangResolver(lang.NewResolver(lang.WithDefault("en"))),
+				WithTemplateStore(mail.NewMockTemplateStore(
+					mail.WithIsTemplateExist(func(name string) (bool, error) {
+						return name == "test-event_en", nil
+					}),
+				)),
+				WithAwsSender(
+					NewMockSender(func(input *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+						if *input.Content.Template.TemplateName != "test-event_en" {
+							return nil, errors.New("unexpected template")
+						}
+						return &sesv2.SendEmailOutput{
+							MessageId: aws.String("5678"),
+						}, nil
+					}),
+				),
+			},
+			notify: &service.Notification{
+				Data: map[string]string{"key": "value"},
+				SendTo: []*service.Info{
+					{
+						Sub:    "test-subject",
+						Name:   "test-name",
+						Email:  "sendto@example.com",
+						Enable: true,
+					},
+				},
+				Event: "test-event",
+				Lang:  "ja",
+				From:  &service.Info{Email: "from@example.com"},
+			},
+			wantErr:       false,
+			expectedMsgId: "5678",
+			expectedTpl:   "test-event_en",
+		},
+		{
+			name: "rendered content",
+			opts: []apiSenderOpt{
+				WithContent(mail.NewTemplateSet(
+					dao.NewTemplate("test-event", "zh-TW", "Hi {{KEY}}", "{{KEY}}", "<p>{{KEY}}</p>"),
+				)),
+				WithAwsSender(
+					NewMockSender(func(input *sesv2.SendEmailInput) (*sesv2.SendEmailOutput, error) {
+						if input.Content.Template != nil ||
+							*input.Content.Simple.Subject.Data != "Hi value" ||
+							*input.Content.Simple.Body.Html.Data != "<p>value</p>" ||
+							*input.Content.Simple.Body.Text.Data != "value" {
+							return nil, errors.New("unexpected content")
+						}
+						return &sesv2.SendEmailOutput{
+							MessageId: aws.String("9012"),
+						}, nil
+					}),
+				),
+			},
+			notify: &service.Notification{
+				Data: map[string]string{"KEY": "value"},
+				SendTo: []*service.Info{
+					{
+						Sub:    "test-subject",
+						Name:   "test-name",
+						Email:  "sendto@example.com",
+						Enable: true,
+					},
+				},
+				Event: "test-event",
+				Lang:  "zh-TW",
+				From:  &service.Info{Email: "from@example.com"},
+			},
+			wantErr:       false,
+			expectedMsgId: "9012",
+			expectedTpl:   "test-event_zh-TW",
 		},
 		{
 			name: "tempate not found notification",
@@ -507,6 +586,7 @@ func TestSend(t *testing.T) {
 			}
 			assert.NoError(t, err)
 			assert.Equal(t, tt.expectedMsgId, msgId)
+			assert.Equal(t, tt.expectedTpl, tt.notify.TemplateUsed)
 		})
 	}
 }
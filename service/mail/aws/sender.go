@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 
 	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
 	"github.com/arwoosa/notifaction/service/mail"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sesv2"
@@ -24,18 +26,36 @@ func WithTemplateStore(store mail.TemplateStore) apiSenderOpt {
 	}
 }
 
+// WithContent sends the templates of tpls rendered with the data instead of the templates stored in SES.
+func WithContent(tpls mail.TemplateReader) apiSenderOpt {
+	return func(a *awsApiSender) {
+		a.tplStore = tpls
+		a.content = true
+	}
+}
+
 func WithAwsSender(sender awsSender) apiSenderOpt {
 	return func(a *awsApiSender) {
 		a.awsSender = sender
 	}
 }
 
+func WithLangResolver(resolver lang.Resolver) apiSenderOpt {
+	return func(a *awsApiSender) {
+		a.langResolver = resolver
+	}
+}
+
 func NewApiSender(opts ...apiSenderOpt) (mail.ApiSender, error) {
 	sender := &awsApiSender{}
 	for _, opt := range opts {
 		opt(sender)
 	}
 
+	if sender.langResolver == nil {
+		sender.langResolver = lang.NewResolver()
+	}
+
 	if sender.awsSender == nil {
 		sess, err := newAwsSession()
 		if err != nil {
@@ -43,11 +63,13 @@ func NewApiSender(opts ...apiSenderOpt) (mail.ApiSender, error) {
 		}
 		sesv2 := sesv2.New(sess)
 		sender.awsSender = sesv2
-		tplStore, err := NewTemplateStore(WithSesSession(sesv2))
-		if err != nil {
-			return nil, err
+		if sender.tplStore == nil {
+			tplStore, err := NewTemplateStore(WithSesSession(sesv2))
+			if err != nil {
+				return nil, err
+			}
+			sender.tplStore = tplStore
 		}
-		sender.tplStore = tplStore
 	}
 
 	if sender.tplStore == nil {
@@ -64,8 +86,10 @@ func NewApiSender(opts ...apiSenderOpt) (mail.ApiSender, error) {
 
 type awsApiSender struct {
 	awsSender
-	tplStore mail.TemplateStore
-	from     string
+	tplStore     mail.TemplateReader
+	content      bool
+	langResolver lang.Resolver
+	from         string
 }
 
 const addressTpl = `"%s" <%s>`
@@ -79,26 +103,31 @@ func (a *awsApiSender) Send(notify *service.Notification) (string, error) {
 	for i, s := range notify.SendTo {
 		addresses[i] = aws.String(fmt.Sprintf(addressTpl, s.Name, s.Email))
 	}
-	tplName := notify.GetTemplateName()
-
-	exist, err := a.tplStore.IsTemplateExist(tplName)
+	tplName, _, err := mail.ResolveTemplate(a.langResolver, a.tplStore.IsTemplateExist, notify.Event, notify.Lang)
 	if err != nil {
 		return "", err
 	}
-	if !exist {
-		return "", errors.New("template does not exist: " + tplName)
+	if tplName != notify.GetTemplateName() {
+		log.Printf("template %s does not exist, fallback to %s", notify.GetTemplateName(), tplName)
+	}
+	notify.TemplateUsed = tplName
+	content := &sesv2.EmailContent{
+		Template: &sesv2.Template{
+			TemplateName: aws.String(tplName),
+			TemplateData: aws.String(string(dataJson)),
+		},
+	}
+	if a.content {
+		if content, err = a.render(tplName, notify); err != nil {
+			return "", err
+		}
 	}
 	output, err := a.SendEmail(&sesv2.SendEmailInput{
 		Destination: &sesv2.Destination{
 			ToAddresses: addresses,
 		},
 		FromEmailAddress: aws.String(a.from),
-		Content: &sesv2.EmailContent{
-			Template: &sesv2.Template{
-				TemplateName: aws.String(tplName),
-				TemplateData: aws.String(string(dataJson)),
-			},
-		},
+		Content:          content,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to send email: %w", err)
@@ -108,3 +137,24 @@ func (a *awsApiSender) Send(notify *service.Notification) (string, error) {
 	}
 	return *output.MessageId, nil
 }
+
+// render fills the template with the data the way the SMTP sender does.
+func (a *awsApiSender) render(tplName string, notify *service.Notification) (*sesv2.EmailContent, error) {
+	detail, err := a.tplStore.Detail(tplName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template detail: %w", err)
+	}
+	mail.Render(detail, notify.Data)
+	body := &sesv2.Body{
+		Html: &sesv2.Content{Data: aws.String(detail.Body.Html)},
+	}
+	if detail.Body.Plaint != "" {
+		body.Text = &sesv2.Content{Data: aws.String(detail.Body.Plaint)}
+	}
+	return &sesv2.EmailContent{
+		Simple: &sesv2.Message{
+			Subject: &sesv2.Content{Data: aws.String(detail.Subject)},
+			Body:    body,
+		},
+	}, nil
+}
@@ -26,6 +26,13 @@ func WithSesSession(ses *sesv2.SESV2) awsTemplateStoreOpt {
 	}
 }
 
+// WithSessionConfig connects to the SES of cfg instead of the one of the aws.ses config block.
+func WithSessionConfig(cfg SessionConfig) awsTemplateStoreOpt {
+	return func(a *awsTplImpl) {
+		a.sessionConfig = &cfg
+	}
+}
+
 func NewTemplateStore(opts ...awsTemplateStoreOpt) (mail.TemplateStore, error) {
 	awsTplImpl := &awsTplImpl{}
 
@@ -34,7 +41,11 @@ func NewTemplateStore(opts ...awsTemplateStoreOpt) (mail.TemplateStore, error) {
 	}
 
 	if awsTplImpl.awsTemplateStore == nil {
-		sess, err := newAwsSession()
+		cfg := ConfigFromViper()
+		if awsTplImpl.sessionConfig != nil {
+			cfg = *awsTplImpl.sessionConfig
+		}
+		sess, err := NewSession(cfg)
 		if err != nil {
 			return nil, fmt.Errorf("new aws session fail: %w", err)
 		}
@@ -46,6 +57,7 @@ func NewTemplateStore(opts ...awsTemplateStoreOpt) (mail.TemplateStore, error) {
 
 type awsTplImpl struct {
 	awsTemplateStore
+	sessionConfig *SessionConfig
 }
 
 // CreateTpl creates a new email template in AWS SES using the provided template data.
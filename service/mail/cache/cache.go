@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+type storeOpt func(*Store)
+
+// WithTTL sets how long an existing template and its content are cached.
+func WithTTL(ttl time.Duration) storeOpt {
+	return func(s *Store) {
+		s.ttl = ttl
+	}
+}
+
+// WithNegativeTTL sets how long a missing template is cached, 0 does not cache missing templates.
+func WithNegativeTTL(ttl time.Duration) storeOpt {
+	return func(s *Store) {
+		s.negativeTTL = ttl
+	}
+}
+
+func WithNow(now func() time.Time) storeOpt {
+	return func(s *Store) {
+		s.now = now
+	}
+}
+
+// NewStore returns a template store caching IsTemplateExist and Detail of next. Concurrent reads of
+// the same uncached template share one call to next, and writes through the store invalidate the template.
+func NewStore(next mail.TemplateStore, opts ...storeOpt) (*Store, error) {
+	if next == nil {
+		return nil, errors.New("store is nil")
+	}
+	s := &Store{
+		next:     next,
+		now:      time.Now,
+		exists:   map[string]*existEntry{},
+		details:  map[string]*detailEntry{},
+		inflight: map[string]*call{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.ttl <= 0 {
+		return nil, errors.New("ttl must be positive")
+	}
+	return s, nil
+}
+
+type existEntry struct {
+	exist   bool
+	expires time.Time
+}
+
+type detailEntry struct {
+	detail  *dao.DetailTemplateResponse
+	expires time.Time
+}
+
+// call is a read of next shared by the concurrent callers of the same key.
+type call struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+type Store struct {
+	next        mail.TemplateStore
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	exists   map[string]*existEntry
+	details  map[string]*detailEntry
+	inflight map[string]*call
+	// generation is increased by every invalidation, so that reads started before it are not cached
+	generation uint64
+}
+
+// do runs fn once for the concurrent callers of key and caches its result with store when
+// no invalidation happened meanwhile.
+func (s *Store) do(key string, fn func() (any, error), store func(value any)) (any, error) {
+	s.mu.Lock()
+	if c, ok := s.inflight[key]; ok {
+		s.mu.Unlock()
+		<-c.done
+		return c.value, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	s.inflight[key] = c
+	generation := s.generation
+	s.mu.Unlock()
+
+	c.value, c.err = fn()
+
+	s.mu.Lock()
+	delete(s.inflight, key)
+	if c.err == nil && generation == s.generation {
+		store(c.value)
+	}
+	s.mu.Unlock()
+	close(c.done)
+	return c.value, c.err
+}
+
+func (s *Store) IsTemplateExist(name string) (bool, error) {
+	s.mu.Lock()
+	if e, ok := s.exists[name]; ok && s.now().Before(e.expires) {
+		s.mu.Unlock()
+		return e.exist, nil
+	}
+	s.mu.Unlock()
+
+	value, err := s.do("exist:"+name, func() (any, error) {
+		return s.next.IsTemplateExist(name)
+	}, func(value any) {
+		s.setExist(name, value.(bool))
+	})
+	if err != nil {
+		return false, err
+	}
+	return value.(bool), nil
+}
+
+// setExist caches the existence of name, the caller holds the lock.
+func (s *Store) setExist(name string, exist bool) {
+	ttl := s.ttl
+	if !exist {
+		ttl = s.negativeTTL
+	}
+	if ttl <= 0 {
+		delete(s.exists, name)
+		return
+	}
+	s.exists[name] = &existEntry{exist: exist, expires: s.now().Add(ttl)}
+}
+
+// Detail returns a copy of the cached content, so callers rendering it in place do not change the cache.
+func (s *Store) Detail(name string) (*dao.DetailTemplateResponse, error) {
+	s.mu.Lock()
+	if e, ok := s.details[name]; ok && s.now().Before(e.expires) {
+		s.mu.Unlock()
+		return copyDetail(e.detail), nil
+	}
+	s.mu.Unlock()
+
+	value, err := s.do("detail:"+name, func() (any, error) {
+		return s.next.Detail(name)
+	}, func(value any) {
+		s.setDetail(name, value.(*dao.DetailTemplateResponse))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyDetail(value.(*dao.DetailTemplateResponse)), nil
+}
+
+// setDetail caches the content of name, which also tells that it exists. The caller holds the lock.
+func (s *Store) setDetail(name string, detail *dao.DetailTemplateResponse) {
+	if detail == nil {
+		return
+	}
+	expires := s.now().Add(s.ttl)
+	s.details[name] = &detailEntry{detail: copyDetail(detail), expires: expires}
+	s.exists[name] = &existEntry{exist: true, expires: expires}
+}
+
+func copyDetail(detail *dao.DetailTemplateResponse) *dao.DetailTemplateResponse {
+	if detail == nil {
+		return nil
+	}
+	c := *detail
+	return &c
+}
+
+func (s *Store) CreateTpl(tpl *dao.Template) error {
+	defer s.Invalidate(tpl.GetName())
+	return s.next.CreateTpl(tpl)
+}
+
+func (s *Store) UpdateTemplate(tpl *dao.Template) error {
+	defer s.Invalidate(tpl.GetName())
+	return s.next.UpdateTemplate(tpl)
+}
+
+func (s *Store) Delete(name string) error {
+	defer s.Invalidate(name)
+	return s.next.Delete(name)
+}
+
+func (s *Store) List(token string) (*dao.ListTemplateResponse, error) {
+	return s.next.List(token)
+}
+
+// Invalidate drops the cached existence and content of name.
+func (s *Store) Invalidate(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.exists, name)
+	delete(s.details, name)
+	s.generation++
+}
+
+// Warm loads the content of every template of next into the cache, returning the number of templates loaded.
+func (s *Store) Warm() (int, error) {
+	tpls, err := mail.ListAll(s.next)
+	if err != nil {
+		return 0, err
+	}
+	for _, tpl := range tpls {
+		if _, err := s.Detail(tpl.Name); err != nil {
+			return 0, fmt.Errorf("failed to load template %s: %w", tpl.Name, err)
+		}
+	}
+	return len(tpls), nil
+}
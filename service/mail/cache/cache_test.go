@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newDetail(name, subject string) *dao.DetailTemplateResponse {
+	return &dao.DetailTemplateResponse{Title: name, Subject: subject}
+}
+
+func TestNewStore(t *testing.T) {
+	_, err := NewStore(nil, WithTTL(time.Minute))
+	assert.EqualError(t, err, "store is nil")
+	_, err = NewStore(mail.NewMockTemplateStore())
+	assert.EqualError(t, err, "ttl must be positive")
+}
+
+func TestStoreIsTemplateExist(t *testing.T) {
+	var calls atomic.Int32
+	existing := map[string]bool{"welcome_en": true}
+	next := mail.NewMockTemplateStore(mail.WithIsTemplateExist(func(name string) (bool, error) {
+		calls.Add(1)
+		return existing[name], nil
+	}))
+	c := &clock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store, err := NewStore(next, WithTTL(time.Minute), WithNegativeTTL(10*time.Second), WithNow(c.Now))
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		exist, err := store.IsTemplateExist("welcome_en")
+		assert.NoError(t, err)
+		assert.True(t, exist)
+		exist, err = store.IsTemplateExist("welcome_ja")
+		assert.NoError(t, err)
+		assert.False(t, exist)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+
+	// the missing template expires first
+	c.now = c.now.Add(30 * time.Second)
+	_, _ = store.IsTemplateExist("welcome_en")
+	_, _ = store.IsTemplateExist("welcome_ja")
+	assert.Equal(t, int32(3), calls.Load())
+
+	c.now = c.now.Add(time.Minute)
+	_, _ = store.IsTemplateExist("welcome_en")
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestStoreNoNegativeCache(t *testing.T) {
+	var calls atomic.Int32
+	next := mail.NewMockTemplateStore(mail.WithIsTemplateExist(func(name string) (bool, error) {
+		calls.Add(1)
+		return false, nil
+	}))
+	store, err := NewStore(next, WithTTL(time.Minute))
+	assert.NoError(t, err)
+	_, _ = store.IsTemplateExist("welcome_en")
+	_, _ = store.IsTemplateExist("welcome_en")
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestStoreErrorsAreNotCached(t *testing.T) {
+	var calls atomic.Int32
+	next := mail.NewMockTemplateStore(mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+		if calls.Add(1) == 1 {
+			return nil, errors.New("throttled")
+		}
+		return newDetail(name, "Hi"), nil
+	}))
+	store, err := NewStore(next, WithTTL(time.Minute))
+	assert.NoError(t, err)
+	_, err = store.Detail("welcome_en")
+	assert.EqualError(t, err, "throttled")
+	detail, err := store.Detail("welcome_en")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi", detail.Subject)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestStoreDetail(t *testing.T) {
+	var detailCalls, existCalls atomic.Int32
+	next := mail.NewMockTemplateStore(
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			detailCalls.Add(1)
+			return newDetail(name, "Hi {{NAME}}"), nil
+		}),
+		mail.WithIsTemplateExist(func(name string) (bool, error) {
+			existCalls.Add(1)
+			return true, nil
+		}),
+	)
+	store, err := NewStore(next, WithTTL(time.Minute))
+	assert.NoError(t, err)
+
+	detail, err := store.Detail("welcome_en")
+	assert.NoError(t, err)
+	// rendering the returned detail does not change the cache
+	detail.Subject = "Hi Peter"
+	detail, err = store.Detail("welcome_en")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi {{NAME}}", detail.Subject)
+	assert.Equal(t, int32(1), detailCalls.Load())
+
+	// the content tells that the template exists
+	exist, err := store.IsTemplateExist("welcome_en")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	assert.Equal(t, int32(0), existCalls.Load())
+}
+
+func TestStoreStampede(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	next := mail.NewMockTemplateStore(mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+		calls.Add(1)
+		<-release
+		return newDetail(name, "Hi"), nil
+	}))
+	store, err := NewStore(next, WithTTL(time.Minute))
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			detail, err := store.Detail("welcome_en")
+			assert.NoError(t, err)
+			assert.Equal(t, "Hi", detail.Subject)
+		}()
+	}
+	// wait until the first reader is inside next, the others wait for it
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestStoreInvalidate(t *testing.T) {
+	subject := "Hi"
+	var calls atomic.Int32
+	next := mail.NewMockTemplateStore(
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			calls.Add(1)
+			return newDetail(name, subject), nil
+		}),
+		mail.WithUpdateTemplate(func(tpl *dao.Template) error {
+			subject = tpl.Subject
+			return nil
+		}),
+		mail.WithCreateTemplate(func(tpl *dao.Template) error { return nil }),
+		mail.WithDeleteTemplate(func(name string) error { return nil }),
+	)
+	store, err := NewStore(next, WithTTL(time.Minute))
+	assert.NoError(t, err)
+
+	_, _ = store.Detail("welcome_en")
+	assert.NoError(t, store.UpdateTemplate(dao.NewTemplate("welcome", "en", "Hello", "Hello", "")))
+	detail, err := store.Detail("welcome_en")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello", detail.Subject)
+	assert.Equal(t, int32(2), calls.Load())
+
+	assert.NoError(t, store.CreateTpl(dao.NewTemplate("welcome", "en", "Hello", "Hello", "")))
+	_, _ = store.Detail("welcome_en")
+	assert.Equal(t, int32(3), calls.Load())
+
+	assert.NoError(t, store.Delete("welcome_en"))
+	_, _ = store.Detail("welcome_en")
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestStoreWarm(t *testing.T) {
+	token := "page2"
+	var calls atomic.Int32
+	next := mail.NewMockTemplateStore(
+		mail.WithListTemplate(func(t string) (*dao.ListTemplateResponse, error) {
+			if t == "" {
+				return &dao.ListTemplateResponse{NextToken: &token, Templates: []*dao.ListTemplate{{Name: "welcome_en"}}}, nil
+			}
+			return &dao.ListTemplateResponse{Templates: []*dao.ListTemplate{{Name: "welcome_ja"}}}, nil
+		}),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			calls.Add(1)
+			return newDetail(name, "Hi"), nil
+		}),
+	)
+	store, err := NewStore(next, WithTTL(time.Minute))
+	assert.NoError(t, err)
+	n, err := store.Warm()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	for _, name := range []string{"welcome_en", "welcome_ja"} {
+		exist, err := store.IsTemplateExist(name)
+		assert.NoError(t, err)
+		assert.True(t, exist)
+		_, err = store.Detail(name)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,162 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+const (
+	CopyCreate    = "create"
+	CopyOverwrite = "overwrite"
+	CopySkip      = "skip"
+	CopyFailed    = "failed"
+)
+
+// CopyItem is what copying a template does, or would do in a dry run.
+type CopyItem struct {
+	Name   string `json:"name" yaml:"name"`
+	Action string `json:"action" yaml:"action"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+type copyOpt func(*copier)
+
+// WithCopyPrefix copies only the templates whose name starts with prefix.
+func WithCopyPrefix(prefix string) copyOpt {
+	return func(c *copier) {
+		c.prefix = prefix
+	}
+}
+
+// WithCopyOverwrite replaces the templates that exist in the target with different content,
+// otherwise they are skipped.
+func WithCopyOverwrite(overwrite bool) copyOpt {
+	return func(c *copier) {
+		c.overwrite = overwrite
+	}
+}
+
+// WithCopyDryRun plans the copy without writing to the target.
+func WithCopyDryRun(dryRun bool) copyOpt {
+	return func(c *copier) {
+		c.dryRun = dryRun
+	}
+}
+
+// WithCopyDraft hands the templates to create or overwrite to draft, grouped by event, instead
+// of writing them to the target, e.g. to draft them in the template workflow of the target.
+func WithCopyDraft(draft func(event string, tpls []*dao.Template) error) copyOpt {
+	return func(c *copier) {
+		c.draft = draft
+	}
+}
+
+type copier struct {
+	prefix    string
+	overwrite bool
+	dryRun    bool
+	draft     func(event string, tpls []*dao.Template) error
+	// drafts are the templates of draft with their items, by event in the order they are listed
+	drafts map[string][]*dao.Template
+	events []string
+	items  map[string][]*CopyItem
+}
+
+// CopyTemplates copies the templates of from into to, sorted as listed by from. A template that
+// can not be copied is reported as failed and the others are still copied.
+func CopyTemplates(from, to TemplateStore, opts ...copyOpt) ([]*CopyItem, error) {
+	if from == nil || to == nil {
+		return nil, errors.New("store is nil")
+	}
+	c := &copier{drafts: map[string][]*dao.Template{}, items: map[string][]*CopyItem{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	tpls, err := ListAll(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	items := []*CopyItem{}
+	for _, tpl := range tpls {
+		if !strings.HasPrefix(tpl.Name, c.prefix) {
+			continue
+		}
+		item := &CopyItem{Name: tpl.Name}
+		if err := c.copy(from, to, item); err != nil {
+			item.Action = CopyFailed
+			item.Reason = err.Error()
+		}
+		items = append(items, item)
+	}
+	c.flushDrafts()
+	return items, nil
+}
+
+func (c *copier) flushDrafts() {
+	for _, event := range c.events {
+		err := c.draft(event, c.drafts[event])
+		for _, item := range c.items[event] {
+			if err != nil {
+				item.Action = CopyFailed
+				item.Reason = err.Error()
+				continue
+			}
+			item.Reason = "drafted"
+		}
+	}
+}
+
+// write creates or updates tpl in the target, or keeps it for draft.
+func (c *copier) write(tpl *dao.Template, item *CopyItem, write func(*dao.Template) error) error {
+	if c.dryRun {
+		return nil
+	}
+	if c.draft == nil {
+		return write(tpl)
+	}
+	if _, ok := c.drafts[tpl.Event]; !ok {
+		c.events = append(c.events, tpl.Event)
+	}
+	c.drafts[tpl.Event] = append(c.drafts[tpl.Event], tpl)
+	c.items[tpl.Event] = append(c.items[tpl.Event], item)
+	return nil
+}
+
+func (c *copier) copy(from, to TemplateStore, item *CopyItem) error {
+	event, lang, err := service.ParseTemplateName(item.Name)
+	if err != nil {
+		return err
+	}
+	src, err := from.Detail(item.Name)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+	exist, err := to.IsTemplateExist(item.Name)
+	if err != nil {
+		return fmt.Errorf("failed to check template exist: %w", err)
+	}
+	tpl := dao.NewTemplate(event, lang, src.Subject, src.Body.Plaint, src.Body.Html)
+	if !exist {
+		item.Action = CopyCreate
+		return c.write(tpl, item, to.CreateTpl)
+	}
+
+	dst, err := to.Detail(item.Name)
+	if err != nil {
+		return fmt.Errorf("failed to read target template: %w", err)
+	}
+	if dst.Subject == src.Subject && dst.Body.Html == src.Body.Html && dst.Body.Plaint == src.Body.Plaint {
+		item.Action, item.Reason = CopySkip, "unchanged"
+		return nil
+	}
+	if !c.overwrite {
+		item.Action, item.Reason = CopySkip, "exists"
+		return nil
+	}
+	item.Action = CopyOverwrite
+	return c.write(tpl, item, to.UpdateTemplate)
+}
@@ -0,0 +1,155 @@
+package mail
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMemoryStore returns a store of the details keyed by name, writes are recorded in written.
+func newMemoryStore(details map[string]*dao.DetailTemplateResponse, written *[]string) TemplateStore {
+	save := func(tpl *dao.Template) error {
+		if tpl.Subject == "fail" {
+			return errors.New("write failed")
+		}
+		detail := &dao.DetailTemplateResponse{Title: tpl.GetName(), Subject: tpl.Subject}
+		detail.Body.Html, detail.Body.Plaint = tpl.Body.Html, tpl.Body.Plaint
+		details[tpl.GetName()] = detail
+		*written = append(*written, tpl.GetName())
+		return nil
+	}
+	return NewMockTemplateStore(
+		WithListTemplate(func(token string) (*dao.ListTemplateResponse, error) {
+			resp := &dao.ListTemplateResponse{}
+			for name := range details {
+				resp.Templates = append(resp.Templates, &dao.ListTemplate{Name: name})
+			}
+			sort.Slice(resp.Templates, func(i, j int) bool { return resp.Templates[i].Name < resp.Templates[j].Name })
+			return resp, nil
+		}),
+		WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			return details[name], nil
+		}),
+		WithIsTemplateExist(func(name string) (bool, error) {
+			_, ok := details[name]
+			return ok, nil
+		}),
+		WithCreateTemplate(save),
+		WithUpdateTemplate(save),
+	)
+}
+
+func newCopyDetail(name, subject string) *dao.DetailTemplateResponse {
+	detail := &dao.DetailTemplateResponse{Title: name, Subject: subject}
+	detail.Body.Html = "<p>" + subject + "</p>"
+	return detail
+}
+
+func TestCopyTemplates(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []copyOpt
+		wantItems   []*CopyItem
+		wantWritten []string
+	}{
+		{
+			name: "skip existing",
+			wantItems: []*CopyItem{
+				{Name: "invalid", Action: CopyFailed, Reason: "invalid template name: invalid"},
+				{Name: "order_en", Action: CopyFailed, Reason: "write failed"},
+				{Name: "welcome_en", Action: CopySkip, Reason: "unchanged"},
+				{Name: "welcome_ja", Action: CopySkip, Reason: "exists"},
+				{Name: "welcome_ko", Action: CopyCreate},
+			},
+			wantWritten: []string{"welcome_ko"},
+		},
+		{
+			name: "overwrite with prefix",
+			opts: []copyOpt{WithCopyOverwrite(true), WithCopyPrefix("welcome_")},
+			wantItems: []*CopyItem{
+				{Name: "welcome_en", Action: CopySkip, Reason: "unchanged"},
+				{Name: "welcome_ja", Action: CopyOverwrite},
+				{Name: "welcome_ko", Action: CopyCreate},
+			},
+			wantWritten: []string{"welcome_ja", "welcome_ko"},
+		},
+		{
+			name: "dry run",
+			opts: []copyOpt{WithCopyOverwrite(true), WithCopyPrefix("welcome_"), WithCopyDryRun(true)},
+			wantItems: []*CopyItem{
+				{Name: "welcome_en", Action: CopySkip, Reason: "unchanged"},
+				{Name: "welcome_ja", Action: CopyOverwrite},
+				{Name: "welcome_ko", Action: CopyCreate},
+			},
+			wantWritten: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromWritten, written []string
+			from := newMemoryStore(map[string]*dao.DetailTemplateResponse{
+				"invalid":    newCopyDetail("invalid", "Hi"),
+				"order_en":   newCopyDetail("order_en", "fail"),
+				"welcome_en": newCopyDetail("welcome_en", "Hi"),
+				"welcome_ja": newCopyDetail("welcome_ja", "こんにちは"),
+				"welcome_ko": newCopyDetail("welcome_ko", "안녕하세요"),
+			}, &fromWritten)
+			written = []string{}
+			to := newMemoryStore(map[string]*dao.DetailTemplateResponse{
+				"welcome_en": newCopyDetail("welcome_en", "Hi"),
+				"welcome_ja": newCopyDetail("welcome_ja", "old"),
+			}, &written)
+
+			items, err := CopyTemplates(from, to, tt.opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantItems, items)
+			assert.Equal(t, tt.wantWritten, written)
+			assert.Empty(t, fromWritten)
+		})
+	}
+}
+
+func TestCopyTemplatesDraft(t *testing.T) {
+	var fromWritten, written []string
+	from := newMemoryStore(map[string]*dao.DetailTemplateResponse{
+		"order_en":   newCopyDetail("order_en", "Order"),
+		"welcome_en": newCopyDetail("welcome_en", "Hi"),
+		"welcome_ja": newCopyDetail("welcome_ja", "こんにちは"),
+	}, &fromWritten)
+	written = []string{}
+	to := newMemoryStore(map[string]*dao.DetailTemplateResponse{
+		"welcome_en": newCopyDetail("welcome_en", "old"),
+	}, &written)
+
+	drafted := map[string][]string{}
+	items, err := CopyTemplates(from, to, WithCopyOverwrite(true), WithCopyDraft(func(event string, tpls []*dao.Template) error {
+		if event == "order" {
+			return errors.New("lint failed")
+		}
+		for _, tpl := range tpls {
+			drafted[event] = append(drafted[event], tpl.GetName())
+		}
+		return nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []*CopyItem{
+		{Name: "order_en", Action: CopyFailed, Reason: "lint failed"},
+		{Name: "welcome_en", Action: CopyOverwrite, Reason: "drafted"},
+		{Name: "welcome_ja", Action: CopyCreate, Reason: "drafted"},
+	}, items)
+	assert.Equal(t, map[string][]string{"welcome": {"welcome_en", "welcome_ja"}}, drafted)
+	assert.Empty(t, written)
+}
+
+func TestCopyTemplatesListError(t *testing.T) {
+	from := NewMockTemplateStore(WithListTemplate(func(token string) (*dao.ListTemplateResponse, error) {
+		return nil, errors.New("access denied")
+	}))
+	_, err := CopyTemplates(from, NewMockTemplateStore())
+	assert.EqualError(t, err, "failed to list templates: access denied")
+	_, err = CopyTemplates(nil, from)
+	assert.EqualError(t, err, "store is nil")
+}
@@ -0,0 +1,128 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
+)
+
+const (
+	markPresent = "✓"
+	markMissing = "✗"
+)
+
+// Matrix is the coverage of events by languages, built from template names.
+type Matrix struct {
+	Events []string
+	Langs  []string
+	// Invalid are the template names that are not <event>_<lang>
+	Invalid []string
+
+	required map[string]bool
+	// event -> normalized lang
+	cells map[string]map[string]bool
+}
+
+// NewMatrix parses the template names into events and languages. Required languages
+// are columns even when no template uses them.
+func NewMatrix(names []string, requiredLangs []string) *Matrix {
+	m := &Matrix{
+		Invalid:  []string{},
+		required: map[string]bool{},
+		cells:    map[string]map[string]bool{},
+	}
+	langs := map[string]string{}
+	addLang := func(l string) {
+		if _, ok := langs[lang.Normalize(l)]; !ok {
+			langs[lang.Normalize(l)] = l
+		}
+	}
+	for _, l := range requiredLangs {
+		m.required[lang.Normalize(l)] = true
+		addLang(l)
+	}
+	for _, name := range names {
+		event, l, err := service.ParseTemplateName(name)
+		if err != nil {
+			m.Invalid = append(m.Invalid, name)
+			continue
+		}
+		if m.cells[event] == nil {
+			m.cells[event] = map[string]bool{}
+			m.Events = append(m.Events, event)
+		}
+		m.cells[event][lang.Normalize(l)] = true
+		addLang(l)
+	}
+	for _, l := range langs {
+		m.Langs = append(m.Langs, l)
+	}
+	sort.Strings(m.Events)
+	sort.Strings(m.Langs)
+	sort.Strings(m.Invalid)
+	return m
+}
+
+func (m *Matrix) Has(event, l string) bool {
+	return m.cells[event][lang.Normalize(l)]
+}
+
+func (m *Matrix) IsRequired(l string) bool {
+	return m.required[lang.Normalize(l)]
+}
+
+// Missing returns the names of the templates missing from the matrix, sorted by event and lang.
+func (m *Matrix) Missing() []string {
+	missing := []string{}
+	for _, event := range m.Events {
+		for _, l := range m.Langs {
+			if !m.Has(event, l) {
+				missing = append(missing, service.GetTemplateName(event, l))
+			}
+		}
+	}
+	return missing
+}
+
+// MissingRequired returns the names of the templates of required languages missing from the matrix.
+func (m *Matrix) MissingRequired() []string {
+	missing := []string{}
+	for _, event := range m.Events {
+		for _, l := range m.Langs {
+			if m.IsRequired(l) && !m.Has(event, l) {
+				missing = append(missing, service.GetTemplateName(event, l))
+			}
+		}
+	}
+	return missing
+}
+
+// Write prints the matrix as a table, required languages are suffixed with *.
+func (m *Matrix) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := []string{"EVENT"}
+	for _, l := range m.Langs {
+		if m.IsRequired(l) {
+			l += "*"
+		}
+		header = append(header, l)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, event := range m.Events {
+		row := []string{event}
+		for _, l := range m.Langs {
+			mark := markMissing
+			if m.Has(event, l) {
+				mark = markPresent
+			}
+			row = append(row, mark)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
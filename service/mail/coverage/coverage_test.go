@@ -0,0 +1,32 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatrix(t *testing.T) {
+	names := []string{"EVENT_JOIN_DENIED_en", "EVENT_JOIN_DENIED_zh-TW", "EVENT_JOINED_en", "EVENT_JOINED_zh-tw", "WELCOME_ja", "invalid"}
+
+	m := NewMatrix(names, nil)
+	assert.Equal(t, []string{"EVENT_JOINED", "EVENT_JOIN_DENIED", "WELCOME"}, m.Events)
+	assert.Equal(t, []string{"en", "ja", "zh-TW"}, m.Langs)
+	assert.Equal(t, []string{"invalid"}, m.Invalid)
+	assert.True(t, m.Has("EVENT_JOINED", "zh-TW"))
+	assert.Equal(t, []string{"EVENT_JOINED_ja", "EVENT_JOIN_DENIED_ja", "WELCOME_en", "WELCOME_zh-TW"}, m.Missing())
+	assert.Empty(t, m.MissingRequired())
+
+	m = NewMatrix(names, []string{"en", "ko"})
+	assert.Equal(t, []string{"en", "ja", "ko", "zh-TW"}, m.Langs)
+	assert.Equal(t, []string{"EVENT_JOINED_ko", "EVENT_JOIN_DENIED_ko", "WELCOME_en", "WELCOME_ko"}, m.MissingRequired())
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.Write(&buf))
+	assert.Equal(t, `EVENT              en*  ja  ko*  zh-TW
+EVENT_JOINED       ✓    ✗   ✗    ✓
+EVENT_JOIN_DENIED  ✓    ✗   ✗    ✓
+WELCOME            ✗    ✓   ✗    ✗
+`, buf.String())
+}
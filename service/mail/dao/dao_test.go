@@ -2,6 +2,9 @@ package dao
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 func TestApplyTemplateInputValidate(t *testing.T) {
@@ -83,3 +86,168 @@ func TestApplyTemplateInputValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePlaceholders(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  []string
+	}{
+		{
+			name:  "no placeholder",
+			texts: []string{"hello"},
+			want:  []string{},
+		},
+		{
+			name:  "placeholders are upper-cased and unique",
+			texts: []string{"{{event_name}} {{ CREATOR_NAME }}", "<a href=\"https://{{X-FORWARDED-HOST}}\">{{EVENT_NAME}}</a>"},
+			want:  []string{"EVENT_NAME", "CREATOR_NAME", "X-FORWARDED-HOST"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParsePlaceholders(tt.texts...))
+		})
+	}
+}
+
+func TestTemplateInferVariables(t *testing.T) {
+	tpl := NewTemplate("event", "en", "Hi {{NAME}}", "{{NAME}} joined {{EVENT_NAME}}", "<p>{{HOST}}</p>")
+	tpl.Variables = Variables{{Name: "host", Default: "oosa.life"}}
+	tpl.InferVariables()
+
+	assert.Len(t, tpl.Variables, 3)
+	assert.False(t, tpl.Variables.Get("HOST").Required)
+	assert.True(t, tpl.Variables.Get("NAME").Required)
+	assert.True(t, tpl.Variables.Get("EVENT_NAME").Required)
+}
+
+func TestVariablesValidate(t *testing.T) {
+	assert.NoError(t, Variables{{Name: "A"}, {Name: "B"}}.Validate())
+	assert.ErrorContains(t, Variables{{Name: ""}}.Validate(), "variable name is required")
+	assert.ErrorContains(t, Variables{{Name: "a"}, {Name: "A"}}.Validate(), "duplicate variable: A")
+}
+
+func TestVariablesMissing(t *testing.T) {
+	vars := Variables{
+		{Name: "EVENT_NAME", Required: true},
+		{Name: "CREATOR_NAME", Required: true},
+		{Name: "HOST", Required: true, Default: "oosa.life"},
+		{Name: "NOTE"},
+		{Name: "TO", Required: true},
+	}
+	assert.Equal(t, []string{"CREATOR_NAME", "EVENT_NAME"}, vars.Missing(map[string]string{}, "to"))
+	assert.Equal(t, []string{"CREATOR_NAME"}, vars.Missing(map[string]string{"event_name": "x"}, "TO"))
+	assert.Equal(t, []string{"TO"}, vars.Missing(map[string]string{"event_name": "x", "creator_name": "y"}))
+}
+
+func TestVariablesApplyDefaults(t *testing.T) {
+	vars := Variables{
+		{Name: "HOST", Default: "oosa.life"},
+		{Name: "NAME", Default: "guest"},
+		{Name: "NOTE"},
+	}
+	data := map[string]string{"name": "john"}
+	vars.ApplyDefaults(data)
+	assert.Equal(t, map[string]string{"name": "john", "HOST": "oosa.life"}, data)
+}
+
+func TestVariablesMerge(t *testing.T) {
+	en := Variables{{Name: "NAME", Required: false, Default: "guest"}}
+	zh := Variables{{Name: "NAME", Required: true, Description: "user name"}, {Name: "HOST"}}
+	merged := en.Merge(zh)
+
+	assert.Len(t, merged, 2)
+	assert.Equal(t, &Variable{Name: "NAME", Required: true, Default: "guest", Description: "user name"}, merged.Get("name"))
+	assert.False(t, en[0].Required)
+}
+
+const multiLangYaml = `
+event: EVENT_JOIN_DENIED
+layout: default
+subject: "{{CREATOR_NAME}} replied"
+variables:
+- name: HOST
+  default: oosa.life
+sample:
+  CREATOR_NAME: Peter
+  EVENT_NAME: Hiking
+body:
+  plaint: "{{CREATOR_NAME}} denied your request to join {{EVENT_NAME}}"
+locales:
+  en: {}
+  zh-TW:
+    subject: "{{CREATOR_NAME}} 回覆了"
+    layout: zh
+    body:
+      plaint: "{{CREATOR_NAME}}婉拒您加入 {{EVENT_NAME}}活動"
+    variables:
+    - name: HOST
+      default: oosa.tw
+    sample:
+      EVENT_NAME: 健行
+`
+
+func TestApplyTemplateInputExpand(t *testing.T) {
+	var input ApplyTemplateInput
+	assert.NoError(t, yaml.Unmarshal([]byte(multiLangYaml), &input))
+	assert.NoError(t, input.Validate())
+
+	tpls := input.Expand()
+	assert.Len(t, tpls, 2)
+
+	en, zh := tpls[0], tpls[1]
+	assert.Equal(t, "EVENT_JOIN_DENIED_en", en.GetName())
+	assert.Equal(t, "default", en.Layout)
+	assert.Equal(t, "{{CREATOR_NAME}} replied", en.Subject)
+	assert.Equal(t, "{{CREATOR_NAME}} denied your request to join {{EVENT_NAME}}", en.Body.Plaint)
+	assert.Equal(t, "oosa.life", en.Variables.Get("HOST").Default)
+	assert.Equal(t, "Hiking", en.Sample["EVENT_NAME"])
+
+	assert.Equal(t, "EVENT_JOIN_DENIED_zh-TW", zh.GetName())
+	assert.Equal(t, "zh", zh.Layout)
+	assert.Equal(t, "{{CREATOR_NAME}} 回覆了", zh.Subject)
+	assert.Equal(t, "{{CREATOR_NAME}}婉拒您加入 {{EVENT_NAME}}活動", zh.Body.Plaint)
+	assert.Equal(t, "oosa.tw", zh.Variables.Get("HOST").Default)
+	assert.Equal(t, map[string]string{"CREATOR_NAME": "Peter", "EVENT_NAME": "健行"}, zh.Sample)
+
+	// shared values are not changed by the locales
+	assert.Equal(t, "default", input.Layout)
+	assert.Equal(t, "Hiking", input.Sample["EVENT_NAME"])
+
+	assert.Equal(t, []string{"ja"}, input.MissingLangs([]string{"en", "zh-tw", "ja"}))
+
+	single := ApplyTemplateInput{Template: *NewTemplate("event", "en", "subject", "plaint", "")}
+	assert.Equal(t, []*Template{&single.Template}, single.Expand())
+}
+
+func TestApplyTemplateInputExpandBody(t *testing.T) {
+	input := ApplyTemplateInput{Template: *NewTemplate("event", "", "subject", "shared plaint", "<p>shared</p>")}
+	input.Locales = map[string]*Locale{"en": {}, "ja": {}, "zh-TW": {}}
+	input.Locales["ja"].Body.Plaint = "ja plaint"
+	input.Locales["zh-TW"].Body.Html = "<p>zh</p>"
+
+	tpls := input.Expand()
+	assert.Len(t, tpls, 3)
+	// a locale overrides only the parts of the body it sets
+	assert.Equal(t, "shared plaint", tpls[0].Body.Plaint)
+	assert.Equal(t, "<p>shared</p>", tpls[0].Body.Html)
+	assert.Equal(t, "ja plaint", tpls[1].Body.Plaint)
+	assert.Equal(t, "<p>shared</p>", tpls[1].Body.Html)
+	assert.Equal(t, "shared plaint", tpls[2].Body.Plaint)
+	assert.Equal(t, "<p>zh</p>", tpls[2].Body.Html)
+}
+
+func TestApplyTemplateInputValidateLocales(t *testing.T) {
+	input := ApplyTemplateInput{
+		Template: *NewTemplate("event", "en", "subject", "plaint", ""),
+		Locales:  map[string]*Locale{"ja": {}},
+	}
+	assert.EqualError(t, input.Validate(), "lang and locales can not be used together")
+
+	input.Lang = ""
+	input.Subject = ""
+	input.Locales["en"] = &Locale{tplContent: tplContent{Subject: "subject"}}
+	assert.EqualError(t, input.Validate(), "locale ja: subject is required")
+}
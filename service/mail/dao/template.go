@@ -2,9 +2,11 @@ package dao
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
 )
 
 type tplContent struct {
@@ -24,38 +26,155 @@ func NewTemplate(event, lang, subject, bodyPlaint, bodyHtml string) *Template {
 }
 
 type Template struct {
-	Event      string
-	Lang       string
+	Event string
+	Lang  string
+	// Layout is the name of the layout wrapping the body, see package layout.
+	Layout     string `yaml:",omitempty"`
 	tplContent `yaml:",inline"`
+	Variables  Variables    `yaml:",omitempty"`
+	Options    ApplyOptions `yaml:",omitempty"`
+	// Sample is the data previewTpl and mail send render the template with.
+	Sample map[string]string `yaml:",omitempty"`
 }
 
 func (t *Template) GetName() string {
 	return service.GetTemplateName(t.Event, t.Lang)
 }
 
+// InferVariables adds every placeholder of subject and body that is not declared
+// in Variables as a required variable.
+func (t *Template) InferVariables() {
+	for _, name := range ParsePlaceholders(t.Subject, t.Body.Plaint, t.Body.Html) {
+		if t.Variables.Get(name) != nil {
+			continue
+		}
+		t.Variables = append(t.Variables, &Variable{Name: name, Required: true})
+	}
+}
+
+type ApplyOptions struct {
+	// GeneratePlaint derives body.plaint from body.html when body.plaint is omitted.
+	GeneratePlaint bool `yaml:"generate_plaint,omitempty"`
+	// InlineCss moves <style> rules of body.html into style attributes.
+	InlineCss bool `yaml:"inline_css,omitempty"`
+}
+
+// Locale is the content of one language of a multi-language template file.
+// Empty fields fall back to the shared ones of the file.
+type Locale struct {
+	Layout     string `yaml:",omitempty"`
+	tplContent `yaml:",inline"`
+	Variables  Variables         `yaml:",omitempty"`
+	Sample     map[string]string `yaml:",omitempty"`
+}
+
+// ApplyTemplateInput is a template file. It holds either one lang, or a locales map of lang
+// to content where the fields of the file are the defaults shared by every locale.
 type ApplyTemplateInput struct {
 	Template `yaml:",inline"`
+	Locales  map[string]*Locale `yaml:",omitempty"`
 }
 
-func (a *ApplyTemplateInput) Validate() error {
-	if a.Event == "" {
+func (t *Template) Validate() error {
+	if t.Event == "" {
 		return fmt.Errorf("event is required")
 	}
 
-	if a.Lang == "" {
+	if t.Lang == "" {
 		return fmt.Errorf("lang is required")
 	}
 
-	if a.Subject == "" {
+	if t.Subject == "" {
 		return fmt.Errorf("subject is required")
 	}
 
-	if a.Body.Plaint == "" && a.Body.Html == "" {
+	if t.Body.Plaint == "" && t.Body.Html == "" {
 		return fmt.Errorf("body.plaint or body.html is required")
 	}
+
+	if err := t.Variables.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *ApplyTemplateInput) Validate() error {
+	if len(a.Locales) == 0 {
+		return a.Template.Validate()
+	}
+	if a.Lang != "" {
+		return fmt.Errorf("lang and locales can not be used together")
+	}
+	for _, tpl := range a.Expand() {
+		if err := tpl.Validate(); err != nil {
+			return fmt.Errorf("locale %s: %w", tpl.Lang, err)
+		}
+	}
 	return nil
 }
 
+// Expand returns one template per language, sorted by lang.
+func (a *ApplyTemplateInput) Expand() []*Template {
+	if len(a.Locales) == 0 {
+		return []*Template{&a.Template}
+	}
+	langs := make([]string, 0, len(a.Locales))
+	for l := range a.Locales {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+
+	result := make([]*Template, len(langs))
+	for i, l := range langs {
+		tpl := a.Template
+		tpl.Lang = l
+		if locale := a.Locales[l]; locale != nil {
+			if locale.Layout != "" {
+				tpl.Layout = locale.Layout
+			}
+			if locale.Subject != "" {
+				tpl.Subject = locale.Subject
+			}
+			if locale.Body.Plaint != "" {
+				tpl.Body.Plaint = locale.Body.Plaint
+			}
+			if locale.Body.Html != "" {
+				tpl.Body.Html = locale.Body.Html
+			}
+			if len(locale.Variables) > 0 {
+				tpl.Variables = locale.Variables.Merge(a.Variables)
+			}
+			if len(locale.Sample) > 0 {
+				sample := map[string]string{}
+				for k, v := range a.Sample {
+					sample[k] = v
+				}
+				for k, v := range locale.Sample {
+					sample[k] = v
+				}
+				tpl.Sample = sample
+			}
+		}
+		result[i] = &tpl
+	}
+	return result
+}
+
+// MissingLangs returns the languages of required that the file does not provide.
+func (a *ApplyTemplateInput) MissingLangs(required []string) []string {
+	provided := map[string]bool{}
+	for _, tpl := range a.Expand() {
+		provided[lang.Normalize(tpl.Lang)] = true
+	}
+	missing := []string{}
+	for _, l := range required {
+		if !provided[lang.Normalize(l)] {
+			missing = append(missing, l)
+		}
+	}
+	return missing
+}
+
 type ListTemplate struct {
 	Name       string
 	CreateTime time.Time
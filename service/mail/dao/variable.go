@@ -0,0 +1,125 @@
+package dao
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var placeholderRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_\-\.]+)\s*\}\}`)
+
+// ParsePlaceholders returns the upper-cased {{NAME}} placeholders found in the given texts,
+// in order of first appearance.
+func ParsePlaceholders(texts ...string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, text := range texts {
+		for _, match := range placeholderRegexp.FindAllStringSubmatch(text, -1) {
+			name := strings.ToUpper(match[1])
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+type Variable struct {
+	Name        string
+	Required    bool
+	Default     string
+	Description string
+}
+
+type Variables []*Variable
+
+func (vs Variables) Validate() error {
+	seen := map[string]bool{}
+	for _, v := range vs {
+		if v == nil || v.Name == "" {
+			return fmt.Errorf("variable name is required")
+		}
+		name := strings.ToUpper(v.Name)
+		if seen[name] {
+			return fmt.Errorf("duplicate variable: %s", v.Name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func (vs Variables) Get(name string) *Variable {
+	name = strings.ToUpper(name)
+	for _, v := range vs {
+		if strings.ToUpper(v.Name) == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Merge combines variables of several templates of the same event.
+// A variable is required if any template requires it.
+func (vs Variables) Merge(others Variables) Variables {
+	result := make(Variables, 0, len(vs)+len(others))
+	for _, v := range vs {
+		copied := *v
+		result = append(result, &copied)
+	}
+	for _, o := range others {
+		exist := result.Get(o.Name)
+		if exist == nil {
+			copied := *o
+			result = append(result, &copied)
+			continue
+		}
+		exist.Required = exist.Required || o.Required
+		if exist.Default == "" {
+			exist.Default = o.Default
+		}
+		if exist.Description == "" {
+			exist.Description = o.Description
+		}
+	}
+	return result
+}
+
+// Missing returns the sorted names of required variables without a default
+// that are neither in data nor in provided keys. Keys are compared case-insensitively.
+func (vs Variables) Missing(data map[string]string, provided ...string) []string {
+	keys := map[string]bool{}
+	for k := range data {
+		keys[strings.ToUpper(k)] = true
+	}
+	for _, k := range provided {
+		keys[strings.ToUpper(k)] = true
+	}
+	missing := []string{}
+	for _, v := range vs {
+		if !v.Required || v.Default != "" {
+			continue
+		}
+		if !keys[strings.ToUpper(v.Name)] {
+			missing = append(missing, strings.ToUpper(v.Name))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// ApplyDefaults sets the default value of every variable that is absent from data.
+func (vs Variables) ApplyDefaults(data map[string]string) {
+	keys := map[string]bool{}
+	for k := range data {
+		keys[strings.ToUpper(k)] = true
+	}
+	for _, v := range vs {
+		if v.Default == "" || keys[strings.ToUpper(v.Name)] {
+			continue
+		}
+		data[strings.ToUpper(v.Name)] = v.Default
+	}
+}
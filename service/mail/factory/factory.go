@@ -3,14 +3,24 @@ package factory
 import (
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/arwoosa/notifaction/service/mail"
 	"github.com/arwoosa/notifaction/service/mail/aws"
+	"github.com/arwoosa/notifaction/service/mail/cache"
 	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/layout"
+	"github.com/arwoosa/notifaction/service/mail/lint"
+	"github.com/arwoosa/notifaction/service/mail/local"
+	"github.com/arwoosa/notifaction/service/mail/schema"
 	"github.com/arwoosa/notifaction/service/mail/smtp"
+	"github.com/arwoosa/notifaction/service/mail/transform"
+	"github.com/arwoosa/notifaction/service/mail/workflow"
+	"github.com/arwoosa/notifaction/service/mongodb"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
 )
@@ -22,34 +32,62 @@ func NewApiSender() (mail.ApiSender, error) {
 	provider := viper.GetString("mail.provider")
 	switch provider {
 	case "aws":
-		return aws.NewApiSender()
-	case "smtp":
-		url := viper.GetString("smtp.url")
-		if url == "" {
-			return nil, errors.New("smtp.url is empty")
-		}
-		from := viper.GetString("mail.from")
-		if from == "" {
-			return nil, errors.New("mail.from is empty")
+		// SES sends the templates stored in SES, so the shared store is only used when it is the SES one
+		if viper.GetString("mail.template.source") != "aws" {
+			return aws.NewApiSender()
 		}
-		tpl, err := NewTemplate()
+		store, err := newTemplateStore(nil)
 		if err != nil {
 			return nil, err
 		}
-		sendCloser, err := smtp.ParseUrl(url)
+		return aws.NewApiSender(aws.WithTemplateStore(store))
+	case "smtp":
+		tpl, err := NewTemplate()
 		if err != nil {
 			return nil, err
 		}
-		return smtp.NewApiSender(
-			smtp.WithSendCloser(sendCloser),
-			smtp.WithFrom(from),
-			smtp.WithTemplate(tpl),
-		)
+		return newSmtpSender(tpl)
+	default:
+		return nil, errors.New("invalid mail provider")
+	}
+}
+
+// NewContentSender returns a sender of the templates of tpls, e.g. those of a template file,
+// instead of the stored ones.
+func NewContentSender(tpls mail.TemplateReader) (mail.ApiSender, error) {
+	if mockSendor != nil {
+		return newMockSender()
+	}
+	switch viper.GetString("mail.provider") {
+	case "aws":
+		return aws.NewApiSender(aws.WithContent(tpls))
+	case "smtp":
+		return newSmtpSender(tpls)
 	default:
 		return nil, errors.New("invalid mail provider")
 	}
 }
 
+func newSmtpSender(tpl mail.TemplateReader) (mail.ApiSender, error) {
+	url := viper.GetString("smtp.url")
+	if url == "" {
+		return nil, errors.New("smtp.url is empty")
+	}
+	from := viper.GetString("mail.from")
+	if from == "" {
+		return nil, errors.New("mail.from is empty")
+	}
+	sendCloser, err := smtp.ParseUrl(url)
+	if err != nil {
+		return nil, err
+	}
+	return smtp.NewApiSender(
+		smtp.WithSendCloser(sendCloser),
+		smtp.WithFrom(from),
+		smtp.WithTemplate(tpl),
+	)
+}
+
 type factoryOpt func(*tplImpl)
 
 func WithAllowedDirs(dirs ...string) factoryOpt {
@@ -66,11 +104,19 @@ func WithAllowedDirs(dirs ...string) factoryOpt {
 	}
 }
 
+func WithRequiredLangs(langs ...string) factoryOpt {
+	return func(a *tplImpl) {
+		a.requiredLangs = langs
+	}
+}
+
 func NewTemplate(opts ...factoryOpt) (mail.Template, error) {
 	if mockTemplate != nil {
 		return newMockTemplate()
 	}
-	tplImpl := &tplImpl{}
+	tplImpl := &tplImpl{
+		requiredLangs: viper.GetStringSlice("mail.lang.required"),
+	}
 	for _, opt := range opts {
 		opt(tplImpl)
 	}
@@ -83,26 +129,173 @@ func NewTemplate(opts ...factoryOpt) (mail.Template, error) {
 		tplImpl.allowedDirs = []string{homeDir}
 	}
 
-	provider := viper.GetString("mail.template.source")
-	var store mail.TemplateStore
-	var err error
-	if provider == "aws" {
-		store, err = aws.NewTemplateStore()
-	}
+	layouts, err := NewLayouts()
 	if err != nil {
 		return nil, err
 	}
-	if store == nil {
-		return nil, errors.New("invalid mail provider")
+	tplImpl.layouts = layouts
+
+	store, err := newTemplateStore(layouts)
+	if err != nil {
+		return nil, err
 	}
 	tplImpl.store = store
+	// the local store resolves layouts when the template is read, so it keeps them unresolved
+	tplImpl.storeRaw = viper.GetString("mail.template.source") == "local"
+
+	varStore, err := NewVariableStore()
+	if err != nil {
+		return nil, err
+	}
+	tplImpl.varStore = varStore
 
 	return tplImpl, nil
 }
 
+var (
+	cacheMu       sync.Mutex
+	templateCache *cache.Store
+)
+
+// ResetTemplateCache drops the cached template store, so that the next store reads the configuration again.
+func ResetTemplateCache() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	templateCache = nil
+}
+
+// newTemplateStore returns the store of mail.template.source. When mail.template.cache.ttl is set
+// the store is cached, and the same cache is shared by every template and sender of the process so
+// that applying or deleting a template invalidates it for the senders too.
+func newTemplateStore(layouts *layout.Set) (mail.TemplateStore, error) {
+	ttl := viper.GetDuration("mail.template.cache.ttl")
+	if ttl <= 0 {
+		return newSourceStore(layouts)
+	}
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if templateCache != nil {
+		return templateCache, nil
+	}
+	store, err := newSourceStore(layouts)
+	if err != nil {
+		return nil, err
+	}
+	templateCache, err = cache.NewStore(store,
+		cache.WithTTL(ttl),
+		cache.WithNegativeTTL(viper.GetDuration("mail.template.cache.negative_ttl")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return templateCache, nil
+}
+
+func newSourceStore(layouts *layout.Set) (mail.TemplateStore, error) {
+	switch viper.GetString("mail.template.source") {
+	case "aws":
+		return aws.NewTemplateStore()
+	case "local":
+		if layouts == nil {
+			var err error
+			if layouts, err = NewLayouts(); err != nil {
+				return nil, err
+			}
+		}
+		return local.NewTemplateStore(viper.GetString("mail.template.dir"), local.WithLayouts(layouts))
+	default:
+		return nil, errors.New("invalid mail provider")
+	}
+}
+
+// NewAwsTemplateStore returns the SES store of a profile of the aws.ses credentials file in a region,
+// empty values use the ones of the aws.ses config block. It is not cached.
+func NewAwsTemplateStore(profile, region string) (mail.TemplateStore, error) {
+	cfg := aws.ConfigFromViper()
+	if profile != "" {
+		cfg.Profile = profile
+	}
+	if region != "" {
+		cfg.Region = region
+	}
+	return aws.NewTemplateStore(aws.WithSessionConfig(cfg))
+}
+
+// WarmTemplateCache loads every template into the cache of mail.template.cache.ttl,
+// returning the number of templates loaded. It does nothing when the cache is disabled.
+func WarmTemplateCache() (int, error) {
+	store, err := newTemplateStore(nil)
+	if err != nil {
+		return 0, err
+	}
+	cached, ok := store.(*cache.Store)
+	if !ok {
+		return 0, nil
+	}
+	return cached.Warm()
+}
+
+// NewTemplateStore returns the store of mail.template.source, see newTemplateStore.
+func NewTemplateStore() (mail.TemplateStore, error) {
+	return newTemplateStore(nil)
+}
+
+// NewVariableStore returns the store of template variables of mail.template.variables.mongo, or of
+// mail.template.variables.dir for templates of the local source. It returns nil without error when
+// neither is configured.
+func NewVariableStore() (mail.VariableStore, error) {
+	if mockVariableStore != nil {
+		return newMockVariableStore()
+	}
+	if viper.GetString("mail.template.variables.mongo.uri") != "" {
+		coll, err := mongodb.Collection("mail.template.variables", "template_variables")
+		if err != nil {
+			return nil, err
+		}
+		return schema.NewMongoStore(coll), nil
+	}
+	dir := viper.GetString("mail.template.variables.dir")
+	if dir == "" {
+		return nil, nil
+	}
+	return schema.NewFileStore(dir)
+}
+
+// NewWorkflow returns the draft/publish workflow of tpl, its changes are stored in mail.template.workflow.mongo.
+// It returns nil without error when no mongo uri is configured, templates are then published when applied.
+func NewWorkflow(tpl mail.Template) (*workflow.Workflow, error) {
+	store := mockWorkflowStore
+	if store == nil {
+		if viper.GetString("mail.template.workflow.mongo.uri") == "" {
+			return nil, nil
+		}
+		coll, err := mongodb.Collection("mail.template.workflow", "template_changes")
+		if err != nil {
+			return nil, err
+		}
+		store = workflow.NewMongoStore(coll)
+	}
+	return workflow.New(tpl, store, workflow.WithApprovers(viper.GetStringSlice("mail.template.workflow.approvers")...))
+}
+
+// NewLayouts loads the layouts and partials of mail.template.layouts.dir.
+// It returns nil without error when no directory is configured.
+func NewLayouts() (*layout.Set, error) {
+	dir := viper.GetString("mail.template.layouts.dir")
+	if dir == "" {
+		return nil, nil
+	}
+	return layout.Load(dir)
+}
+
 type tplImpl struct {
-	store       mail.TemplateStore
-	allowedDirs []string
+	store    mail.TemplateStore
+	varStore mail.VariableStore
+	layouts  *layout.Set
+	storeRaw bool
+	// requiredLangs are the languages every multi-language template file must provide
+	requiredLangs []string
+	allowedDirs   []string
 }
 
 func (a *tplImpl) isFileAllowed(file string) bool {
@@ -117,48 +310,168 @@ func (a *tplImpl) isFileAllowed(file string) bool {
 	return false
 }
 
-func (a *tplImpl) Apply(file string) error {
-	absFile, err := filepath.Abs(file)
+// ListTemplateFiles returns the YAML files under dir, walking sub directories.
+func ListTemplateFiles(dir string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml":
+			files = append(files, path)
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to list template files in %s: %w", dir, err)
 	}
-	if !a.isFileAllowed(absFile) {
-		return errors.New("file is not in allowed dir: " + strings.Join(a.allowedDirs, ", "))
+	return files, nil
+}
+
+// ReadTemplateFile reads and unmarshals a template YAML file without validating it.
+func ReadTemplateFile(file string) (*dao.ApplyTemplateInput, error) {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
 	}
 	// check file exist
 	if _, err := os.Stat(absFile); err != nil {
-		return fmt.Errorf("file %s does not exist", file)
+		return nil, fmt.Errorf("file %s does not exist", file)
 	}
 
 	// read file
 	data, err := os.ReadFile(filepath.Clean(absFile))
 	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", absFile, err)
+		return nil, fmt.Errorf("failed to read file %s: %w", absFile, err)
 	}
 	// yaml unmarshal
 	var tplDao dao.ApplyTemplateInput
 	if err := yaml.Unmarshal(data, &tplDao); err != nil {
-		return fmt.Errorf("failed to unmarshal yaml: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+	}
+	return &tplDao, nil
+}
+
+// BuildTemplates validates, builds and lints every language of input with the configured layouts
+// without storing them.
+func BuildTemplates(input *dao.ApplyTemplateInput) (*mail.TemplateSet, error) {
+	layouts, err := NewLayouts()
+	if err != nil {
+		return nil, err
+	}
+	tpls, err := buildTemplates(input, layouts, nil)
+	if err != nil {
+		return nil, err
+	}
+	built := make([]*dao.Template, len(tpls))
+	for i, tpl := range tpls {
+		built[i] = tpl.built
 	}
+	return mail.NewTemplateSet(built...), nil
+}
+
+type builtTemplate struct {
+	raw   *dao.Template
+	built *dao.Template
+}
 
-	// validate template dao
-	if err := tplDao.Validate(); err != nil {
+// buildTemplates validates, builds and lints every language of input, so that none is stored when one fails.
+func buildTemplates(input *dao.ApplyTemplateInput, layouts *layout.Set, requiredLangs []string) ([]*builtTemplate, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if len(input.Locales) > 0 && len(requiredLangs) > 0 {
+		if missing := input.MissingLangs(requiredLangs); len(missing) > 0 {
+			return nil, fmt.Errorf("template %s is missing required languages: %s", input.Event, strings.Join(missing, ", "))
+		}
+	}
+	tpls := []*builtTemplate{}
+	for _, tpl := range input.Expand() {
+		built, err := transform.Build(tpl, layouts)
+		if err != nil {
+			return nil, err
+		}
+		built.InferVariables()
+		tpl.Variables = built.Variables
+
+		findings := lint.Lint(built)
+		if findings.HasError() {
+			return nil, fmt.Errorf("template %s has lint errors: %w", tpl.GetName(), findings.Errors())
+		}
+		for _, f := range findings.Warnings() {
+			log.Printf("template %s: %s", tpl.GetName(), f)
+		}
+		tpls = append(tpls, &builtTemplate{raw: tpl, built: built})
+	}
+	return tpls, nil
+}
+
+func (a *tplImpl) Apply(file string) error {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if !a.isFileAllowed(absFile) {
+		return errors.New("file is not in allowed dir: " + strings.Join(a.allowedDirs, ", "))
+	}
+	tplDao, err := ReadTemplateFile(file)
+	if err != nil {
 		return err
 	}
+	return a.ApplyTemplate(tplDao)
+}
+
+func (a *tplImpl) ApplyTemplate(tplDao *dao.ApplyTemplateInput) error {
+	tpls, err := buildTemplates(tplDao, a.layouts, a.requiredLangs)
+	if err != nil {
+		return err
+	}
+	for _, tpl := range tpls {
+		if err := a.save(tpl.raw, tpl.built); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *tplImpl) SaveBuilt(tpl *dao.Template) error {
+	return a.save(tpl, tpl)
+}
+
+func (a *tplImpl) save(raw, built *dao.Template) error {
+	stored := built
+	if a.storeRaw {
+		stored = raw
+	}
 
 	// check template exist
-	name := tplDao.GetName()
+	name := built.GetName()
 	exist, err := a.store.IsTemplateExist(name)
 	if err != nil {
 		return fmt.Errorf("failed to check template exist: %w", err)
 	}
 	if exist {
 		// update template
-		return a.store.UpdateTemplate(&tplDao.Template)
+		err = a.store.UpdateTemplate(stored)
+	} else {
+		// aws create template
+		err = a.store.CreateTpl(stored)
+	}
+	if err != nil {
+		return err
 	}
 
-	// aws create template
-	return a.store.CreateTpl(&tplDao.Template)
+	if a.varStore == nil {
+		return nil
+	}
+	if err := a.varStore.Save(built); err != nil {
+		return fmt.Errorf("failed to save template variables: %w", err)
+	}
+	return nil
 }
 
 func (a *tplImpl) Delete(name string) error {
@@ -170,7 +483,17 @@ func (a *tplImpl) Delete(name string) error {
 		return errors.New("template does not exist")
 	}
 
-	return a.store.Delete(name)
+	if err := a.store.Delete(name); err != nil {
+		return err
+	}
+	if a.varStore == nil {
+		return nil
+	}
+	return a.varStore.Delete(name)
+}
+
+func (a *tplImpl) IsTemplateExist(name string) (bool, error) {
+	return a.store.IsTemplateExist(name)
 }
 
 func (a *tplImpl) List(nextToken string) (*dao.ListTemplateResponse, error) {
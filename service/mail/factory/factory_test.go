@@ -9,6 +9,8 @@ import (
 
 	"github.com/arwoosa/notifaction/service/mail"
 	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/layout"
+	"github.com/arwoosa/notifaction/service/mail/schema"
 	"github.com/spf13/viper"
 	"golang.org/x/exp/slices"
 )
@@ -111,6 +113,24 @@ func TestNewTemplate(t *testing.T) {
 			},
 			wantDirs: []string{projectPath + "/service/mail"},
 		},
+		{
+			name: "local provider",
+			preFunc: func() {
+				viper.Set("mail.template.source", "local")
+				viper.Set("mail.template.dir", os.TempDir()+"/notifaction_test_templates")
+				viper.Set("mail.template.layouts.dir", "./test_layouts")
+			},
+			wantDirs: []string{wantDir},
+		},
+		{
+			name: "layouts dir does not exist",
+			preFunc: func() {
+				viper.Set("mail.template.source", "local")
+				viper.Set("mail.template.dir", os.TempDir()+"/notifaction_test_templates")
+				viper.Set("mail.template.layouts.dir", "./notexist")
+			},
+			wantErr: true,
+		},
 		{
 			name:     "invalid mail provider",
 			preFunc:  func() { viper.Set("mail.template.source", "notexist") },
@@ -322,6 +342,14 @@ func TestTplImpl_Apply(t *testing.T) {
 			wantErr:    true,
 			errMsg:     "lang is required",
 		},
+		{
+			name:       "Template lint error",
+			file:       "./test_lint_error.yaml",
+			allowedDir: userDir,
+			store:      mail.NewMockTemplateStore(),
+			wantErr:    true,
+			errMsg:     "template aaa_en has lint errors: [error] unbalanced-braces: subject: unclosed {{ at offset 6",
+		},
 		{
 			name:       "Template exists and update succeeds",
 			file:       "./test_valid.yaml",
@@ -459,3 +487,253 @@ type mockTemplateStore struct {
 func (m *mockTemplateStore) List(nextToken string) (*dao.ListTemplateResponse, error) {
 	return m.ListFunc(nextToken)
 }
+
+func TestTplImpl_ApplySaveVariables(t *testing.T) {
+	userDir, _ := os.UserHomeDir()
+	varDir := t.TempDir()
+	varStore, err := schema.NewFileStore(varDir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	a := &tplImpl{
+		allowedDirs: []string{userDir},
+		varStore:    varStore,
+		store: mail.NewMockTemplateStore(
+			mail.WithIsTemplateExist(func(name string) (bool, error) {
+				return false, nil
+			}),
+			mail.WithCreateTemplate(func(tpl *dao.Template) error {
+				return nil
+			}),
+		),
+	}
+	file, _ := filepath.Abs("./test_valid_variables.yaml")
+	if err := a.Apply(file); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	vars, err := varStore.Get("EVENT_JOIN_DENIED")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := dao.Variables{
+		{Name: "X-FORWARDED-HOST", Default: "oosa.life", Description: "site host"},
+		{Name: "CREATOR_NAME", Required: true},
+		{Name: "EVENT_NAME", Required: true},
+	}
+	if len(vars) != len(want) {
+		t.Fatalf("Get() = %v, want %v", vars, want)
+	}
+	for i, v := range want {
+		if *vars[i] != *v {
+			t.Errorf("Get()[%d] = %v, want %v", i, vars[i], v)
+		}
+	}
+}
+
+func TestTplImpl_ApplyLayout(t *testing.T) {
+	userDir, _ := os.UserHomeDir()
+	layouts, err := layout.Load("./test_layouts")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	tests := []struct {
+		name       string
+		storeRaw   bool
+		wantPlaint string
+		wantHtml   string
+		wantLayout string
+	}{
+		{
+			name:       "resolved before pushing",
+			wantPlaint: "hello {{NAME}}\n-- OOSA https://{{HOST}}",
+			wantHtml:   "<div><p>hello {{NAME}}</p><p>OOSA</p></div>",
+		},
+		{
+			name:       "kept for stores resolving at render time",
+			storeRaw:   true,
+			wantPlaint: "hello {{NAME}}\n{{> footer}}",
+			wantHtml:   "<p>hello {{NAME}}</p>",
+			wantLayout: "default",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *dao.Template
+			a := &tplImpl{
+				allowedDirs: []string{userDir},
+				layouts:     layouts,
+				storeRaw:    tt.storeRaw,
+				store: mail.NewMockTemplateStore(
+					mail.WithIsTemplateExist(func(name string) (bool, error) {
+						return false, nil
+					}),
+					mail.WithCreateTemplate(func(tpl *dao.Template) error {
+						created = tpl
+						return nil
+					}),
+				),
+			}
+			file, _ := filepath.Abs("./test_valid_layout.yaml")
+			if err := a.Apply(file); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if created.Body.Plaint != tt.wantPlaint || created.Body.Html != tt.wantHtml || created.Layout != tt.wantLayout {
+				t.Errorf("Apply() stored %+v", created)
+			}
+			// variables of the layout are inferred in both cases
+			if created.Variables.Get("HOST") == nil {
+				t.Errorf("Apply() variables = %v, want HOST", created.Variables)
+			}
+		})
+	}
+}
+
+func TestTplImpl_ApplyLocales(t *testing.T) {
+	userDir, _ := os.UserHomeDir()
+	tests := []struct {
+		name          string
+		requiredLangs []string
+		wantCreated   []string
+		wantErr       string
+	}{
+		{
+			name:        "one template per language",
+			wantCreated: []string{"EVENT_LOCALES_en", "EVENT_LOCALES_zh-TW"},
+		},
+		{
+			name:          "required languages provided",
+			requiredLangs: []string{"zh-tw", "en"},
+			wantCreated:   []string{"EVENT_LOCALES_en", "EVENT_LOCALES_zh-TW"},
+		},
+		{
+			name:          "required languages missing",
+			requiredLangs: []string{"en", "ja", "ko"},
+			wantErr:       "template EVENT_LOCALES is missing required languages: ja, ko",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := []string{}
+			a := &tplImpl{
+				allowedDirs:   []string{userDir},
+				requiredLangs: tt.requiredLangs,
+				store: mail.NewMockTemplateStore(
+					mail.WithIsTemplateExist(func(name string) (bool, error) {
+						return false, nil
+					}),
+					mail.WithCreateTemplate(func(tpl *dao.Template) error {
+						created = append(created, tpl.GetName())
+						return nil
+					}),
+				),
+			}
+			file, _ := filepath.Abs("./test_valid_locales.yaml")
+			err := a.Apply(file)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Apply() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !slices.Equal(created, tt.wantCreated) {
+				t.Errorf("Apply() created = %v, want %v", created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestBuildTemplates(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		wantNames []string
+		wantErr   string
+	}{
+		{
+			name:      "every language",
+			file:      "./test_valid_locales.yaml",
+			wantNames: []string{"EVENT_LOCALES_en", "EVENT_LOCALES_zh-TW"},
+		},
+		{
+			name:    "lint error",
+			file:    "./test_lint_error.yaml",
+			wantErr: "template aaa_en has lint errors: [error] unbalanced-braces: subject: unclosed {{ at offset 6",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := ReadTemplateFile(tt.file)
+			if err != nil {
+				t.Fatalf("ReadTemplateFile() error = %v", err)
+			}
+			tpls, err := BuildTemplates(input)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("BuildTemplates() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildTemplates() error = %v", err)
+			}
+			for _, name := range tt.wantNames {
+				if ok, _ := tpls.IsTemplateExist(name); !ok {
+					t.Errorf("BuildTemplates() is missing %s", name)
+				}
+			}
+			if v := tpls.Variables().Get("NAME"); v == nil {
+				t.Errorf("BuildTemplates() did not infer NAME")
+			}
+		})
+	}
+}
+
+func TestNewTemplateCache(t *testing.T) {
+	viper.Reset()
+	ResetTemplateCache()
+	defer func() {
+		viper.Reset()
+		ResetTemplateCache()
+	}()
+	viper.Set("mail.template.source", "local")
+	viper.Set("mail.template.dir", t.TempDir())
+	viper.Set("mail.template.cache.ttl", "1m")
+
+	applier, err := NewTemplate()
+	if err != nil {
+		t.Fatalf("NewTemplate() error = %v", err)
+	}
+	reader, err := NewTemplate()
+	if err != nil {
+		t.Fatalf("NewTemplate() error = %v", err)
+	}
+	if applier.(*tplImpl).store != reader.(*tplImpl).store {
+		t.Errorf("NewTemplate() stores are not shared")
+	}
+
+	input := &dao.ApplyTemplateInput{}
+	input.Event, input.Lang, input.Subject = "EVENT", "en", "Hi"
+	input.Body.Plaint = "Hi"
+	// applying through one template invalidates the content cached by the other
+	for _, subject := range []string{"Hi", "Hello"} {
+		input.Subject = subject
+		if err := applier.ApplyTemplate(input); err != nil {
+			t.Fatalf("ApplyTemplate() error = %v", err)
+		}
+		detail, err := reader.Detail("EVENT_en")
+		if err != nil {
+			t.Fatalf("Detail() error = %v", err)
+		}
+		if detail.Subject != subject {
+			t.Errorf("Detail() subject = %v, want %v", detail.Subject, subject)
+		}
+	}
+
+	n, err := WarmTemplateCache()
+	if err != nil || n != 1 {
+		t.Errorf("WarmTemplateCache() = %v, %v, want 1", n, err)
+	}
+}
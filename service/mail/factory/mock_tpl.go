@@ -27,6 +27,30 @@ func SetMockList(listFunc func(nextToken string) (*dao.ListTemplateResponse, err
 	mockTemplate = mock
 }
 
+func SetMockDetail(detailFunc func(name string) (*dao.DetailTemplateResponse, error)) {
+	mock := getMockTemplate()
+	mock.detailFunc = detailFunc
+	mockTemplate = mock
+}
+
+func SetMockApplyTemplate(applyFunc func(input *dao.ApplyTemplateInput) error) {
+	mock := getMockTemplate()
+	mock.applyFunc = applyFunc
+	mockTemplate = mock
+}
+
+func SetMockSaveBuilt(saveFunc func(tpl *dao.Template) error) {
+	mock := getMockTemplate()
+	mock.saveFunc = saveFunc
+	mockTemplate = mock
+}
+
+func SetMockDelete(deleteFunc func(name string) error) {
+	mock := getMockTemplate()
+	mock.deleteFunc = deleteFunc
+	mockTemplate = mock
+}
+
 func SetMockNewTemplateException(e error) {
 	mock := getMockTemplate()
 	mock.newException = e
@@ -47,6 +71,10 @@ func newMockTemplate() (mail.Template, error) {
 type mockTemplateImpl struct {
 	newException error
 	listFunc     func(nextToken string) (*dao.ListTemplateResponse, error)
+	detailFunc   func(name string) (*dao.DetailTemplateResponse, error)
+	applyFunc    func(input *dao.ApplyTemplateInput) error
+	saveFunc     func(tpl *dao.Template) error
+	deleteFunc   func(name string) error
 }
 
 func (m *mockTemplateImpl) List(nextToken string) (*dao.ListTemplateResponse, error) {
@@ -60,10 +88,34 @@ func (m *mockTemplateImpl) Apply(tplfile string) error {
 	return nil
 }
 
+func (m *mockTemplateImpl) ApplyTemplate(input *dao.ApplyTemplateInput) error {
+	if m.applyFunc == nil {
+		return nil
+	}
+	return m.applyFunc(input)
+}
+
+func (m *mockTemplateImpl) SaveBuilt(tpl *dao.Template) error {
+	if m.saveFunc == nil {
+		return nil
+	}
+	return m.saveFunc(tpl)
+}
+
+func (m *mockTemplateImpl) IsTemplateExist(name string) (bool, error) {
+	return true, nil
+}
+
 func (m *mockTemplateImpl) Delete(name string) error {
-	return nil
+	if m.deleteFunc == nil {
+		return nil
+	}
+	return m.deleteFunc(name)
 }
 
 func (m *mockTemplateImpl) Detail(name string) (*dao.DetailTemplateResponse, error) {
-	return nil, nil
+	if m.detailFunc == nil {
+		return nil, nil
+	}
+	return m.detailFunc(name)
 }
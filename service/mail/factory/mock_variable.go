@@ -0,0 +1,65 @@
+package factory
+
+import (
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+var mockVariableStore mail.VariableStore
+
+func ResetMockVariableStore() {
+	mockVariableStore = nil
+}
+
+func getMockVariableStore() *mockVariableStoreImpl {
+	var mock *mockVariableStoreImpl
+	if mockVariableStore == nil {
+		mock = &mockVariableStoreImpl{}
+	} else {
+		mock = mockVariableStore.(*mockVariableStoreImpl)
+	}
+	return mock
+}
+
+func SetMockGetVariables(getFunc func(event string) (dao.Variables, error)) {
+	mock := getMockVariableStore()
+	mock.getFunc = getFunc
+	mockVariableStore = mock
+}
+
+func SetMockNewVariableStoreException(e error) {
+	mock := getMockVariableStore()
+	mock.newException = e
+	mockVariableStore = mock
+}
+
+func newMockVariableStore() (mail.VariableStore, error) {
+	if mockVariableStore == nil {
+		return nil, nil
+	}
+	mock := getMockVariableStore()
+	if mock.newException != nil {
+		return nil, mock.newException
+	}
+	return mockVariableStore, nil
+}
+
+type mockVariableStoreImpl struct {
+	newException error
+	getFunc      func(event string) (dao.Variables, error)
+}
+
+func (m *mockVariableStoreImpl) Save(tpl *dao.Template) error {
+	return nil
+}
+
+func (m *mockVariableStoreImpl) Get(event string) (dao.Variables, error) {
+	if m.getFunc == nil {
+		return dao.Variables{}, nil
+	}
+	return m.getFunc(event)
+}
+
+func (m *mockVariableStoreImpl) Delete(name string) error {
+	return nil
+}
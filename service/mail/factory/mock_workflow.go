@@ -0,0 +1,14 @@
+package factory
+
+import "github.com/arwoosa/notifaction/service/mail/workflow"
+
+var mockWorkflowStore workflow.Store
+
+// SetMockWorkflowStore enables the workflow with store instead of the one of mail.template.workflow.mongo.
+func SetMockWorkflowStore(store workflow.Store) {
+	mockWorkflowStore = store
+}
+
+func ResetMockWorkflowStore() {
+	mockWorkflowStore = nil
+}
@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"strings"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+// ListFilter selects listed templates, zero fields match every template.
+type ListFilter struct {
+	EventPrefix   string
+	Lang          string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// UpdatedAfter and UpdatedBefore use the create time of templates never updated.
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+}
+
+func (f *ListFilter) Match(tpl *dao.ListTemplate) bool {
+	if f.EventPrefix != "" || f.Lang != "" {
+		event, l, err := service.ParseTemplateName(tpl.Name)
+		if err != nil {
+			return false
+		}
+		if !strings.HasPrefix(event, f.EventPrefix) {
+			return false
+		}
+		if f.Lang != "" && lang.Normalize(l) != lang.Normalize(f.Lang) {
+			return false
+		}
+	}
+	if !inRange(tpl.CreateTime, f.CreatedAfter, f.CreatedBefore) {
+		return false
+	}
+	updated := tpl.CreateTime
+	if tpl.UpdateTime != nil {
+		updated = *tpl.UpdateTime
+	}
+	return inRange(updated, f.UpdatedAfter, f.UpdatedBefore)
+}
+
+func inRange(t, after, before time.Time) bool {
+	if !after.IsZero() && t.Before(after) {
+		return false
+	}
+	if !before.IsZero() && !t.Before(before) {
+		return false
+	}
+	return true
+}
+
+// Filter returns the templates matching the filter.
+func (f *ListFilter) Filter(tpls []*dao.ListTemplate) []*dao.ListTemplate {
+	result := []*dao.ListTemplate{}
+	for _, tpl := range tpls {
+		if f.Match(tpl) {
+			result = append(result, tpl)
+		}
+	}
+	return result
+}
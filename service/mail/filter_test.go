@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListFilter(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	updated := day(20)
+	tpls := []*dao.ListTemplate{
+		{Name: "EVENT_JOIN_DENIED_en", CreateTime: day(1)},
+		{Name: "EVENT_JOIN_DENIED_zh-TW", CreateTime: day(5), UpdateTime: &updated},
+		{Name: "WELCOME_en", CreateTime: day(10)},
+		{Name: "invalid", CreateTime: day(10)},
+	}
+	names := func(tpls []*dao.ListTemplate) []string {
+		result := []string{}
+		for _, tpl := range tpls {
+			result = append(result, tpl.Name)
+		}
+		return result
+	}
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   []string
+	}{
+		{
+			name: "no filter",
+			want: []string{"EVENT_JOIN_DENIED_en", "EVENT_JOIN_DENIED_zh-TW", "WELCOME_en", "invalid"},
+		},
+		{
+			name:   "event prefix",
+			filter: ListFilter{EventPrefix: "EVENT_"},
+			want:   []string{"EVENT_JOIN_DENIED_en", "EVENT_JOIN_DENIED_zh-TW"},
+		},
+		{
+			name:   "lang is normalized",
+			filter: ListFilter{Lang: "zh-tw"},
+			want:   []string{"EVENT_JOIN_DENIED_zh-TW"},
+		},
+		{
+			name:   "created range",
+			filter: ListFilter{CreatedAfter: day(5), CreatedBefore: day(10)},
+			want:   []string{"EVENT_JOIN_DENIED_zh-TW"},
+		},
+		{
+			name:   "updated falls back to created",
+			filter: ListFilter{UpdatedAfter: day(10)},
+			want:   []string{"EVENT_JOIN_DENIED_zh-TW", "WELCOME_en", "invalid"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, names(tt.filter.Filter(tpls)))
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/factory"
+)
+
+// Unit is a translatable segment of a template.
+type Unit struct {
+	ID     string
+	Source string
+	Target string
+	// Note tells translators where the segment comes from.
+	Note string
+}
+
+type Catalog struct {
+	SourceLang string
+	TargetLang string
+	Units      []*Unit
+}
+
+// Export extracts the segments of the templates, which are sorted by event.
+func Export(tpls []*dao.Template, sourceLang, targetLang string) (*Catalog, error) {
+	sorted := append([]*dao.Template{}, tpls...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Event < sorted[j].Event })
+	c := &Catalog{SourceLang: sourceLang, TargetLang: targetLang, Units: []*Unit{}}
+	for _, tpl := range sorted {
+		_, err := rewrite(tpl, func(id, note, source string) string {
+			c.Units = append(c.Units, &Unit{ID: id, Source: source, Note: note})
+			return source
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Import rebuilds the templates of the events of the catalog in targetLang from their source templates.
+// Every segment of a rebuilt template must be translated from the current source and keep its placeholders,
+// otherwise the errors of all segments are returned.
+func Import(c *Catalog, tpls []*dao.Template, targetLang string) ([]*dao.Template, error) {
+	if targetLang == "" {
+		return nil, errors.New("target lang is required")
+	}
+	units := map[string]*Unit{}
+	events := []string{}
+	for _, u := range c.Units {
+		if _, ok := units[u.ID]; ok {
+			return nil, fmt.Errorf("segment %s is duplicated", u.ID)
+		}
+		units[u.ID] = u
+		if event := EventOf(u.ID); len(events) == 0 || events[len(events)-1] != event {
+			events = append(events, event)
+		}
+	}
+	sources := map[string]*dao.Template{}
+	for _, tpl := range tpls {
+		sources[tpl.Event] = tpl
+	}
+
+	result := []*dao.Template{}
+	var errs []error
+	seen := map[string]bool{}
+	for _, event := range events {
+		if seen[event] {
+			continue
+		}
+		seen[event] = true
+		source, ok := sources[event]
+		if !ok {
+			errs = append(errs, fmt.Errorf("template %s is not found", service.GetTemplateName(event, c.SourceLang)))
+			continue
+		}
+		used := map[string]bool{}
+		tpl, err := rewrite(source, func(id, note, text string) string {
+			used[id] = true
+			u, ok := units[id]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("segment %s is missing", id))
+			case u.Source != text:
+				errs = append(errs, fmt.Errorf("segment %s: source changed since export", id))
+			case u.Target == "":
+				errs = append(errs, fmt.Errorf("segment %s is not translated", id))
+			default:
+				if err := CheckTokens(text, u.Target); err != nil {
+					errs = append(errs, fmt.Errorf("segment %s: %w", id, err))
+					return text
+				}
+				return u.Target
+			}
+			return text
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, u := range c.Units {
+			if EventOf(u.ID) == event && !used[u.ID] {
+				errs = append(errs, fmt.Errorf("segment %s is not in the source template", u.ID))
+			}
+		}
+		tpl.Lang = targetLang
+		result = append(result, tpl)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+// LoadTemplates returns the templates of sourceLang in the template files of dir, including
+// the locales of multi-language files. Files without an event, like layouts, are skipped.
+func LoadTemplates(dir, sourceLang string) ([]*dao.Template, error) {
+	files, err := factory.ListTemplateFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	tpls := []*dao.Template{}
+	found := map[string]string{}
+	for _, file := range files {
+		input, err := factory.ReadTemplateFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if input.Event == "" {
+			continue
+		}
+		if err := input.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, tpl := range input.Expand() {
+			if lang.Normalize(tpl.Lang) != lang.Normalize(sourceLang) {
+				continue
+			}
+			if other, ok := found[tpl.Event]; ok {
+				return nil, fmt.Errorf("template %s is in both %s and %s", tpl.GetName(), other, file)
+			}
+			found[tpl.Event] = file
+			tpls = append(tpls, tpl)
+		}
+	}
+	return tpls, nil
+}
+
+const (
+	FormatXliff = "xliff"
+	FormatPo    = "po"
+)
+
+// FormatOf returns the format of a file by its extension, empty when it is unknown.
+func FormatOf(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".xlf", ".xliff":
+		return FormatXliff
+	case ".po":
+		return FormatPo
+	default:
+		return ""
+	}
+}
+
+func Write(w io.Writer, format string, c *Catalog) error {
+	switch format {
+	case FormatXliff:
+		return WriteXliff(w, c)
+	case FormatPo:
+		return WritePo(w, c)
+	default:
+		return fmt.Errorf("invalid format: %s", format)
+	}
+}
+
+func Read(r io.Reader, format string) (*Catalog, error) {
+	switch format {
+	case FormatXliff:
+		return ReadXliff(r)
+	case FormatPo:
+		return ReadPo(r)
+	default:
+		return nil, fmt.Errorf("invalid format: %s", format)
+	}
+}
@@ -0,0 +1,208 @@
+package i18n
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTestTemplates(t *testing.T) []*dao.Template {
+	tpls, err := LoadTemplates("./test_templates", "zh-tw")
+	assert.NoError(t, err)
+	return tpls
+}
+
+func TestLoadTemplates(t *testing.T) {
+	names := []string{}
+	for _, tpl := range loadTestTemplates(t) {
+		names = append(names, tpl.GetName())
+	}
+	assert.ElementsMatch(t, []string{"order_zh-TW", "welcome_zh-TW"}, names)
+
+	tpls, err := LoadTemplates("./test_templates", "ko")
+	assert.NoError(t, err)
+	assert.Empty(t, tpls)
+}
+
+// translate fills the targets of c with ja translations keeping the tokens.
+func translate(c *Catalog) {
+	ja := map[string]string{
+		"order/subject":    "注文 {{ORDER_ID}}",
+		"order/plaint/1":   "注文 {{ORDER_ID}} が作成されました",
+		"welcome/subject":  "ようこそ {{NAME}}",
+		"welcome/plaint/1": "こんにちは {{NAME}}、",
+		"welcome/plaint/2": "はじめる：{{URL}}",
+		"welcome/html/1":   "こんにちは",
+		"welcome/html/2":   "、ようこそ！",
+		"welcome/html/3":   "はじめる",
+	}
+	for _, u := range c.Units {
+		u.Target = ja[u.ID]
+	}
+}
+
+func TestImport(t *testing.T) {
+	sources := loadTestTemplates(t)
+	c, err := Export(sources, "zh-TW", "ja")
+	assert.NoError(t, err)
+	translate(c)
+
+	tpls, err := Import(c, sources, "ja")
+	assert.NoError(t, err)
+	assert.Len(t, tpls, 2)
+	order, welcome := tpls[0], tpls[1]
+	assert.Equal(t, "order_ja", order.GetName())
+	assert.Equal(t, "注文 {{ORDER_ID}} が作成されました", order.Body.Plaint)
+	assert.Equal(t, "welcome_ja", welcome.GetName())
+	assert.Equal(t, "ようこそ {{NAME}}", welcome.Subject)
+	assert.Equal(t, "こんにちは {{NAME}}、\n\nはじめる：{{URL}}\n", welcome.Body.Plaint)
+	assert.Equal(t, `<html><head><style>p { color: red; }</style></head>
+<body>
+  <p>こんにちは <b>{{NAME}}</b>、ようこそ！</p>
+  <p><a href="{{URL}}">はじめる</a> {{> footer}}</p>
+</body></html>
+`, welcome.Body.Html)
+	// the source templates are not changed
+	assert.Equal(t, "zh-TW", sources[1].Lang)
+	assert.Equal(t, "歡迎 {{NAME}}", sources[1].Subject)
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Catalog)
+		lang    string
+		wantErr string
+	}{
+		{
+			name:    "missing placeholder",
+			change:  func(c *Catalog) { c.Units[0].Target = "注文" },
+			lang:    "ja",
+			wantErr: "segment order/subject: missing placeholder {{ORDER_ID}}",
+		},
+		{
+			name:    "changed placeholder",
+			change:  func(c *Catalog) { c.Units[0].Target = "注文 {{ORDER}}" },
+			lang:    "ja",
+			wantErr: "segment order/subject: missing placeholder {{ORDER_ID}}\nunknown placeholder {{ORDER}}",
+		},
+		{
+			name: "untranslated and source changed",
+			change: func(c *Catalog) {
+				c.Units[1].Target = ""
+				c.Units[2].Source = "歡迎"
+			},
+			lang:    "ja",
+			wantErr: "segment order/plaint/1 is not translated\nsegment welcome/subject: source changed since export",
+		},
+		{
+			name: "unknown segment and event",
+			change: func(c *Catalog) {
+				c.Units = append(c.Units, &Unit{ID: "order/html/1", Source: "x", Target: "y"}, &Unit{ID: "refund/subject", Source: "x", Target: "y"})
+			},
+			lang:    "ja",
+			wantErr: "segment order/html/1 is not in the source template\ntemplate refund_zh-TW is not found",
+		},
+		{
+			name:    "no target lang",
+			change:  func(c *Catalog) {},
+			wantErr: "target lang is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := loadTestTemplates(t)
+			c, err := Export(sources, "zh-TW", "ja")
+			assert.NoError(t, err)
+			translate(c)
+			tt.change(c)
+			tpls, err := Import(c, sources, tt.lang)
+			assert.EqualError(t, err, tt.wantErr)
+			assert.Nil(t, tpls)
+		})
+	}
+}
+
+func TestFormatsRoundTrip(t *testing.T) {
+	c, err := Export(loadTestTemplates(t), "zh-TW", "ja")
+	assert.NoError(t, err)
+	translate(c)
+	c.Units[2].Target = "" // an untranslated segment
+
+	for _, format := range []string{FormatXliff, FormatPo} {
+		t.Run(format, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			assert.NoError(t, Write(buf, format, c))
+			got, err := Read(buf, format)
+			assert.NoError(t, err)
+			assert.Equal(t, c, got)
+		})
+	}
+	assert.EqualError(t, Write(&bytes.Buffer{}, "csv", c), "invalid format: csv")
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, FormatXliff, FormatOf("ja.xlf"))
+	assert.Equal(t, FormatXliff, FormatOf("ja.XLIFF"))
+	assert.Equal(t, FormatPo, FormatOf("ja.po"))
+	assert.Equal(t, "", FormatOf("ja.yaml"))
+}
+
+func TestReadXliffToolPlaceholders(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="notifaction" datatype="plaintext" source-language="en" target-language="ja">
+    <body>
+      <trans-unit id="welcome/subject">
+        <source>Hi <ph id="1">{{NAME}}</ph>, <ph id="2">{{URL}}</ph> &amp; more</source>
+        <target><x id="2"/> <g id="g1">こんにちは</g> <ph id="1"/> &amp;</target>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>`
+	c, err := ReadXliff(bytes.NewBufferString(doc))
+	assert.NoError(t, err)
+	assert.Equal(t, "en", c.SourceLang)
+	assert.Equal(t, "ja", c.TargetLang)
+	assert.Equal(t, "Hi {{NAME}}, {{URL}} & more", c.Units[0].Source)
+	assert.Equal(t, "{{URL}} こんにちは {{NAME}} &", c.Units[0].Target)
+
+	_, err = ReadXliff(bytes.NewBufferString(`<xliff><file><body><trans-unit id="a"><source>x</source><target><x id="3"/></target></trans-unit></body></file></xliff>`))
+	assert.EqualError(t, err, `trans-unit a: unknown placeholder id "3"`)
+}
+
+func TestReadPo(t *testing.T) {
+	po := `# translator comment
+msgid ""
+msgstr ""
+"Language: ja\n"
+"X-Source-Language: en\n"
+
+#. welcome_en subject
+msgctxt "welcome/subject"
+msgid "Hi {{NAME}}"
+msgstr ""
+"こんにちは "
+"{{NAME}}"
+
+#, fuzzy
+msgctxt "welcome/plaint/1"
+msgid "Thanks"
+msgstr "ありがとう"
+`
+	c, err := ReadPo(bytes.NewBufferString(po))
+	assert.NoError(t, err)
+	assert.Equal(t, &Catalog{
+		SourceLang: "en",
+		TargetLang: "ja",
+		Units: []*Unit{
+			{ID: "welcome/subject", Source: "Hi {{NAME}}", Target: "こんにちは {{NAME}}", Note: "welcome_en subject"},
+			{ID: "welcome/plaint/1", Source: "Thanks"},
+		},
+	}, c)
+
+	_, err = ReadPo(bytes.NewBufferString("msgid \"a\nmsgstr \"b\"\n"))
+	assert.Error(t, err)
+}
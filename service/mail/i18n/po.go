@@ -0,0 +1,120 @@
+package i18n
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WritePo writes the catalog as a gettext PO file, the segment id is the msgctxt.
+// Placeholders and partials are kept as they are, translation tools flag them as format tokens.
+func WritePo(w io.Writer, c *Catalog) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	bw.WriteString("\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	if c.TargetLang != "" {
+		fmt.Fprintf(bw, "%s\n", strconv.Quote("Language: "+c.TargetLang+"\n"))
+	}
+	fmt.Fprintf(bw, "%s\n", strconv.Quote("X-Source-Language: "+c.SourceLang+"\n"))
+	for _, u := range c.Units {
+		bw.WriteString("\n")
+		if u.Note != "" {
+			fmt.Fprintf(bw, "#. %s\n", u.Note)
+		}
+		fmt.Fprintf(bw, "msgctxt %s\n", strconv.Quote(u.ID))
+		fmt.Fprintf(bw, "msgid %s\n", strconv.Quote(u.Source))
+		fmt.Fprintf(bw, "msgstr %s\n", strconv.Quote(u.Target))
+	}
+	return bw.Flush()
+}
+
+type poEntry struct {
+	ctxt, id, str string
+	notes         []string
+	fuzzy         bool
+	// field is the string continuation lines are appended to
+	field  *string
+	hasStr bool
+}
+
+// ReadPo reads a PO file written by WritePo. Fuzzy translations are not used.
+func ReadPo(r io.Reader) (*Catalog, error) {
+	c := &Catalog{Units: []*Unit{}}
+	entry := &poEntry{}
+	flush := func() {
+		if !entry.hasStr {
+			entry = &poEntry{}
+			return
+		}
+		if entry.ctxt == "" && entry.id == "" {
+			for _, line := range strings.Split(entry.str, "\n") {
+				key, value, _ := strings.Cut(line, ":")
+				switch strings.TrimSpace(key) {
+				case "Language":
+					c.TargetLang = strings.TrimSpace(value)
+				case "X-Source-Language":
+					c.SourceLang = strings.TrimSpace(value)
+				}
+			}
+		} else {
+			u := &Unit{ID: entry.ctxt, Source: entry.id, Target: entry.str, Note: strings.Join(entry.notes, "\n")}
+			if entry.fuzzy {
+				u.Target = ""
+			}
+			c.Units = append(c.Units, u)
+		}
+		entry = &poEntry{}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		keyword, rest, _ := strings.Cut(line, " ")
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "#,"):
+			entry.fuzzy = strings.Contains(line, "fuzzy")
+		case strings.HasPrefix(line, "#."):
+			entry.notes = append(entry.notes, strings.TrimSpace(strings.TrimPrefix(line, "#.")))
+		case strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, `"`):
+			if entry.field == nil {
+				return nil, fmt.Errorf("line %d: string without keyword", lineNo)
+			}
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			*entry.field += s
+		case keyword == "msgctxt" || keyword == "msgid" || keyword == "msgstr":
+			if keyword != "msgstr" && entry.hasStr {
+				flush()
+			}
+			s, err := strconv.Unquote(strings.TrimSpace(rest))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			switch keyword {
+			case "msgctxt":
+				entry.ctxt, entry.field = s, &entry.ctxt
+			case "msgid":
+				entry.id, entry.field = s, &entry.id
+			case "msgstr":
+				entry.str, entry.field, entry.hasStr = s, &entry.str, true
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unsupported %s", lineNo, keyword)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read po: %w", err)
+	}
+	flush()
+	return c, nil
+}
@@ -0,0 +1,168 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"golang.org/x/net/html"
+)
+
+// tokenRegexp matches the {{NAME}} placeholders and {{> name}} partials translators must keep.
+var tokenRegexp = regexp.MustCompile(`\{\{\s*>?\s*[A-Za-z0-9_\-\.]+\s*\}\}`)
+
+// skipElements hold text that is not shown to the reader.
+var skipElements = map[string]bool{
+	"style": true, "script": true,
+}
+
+const (
+	fieldSubject = "subject"
+	fieldPlaint  = "plaint"
+	fieldHtml    = "html"
+)
+
+// Tokens returns the protected tokens of text in order.
+func Tokens(text string) []string {
+	return tokenRegexp.FindAllString(text, -1)
+}
+
+func normalizeToken(token string) string {
+	return strings.Join(strings.Fields(token), "")
+}
+
+// CheckTokens returns an error when target does not keep exactly the placeholders and partials of source.
+func CheckTokens(source, target string) error {
+	count := map[string]int{}
+	for _, t := range Tokens(source) {
+		count[normalizeToken(t)]++
+	}
+	for _, t := range Tokens(target) {
+		count[normalizeToken(t)]--
+	}
+	missing, unknown := []string{}, []string{}
+	for token, n := range count {
+		switch {
+		case n > 0:
+			missing = append(missing, token)
+		case n < 0:
+			unknown = append(unknown, token)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unknown)
+	var errs []error
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("missing placeholder %s", strings.Join(missing, ", ")))
+	}
+	if len(unknown) > 0 {
+		errs = append(errs, fmt.Errorf("unknown placeholder %s", strings.Join(unknown, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// translatable reports whether text has words besides its tokens.
+func translatable(text string) bool {
+	return strings.IndexFunc(tokenRegexp.ReplaceAllString(text, ""), unicode.IsLetter) >= 0
+}
+
+// translateFunc returns the text replacing the segment source of id.
+type translateFunc func(id, note, source string) string
+
+func segmentID(event, field string, n int) string {
+	if field == fieldSubject {
+		return event + "/" + field
+	}
+	return fmt.Sprintf("%s/%s/%d", event, field, n)
+}
+
+// EventOf returns the event of a segment id.
+func EventOf(id string) string {
+	event, _, _ := strings.Cut(id, "/")
+	return event
+}
+
+// rewrite returns a copy of tpl whose segments are replaced by translate. Extracting and
+// importing both walk the template here, so that segment ids always match.
+func rewrite(tpl *dao.Template, translate translateFunc) (*dao.Template, error) {
+	result := *tpl
+	name := tpl.GetName()
+	if subject := strings.TrimSpace(tpl.Subject); translatable(subject) {
+		result.Subject = translate(segmentID(tpl.Event, fieldSubject, 0), name+" subject", subject)
+	}
+
+	n := 0
+	lines := strings.Split(tpl.Body.Plaint, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !translatable(trimmed) {
+			continue
+		}
+		n++
+		lead, trail := spaces(line)
+		lines[i] = lead + translate(segmentID(tpl.Event, fieldPlaint, n), name+" body.plaint", trimmed) + trail
+	}
+	result.Body.Plaint = strings.Join(lines, "\n")
+
+	body, err := rewriteHtml(tpl.Body.Html, func(n int, source string) string {
+		return translate(segmentID(tpl.Event, fieldHtml, n), name+" body.html", source)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	result.Body.Html = body
+	return &result, nil
+}
+
+// spaces returns the leading and trailing white space of text.
+func spaces(text string) (string, string) {
+	trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+	lead := text[:len(text)-len(trimmed)]
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	return lead, text[len(lead)+len(trimmed):]
+}
+
+// rewriteHtml replaces the translatable text nodes of body, keeping the markup byte for byte.
+// White space inside a text node is collapsed in the segment.
+func rewriteHtml(body string, translate func(n int, source string) string) (string, error) {
+	z := html.NewTokenizer(strings.NewReader(body))
+	b := strings.Builder{}
+	skip := 0
+	n := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if errors.Is(z.Err(), io.EOF) {
+				return b.String(), nil
+			}
+			return "", fmt.Errorf("failed to parse html: %w", z.Err())
+		}
+		// TagName lower-cases the buffer in place, so raw is copied first
+		raw := string(z.Raw())
+		switch tt {
+		case html.StartTagToken:
+			if name, _ := z.TagName(); skipElements[string(name)] {
+				skip++
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); skipElements[string(name)] && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			text := html.UnescapeString(raw)
+			if skip > 0 || !translatable(text) {
+				break
+			}
+			n++
+			lead, trail := spaces(text)
+			source := strings.Join(strings.Fields(text), " ")
+			raw = lead + html.EscapeString(translate(n, source)) + trail
+		}
+		b.WriteString(raw)
+	}
+}
@@ -0,0 +1,66 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		target  string
+		wantErr string
+	}{
+		{name: "same", source: "Hi {{NAME}} {{> footer}}", target: "{{> footer}} こんにちは {{ NAME }}"},
+		{name: "no tokens", source: "Hi", target: "こんにちは"},
+		{name: "missing", source: "Hi {{NAME}}, {{URL}}", target: "こんにちは", wantErr: "missing placeholder {{NAME}}, {{URL}}"},
+		{name: "changed", source: "Hi {{NAME}}", target: "こんにちは {{NAMAE}}", wantErr: "missing placeholder {{NAME}}\nunknown placeholder {{NAMAE}}"},
+		{name: "repeated", source: "{{NAME}} {{NAME}}", target: "{{NAME}}", wantErr: "missing placeholder {{NAME}}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckTokens(tt.source, tt.target)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tpl := dao.NewTemplate("welcome", "en", " Hi {{NAME}} ", "Hi {{NAME}},\n\n{{URL}}\n  Thanks\n",
+		`<style>p { color: red; }</style><p class="x">Hi <b>{{NAME}}</b>,
+  welcome &amp; enjoy</p><p>{{> footer}}</p>`)
+	c, err := Export([]*dao.Template{tpl}, "en", "ja")
+	assert.NoError(t, err)
+	got := map[string]string{}
+	for _, u := range c.Units {
+		got[u.ID] = u.Source
+	}
+	assert.Equal(t, map[string]string{
+		"welcome/subject":  "Hi {{NAME}}",
+		"welcome/plaint/1": "Hi {{NAME}},",
+		"welcome/plaint/2": "Thanks",
+		"welcome/html/1":   "Hi",
+		"welcome/html/2":   ", welcome & enjoy",
+	}, got)
+	assert.Equal(t, "welcome_en body.html", c.Units[len(c.Units)-1].Note)
+}
+
+func TestRewriteKeepsMarkup(t *testing.T) {
+	body := `<!DOCTYPE html><html><head><style>p{color:red}</style></head><body><!-- note -->
+<p style="margin:0">Hello <a href="{{URL}}">world</a> &lt;3</p></body></html>`
+	got, err := rewriteHtml(body, func(n int, source string) string { return source })
+	assert.NoError(t, err)
+	assert.Equal(t, body, got)
+
+	got, err = rewriteHtml(body, func(n int, source string) string { return "<T" + source + ">" })
+	assert.NoError(t, err)
+	assert.Equal(t, `<!DOCTYPE html><html><head><style>p{color:red}</style></head><body><!-- note -->
+<p style="margin:0">&lt;THello&gt; <a href="{{URL}}">&lt;Tworld&gt;</a> &lt;3</p></body></html>`, got)
+}
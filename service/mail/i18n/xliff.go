@@ -0,0 +1,176 @@
+package i18n
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteXliff writes the catalog as XLIFF 1.2, placeholders and partials become <ph> elements
+// so that translation tools protect them.
+func WriteXliff(w io.Writer, c *Catalog) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">` + "\n")
+	fmt.Fprintf(bw, `  <file original="notifaction" datatype="plaintext" source-language="%s"`, escapeXml(c.SourceLang))
+	if c.TargetLang != "" {
+		fmt.Fprintf(bw, ` target-language="%s"`, escapeXml(c.TargetLang))
+	}
+	bw.WriteString(">\n    <body>\n")
+	for _, u := range c.Units {
+		fmt.Fprintf(bw, "      <trans-unit id=\"%s\">\n", escapeXml(u.ID))
+		fmt.Fprintf(bw, "        <source>%s</source>\n", inlineXml(u.Source))
+		if u.Target != "" {
+			fmt.Fprintf(bw, "        <target>%s</target>\n", inlineXml(u.Target))
+		}
+		if u.Note != "" {
+			fmt.Fprintf(bw, "        <note>%s</note>\n", escapeXml(u.Note))
+		}
+		bw.WriteString("      </trans-unit>\n")
+	}
+	bw.WriteString("    </body>\n  </file>\n</xliff>\n")
+	return bw.Flush()
+}
+
+func escapeXml(s string) string {
+	b := strings.Builder{}
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// inlineXml escapes text, wrapping its tokens in numbered <ph> elements.
+func inlineXml(text string) string {
+	b := strings.Builder{}
+	last := 0
+	for i, loc := range tokenRegexp.FindAllStringIndex(text, -1) {
+		b.WriteString(escapeXml(text[last:loc[0]]))
+		fmt.Fprintf(&b, `<ph id="%d">%s</ph>`, i+1, escapeXml(text[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(escapeXml(text[last:]))
+	return b.String()
+}
+
+type xliffDoc struct {
+	Files []*xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	SourceLang string       `xml:"source-language,attr"`
+	TargetLang string       `xml:"target-language,attr"`
+	Units      []*xliffUnit `xml:"body>trans-unit"`
+}
+
+type xliffUnit struct {
+	ID     string     `xml:"id,attr"`
+	Source xliffText  `xml:"source"`
+	Target *xliffText `xml:"target"`
+	Notes  []string   `xml:"note"`
+}
+
+type xliffText struct {
+	Inner string `xml:",innerxml"`
+}
+
+// ReadXliff reads an XLIFF 1.2 file. Tools may turn <ph> elements into empty <ph/> or <x/> elements,
+// which are restored from the token of the same number in the source.
+func ReadXliff(r io.Reader) (*Catalog, error) {
+	var doc xliffDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse xliff: %w", err)
+	}
+	if len(doc.Files) == 0 {
+		return nil, errors.New("xliff has no file")
+	}
+	c := &Catalog{
+		SourceLang: doc.Files[0].SourceLang,
+		TargetLang: doc.Files[0].TargetLang,
+		Units:      []*Unit{},
+	}
+	for _, f := range doc.Files {
+		for _, xu := range f.Units {
+			source, err := readInline(xu.Source.Inner, nil)
+			if err != nil {
+				return nil, fmt.Errorf("trans-unit %s: %w", xu.ID, err)
+			}
+			u := &Unit{ID: xu.ID, Source: source, Note: strings.Join(xu.Notes, "\n")}
+			if xu.Target != nil {
+				if u.Target, err = readInline(xu.Target.Inner, Tokens(source)); err != nil {
+					return nil, fmt.Errorf("trans-unit %s: %w", xu.ID, err)
+				}
+			}
+			c.Units = append(c.Units, u)
+		}
+	}
+	return c, nil
+}
+
+// readInline returns the text of inline XLIFF content, restoring placeholders from their elements.
+func readInline(inner string, sourceTokens []string) (string, error) {
+	d := xml.NewDecoder(strings.NewReader("<inline>" + inner + "</inline>"))
+	b := strings.Builder{}
+	// the content of a <ph> is the token itself, id is used when it is empty
+	var ph *strings.Builder
+	phID := ""
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return b.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			if ph != nil {
+				ph.Write(t)
+			} else {
+				b.Write(t)
+			}
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "ph":
+				ph, phID = &strings.Builder{}, attr(t, "id")
+			case "x":
+				token, err := sourceToken(sourceTokens, attr(t, "id"))
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(token)
+			}
+		case xml.EndElement:
+			if t.Name.Local != "ph" || ph == nil {
+				continue
+			}
+			token := strings.TrimSpace(ph.String())
+			if token == "" {
+				if token, err = sourceToken(sourceTokens, phID); err != nil {
+					return "", err
+				}
+			}
+			b.WriteString(token)
+			ph = nil
+		}
+	}
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func sourceToken(tokens []string, id string) (string, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 1 || n > len(tokens) {
+		return "", fmt.Errorf("unknown placeholder id %q", id)
+	}
+	return tokens[n-1], nil
+}
@@ -0,0 +1,189 @@
+package layout
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"gopkg.in/yaml.v2"
+)
+
+// ContentPartial is the partial a layout uses to place the body of the template.
+const ContentPartial = "content"
+
+const maxDepth = 10
+
+var partialRegexp = regexp.MustCompile(`\{\{>\s*([A-Za-z0-9_\-\.]+)\s*\}\}`)
+
+type Part struct {
+	Plaint string
+	Html   string
+}
+
+// file is the format of a layout file. Partials and layouts of a file without lang
+// are shared by every language.
+type file struct {
+	Lang     string
+	Partials map[string]*Part
+	Layouts  map[string]*Part
+}
+
+type Set struct {
+	// lang -> name -> part, lang "" holds the shared ones
+	partials map[string]map[string]*Part
+	layouts  map[string]map[string]*Part
+}
+
+func NewSet() *Set {
+	return &Set{
+		partials: map[string]map[string]*Part{},
+		layouts:  map[string]map[string]*Part{},
+	}
+}
+
+// Load reads every YAML file in dir.
+func Load(dir string) (*Set, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layout dir %s: %w", dir, err)
+	}
+	set := NewSet()
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layout file %s: %w", e.Name(), err)
+		}
+		var f file
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal layout file %s: %w", e.Name(), err)
+		}
+		for name, part := range f.Partials {
+			if err := set.AddPartial(f.Lang, name, part); err != nil {
+				return nil, fmt.Errorf("layout file %s: %w", e.Name(), err)
+			}
+		}
+		for name, part := range f.Layouts {
+			if err := set.AddLayout(f.Lang, name, part); err != nil {
+				return nil, fmt.Errorf("layout file %s: %w", e.Name(), err)
+			}
+		}
+	}
+	return set, nil
+}
+
+func add(parts map[string]map[string]*Part, kind, lang, name string, part *Part) error {
+	if name == "" || name == ContentPartial {
+		return fmt.Errorf("invalid %s name: %q", kind, name)
+	}
+	if part == nil {
+		return fmt.Errorf("%s %s is empty", kind, name)
+	}
+	if parts[lang] == nil {
+		parts[lang] = map[string]*Part{}
+	}
+	if _, ok := parts[lang][name]; ok {
+		return fmt.Errorf("duplicate %s %s for lang %q", kind, name, lang)
+	}
+	parts[lang][name] = part
+	return nil
+}
+
+func (s *Set) AddPartial(lang, name string, part *Part) error {
+	return add(s.partials, "partial", lang, name, part)
+}
+
+func (s *Set) AddLayout(lang, name string, part *Part) error {
+	return add(s.layouts, "layout", lang, name, part)
+}
+
+func lookup(parts map[string]map[string]*Part, lang, name string) *Part {
+	if p, ok := parts[lang][name]; ok {
+		return p
+	}
+	return parts[""][name]
+}
+
+// Resolve returns a copy of tpl with its layout applied and every {{> partial}} expanded.
+// A nil Set resolves like an empty one.
+func (s *Set) Resolve(tpl *dao.Template) (*dao.Template, error) {
+	if s == nil {
+		s = NewSet()
+	}
+	resolved := *tpl
+	resolved.Layout = ""
+
+	html, plaint := tpl.Body.Html, tpl.Body.Plaint
+	if tpl.Layout != "" {
+		layout := lookup(s.layouts, tpl.Lang, tpl.Layout)
+		if layout == nil {
+			return nil, fmt.Errorf("layout %s not found for lang %s", tpl.Layout, tpl.Lang)
+		}
+		if html != "" {
+			html = replaceContent(layout.Html, html)
+		}
+		if plaint != "" {
+			plaint = replaceContent(layout.Plaint, plaint)
+		}
+	}
+
+	var err error
+	if resolved.Subject, err = s.expand(tpl.Lang, tpl.Subject, false, 0); err != nil {
+		return nil, err
+	}
+	if resolved.Body.Html, err = s.expand(tpl.Lang, html, true, 0); err != nil {
+		return nil, err
+	}
+	if resolved.Body.Plaint, err = s.expand(tpl.Lang, plaint, false, 0); err != nil {
+		return nil, err
+	}
+	return &resolved, nil
+}
+
+func replaceContent(layout, content string) string {
+	if layout == "" {
+		return content
+	}
+	return partialRegexp.ReplaceAllStringFunc(layout, func(match string) string {
+		if partialRegexp.FindStringSubmatch(match)[1] == ContentPartial {
+			return content
+		}
+		return match
+	})
+}
+
+func (s *Set) expand(lang, text string, isHtml bool, depth int) (string, error) {
+	if depth > maxDepth {
+		return "", errors.New("partials are nested too deeply, check for cycles")
+	}
+	var resultErr error
+	result := partialRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		if resultErr != nil {
+			return match
+		}
+		name := partialRegexp.FindStringSubmatch(match)[1]
+		part := lookup(s.partials, lang, name)
+		if part == nil {
+			resultErr = fmt.Errorf("partial %s not found for lang %s", name, lang)
+			return match
+		}
+		content := part.Plaint
+		if isHtml {
+			content = part.Html
+		}
+		expanded, err := s.expand(lang, strings.TrimRight(content, "\n"), isHtml, depth+1)
+		if err != nil {
+			resultErr = err
+			return match
+		}
+		return expanded
+	})
+	return result, resultErr
+}
@@ -0,0 +1,139 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+const sharedFile = `
+partials:
+  footer:
+    plaint: "-- OOSA"
+    html: "<p>OOSA</p>"
+  signature:
+    plaint: "{{> footer}} team"
+    html: "{{> footer}}<p>team</p>"
+  loop:
+    plaint: "{{> loop}}"
+layouts:
+  default:
+    plaint: "{{> content}}\n{{> signature}}"
+    html: "<html><body>{{> content}}{{> signature}}</body></html>"
+`
+
+const zhFile = `
+lang: zh-TW
+partials:
+  footer:
+    plaint: "-- OOSA 團隊敬上"
+    html: "<p>OOSA 團隊敬上</p>"
+`
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"shared.yaml": sharedFile, "zh.yml": zhFile, "README.md": "ignored"})
+	set, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Len(t, set.partials[""], 3)
+	assert.Len(t, set.partials["zh-TW"], 1)
+	assert.Len(t, set.layouts[""], 1)
+
+	dir = writeFiles(t, map[string]string{"a.yaml": zhFile, "b.yaml": zhFile})
+	_, err = Load(dir)
+	assert.ErrorContains(t, err, "duplicate partial footer")
+
+	dir = writeFiles(t, map[string]string{"a.yaml": "partials:\n  content:\n    plaint: x\n"})
+	_, err = Load(dir)
+	assert.ErrorContains(t, err, `invalid partial name: "content"`)
+
+	_, err = Load(filepath.Join(dir, "notexist"))
+	assert.ErrorContains(t, err, "failed to read layout dir")
+}
+
+func TestResolve(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"shared.yaml": sharedFile, "zh.yaml": zhFile})
+	set, err := Load(dir)
+	assert.NoError(t, err)
+
+	withLayout := func(tpl *dao.Template, layout string) *dao.Template {
+		tpl.Layout = layout
+		return tpl
+	}
+	tests := []struct {
+		name       string
+		set        *Set
+		tpl        *dao.Template
+		wantPlaint string
+		wantHtml   string
+		wantErr    string
+	}{
+		{
+			name:       "layout with nested partials",
+			set:        set,
+			tpl:        withLayout(dao.NewTemplate("event", "en", "subject", "Hi {{NAME}}", "<p>Hi {{NAME}}</p>"), "default"),
+			wantPlaint: "Hi {{NAME}}\n-- OOSA team",
+			wantHtml:   "<html><body><p>Hi {{NAME}}</p><p>OOSA</p><p>team</p></body></html>",
+		},
+		{
+			name:       "language partial takes precedence",
+			set:        set,
+			tpl:        withLayout(dao.NewTemplate("event", "zh-TW", "subject", "您好", ""), "default"),
+			wantPlaint: "您好\n-- OOSA 團隊敬上 team",
+		},
+		{
+			name:       "partial without layout",
+			set:        set,
+			tpl:        dao.NewTemplate("event", "en", "subject", "Hi\n{{>  footer }}", "<p>Hi</p>{{>footer}}"),
+			wantPlaint: "Hi\n-- OOSA",
+			wantHtml:   "<p>Hi</p><p>OOSA</p>",
+		},
+		{
+			name:       "nil set without references",
+			tpl:        dao.NewTemplate("event", "en", "subject", "Hi {{NAME}}", ""),
+			wantPlaint: "Hi {{NAME}}",
+		},
+		{
+			name:    "missing layout",
+			set:     set,
+			tpl:     withLayout(dao.NewTemplate("event", "en", "subject", "Hi", ""), "notexist"),
+			wantErr: "layout notexist not found for lang en",
+		},
+		{
+			name:    "missing partial",
+			tpl:     dao.NewTemplate("event", "en", "subject", "{{> footer}}", ""),
+			wantErr: "partial footer not found for lang en",
+		},
+		{
+			name:    "cycle",
+			set:     set,
+			tpl:     dao.NewTemplate("event", "en", "subject", "{{> loop}}", ""),
+			wantErr: "partials are nested too deeply",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.set.Resolve(tt.tpl)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "", got.Layout)
+			assert.Equal(t, tt.wantPlaint, got.Body.Plaint)
+			assert.Equal(t, tt.wantHtml, got.Body.Html)
+		})
+	}
+}
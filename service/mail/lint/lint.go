@@ -0,0 +1,242 @@
+package lint
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"golang.org/x/net/html"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+const (
+	// MaxSubjectLength is the number of characters most mail clients show without truncating.
+	MaxSubjectLength = 78
+	// MaxMessageSize is the size of subject and bodies over which Gmail clips the message.
+	MaxMessageSize = 102 * 1024
+)
+
+type Finding struct {
+	Severity Severity
+	Rule     string
+	Message  string
+}
+
+func (f *Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s", f.Severity, f.Rule, f.Message)
+}
+
+type Findings []*Finding
+
+func (fs Findings) HasError() bool {
+	for _, f := range fs {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (fs Findings) Errors() Findings {
+	result := Findings{}
+	for _, f := range fs {
+		if f.Severity == SeverityError {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func (fs Findings) Warnings() Findings {
+	result := Findings{}
+	for _, f := range fs {
+		if f.Severity == SeverityWarning {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func (fs Findings) Error() string {
+	msgs := make([]string, len(fs))
+	for i, f := range fs {
+		msgs[i] = f.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (fs *Findings) add(severity Severity, rule, format string, args ...any) {
+	*fs = append(*fs, &Finding{Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+// Lint checks the subject and bodies of the template. Findings with SeverityError
+// should block the template from being applied.
+func Lint(tpl *dao.Template) Findings {
+	findings := Findings{}
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"subject", tpl.Subject},
+		{"body.plaint", tpl.Body.Plaint},
+		{"body.html", tpl.Body.Html},
+	}
+	for _, field := range fields {
+		if msg := checkBraces(field.value); msg != "" {
+			findings.add(SeverityError, "unbalanced-braces", "%s: %s", field.name, msg)
+		}
+	}
+
+	if tpl.Body.Plaint != "" && tpl.Body.Html != "" {
+		checkPlaceholders(&findings, tpl.Body.Plaint, tpl.Body.Html)
+	}
+
+	if length := utf8.RuneCountInString(tpl.Subject); length > MaxSubjectLength {
+		findings.add(SeverityWarning, "subject-length", "subject has %d characters, more than %d may be truncated", length, MaxSubjectLength)
+	}
+
+	if tpl.Body.Html != "" {
+		checkHtml(&findings, tpl.Body.Html)
+	}
+	if size := len(tpl.Subject) + len(tpl.Body.Html) + len(tpl.Body.Plaint); size > MaxMessageSize {
+		findings.add(SeverityError, "message-size", "subject and body are %d bytes, Gmail clips messages over %d bytes", size, MaxMessageSize)
+	}
+
+	for _, link := range plainLinkRegexp.FindAllString(tpl.Body.Plaint, -1) {
+		findings.add(SeverityWarning, "insecure-link", "body.plaint: link %s is not https", link)
+	}
+	return findings
+}
+
+// checkBraces returns a description of the first unbalanced {{ or }}, or empty string.
+func checkBraces(s string) string {
+	open := -1
+	for i := 0; i < len(s)-1; i++ {
+		switch s[i : i+2] {
+		case "{{":
+			if open >= 0 {
+				return fmt.Sprintf("unclosed {{ at offset %d", open)
+			}
+			open = i
+			i++
+		case "}}":
+			if open < 0 {
+				return fmt.Sprintf("unexpected }} at offset %d", i)
+			}
+			open = -1
+			i++
+		}
+	}
+	if open >= 0 {
+		return fmt.Sprintf("unclosed {{ at offset %d", open)
+	}
+	return ""
+}
+
+func checkPlaceholders(findings *Findings, plaint, htmlBody string) {
+	inPlaint := map[string]bool{}
+	for _, p := range dao.ParsePlaceholders(plaint) {
+		inPlaint[p] = true
+	}
+	inHtml := map[string]bool{}
+	for _, p := range dao.ParsePlaceholders(htmlBody) {
+		inHtml[p] = true
+		if !inPlaint[p] {
+			findings.add(SeverityWarning, "placeholder-mismatch", "{{%s}} is in body.html but not in body.plaint", p)
+		}
+	}
+	for _, p := range dao.ParsePlaceholders(plaint) {
+		if !inHtml[p] {
+			findings.add(SeverityWarning, "placeholder-mismatch", "{{%s}} is in body.plaint but not in body.html", p)
+		}
+	}
+}
+
+var plainLinkRegexp = regexp.MustCompile(`http://[^\s"'<>]+`)
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// optionalEndElements may be left unclosed in valid HTML.
+var optionalEndElements = map[string]bool{
+	"p": true, "li": true, "dt": true, "dd": true, "tr": true, "td": true, "th": true,
+	"thead": true, "tbody": true, "tfoot": true, "option": true, "html": true, "head": true, "body": true,
+}
+
+func checkHtml(findings *Findings, body string) {
+	z := html.NewTokenizer(strings.NewReader(body))
+	stack := []string{}
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				findings.add(SeverityError, "malformed-html", "%v", z.Err())
+				return
+			}
+			for _, tag := range stack {
+				if !optionalEndElements[tag] {
+					findings.add(SeverityError, "malformed-html", "<%s> is not closed", tag)
+				}
+			}
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			checkAttrs(findings, token)
+			if tt == html.StartTagToken && !voidElements[token.Data] {
+				stack = append(stack, token.Data)
+			}
+		case html.EndTagToken:
+			token := z.Token()
+			if voidElements[token.Data] {
+				continue
+			}
+			idx := -1
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == token.Data {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				findings.add(SeverityError, "malformed-html", "unexpected </%s>", token.Data)
+				continue
+			}
+			for _, tag := range stack[idx+1:] {
+				if !optionalEndElements[tag] {
+					findings.add(SeverityError, "malformed-html", "<%s> is not closed before </%s>", tag, token.Data)
+				}
+			}
+			stack = stack[:idx]
+		}
+	}
+}
+
+func checkAttrs(findings *Findings, token html.Token) {
+	hasAlt := false
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "href", "src":
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Val)), "http://") {
+				findings.add(SeverityWarning, "insecure-link", "body.html: <%s %s=%q> is not https", token.Data, attr.Key, attr.Val)
+			}
+		case "alt":
+			// alt="" marks a decorative image
+			hasAlt = true
+		}
+	}
+	if token.Data == "img" && !hasAlt {
+		findings.add(SeverityWarning, "missing-alt", "body.html: <img> has no alt attribute")
+	}
+}
@@ -0,0 +1,101 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func rules(findings Findings) []string {
+	result := []string{}
+	for _, f := range findings {
+		result = append(result, string(f.Severity)+":"+f.Rule)
+	}
+	return result
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name      string
+		tpl       *dao.Template
+		wantRules []string
+		wantError bool
+	}{
+		{
+			name:      "valid template",
+			tpl:       dao.NewTemplate("event", "en", "Hi {{NAME}}", "Hi {{NAME}} https://{{HOST}}", `<p>Hi {{NAME}}</p><a href="https://{{HOST}}"><img src="https://oosa.life/logo.png" alt="OOSA"/></a><br/>`),
+			wantRules: []string{},
+		},
+		{
+			name:      "unbalanced braces",
+			tpl:       dao.NewTemplate("event", "en", "Hi {{NAME}", "Hi NAME}}", "<p>{{NAME {{HOST}}</p>"),
+			wantRules: []string{"error:unbalanced-braces", "error:unbalanced-braces", "error:unbalanced-braces", "warning:placeholder-mismatch"},
+			wantError: true,
+		},
+		{
+			name:      "placeholder mismatch",
+			tpl:       dao.NewTemplate("event", "en", "subject", "Hi {{NAME}}", "<p>{{HOST}}</p>"),
+			wantRules: []string{"warning:placeholder-mismatch", "warning:placeholder-mismatch"},
+		},
+		{
+			name:      "malformed html",
+			tpl:       dao.NewTemplate("event", "en", "subject", "", "<div><span>text</div></table><p>optional end"),
+			wantRules: []string{"error:malformed-html", "error:malformed-html"},
+			wantError: true,
+		},
+		{
+			name:      "unclosed html",
+			tpl:       dao.NewTemplate("event", "en", "subject", "", "<div>text"),
+			wantRules: []string{"error:malformed-html"},
+			wantError: true,
+		},
+		{
+			name:      "insecure links and missing alt",
+			tpl:       dao.NewTemplate("event", "en", "subject", "see http://oosa.life", `<a href="http://oosa.life">link</a><img src="https://oosa.life/a.png">`),
+			wantRules: []string{"warning:insecure-link", "warning:missing-alt", "warning:insecure-link"},
+		},
+		{
+			name:      "long subject",
+			tpl:       dao.NewTemplate("event", "en", strings.Repeat("長", MaxSubjectLength+1), "text", ""),
+			wantRules: []string{"warning:subject-length"},
+		},
+		{
+			name:      "decorative image",
+			tpl:       dao.NewTemplate("event", "en", "subject", "text", `<img src="https://oosa.life/line.png" alt="">`),
+			wantRules: []string{},
+		},
+		{
+			name:      "html over clipping limit",
+			tpl:       dao.NewTemplate("event", "en", "subject", "", "<p>"+strings.Repeat("a", MaxMessageSize)+"</p>"),
+			wantRules: []string{"error:message-size"},
+			wantError: true,
+		},
+		{
+			name:      "html and plain text over clipping limit",
+			tpl:       dao.NewTemplate("event", "en", "subject", strings.Repeat("a", MaxMessageSize/2), "<p>"+strings.Repeat("a", MaxMessageSize/2)+"</p>"),
+			wantRules: []string{"error:message-size"},
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := Lint(tt.tpl)
+			assert.Equal(t, tt.wantRules, rules(findings))
+			assert.Equal(t, tt.wantError, findings.HasError())
+		})
+	}
+}
+
+func TestFindings(t *testing.T) {
+	findings := Findings{
+		{Severity: SeverityError, Rule: "a", Message: "msg a"},
+		{Severity: SeverityWarning, Rule: "b", Message: "msg b"},
+	}
+	assert.True(t, findings.HasError())
+	assert.Len(t, findings.Errors(), 1)
+	assert.Len(t, findings.Warnings(), 1)
+	assert.Equal(t, "[error] a: msg a; [warning] b: msg b", findings.Error())
+	assert.False(t, findings.Warnings().HasError())
+}
@@ -0,0 +1,191 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/layout"
+	"github.com/arwoosa/notifaction/service/mail/transform"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	fileExt  = ".yaml"
+	pageSize = 100
+)
+
+type localTemplateStoreOpt func(*localTplImpl)
+
+// WithLayouts sets the layouts and partials resolved when a template is read.
+func WithLayouts(layouts *layout.Set) localTemplateStoreOpt {
+	return func(l *localTplImpl) {
+		l.layouts = layouts
+	}
+}
+
+// NewTemplateStore returns a template store keeping one YAML file per template under dir/<name>.yaml.
+// Templates are stored as written, layouts and partials are resolved when the template is rendered.
+func NewTemplateStore(dir string, opts ...localTemplateStoreOpt) (mail.TemplateStore, error) {
+	if dir == "" {
+		return nil, errors.New("dir is empty")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absDir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create dir %s: %w", absDir, err)
+	}
+	impl := &localTplImpl{dir: absDir}
+	for _, opt := range opts {
+		opt(impl)
+	}
+	return impl, nil
+}
+
+type localTplImpl struct {
+	dir     string
+	layouts *layout.Set
+}
+
+func (l *localTplImpl) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid template name: %q", name)
+	}
+	return filepath.Join(l.dir, name+fileExt), nil
+}
+
+func (l *localTplImpl) write(tpl *dao.Template) error {
+	path, err := l.path(tpl.GetName())
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(tpl)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %w", err)
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func (l *localTplImpl) read(name string) (*dao.Template, error) {
+	path, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("template %s does not exist", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+	var tpl dao.Template
+	if err := yaml.Unmarshal(data, &tpl); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal template %s: %w", name, err)
+	}
+	return &tpl, nil
+}
+
+func (l *localTplImpl) IsTemplateExist(name string) (bool, error) {
+	path, err := l.path(name)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *localTplImpl) CreateTpl(tpl *dao.Template) error {
+	exist, err := l.IsTemplateExist(tpl.GetName())
+	if err != nil {
+		return err
+	}
+	if exist {
+		return fmt.Errorf("template %s already exists", tpl.GetName())
+	}
+	return l.write(tpl)
+}
+
+func (l *localTplImpl) UpdateTemplate(tpl *dao.Template) error {
+	exist, err := l.IsTemplateExist(tpl.GetName())
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return fmt.Errorf("template %s does not exist", tpl.GetName())
+	}
+	return l.write(tpl)
+}
+
+func (l *localTplImpl) Delete(name string) error {
+	path, err := l.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// List returns the templates sorted by name, the token is the name of the first template of the page.
+func (l *localTplImpl) List(token string) (*dao.ListTemplateResponse, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir %s: %w", l.dir, err)
+	}
+	results := []*dao.ListTemplate{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != fileExt {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), fileExt)
+		if name < token {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		modTime := info.ModTime()
+		results = append(results, &dao.ListTemplate{
+			Name:       name,
+			CreateTime: modTime,
+			UpdateTime: &modTime,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	resp := &dao.ListTemplateResponse{Templates: results}
+	if len(results) > pageSize {
+		resp.NextToken = &results[pageSize].Name
+		resp.Templates = results[:pageSize]
+	}
+	return resp, nil
+}
+
+// Detail returns the template with its layout and partials resolved and its options applied.
+func (l *localTplImpl) Detail(name string) (*dao.DetailTemplateResponse, error) {
+	tpl, err := l.read(name)
+	if err != nil {
+		return nil, err
+	}
+	built, err := transform.Build(tpl, l.layouts)
+	if err != nil {
+		return nil, err
+	}
+	resp := &dao.DetailTemplateResponse{}
+	resp.Title = name
+	resp.Subject = built.Subject
+	resp.Body.Plaint = built.Body.Plaint
+	resp.Body.Html = built.Body.Html
+	return resp, nil
+}
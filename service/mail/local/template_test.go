@@ -0,0 +1,65 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/layout"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalTemplateStore(t *testing.T) {
+	_, err := NewTemplateStore("")
+	assert.EqualError(t, err, "dir is empty")
+
+	layouts := layout.NewSet()
+	assert.NoError(t, layouts.AddPartial("", "footer", &layout.Part{Plaint: "-- OOSA", Html: "<p>OOSA</p>"}))
+	assert.NoError(t, layouts.AddLayout("", "default", &layout.Part{Html: "<div>{{> content}}{{> footer}}</div>"}))
+
+	store, err := NewTemplateStore(t.TempDir(), WithLayouts(layouts))
+	assert.NoError(t, err)
+
+	tpl := dao.NewTemplate("event", "en", "Hi {{NAME}}", "", "<p>Hi {{NAME}}</p>")
+	tpl.Layout = "default"
+	tpl.Options.GeneratePlaint = true
+
+	exist, err := store.IsTemplateExist("event_en")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+	assert.EqualError(t, store.UpdateTemplate(tpl), "template event_en does not exist")
+	assert.NoError(t, store.CreateTpl(tpl))
+	assert.EqualError(t, store.CreateTpl(tpl), "template event_en already exists")
+	exist, err = store.IsTemplateExist("event_en")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	detail, err := store.Detail("event_en")
+	assert.NoError(t, err)
+	assert.Equal(t, "event_en", detail.Title)
+	assert.Equal(t, "Hi {{NAME}}", detail.Subject)
+	assert.Equal(t, "<div><p>Hi {{NAME}}</p><p>OOSA</p></div>", detail.Body.Html)
+	assert.Equal(t, "Hi {{NAME}}\n\nOOSA\n", detail.Body.Plaint)
+
+	// partials changed after the template was stored are used when rendering
+	tpl.Body.Html = "<p>Bye</p>"
+	assert.NoError(t, store.UpdateTemplate(tpl))
+	detail, err = store.Detail("event_en")
+	assert.NoError(t, err)
+	assert.Equal(t, "<div><p>Bye</p><p>OOSA</p></div>", detail.Body.Html)
+
+	assert.NoError(t, store.CreateTpl(dao.NewTemplate("another", "en", "subject", "text", "")))
+	list, err := store.List("")
+	assert.NoError(t, err)
+	assert.Nil(t, list.NextToken)
+	assert.Len(t, list.Templates, 2)
+	assert.Equal(t, "another_en", list.Templates[0].Name)
+	list, err = store.List("b")
+	assert.NoError(t, err)
+	assert.Len(t, list.Templates, 1)
+
+	assert.NoError(t, store.Delete("event_en"))
+	_, err = store.Detail("event_en")
+	assert.EqualError(t, err, "template event_en does not exist")
+	_, err = store.IsTemplateExist("../event_en")
+	assert.EqualError(t, err, `invalid template name: "../event_en"`)
+}
@@ -0,0 +1,74 @@
+package preview
+
+import "html/template"
+
+var pages = template.Must(template.New("pages").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}} - notifaction preview</title>
+<style>
+body { font-family: sans-serif; margin: 16px; }
+.langs { display: flex; gap: 16px; align-items: flex-start; overflow-x: auto; }
+.lang { flex: 1; min-width: 420px; border: 1px solid #ccc; padding: 8px; }
+.subject { font-weight: bold; }
+iframe { width: 100%; height: 480px; border: 1px solid #eee; }
+pre { white-space: pre-wrap; background: #f6f6f6; padding: 8px; }
+.error { color: #b00020; }
+.warning { color: #8a6d00; }
+</style>
+</head>
+<body>{{end}}
+
+{{define "footer"}}
+<script>
+(function() {
+  var version = "{{.}}";
+  setInterval(function() {
+    fetch("/version").then(function(r) { return r.text(); }).then(function(v) {
+      if (v !== version) { location.reload(); }
+    }).catch(function() {});
+  }, 1000);
+})();
+</script>
+</body>
+</html>{{end}}
+
+{{define "error"}}{{template "header" "error"}}
+<p><a href="/">events</a></p>
+<pre class="error">{{.Data}}</pre>
+{{template "footer" .Version}}{{end}}
+
+{{define "index"}}{{template "header" "events"}}
+<h1>Events</h1>
+<ul>
+{{range .Data.Events}}<li><a href="/event/{{.Event}}">{{.Event}}</a> {{range .Langs}}<code>{{.}}</code> {{end}}{{if .Errors}}<span class="error">{{.Errors}} error(s)</span>{{end}}</li>
+{{else}}<li>no templates</li>
+{{end}}
+</ul>
+{{if .Data.Invalid}}<h2>Invalid files</h2>
+<ul>
+{{range .Data.Invalid}}<li>{{.File}}: <span class="error">{{.Err}}</span></li>
+{{end}}
+</ul>{{end}}
+{{template "footer" .Version}}{{end}}
+
+{{define "event"}}{{template "header" .Data.Event}}
+<p><a href="/">events</a></p>
+<h1>{{.Data.Event}}</h1>
+<div class="langs">
+{{range .Data.Previews}}<div class="lang">
+<h2>{{.Lang}}</h2>
+<p><small>{{.File}}</small></p>
+{{if .Err}}<pre class="error">{{.Err}}</pre>{{else}}
+<p class="subject">{{.Subject}}</p>
+{{range .Warnings}}<p class="warning">{{.}}</p>{{end}}
+<h3>HTML</h3>
+<iframe sandbox srcdoc="{{.Html}}"></iframe>
+<h3>Plain</h3>
+<pre>{{.Plaint}}</pre>
+{{end}}</div>
+{{end}}</div>
+{{template "footer" .Version}}{{end}}
+`))
@@ -0,0 +1,383 @@
+package preview
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/factory"
+	"github.com/arwoosa/notifaction/service/mail/layout"
+	"github.com/arwoosa/notifaction/service/mail/lint"
+	"github.com/arwoosa/notifaction/service/mail/transform"
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
+)
+
+type serverOpt func(*Server)
+
+// WithDataFile sets the JSON or YAML file of sample data, overriding the sample of the template files. String values are used for every event,
+// object values keyed by an event name are used for that event only.
+func WithDataFile(file string) serverOpt {
+	return func(s *Server) {
+		s.dataFile = file
+	}
+}
+
+// WithLayoutDir sets the directory of layouts and partials.
+func WithLayoutDir(dir string) serverOpt {
+	return func(s *Server) {
+		s.layoutDir = dir
+	}
+}
+
+// NewServer returns a server previewing the template files of dir. Files are read on every
+// request so that the page always shows the current content.
+func NewServer(dir string, opts ...serverOpt) (*Server, error) {
+	if dir == "" {
+		return nil, errors.New("dir is empty")
+	}
+	s := &Server{dir: dir}
+	for _, opt := range opts {
+		opt(s)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.index)
+	mux.HandleFunc("/event/", s.event)
+	mux.HandleFunc("/version", s.currentVersion)
+	s.mux = mux
+	return s, nil
+}
+
+type Server struct {
+	dir       string
+	dataFile  string
+	layoutDir string
+	mux       *http.ServeMux
+	// version is increased on every file change, the pages poll it to reload
+	version atomic.Int64
+}
+
+// Preview is a template file rendered with the sample data.
+type Preview struct {
+	File     string
+	Event    string
+	Lang     string
+	Subject  string
+	Html     string
+	Plaint   string
+	Warnings []string
+	Err      string
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Watch increases the version whenever a file of the template dir, the layout dir or the data file changes.
+// It blocks until done is closed.
+func (s *Server) Watch(done <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	dirs := []string{s.dir}
+	if s.layoutDir != "" {
+		dirs = append(dirs, s.layoutDir)
+	}
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return err
+			}
+			return watcher.Add(path)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	if s.dataFile != "" {
+		// editors replace files on save, so the directory is watched instead of the file
+		if err := watcher.Add(filepath.Dir(s.dataFile)); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", s.dataFile, err)
+		}
+	}
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = watcher.Add(event.Name)
+				}
+			}
+			s.version.Add(1)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("watch error: %v", err)
+		}
+	}
+}
+
+// ReadDataFile reads a JSON or YAML data file, chosen by the file extension.
+func ReadDataFile(file string) (map[string]any, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file %s: %w", file, err)
+	}
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		var yamlRaw map[string]any
+		if err := yaml.Unmarshal(data, &yamlRaw); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal data file %s: %w", file, err)
+		}
+		for k, v := range yamlRaw {
+			raw[k] = fromYaml(v)
+		}
+	default:
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal data file %s: %w", file, err)
+		}
+	}
+	return raw, nil
+}
+
+// fromYaml converts the map[interface{}]interface{} of yaml.v2 to map[string]any.
+func fromYaml(v any) any {
+	m, ok := v.(map[any]any)
+	if !ok {
+		return v
+	}
+	result := map[string]any{}
+	for k, mv := range m {
+		result[fmt.Sprint(k)] = fromYaml(mv)
+	}
+	return result
+}
+
+// ReadFlatDataFile reads a data file whose values are all used as template data.
+func ReadFlatDataFile(file string) (map[string]string, error) {
+	raw, err := ReadDataFile(file)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]string{}
+	for k, v := range raw {
+		if _, ok := v.(map[string]any); ok {
+			return nil, fmt.Errorf("data file %s: value of %s is not a scalar", file, k)
+		}
+		result[k] = toString(v)
+	}
+	return result, nil
+}
+
+// LoadData reads the sample data file, returning the data shared by all events and the data per event.
+func LoadData(file string) (map[string]string, map[string]map[string]string, error) {
+	shared := map[string]string{}
+	events := map[string]map[string]string{}
+	if file == "" {
+		return shared, events, nil
+	}
+	raw, err := ReadDataFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range raw {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			shared[k] = toString(v)
+			continue
+		}
+		events[k] = map[string]string{}
+		for ek, ev := range obj {
+			events[k][ek] = toString(ev)
+		}
+	}
+	return shared, events, nil
+}
+
+func toString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// Render builds a template the way the local store does and fills it with data the way the SMTP sender does.
+func Render(tpl *dao.Template, layouts *layout.Set, data map[string]string) (*dao.DetailTemplateResponse, lint.Findings, error) {
+	built, err := transform.Build(tpl, layouts)
+	if err != nil {
+		return nil, nil, err
+	}
+	findings := lint.Lint(built)
+	detail := &dao.DetailTemplateResponse{Title: built.GetName(), Subject: built.Subject}
+	detail.Body.Plaint = built.Body.Plaint
+	detail.Body.Html = built.Body.Html
+
+	withDefaults := map[string]string{}
+	for k, v := range data {
+		withDefaults[strings.ToUpper(k)] = v
+	}
+	built.Variables.ApplyDefaults(withDefaults)
+	mail.Render(detail, withDefaults)
+	return detail, findings, nil
+}
+
+// Load renders every template file of the dir, sorted by event and lang.
+func (s *Server) Load() ([]*Preview, error) {
+	var layouts *layout.Set
+	if s.layoutDir != "" {
+		var err error
+		if layouts, err = layout.Load(s.layoutDir); err != nil {
+			return nil, err
+		}
+	}
+	shared, events, err := LoadData(s.dataFile)
+	if err != nil {
+		return nil, err
+	}
+	files, err := factory.ListTemplateFiles(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := []*Preview{}
+	for _, file := range files {
+		previews = append(previews, s.preview(file, layouts, shared, events)...)
+	}
+	sort.SliceStable(previews, func(i, j int) bool {
+		if previews[i].Event != previews[j].Event {
+			return previews[i].Event < previews[j].Event
+		}
+		return previews[i].Lang < previews[j].Lang
+	})
+	return previews, nil
+}
+
+// preview renders every language of a template file.
+func (s *Server) preview(file string, layouts *layout.Set, shared map[string]string, events map[string]map[string]string) []*Preview {
+	input, err := factory.ReadTemplateFile(file)
+	if err != nil {
+		return []*Preview{{File: file, Err: err.Error()}}
+	}
+	if err := input.Validate(); err != nil {
+		return []*Preview{{File: file, Event: input.Event, Lang: input.Lang, Err: err.Error()}}
+	}
+
+	previews := []*Preview{}
+	for _, tpl := range input.Expand() {
+		p := &Preview{File: file, Event: tpl.Event, Lang: tpl.Lang}
+		previews = append(previews, p)
+
+		// the sample of the template file, then the shared data, then the data of the event
+		data := map[string]string{}
+		for _, source := range []map[string]string{tpl.Sample, shared, events[tpl.Event]} {
+			for k, v := range source {
+				data[strings.ToUpper(k)] = v
+			}
+		}
+		detail, findings, err := Render(tpl, layouts, data)
+		if err != nil {
+			p.Err = err.Error()
+			continue
+		}
+		p.Subject, p.Html, p.Plaint = detail.Subject, detail.Body.Html, detail.Body.Plaint
+		for _, f := range findings {
+			p.Warnings = append(p.Warnings, f.String())
+		}
+	}
+	return previews
+}
+
+func (s *Server) currentVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, strconv.FormatInt(s.version.Load(), 10))
+}
+
+type eventSummary struct {
+	Event  string
+	Langs  []string
+	Errors int
+}
+
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	previews, err := s.Load()
+	if err != nil {
+		s.render(w, "error", err.Error())
+		return
+	}
+	summaries := []*eventSummary{}
+	invalid := []*Preview{}
+	for _, p := range previews {
+		if p.Event == "" {
+			invalid = append(invalid, p)
+			continue
+		}
+		if len(summaries) == 0 || summaries[len(summaries)-1].Event != p.Event {
+			summaries = append(summaries, &eventSummary{Event: p.Event})
+		}
+		last := summaries[len(summaries)-1]
+		last.Langs = append(last.Langs, p.Lang)
+		if p.Err != "" {
+			last.Errors++
+		}
+	}
+	s.render(w, "index", map[string]any{"Events": summaries, "Invalid": invalid})
+}
+
+func (s *Server) event(w http.ResponseWriter, r *http.Request) {
+	event := strings.TrimPrefix(r.URL.Path, "/event/")
+	previews, err := s.Load()
+	if err != nil {
+		s.render(w, "error", err.Error())
+		return
+	}
+	selected := []*Preview{}
+	for _, p := range previews {
+		if p.Event == event {
+			selected = append(selected, p)
+		}
+	}
+	if len(selected) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	s.render(w, "event", map[string]any{"Event": event, "Previews": selected})
+}
+
+func (s *Server) render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.ExecuteTemplate(w, name, map[string]any{
+		"Version": s.version.Load(),
+		"Data":    data,
+	}); err != nil {
+		log.Printf("failed to render preview page: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package preview
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadData(t *testing.T) {
+	shared, events, err := LoadData("./test_data.json")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"CREATOR_NAME": "Peter"}, shared)
+	assert.Equal(t, map[string]map[string]string{"EVENT_JOIN_DENIED": {"event_name": "Hiking"}}, events)
+
+	shared, events, err = LoadData("")
+	assert.NoError(t, err)
+	assert.Empty(t, shared)
+	assert.Empty(t, events)
+
+	_, _, err = LoadData("./notexist.json")
+	assert.ErrorContains(t, err, "failed to read data file")
+}
+
+func TestReadFlatDataFile(t *testing.T) {
+	data, err := ReadFlatDataFile("./test_data.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"NAME":    "王小明",
+		"QUERY":   "a=1&b=2",
+		"MESSAGE": "line 1\nline 2\n",
+		"COUNT":   "3",
+	}, data)
+
+	_, err = ReadFlatDataFile("./test_data.json")
+	assert.EqualError(t, err, "data file ./test_data.json: value of EVENT_JOIN_DENIED is not a scalar")
+}
+
+func TestServer_Load(t *testing.T) {
+	_, err := NewServer("")
+	assert.EqualError(t, err, "dir is empty")
+
+	s, err := NewServer("./test_templates", WithDataFile("./test_data.json"))
+	assert.NoError(t, err)
+	previews, err := s.Load()
+	assert.NoError(t, err)
+	assert.Len(t, previews, 3)
+
+	assert.Equal(t, "lang is required", previews[0].Err)
+	assert.Equal(t, "EVENT_JOIN_DENIED", previews[1].Event)
+	assert.Equal(t, "en", previews[1].Lang)
+	assert.Equal(t, "Peter replied", previews[1].Subject)
+	assert.Equal(t, "Peter denied your request to join Hiking. https://oosa.life", previews[1].Plaint)
+	assert.Equal(t, `<p>Peter denied your request to join Hiking. <a href="https://oosa.life">OOSA</a></p>`, previews[1].Html)
+	assert.Equal(t, "zh-TW", previews[2].Lang)
+	assert.Equal(t, "Peter婉拒您加入 陽明山Hiking活動", previews[2].Plaint)
+}
+
+func TestServer_Pages(t *testing.T) {
+	s, err := NewServer("./test_templates", WithDataFile("./test_data.json"))
+	assert.NoError(t, err)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("/")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `<a href="/event/EVENT_JOIN_DENIED">EVENT_JOIN_DENIED</a> <code>en</code> <code>zh-TW</code>`)
+	assert.Contains(t, body, `EVENT_INVALID</a> <code></code> <span class="error">1 error(s)</span>`)
+
+	status, body = get("/event/EVENT_JOIN_DENIED")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "<h2>en</h2>")
+	assert.Contains(t, body, "<h2>zh-TW</h2>")
+	assert.Contains(t, body, `srcdoc="&lt;p&gt;Peter denied`)
+
+	status, _ = get("/event/notexist")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	s.version.Add(1)
+	_, body = get("/version")
+	assert.Equal(t, "1", body)
+}
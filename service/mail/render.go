@@ -0,0 +1,18 @@
+package mail
+
+import (
+	"strings"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+// Render replaces the {{KEY}} placeholders of subject and body with data.
+// Keys are upper-cased like the placeholders, placeholders without data are kept.
+func Render(tpl *dao.DetailTemplateResponse, data map[string]string) {
+	for k, v := range data {
+		placeholder := "{{" + strings.ToUpper(k) + "}}"
+		tpl.Body.Html = strings.ReplaceAll(tpl.Body.Html, placeholder, v)
+		tpl.Body.Plaint = strings.ReplaceAll(tpl.Body.Plaint, placeholder, v)
+		tpl.Subject = strings.ReplaceAll(tpl.Subject, placeholder, v)
+	}
+}
@@ -0,0 +1,19 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	tpl := &dao.DetailTemplateResponse{Subject: "Hi {{NAME}}"}
+	tpl.Body.Plaint = "Hi {{NAME}}, see {{HOST}}"
+	tpl.Body.Html = "<p>Hi {{NAME}}</p>"
+
+	Render(tpl, map[string]string{"name": "Peter", "OTHER": "x"})
+	assert.Equal(t, "Hi Peter", tpl.Subject)
+	assert.Equal(t, "Hi Peter, see {{HOST}}", tpl.Body.Plaint)
+	assert.Equal(t, "<p>Hi Peter</p>", tpl.Body.Html)
+}
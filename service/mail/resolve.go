@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
+)
+
+// ErrTemplateNotFound is returned when no template of an event exists in any fallback lang.
+var ErrTemplateNotFound = errors.New("template does not exist")
+
+// ErrDraftEvent is returned when the event of a template draft is resolved for sending.
+var ErrDraftEvent = errors.New("drafts can not be sent")
+
+// DraftPrefix starts the event of a template draft, see package workflow.
+const DraftPrefix = "draft-"
+
+// ResolveTemplate returns the name and lang of the first existing template of the event
+// among the fallback candidates of userLang.
+func ResolveTemplate(resolver lang.Resolver, exist func(name string) (bool, error), event, userLang string) (string, string, error) {
+	if strings.HasPrefix(event, DraftPrefix) {
+		return "", "", fmt.Errorf("%w: %s", ErrDraftEvent, event)
+	}
+	candidates := resolver.Candidates(userLang)
+	names := make([]string, len(candidates))
+	for i, l := range candidates {
+		name := service.GetTemplateName(event, l)
+		ok, err := exist(name)
+		if err != nil {
+			return "", "", err
+		}
+		if ok {
+			return name, l, nil
+		}
+		names[i] = name
+	}
+	return "", "", fmt.Errorf("%w: %s (tried: %s)", ErrTemplateNotFound, service.GetTemplateName(event, userLang), strings.Join(names, ", "))
+}
@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTemplate(t *testing.T) {
+	resolver := lang.NewResolver(lang.WithDefault("en"), lang.WithFallback("zh-Hant-TW", "zh-TW"))
+	tests := []struct {
+		name     string
+		event    string
+		lang     string
+		exist    map[string]bool
+		existErr error
+		wantName string
+		wantLang string
+		wantErr  string
+	}{
+		{
+			name:     "exact match",
+			lang:     "zh-TW",
+			exist:    map[string]bool{"EVENT_zh-TW": true, "EVENT_en": true},
+			wantName: "EVENT_zh-TW",
+			wantLang: "zh-TW",
+		},
+		{
+			name:     "configured fallback",
+			lang:     "zh-Hant-TW",
+			exist:    map[string]bool{"EVENT_zh-TW": true, "EVENT_en": true},
+			wantName: "EVENT_zh-TW",
+			wantLang: "zh-TW",
+		},
+		{
+			name:     "default lang",
+			lang:     "ja",
+			exist:    map[string]bool{"EVENT_en": true},
+			wantName: "EVENT_en",
+			wantLang: "en",
+		},
+		{
+			name:    "no template",
+			lang:    "ja",
+			exist:   map[string]bool{},
+			wantErr: "template does not exist: EVENT_ja (tried: EVENT_ja, EVENT_en)",
+		},
+		{
+			name:    "draft event",
+			event:   "draft-EVENT",
+			lang:    "en",
+			exist:   map[string]bool{"draft-EVENT_en": true},
+			wantErr: "drafts can not be sent: draft-EVENT",
+		},
+		{
+			name:     "exist error",
+			lang:     "ja",
+			existErr: errors.New("exist error"),
+			wantErr:  "exist error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exist := func(name string) (bool, error) {
+				return tt.exist[name], tt.existErr
+			}
+			event := tt.event
+			if event == "" {
+				event = "EVENT"
+			}
+			name, l, err := ResolveTemplate(resolver, exist, event, tt.lang)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantLang, l)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoStore returns a variable store keeping one document per template in collection,
+// so that every replica and the CLI share the variables of the templates they apply.
+func NewMongoStore(collection *mongo.Collection) mail.VariableStore {
+	return &mongoStore{collection: collection, timeout: 5 * time.Second}
+}
+
+type variablesDocument struct {
+	Name      string        `bson:"_id"`
+	Event     string        `bson:"event"`
+	Lang      string        `bson:"lang"`
+	Variables dao.Variables `bson:"variables"`
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func (m *mongoStore) Save(tpl *dao.Template) error {
+	if tpl.Event == "" || tpl.Lang == "" {
+		return errors.New("event and lang are required")
+	}
+	doc := &variablesDocument{Name: tpl.GetName(), Event: tpl.Event, Lang: tpl.Lang, Variables: tpl.Variables}
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.ReplaceOne(ctx, bson.M{"_id": doc.Name}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+// Get merges the variables of the languages of event in lang order, like the file store.
+func (m *mongoStore) Get(event string) (dao.Variables, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	cursor, err := m.collection.Find(ctx, bson.M{"event": event}, options.Find().SetSort(bson.D{{Key: "lang", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	docs := []*variablesDocument{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	result := dao.Variables{}
+	for _, doc := range docs {
+		result = result.Merge(doc.Variables)
+	}
+	return result, nil
+}
+
+func (m *mongoStore) Delete(name string) error {
+	if _, _, err := service.ParseTemplateName(name); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.DeleteOne(ctx, bson.M{"_id": name})
+	return err
+}
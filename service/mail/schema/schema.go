@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"gopkg.in/yaml.v2"
+)
+
+const fileExt = ".yaml"
+
+// NewFileStore returns a variable store keeping one YAML file per template
+// under dir/<event>/<lang>.yaml.
+func NewFileStore(dir string) (mail.VariableStore, error) {
+	if dir == "" {
+		return nil, errors.New("dir is empty")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &fileStore{dir: absDir}, nil
+}
+
+type fileStore struct {
+	dir string
+}
+
+func checkPathPart(part string) error {
+	if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `/\`) {
+		return fmt.Errorf("invalid path part: %q", part)
+	}
+	return nil
+}
+
+func (f *fileStore) eventDir(event string) (string, error) {
+	if err := checkPathPart(event); err != nil {
+		return "", err
+	}
+	return filepath.Join(f.dir, event), nil
+}
+
+func (f *fileStore) Save(tpl *dao.Template) error {
+	dir, err := f.eventDir(tpl.Event)
+	if err != nil {
+		return err
+	}
+	if err := checkPathPart(tpl.Lang); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create dir %s: %w", dir, err)
+	}
+	data, err := yaml.Marshal(tpl.Variables)
+	if err != nil {
+		return fmt.Errorf("failed to marshal variables: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, tpl.Lang+fileExt), data, 0o600)
+}
+
+func (f *fileStore) Get(event string) (dao.Variables, error) {
+	dir, err := f.eventDir(event)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return dao.Variables{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir %s: %w", dir, err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != fileExt {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	result := dao.Variables{}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", name, err)
+		}
+		var vars dao.Variables
+		if err := yaml.Unmarshal(data, &vars); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal yaml %s: %w", name, err)
+		}
+		result = result.Merge(vars)
+	}
+	return result, nil
+}
+
+func (f *fileStore) Delete(name string) error {
+	event, lang, err := service.ParseTemplateName(name)
+	if err != nil {
+		return err
+	}
+	dir, err := f.eventDir(event)
+	if err != nil {
+		return err
+	}
+	if err := checkPathPart(lang); err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(dir, lang+fileExt))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileStore(t *testing.T) {
+	_, err := NewFileStore("")
+	assert.ErrorContains(t, err, "dir is empty")
+
+	store, err := NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	assert.NotNil(t, store)
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	assert.NoError(t, err)
+
+	en := dao.NewTemplate("EVENT_JOIN", "en", "subject", "plaint", "html")
+	en.Variables = dao.Variables{{Name: "NAME", Default: "guest"}}
+	zh := dao.NewTemplate("EVENT_JOIN", "zh-TW", "subject", "plaint", "html")
+	zh.Variables = dao.Variables{{Name: "NAME", Required: true}, {Name: "EVENT_NAME", Required: true}}
+
+	assert.NoError(t, store.Save(en))
+	assert.NoError(t, store.Save(zh))
+	assert.FileExists(t, filepath.Join(dir, "EVENT_JOIN", "zh-TW.yaml"))
+
+	vars, err := store.Get("EVENT_JOIN")
+	assert.NoError(t, err)
+	assert.Len(t, vars, 2)
+	assert.Equal(t, &dao.Variable{Name: "NAME", Required: true, Default: "guest"}, vars.Get("NAME"))
+
+	// re-apply replaces the variables of the same lang
+	zh.Variables = dao.Variables{{Name: "NAME", Required: true}}
+	assert.NoError(t, store.Save(zh))
+	vars, err = store.Get("EVENT_JOIN")
+	assert.NoError(t, err)
+	assert.Nil(t, vars.Get("EVENT_NAME"))
+
+	assert.NoError(t, store.Delete("EVENT_JOIN_zh-TW"))
+	vars, err = store.Get("EVENT_JOIN")
+	assert.NoError(t, err)
+	assert.Equal(t, dao.Variables{{Name: "NAME", Default: "guest"}}, vars)
+
+	// deleting a template without variables is not an error
+	assert.NoError(t, store.Delete("EVENT_JOIN_ja"))
+
+	vars, err = store.Get("NOT_EXIST")
+	assert.NoError(t, err)
+	assert.Empty(t, vars)
+}
+
+func TestFileStoreInvalidPath(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+
+	assert.Error(t, store.Save(dao.NewTemplate("../event", "en", "s", "p", "h")))
+	assert.Error(t, store.Save(dao.NewTemplate("event", "en/..", "s", "p", "h")))
+	_, err = store.Get("..")
+	assert.Error(t, err)
+	assert.Error(t, store.Delete("invalid"))
+}
+
+func TestFileStoreInvalidYaml(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "EVENT"), 0o750))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "EVENT", "en.yaml"), []byte("invalid yaml"), 0o600))
+
+	_, err = store.Get("EVENT")
+	assert.ErrorContains(t, err, "failed to unmarshal yaml")
+}
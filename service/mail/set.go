@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"fmt"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+// TemplateReader is the part of a template store a sender reads.
+type TemplateReader interface {
+	IsTemplateExist(name string) (bool, error)
+	Detail(name string) (*dao.DetailTemplateResponse, error)
+}
+
+// TemplateSet holds built templates in memory, e.g. those of a template file that is not stored.
+type TemplateSet struct {
+	tpls map[string]*dao.Template
+}
+
+func NewTemplateSet(tpls ...*dao.Template) *TemplateSet {
+	s := &TemplateSet{tpls: map[string]*dao.Template{}}
+	for _, tpl := range tpls {
+		s.tpls[tpl.GetName()] = tpl
+	}
+	return s
+}
+
+func (s *TemplateSet) IsTemplateExist(name string) (bool, error) {
+	_, ok := s.tpls[name]
+	return ok, nil
+}
+
+func (s *TemplateSet) Detail(name string) (*dao.DetailTemplateResponse, error) {
+	tpl, ok := s.tpls[name]
+	if !ok {
+		return nil, fmt.Errorf("template %s does not exist", name)
+	}
+	resp := &dao.DetailTemplateResponse{}
+	resp.Title = name
+	resp.Subject = tpl.Subject
+	resp.Body.Plaint = tpl.Body.Plaint
+	resp.Body.Html = tpl.Body.Html
+	return resp, nil
+}
+
+// Variables returns the variables of all templates merged together.
+func (s *TemplateSet) Variables() dao.Variables {
+	var result dao.Variables
+	for _, tpl := range s.tpls {
+		result = result.Merge(tpl.Variables)
+	}
+	return result
+}
@@ -2,18 +2,19 @@ package smtp
 
 import (
 	"fmt"
+	"log"
 	"net/url"
 	"strconv"
-	"strings"
 
 	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
 	"github.com/arwoosa/notifaction/service/mail"
 	"github.com/go-gomail/gomail"
 )
 
 type apiSenderOpt func(*smtp)
 
-func WithTemplate(tpl mail.Template) apiSenderOpt {
+func WithTemplate(tpl mail.TemplateReader) apiSenderOpt {
 	return func(s *smtp) {
 		s.tpl = tpl
 	}
@@ -55,11 +56,20 @@ func WithFrom(from string) apiSenderOpt {
 	}
 }
 
+func WithLangResolver(resolver lang.Resolver) apiSenderOpt {
+	return func(s *smtp) {
+		s.langResolver = resolver
+	}
+}
+
 func NewApiSender(opts ...apiSenderOpt) (mail.ApiSender, error) {
 	s := &smtp{}
 	for _, opt := range opts {
 		opt(s)
 	}
+	if s.langResolver == nil {
+		s.langResolver = lang.NewResolver()
+	}
 	if s.sendCloser == nil {
 		return nil, fmt.Errorf("sendCloser is required")
 	}
@@ -73,9 +83,10 @@ func NewApiSender(opts ...apiSenderOpt) (mail.ApiSender, error) {
 }
 
 type smtp struct {
-	tpl        mail.Template
-	from       string
-	sendCloser gomail.SendCloser
+	tpl          mail.TemplateReader
+	langResolver lang.Resolver
+	from         string
+	sendCloser   gomail.SendCloser
 }
 
 func (s *smtp) Send(notify *service.Notification) (string, error) {
@@ -95,8 +106,15 @@ func (s *smtp) Send(notify *service.Notification) (string, error) {
 		msg.SetHeader("To", to.Email)
 	}
 
-	// Get template name
-	tplName := notify.GetTemplateName()
+	// Get template name, falling back to other languages when missing
+	tplName, _, err := mail.ResolveTemplate(s.langResolver, s.tpl.IsTemplateExist, notify.Event, notify.Lang)
+	if err != nil {
+		return "", err
+	}
+	if tplName != notify.GetTemplateName() {
+		log.Printf("template %s does not exist, fallback to %s", notify.GetTemplateName(), tplName)
+	}
+	notify.TemplateUsed = tplName
 
 	// Get template content
 	tplDetail, err := s.tpl.Detail(tplName)
@@ -105,11 +123,7 @@ func (s *smtp) Send(notify *service.Notification) (string, error) {
 	}
 
 	// replace template variables {{Variable}}
-	for k, v := range notify.UpperKeyData() {
-		tplDetail.Body.Html = strings.ReplaceAll(tplDetail.Body.Html, "{{"+k+"}}", v)
-		tplDetail.Body.Plaint = strings.ReplaceAll(tplDetail.Body.Plaint, "{{"+k+"}}", v)
-		tplDetail.Subject = strings.ReplaceAll(tplDetail.Subject, "{{"+k+"}}", v)
-	}
+	mail.Render(tplDetail, notify.Data)
 
 	// Set subject
 	msg.SetHeader("Subject", tplDetail.Subject)
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/lang"
 	"github.com/arwoosa/notifaction/service/mail"
 	"github.com/arwoosa/notifaction/service/mail/dao"
 	"github.com/go-gomail/gomail"
@@ -16,6 +17,14 @@ import (
 type MockTemplate struct {
 	mail.Template
 	DetailFunc func(name string) (*dao.DetailTemplateResponse, error)
+	ExistFunc  func(name string) (bool, error)
+}
+
+func (m *MockTemplate) IsTemplateExist(name string) (bool, error) {
+	if m.ExistFunc != nil {
+		return m.ExistFunc(name)
+	}
+	return true, nil
 }
 
 func (m *MockTemplate) Detail(name string) (*dao.DetailTemplateResponse, error) {
@@ -157,6 +166,55 @@ func TestSend(t *testing.T) {
 			wantErr:     false,
 			wantMessage: "",
 		},
+		{
+			name: "fallback language template",
+			setup: func(s *smtp) {
+				s.tpl = &MockTemplate{
+					ExistFunc: func(name string) (bool, error) {
+						return name == "test_template_en", nil
+					},
+					DetailFunc: func(name string) (*dao.DetailTemplateResponse, error) {
+						if name != "test_template_en" {
+							return nil, fmt.Errorf("template not found")
+						}
+						return &dao.DetailTemplateResponse{Subject: "Hello"}, nil
+					},
+				}
+				s.langResolver = lang.NewResolver(lang.WithDefault("en"))
+				s.sendCloser = newMockSendCloser()
+				s.from = "test@example.com"
+			},
+			notification: &service.Notification{
+				Event: "test_template",
+				Lang:  "ja",
+				SendTo: []*service.Info{{
+					Email: "test@example.com",
+				}},
+			},
+			wantErr:     false,
+			wantMessage: "",
+		},
+		{
+			name: "template not exist in any language",
+			setup: func(s *smtp) {
+				s.tpl = &MockTemplate{
+					ExistFunc: func(name string) (bool, error) {
+						return false, nil
+					},
+				}
+				s.sendCloser = newMockSendCloser()
+				s.from = "test@example.com"
+			},
+			notification: &service.Notification{
+				Event: "test_template",
+				Lang:  "ja",
+				SendTo: []*service.Info{{
+					Email: "test@example.com",
+				}},
+			},
+			wantErr:     true,
+			wantMessage: "",
+		},
 		{
 			name: "empty recipients",
 			setup: func(s *smtp) {
@@ -200,7 +258,7 @@ func TestSend(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &smtp{}
+			s := &smtp{langResolver: lang.NewResolver()}
 			tt.setup(s)
 			msg, err := s.Send(tt.notification)
 			if tt.wantErr {
@@ -4,6 +4,11 @@ import "github.com/arwoosa/notifaction/service/mail/dao"
 
 type Template interface {
 	Apply(tplfile string) error
+	// ApplyTemplate validates, builds and stores a template that was not read from a file.
+	ApplyTemplate(input *dao.ApplyTemplateInput) error
+	// SaveBuilt stores a template that is already built, e.g. an approved draft, as it is.
+	SaveBuilt(tpl *dao.Template) error
+	IsTemplateExist(name string) (bool, error)
 	List(nextToken string) (*dao.ListTemplateResponse, error)
 	Delete(name string) error
 	Detail(name string) (*dao.DetailTemplateResponse, error)
@@ -17,3 +22,32 @@ type TemplateStore interface {
 	List(token string) (*dao.ListTemplateResponse, error)
 	Detail(name string) (*dao.DetailTemplateResponse, error)
 }
+
+type VariableStore interface {
+	// Save stores the variables of a template, replacing the previous ones of the same event and lang.
+	Save(tpl *dao.Template) error
+	// Get returns the variables of all languages of the event merged together.
+	Get(event string) (dao.Variables, error)
+	Delete(name string) error
+}
+
+type Lister interface {
+	List(nextToken string) (*dao.ListTemplateResponse, error)
+}
+
+// ListAll returns the templates of every page, following NextToken until it is empty.
+func ListAll(lister Lister) ([]*dao.ListTemplate, error) {
+	result := []*dao.ListTemplate{}
+	token := ""
+	for {
+		resp, err := lister.List(token)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp.Templates...)
+		if resp.NextToken == nil || *resp.NextToken == "" || *resp.NextToken == token {
+			return result, nil
+		}
+		token = *resp.NextToken
+	}
+}
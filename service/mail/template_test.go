@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAll(t *testing.T) {
+	pages := map[string]*dao.ListTemplateResponse{
+		"":   {NextToken: strPtr("p2"), Templates: []*dao.ListTemplate{{Name: "a_en"}, {Name: "a_ja"}}},
+		"p2": {NextToken: strPtr("p3"), Templates: []*dao.ListTemplate{{Name: "b_en"}}},
+		"p3": {NextToken: strPtr(""), Templates: []*dao.ListTemplate{{Name: "c_en"}}},
+	}
+	store := NewMockTemplateStore(WithListTemplate(func(token string) (*dao.ListTemplateResponse, error) {
+		return pages[token], nil
+	}))
+	tpls, err := ListAll(store)
+	assert.NoError(t, err)
+	names := []string{}
+	for _, tpl := range tpls {
+		names = append(names, tpl.Name)
+	}
+	assert.Equal(t, []string{"a_en", "a_ja", "b_en", "c_en"}, names)
+
+	store = NewMockTemplateStore(WithListTemplate(func(token string) (*dao.ListTemplateResponse, error) {
+		return nil, errors.New("list error")
+	}))
+	_, err = ListAll(store)
+	assert.EqualError(t, err, "list error")
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,349 @@
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	commentRegexp  = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	compoundRegexp = regexp.MustCompile(`^(\*|[a-zA-Z][a-zA-Z0-9-]*)?((\.[a-zA-Z0-9_-]+)|(#[a-zA-Z0-9_-]+))*$`)
+	partRegexp     = regexp.MustCompile(`[.#]?[a-zA-Z0-9_*-]+`)
+)
+
+type declaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+type cssRule struct {
+	selector    []*compound
+	specificity [3]int
+	order       int
+	decls       []*declaration
+}
+
+// InlineCss moves the rules of <style> elements into the style attribute of the matching elements.
+// Only tag, class, id and descendant selectors are inlined; at-rules such as @media and rules
+// with other selectors are kept in the <style> element.
+func InlineCss(body string) (string, error) {
+	root, isDoc, err := parse(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	var rules []*cssRule
+	var styles []*html.Node
+	walk(root, func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "style" {
+			styles = append(styles, n)
+		}
+	})
+	for _, style := range styles {
+		css := ""
+		for c := style.FirstChild; c != nil; c = c.NextSibling {
+			css += c.Data
+		}
+		parsed, remaining := parseCss(css, len(rules))
+		rules = append(rules, parsed...)
+		if strings.TrimSpace(remaining) == "" {
+			style.Parent.RemoveChild(style)
+			continue
+		}
+		for style.FirstChild != nil {
+			style.RemoveChild(style.FirstChild)
+		}
+		style.AppendChild(&html.Node{Type: html.TextNode, Data: remaining})
+	}
+	if len(rules) == 0 {
+		return body, nil
+	}
+
+	walk(root, func(n *html.Node) {
+		if n.Type == html.ElementNode && (isDoc || n != root) {
+			applyRules(n, rules)
+		}
+	})
+
+	var buf bytes.Buffer
+	if isDoc {
+		err = html.Render(&buf, root)
+	} else {
+		for c := root.FirstChild; c != nil && err == nil; c = c.NextSibling {
+			err = html.Render(&buf, c)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to render html: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func walk(n *html.Node, f func(*html.Node)) {
+	f(n)
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		walk(c, f)
+		c = next
+	}
+}
+
+// parseCss returns the inlinable rules and the css that must stay in the style element.
+func parseCss(css string, order int) ([]*cssRule, string) {
+	css = commentRegexp.ReplaceAllString(css, "")
+	var rules []*cssRule
+	var remaining strings.Builder
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			break
+		}
+		open := strings.Index(css, "{")
+		if open < 0 {
+			remaining.WriteString(css)
+			break
+		}
+		end := matchingBrace(css, open)
+		if end < 0 {
+			remaining.WriteString(css)
+			break
+		}
+		prelude := strings.TrimSpace(css[:open])
+		block := css[open+1 : end]
+		raw := css[:end+1]
+		css = css[end+1:]
+
+		if strings.HasPrefix(prelude, "@") {
+			remaining.WriteString(raw + "\n")
+			continue
+		}
+		decls := parseDeclarations(block)
+		kept := []string{}
+		for _, sel := range strings.Split(prelude, ",") {
+			sel = strings.TrimSpace(sel)
+			parsed, ok := parseSelector(sel)
+			if !ok {
+				kept = append(kept, sel)
+				continue
+			}
+			rules = append(rules, &cssRule{
+				selector:    parsed,
+				specificity: specificity(parsed),
+				order:       order,
+				decls:       decls,
+			})
+			order++
+		}
+		if len(kept) > 0 {
+			remaining.WriteString(strings.Join(kept, ", ") + " {" + block + "}\n")
+		}
+	}
+	return rules, remaining.String()
+}
+
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseDeclarations(block string) []*declaration {
+	var decls []*declaration
+	for _, d := range strings.Split(block, ";") {
+		prop, value, ok := strings.Cut(d, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		value = strings.TrimSpace(value)
+		if prop == "" || value == "" {
+			continue
+		}
+		important := false
+		if idx := strings.Index(strings.ToLower(value), "!important"); idx >= 0 {
+			important = true
+			value = strings.TrimSpace(value[:idx])
+		}
+		decls = append(decls, &declaration{property: prop, value: value, important: important})
+	}
+	return decls
+}
+
+func parseSelector(sel string) ([]*compound, bool) {
+	parts := strings.Fields(sel)
+	if len(parts) == 0 {
+		return nil, false
+	}
+	result := make([]*compound, len(parts))
+	for i, part := range parts {
+		if !compoundRegexp.MatchString(part) {
+			return nil, false
+		}
+		c := &compound{}
+		for _, p := range partRegexp.FindAllString(part, -1) {
+			switch p[0] {
+			case '.':
+				c.classes = append(c.classes, p[1:])
+			case '#':
+				c.id = p[1:]
+			default:
+				c.tag = strings.ToLower(p)
+			}
+		}
+		result[i] = c
+	}
+	return result, true
+}
+
+func specificity(sel []*compound) [3]int {
+	var s [3]int
+	for _, c := range sel {
+		if c.id != "" {
+			s[0]++
+		}
+		s[1] += len(c.classes)
+		if c.tag != "" && c.tag != "*" {
+			s[2]++
+		}
+	}
+	return s
+}
+
+func (c *compound) match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if c.tag != "" && c.tag != "*" && c.tag != n.Data {
+		return false
+	}
+	if c.id != "" && attr(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(attr(n, "class"))
+		for _, want := range c.classes {
+			found := false
+			for _, class := range classes {
+				if class == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchSelector(sel []*compound, n *html.Node) bool {
+	last := len(sel) - 1
+	if !sel[last].match(n) {
+		return false
+	}
+	idx := last - 1
+	for p := n.Parent; p != nil && idx >= 0; p = p.Parent {
+		if sel[idx].match(p) {
+			idx--
+		}
+	}
+	return idx < 0
+}
+
+func less(a, b *cssRule) bool {
+	for i := range a.specificity {
+		if a.specificity[i] != b.specificity[i] {
+			return a.specificity[i] < b.specificity[i]
+		}
+	}
+	return a.order < b.order
+}
+
+func applyRules(n *html.Node, rules []*cssRule) {
+	var matched []*cssRule
+	for _, r := range rules {
+		if matchSelector(r.selector, n) {
+			matched = append(matched, r)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return less(matched[i], matched[j])
+	})
+
+	inline := parseDeclarations(attr(n, "style"))
+	props := []string{}
+	values := map[string]string{}
+	set := func(d *declaration) {
+		if _, ok := values[d.property]; !ok {
+			props = append(props, d.property)
+		}
+		values[d.property] = d.value
+	}
+	// normal declarations, then inline style, then !important ones
+	for _, r := range matched {
+		for _, d := range r.decls {
+			if !d.important {
+				set(d)
+			}
+		}
+	}
+	for _, d := range inline {
+		if !d.important {
+			set(d)
+		}
+	}
+	for _, r := range matched {
+		for _, d := range r.decls {
+			if d.important {
+				set(d)
+			}
+		}
+	}
+	for _, d := range inline {
+		if d.important {
+			set(d)
+		}
+	}
+
+	decls := make([]string, len(props))
+	for i, p := range props {
+		decls[i] = p + ": " + values[p]
+	}
+	setAttr(n, "style", strings.Join(decls, "; "))
+}
+
+func setAttr(n *html.Node, key, value string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
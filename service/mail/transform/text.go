@@ -0,0 +1,204 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var blockElements = map[string]bool{
+	"p": true, "div": true, "table": true, "tr": true, "ul": true, "ol": true, "li": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "pre": true, "section": true, "article": true, "header": true, "footer": true,
+}
+
+// paragraphElements are separated from their siblings by a blank line.
+var paragraphElements = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+var skipElements = map[string]bool{
+	"head": true, "style": true, "script": true, "title": true,
+}
+
+// markers keep line breaks and list indents apart from the collapsed source whitespace
+const (
+	newlineMarker = "\x00"
+	indentMarker  = "\x01"
+)
+
+var (
+	spacesRegexp  = regexp.MustCompile(`[ \t\r\n\f]+`)
+	lineEndRegexp = regexp.MustCompile(` *\x00 *`)
+	blankRegexp   = regexp.MustCompile(`\n{3,}`)
+	markerReplace = strings.NewReplacer(newlineMarker, "\n", indentMarker, "  ")
+)
+
+// HtmlToText converts an HTML body to plain text. Links become "text (url)",
+// list items are kept as "- item" or "1. item" and images are replaced by their alt text.
+func HtmlToText(body string) (string, error) {
+	root, _, err := parse(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %w", err)
+	}
+	w := &textWriter{}
+	w.children(root)
+	text := lineEndRegexp.ReplaceAllString(w.String(), newlineMarker)
+	text = markerReplace.Replace(text)
+	text = blankRegexp.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text) + "\n", nil
+}
+
+type listState struct {
+	ordered bool
+	index   int
+}
+
+type textWriter struct {
+	buf   []byte
+	lists []*listState
+}
+
+func (w *textWriter) WriteString(s string) {
+	w.buf = append(w.buf, s...)
+}
+
+func (w *textWriter) String() string {
+	return string(w.buf)
+}
+
+// newline ends the current line so that at least count line breaks precede the next text.
+func (w *textWriter) newline(count int) {
+	trimmed := strings.TrimRight(string(w.buf), " ")
+	existing := len(trimmed) - len(strings.TrimRight(trimmed, newlineMarker))
+	w.buf = []byte(trimmed)
+	if existing < count {
+		w.WriteString(strings.Repeat(newlineMarker, count-existing))
+	}
+}
+
+func (w *textWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+func (w *textWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.WriteString(spacesRegexp.ReplaceAllString(n.Data, " "))
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+
+	if skipElements[n.Data] {
+		return
+	}
+	switch n.Data {
+	case "br":
+		w.newline(1)
+	case "hr":
+		w.newline(1)
+		w.WriteString("----------")
+		w.newline(1)
+	case "img":
+		if alt := attr(n, "alt"); alt != "" {
+			w.WriteString(alt)
+		}
+	case "a":
+		inner := &textWriter{lists: w.lists}
+		inner.children(n)
+		text := strings.TrimSpace(inner.String())
+		href := attr(n, "href")
+		switch {
+		case href == "" || strings.HasPrefix(href, "#"):
+			w.WriteString(text)
+		case text == "" || text == href || strings.TrimPrefix(href, "mailto:") == text:
+			w.WriteString(href)
+		default:
+			w.WriteString(fmt.Sprintf("%s (%s)", text, href))
+		}
+	case "ul", "ol":
+		w.lists = append(w.lists, &listState{ordered: n.Data == "ol"})
+		w.newline(1)
+		w.children(n)
+		w.lists = w.lists[:len(w.lists)-1]
+		w.newline(1)
+	case "li":
+		w.newline(1)
+		indent := ""
+		var list *listState
+		if len(w.lists) > 0 {
+			list = w.lists[len(w.lists)-1]
+			indent = strings.Repeat(indentMarker, len(w.lists)-1)
+		}
+		if list != nil && list.ordered {
+			list.index++
+			w.WriteString(fmt.Sprintf("%s%d. ", indent, list.index))
+		} else {
+			w.WriteString(indent + "- ")
+		}
+		inner := &textWriter{lists: w.lists}
+		inner.children(n)
+		w.WriteString(strings.TrimSpace(inner.String()))
+		w.newline(1)
+	case "td", "th":
+		w.children(n)
+		w.WriteString(" ")
+	default:
+		if !blockElements[n.Data] {
+			w.children(n)
+			return
+		}
+		if paragraphElements[n.Data] {
+			w.newline(2)
+			w.children(n)
+			w.newline(2)
+			return
+		}
+		w.newline(1)
+		w.children(n)
+		w.newline(1)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func isDocument(body string) bool {
+	lower := strings.ToLower(body)
+	return strings.Contains(lower, "<html") || strings.Contains(lower, "<!doctype")
+}
+
+// parse returns the root of a full document, or a body element holding the nodes of a fragment.
+func parse(body string) (*html.Node, bool, error) {
+	if isDocument(body) {
+		doc, err := html.Parse(strings.NewReader(body))
+		return doc, true, err
+	}
+	root := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(body), root)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	return root, false, nil
+}
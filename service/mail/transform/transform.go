@@ -0,0 +1,40 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/arwoosa/notifaction/service/mail/layout"
+)
+
+// Apply applies the options of a template to its body before the template is stored.
+func Apply(tpl *dao.Template) error {
+	if tpl.Options.InlineCss && tpl.Body.Html != "" {
+		html, err := InlineCss(tpl.Body.Html)
+		if err != nil {
+			return fmt.Errorf("failed to inline css: %w", err)
+		}
+		tpl.Body.Html = html
+	}
+	if tpl.Options.GeneratePlaint && tpl.Body.Plaint == "" && tpl.Body.Html != "" {
+		text, err := HtmlToText(tpl.Body.Html)
+		if err != nil {
+			return fmt.Errorf("failed to generate plain text: %w", err)
+		}
+		tpl.Body.Plaint = text
+	}
+	return nil
+}
+
+// Build returns a copy of tpl with its layout and partials resolved and its options applied,
+// which is the content that is sent. layouts may be nil when none are configured.
+func Build(tpl *dao.Template, layouts *layout.Set) (*dao.Template, error) {
+	built, err := layouts.Resolve(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve layout of template %s: %w", tpl.GetName(), err)
+	}
+	if err := Apply(built); err != nil {
+		return nil, err
+	}
+	return built, nil
+}
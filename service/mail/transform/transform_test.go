@@ -0,0 +1,117 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHtmlToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "paragraphs and links",
+			html: `<p>親愛的用戶您好，{{CREATOR_NAME}}婉拒您加入 {{EVENT_NAME}}活動</p>
+<p>點此進入OOSA查看活動詳情👉🏻：<a href="https://{{X-FORWARDED-HOST}}">OOSA</a></p>
+<br/>
+<p>OOSA-人類野放計劃 團隊敬上</p>`,
+			want: "親愛的用戶您好，{{CREATOR_NAME}}婉拒您加入 {{EVENT_NAME}}活動\n\n" +
+				"點此進入OOSA查看活動詳情👉🏻：OOSA (https://{{X-FORWARDED-HOST}})\n\n" +
+				"OOSA-人類野放計劃 團隊敬上\n",
+		},
+		{
+			name: "link text equals url",
+			html: `<a href="https://oosa.life">https://oosa.life</a> <a href="mailto:a@oosa.life">a@oosa.life</a>`,
+			want: "https://oosa.life mailto:a@oosa.life\n",
+		},
+		{
+			name: "lists",
+			html: `<ul>
+  <li>first</li>
+  <li>second
+    <ol><li>one</li><li>two</li></ol>
+  </li>
+</ul>`,
+			want: "- first\n- second\n  1. one\n  2. two\n",
+		},
+		{
+			name: "document with style and image",
+			html: `<!DOCTYPE html><html><head><title>t</title><style>p{color:red}</style></head>
+<body><h1>Title</h1><img src="logo.png" alt="OOSA"><table><tr><td>a</td><td>b</td></tr></table></body></html>`,
+			want: "Title\n\nOOSA\na b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HtmlToText(tt.html)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInlineCss(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "no style",
+			html: `<p>text</p>`,
+			want: `<p>text</p>`,
+		},
+		{
+			name: "tag, class and id selectors with specificity",
+			html: `<style>
+/* comment */
+p { color: red; margin: 0 }
+.note { color: blue }
+#main { color: green }
+</style><p id="main" class="note" style="margin: 4px">a</p><p class="note">b</p><p>c</p>`,
+			want: `<p id="main" class="note" style="color: green; margin: 4px">a</p><p class="note" style="color: blue; margin: 0">b</p><p style="color: red; margin: 0">c</p>`,
+		},
+		{
+			name: "descendant selector and important",
+			html: `<style>div a { color: red !important } a { color: blue }</style><div><p><a href="https://{{HOST}}" style="color: black">x</a></p></div><a>y</a>`,
+			want: `<div><p><a href="https://{{HOST}}" style="color: red">x</a></p></div><a style="color: blue">y</a>`,
+		},
+		{
+			name: "keeps media queries and pseudo classes",
+			html: `<style>@media (max-width: 600px) { p { color: red } } a:hover { color: red } p, a { color: blue }</style><p>x</p>`,
+			want: "<style>@media (max-width: 600px) { p { color: red } }\na:hover { color: red }\n</style><p style=\"color: blue\">x</p>",
+		},
+		{
+			name: "full document",
+			html: `<html><head><style>body { margin: 0 }</style></head><body><p>x</p></body></html>`,
+			want: `<html><head></head><body style="margin: 0"><p>x</p></body></html>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InlineCss(tt.html)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	input := dao.NewTemplate("event", "en", "subject", "", `<style>p { color: red }</style><p>Hi {{NAME}}</p>`)
+	assert.NoError(t, Apply(input))
+	assert.Equal(t, "", input.Body.Plaint)
+	assert.Equal(t, `<style>p { color: red }</style><p>Hi {{NAME}}</p>`, input.Body.Html)
+
+	input.Options = dao.ApplyOptions{GeneratePlaint: true, InlineCss: true}
+	assert.NoError(t, Apply(input))
+	assert.Equal(t, "Hi {{NAME}}\n", input.Body.Plaint)
+	assert.Equal(t, `<p style="color: red">Hi {{NAME}}</p>`, input.Body.Html)
+
+	input.Body.Plaint = "hand written"
+	assert.NoError(t, Apply(input))
+	assert.Equal(t, "hand written", input.Body.Plaint)
+}
@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NewMockStore returns a store keeping the changes in memory.
+func NewMockStore() Store {
+	return &mockStore{changes: map[string][]byte{}}
+}
+
+// mockStore keeps the changes marshalled the way the mongo store does, so callers never share them.
+type mockStore struct {
+	mu      sync.Mutex
+	changes map[string][]byte
+}
+
+func (m *mockStore) Get(event string) (*Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.changes[event]
+	if !ok {
+		return nil, nil
+	}
+	var change Change
+	if err := yaml.Unmarshal(data, &change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (m *mockStore) Save(change *Change) error {
+	data, err := yaml.Marshal(change)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.changes[change.Event] = data
+	return nil
+}
+
+func (m *mockStore) List() ([]*Change, error) {
+	m.mu.Lock()
+	events := make([]string, 0, len(m.changes))
+	for event := range m.changes {
+		events = append(events, event)
+	}
+	m.mu.Unlock()
+	sort.Strings(events)
+	changes := make([]*Change, 0, len(events))
+	for _, event := range events {
+		change, err := m.Get(event)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
@@ -0,0 +1,88 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/yaml.v2"
+)
+
+// NewMongoStore returns the store of the changes in collection, keyed by event, so that every
+// replica and every CLI of the deployment shares the drafts and their audit trail.
+func NewMongoStore(collection *mongo.Collection) Store {
+	return &mongoStore{collection: collection, timeout: 5 * time.Second}
+}
+
+// changeDocument holds the change as YAML, the format of the template files it is drafted from.
+type changeDocument struct {
+	Event  string `bson:"_id"`
+	Status Status `bson:"status"`
+	Change string `bson:"change"`
+}
+
+func (d *changeDocument) decode() (*Change, error) {
+	var change Change
+	if err := yaml.Unmarshal([]byte(d.Change), &change); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal change %s: %w", d.Event, err)
+	}
+	return &change, nil
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func (m *mongoStore) Get(event string) (*Change, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	doc := &changeDocument{}
+	err := m.collection.FindOne(ctx, bson.M{"_id": event}).Decode(doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.decode()
+}
+
+func (m *mongoStore) Save(change *Change) error {
+	data, err := yaml.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal change: %w", err)
+	}
+	doc := &changeDocument{Event: change.Event, Status: change.Status, Change: string(data)}
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err = m.collection.ReplaceOne(ctx, bson.M{"_id": change.Event}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+// List returns the changes sorted by event.
+func (m *mongoStore) List() ([]*Change, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	cursor, err := m.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	docs := []*changeDocument{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	changes := make([]*Change, 0, len(docs))
+	for _, doc := range docs {
+		change, err := doc.decode()
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
@@ -0,0 +1,397 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+)
+
+// DraftPrefix is prepended to the event of a draft, so drafts are stored next to the published
+// templates and can be previewed, while senders refuse to resolve them.
+const DraftPrefix = mail.DraftPrefix
+
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusApproved  Status = "approved"
+	StatusPublished Status = "published"
+)
+
+const (
+	ActionDraft   = "draft"
+	ActionApprove = "approve"
+	ActionPublish = "publish"
+)
+
+func DraftEvent(event string) string {
+	return DraftPrefix + event
+}
+
+// IsDraftName reports whether a template name is the one of a draft.
+func IsDraftName(name string) bool {
+	return strings.HasPrefix(name, DraftPrefix)
+}
+
+// UserOf returns the user an authenticated identity is recorded as in the audit trail and matched
+// against the approvers: its email, or its sub when it has none.
+func UserOf(info *service.Info) (string, error) {
+	if info == nil || !info.Enable {
+		return "", errors.New("identity is not active")
+	}
+	if info.Email != "" {
+		return info.Email, nil
+	}
+	if info.Sub == "" {
+		return "", errors.New("identity has no sub")
+	}
+	return info.Sub, nil
+}
+
+// Entry is one action of the audit trail of a change.
+type Entry struct {
+	Action string    `yaml:"action"`
+	User   string    `yaml:"user"`
+	Time   time.Time `yaml:"time"`
+}
+
+// Change is the latest draft of an event with its audit trail.
+type Change struct {
+	Event      string `yaml:"event"`
+	Status     Status `yaml:"status"`
+	DraftedBy  string `yaml:"drafted_by"`
+	ApprovedBy string `yaml:"approved_by,omitempty"`
+	// Input is the template file the change was drafted from, nil for copies and deletions.
+	Input *dao.ApplyTemplateInput `yaml:"input,omitempty"`
+	// Templates are the rendered drafts, published as they are once approved.
+	Templates []*dao.Template `yaml:"templates,omitempty"`
+	// Deletes are the names of the templates deleted when the change is published.
+	Deletes []string `yaml:"deletes,omitempty"`
+	History []*Entry `yaml:"history"`
+}
+
+// DraftNames returns the names of the draft templates of the change.
+func (c *Change) DraftNames() []string {
+	names := []string{}
+	for _, tpl := range c.Templates {
+		names = append(names, service.GetTemplateName(DraftEvent(tpl.Event), tpl.Lang))
+	}
+	return names
+}
+
+// TemplateNames returns the names of the templates the change publishes.
+func (c *Change) TemplateNames() []string {
+	names := []string{}
+	for _, tpl := range c.Templates {
+		names = append(names, tpl.GetName())
+	}
+	return names
+}
+
+func (c *Change) pending() bool {
+	return c.Status == StatusDraft || c.Status == StatusApproved
+}
+
+type Store interface {
+	// Get returns nil without error when the event has no change.
+	Get(event string) (*Change, error)
+	Save(change *Change) error
+	List() ([]*Change, error)
+}
+
+type workflowOpt func(*Workflow)
+
+// WithApprovers sets the users allowed to approve a draft of another user, see UserOf.
+func WithApprovers(users ...string) workflowOpt {
+	return func(w *Workflow) {
+		w.approvers = users
+	}
+}
+
+func WithNow(now func() time.Time) workflowOpt {
+	return func(w *Workflow) {
+		w.now = now
+	}
+}
+
+// New returns a workflow storing drafts and published templates through tpl and changes in store.
+func New(tpl mail.Template, store Store, opts ...workflowOpt) (*Workflow, error) {
+	if tpl == nil {
+		return nil, errors.New("template is nil")
+	}
+	if store == nil {
+		return nil, errors.New("store is nil")
+	}
+	w := &Workflow{tpl: tpl, store: store, now: time.Now}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
+}
+
+type Workflow struct {
+	tpl       mail.Template
+	store     Store
+	approvers []string
+	now       func() time.Time
+}
+
+func (w *Workflow) record(change *Change, action, user string) {
+	change.History = append(change.History, &Entry{Action: action, User: user, Time: w.now().UTC()})
+}
+
+func draftInput(input *dao.ApplyTemplateInput) *dao.ApplyTemplateInput {
+	draft := *input
+	draft.Event = DraftEvent(input.Event)
+	return &draft
+}
+
+// Draft stores input as the draft of its event, replacing a previous draft and its approval.
+// The rendered drafts are kept in the change, so that the content approved is the one published.
+func (w *Workflow) Draft(user string, input *dao.ApplyTemplateInput) (*Change, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	change, err := w.begin(user, input.Event)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.tpl.ApplyTemplate(draftInput(input)); err != nil {
+		return nil, err
+	}
+	tpls := []*dao.Template{}
+	for _, tpl := range input.Expand() {
+		rendered, err := w.rendered(tpl)
+		if err != nil {
+			return nil, err
+		}
+		tpls = append(tpls, rendered)
+	}
+	return w.draft(user, change, input, tpls, nil)
+}
+
+// DraftTemplates stores templates rendered elsewhere, e.g. copied from another SES, as the draft of event.
+func (w *Workflow) DraftTemplates(user, event string, tpls []*dao.Template) (*Change, error) {
+	if len(tpls) == 0 {
+		return nil, errors.New("templates are required")
+	}
+	drafts := make([]*dao.Template, len(tpls))
+	for i, tpl := range tpls {
+		if tpl.Event != event {
+			return nil, fmt.Errorf("template %s is not of event %s", tpl.GetName(), event)
+		}
+		if err := tpl.Validate(); err != nil {
+			return nil, fmt.Errorf("template %s: %w", tpl.GetName(), err)
+		}
+		draft := *tpl
+		draft.Variables = slices.Clone(tpl.Variables)
+		draft.InferVariables()
+		drafts[i] = &draft
+	}
+	change, err := w.begin(user, event)
+	if err != nil {
+		return nil, err
+	}
+	for _, tpl := range drafts {
+		draft := *tpl
+		draft.Event = DraftEvent(tpl.Event)
+		if err := w.tpl.SaveBuilt(&draft); err != nil {
+			return nil, err
+		}
+	}
+	return w.draft(user, change, nil, drafts, nil)
+}
+
+// DraftDelete drafts the deletion of the template name. Deletions of the same event add up
+// until the change is published.
+func (w *Workflow) DraftDelete(user, name string) (*Change, error) {
+	event, _, err := service.ParseTemplateName(name)
+	if err != nil {
+		return nil, err
+	}
+	change, err := w.begin(user, event)
+	if err != nil {
+		return nil, err
+	}
+	exist, err := w.tpl.IsTemplateExist(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, fmt.Errorf("template %s does not exist", name)
+	}
+	deletes := []string{name}
+	if change.pending() && len(change.Templates) == 0 {
+		deletes = change.Deletes
+		if !slices.Contains(deletes, name) {
+			deletes = append(deletes, name)
+		}
+	}
+	return w.draft(user, change, nil, nil, deletes)
+}
+
+// begin returns the change of event to draft, a new one when the event has none.
+func (w *Workflow) begin(user, event string) (*Change, error) {
+	if user == "" {
+		return nil, errors.New("user is required")
+	}
+	if strings.HasPrefix(event, DraftPrefix) {
+		return nil, fmt.Errorf("event can not start with %s", DraftPrefix)
+	}
+	change, err := w.store.Get(event)
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		change = &Change{Event: event}
+	}
+	return change, nil
+}
+
+// rendered returns the stored draft of tpl as the template to publish.
+func (w *Workflow) rendered(tpl *dao.Template) (*dao.Template, error) {
+	name := service.GetTemplateName(DraftEvent(tpl.Event), tpl.Lang)
+	detail, err := w.tpl.Detail(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read draft %s: %w", name, err)
+	}
+	rendered := dao.NewTemplate(tpl.Event, tpl.Lang, detail.Subject, detail.Body.Plaint, detail.Body.Html)
+	rendered.Variables = slices.Clone(tpl.Variables)
+	rendered.InferVariables()
+	return rendered, nil
+}
+
+// draft replaces the content of change, resetting its approval, and removes the drafts it no longer has.
+func (w *Workflow) draft(user string, change *Change, input *dao.ApplyTemplateInput, tpls []*dao.Template, deletes []string) (*Change, error) {
+	var stale []string
+	if change.pending() {
+		stale = change.DraftNames()
+	}
+	change.Status = StatusDraft
+	change.DraftedBy = user
+	change.ApprovedBy = ""
+	change.Input = input
+	change.Templates = tpls
+	change.Deletes = deletes
+	w.record(change, ActionDraft, user)
+	if err := w.store.Save(change); err != nil {
+		return nil, err
+	}
+	current := change.DraftNames()
+	stale = slices.DeleteFunc(stale, func(name string) bool {
+		return slices.Contains(current, name)
+	})
+	if err := w.deleteDrafts(stale); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (w *Workflow) deleteDrafts(names []string) error {
+	for _, name := range names {
+		exist, err := w.tpl.IsTemplateExist(name)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			continue
+		}
+		if err := w.tpl.Delete(name); err != nil {
+			return fmt.Errorf("failed to delete draft %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Approve approves the draft of event. The approver has to be a configured approver other than the drafter.
+func (w *Workflow) Approve(user, event string) (*Change, error) {
+	if user == "" {
+		return nil, errors.New("user is required")
+	}
+	if !slices.Contains(w.approvers, user) {
+		return nil, fmt.Errorf("user %s is not an approver", user)
+	}
+	change, err := w.get(event)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != StatusDraft {
+		return nil, fmt.Errorf("template %s is %s, only drafts can be approved", event, change.Status)
+	}
+	if change.DraftedBy == user {
+		return nil, fmt.Errorf("template %s must be approved by a user other than %s", event, user)
+	}
+	change.Status = StatusApproved
+	change.ApprovedBy = user
+	w.record(change, ActionApprove, user)
+	if err := w.store.Save(change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// Publish stores the rendered templates of the approved change as the published ones, deletes the
+// templates of its deletions and removes the draft templates.
+func (w *Workflow) Publish(user, event string) (*Change, error) {
+	if user == "" {
+		return nil, errors.New("user is required")
+	}
+	change, err := w.get(event)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != StatusApproved {
+		return nil, fmt.Errorf("template %s is %s, it must be approved before publishing", event, change.Status)
+	}
+	for _, tpl := range change.Templates {
+		if err := w.tpl.SaveBuilt(tpl); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range change.Deletes {
+		exist, err := w.tpl.IsTemplateExist(name)
+		if err != nil {
+			return nil, err
+		}
+		if !exist {
+			continue
+		}
+		if err := w.tpl.Delete(name); err != nil {
+			return nil, fmt.Errorf("failed to delete template %s: %w", name, err)
+		}
+	}
+	if err := w.deleteDrafts(change.DraftNames()); err != nil {
+		return nil, err
+	}
+	change.Status = StatusPublished
+	w.record(change, ActionPublish, user)
+	if err := w.store.Save(change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (w *Workflow) Get(event string) (*Change, error) {
+	return w.get(event)
+}
+
+func (w *Workflow) List() ([]*Change, error) {
+	return w.store.List()
+}
+
+func (w *Workflow) get(event string) (*Change, error) {
+	change, err := w.store.Get(event)
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		return nil, fmt.Errorf("template %s has no draft", event)
+	}
+	return change, nil
+}
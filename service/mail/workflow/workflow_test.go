@@ -0,0 +1,292 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTemplate renders applied templates by wrapping their html in layout, standing in for
+// the layouts and partials that may change between drafting and publishing.
+type fakeTemplate struct {
+	stored   map[string]*dao.Template
+	layout   string
+	applyErr error
+}
+
+func newFakeTemplate() *fakeTemplate {
+	return &fakeTemplate{stored: map[string]*dao.Template{}, layout: "v1"}
+}
+
+func (f *fakeTemplate) Apply(tplfile string) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeTemplate) ApplyTemplate(input *dao.ApplyTemplateInput) error {
+	if f.applyErr != nil {
+		return f.applyErr
+	}
+	for _, tpl := range input.Expand() {
+		built := *tpl
+		built.Body.Html = f.layout + ":" + tpl.Body.Html
+		f.stored[tpl.GetName()] = &built
+	}
+	return nil
+}
+
+func (f *fakeTemplate) SaveBuilt(tpl *dao.Template) error {
+	saved := *tpl
+	f.stored[tpl.GetName()] = &saved
+	return nil
+}
+
+func (f *fakeTemplate) IsTemplateExist(name string) (bool, error) {
+	_, ok := f.stored[name]
+	return ok, nil
+}
+
+func (f *fakeTemplate) List(nextToken string) (*dao.ListTemplateResponse, error) {
+	return &dao.ListTemplateResponse{}, nil
+}
+
+func (f *fakeTemplate) Delete(name string) error {
+	delete(f.stored, name)
+	return nil
+}
+
+func (f *fakeTemplate) Detail(name string) (*dao.DetailTemplateResponse, error) {
+	tpl, ok := f.stored[name]
+	if !ok {
+		return nil, errors.New("template does not exist")
+	}
+	detail := &dao.DetailTemplateResponse{Title: name, Subject: tpl.Subject}
+	detail.Body.Html = tpl.Body.Html
+	detail.Body.Plaint = tpl.Body.Plaint
+	return detail, nil
+}
+
+func newInput(subject string) *dao.ApplyTemplateInput {
+	input := &dao.ApplyTemplateInput{}
+	input.Event = "welcome"
+	input.Subject = subject
+	input.Body.Html = "<p>Hi</p>"
+	input.Locales = map[string]*dao.Locale{"en": {}, "ja": {}}
+	return input
+}
+
+func newTestWorkflow(t *testing.T, tpl *fakeTemplate) *Workflow {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	wf, err := New(tpl, NewMockStore(), WithApprovers("alice", "bob"), WithNow(func() time.Time { return now }))
+	assert.NoError(t, err)
+	return wf
+}
+
+func TestWorkflow(t *testing.T) {
+	tpl := newFakeTemplate()
+	wf := newTestWorkflow(t, tpl)
+
+	change, err := wf.Draft("alice", newInput("Hi"))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDraft, change.Status)
+	assert.Equal(t, []string{"draft-welcome_en", "draft-welcome_ja"}, change.DraftNames())
+	assert.Contains(t, tpl.stored, "draft-welcome_en")
+	assert.NotContains(t, tpl.stored, "welcome_en")
+
+	_, err = wf.Publish("alice", "welcome")
+	assert.EqualError(t, err, "template welcome is draft, it must be approved before publishing")
+	_, err = wf.Approve("alice", "welcome")
+	assert.EqualError(t, err, "template welcome must be approved by a user other than alice")
+	_, err = wf.Approve("carol", "welcome")
+	assert.EqualError(t, err, "user carol is not an approver")
+
+	change, err = wf.Approve("bob", "welcome")
+	assert.NoError(t, err)
+	assert.Equal(t, StatusApproved, change.Status)
+	assert.Equal(t, "bob", change.ApprovedBy)
+
+	// the layout changing after the approval does not change what is published
+	tpl.layout = "v2"
+	change, err = wf.Publish("carol", "welcome")
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPublished, change.Status)
+	assert.Equal(t, []string{"welcome_en", "welcome_ja"}, change.TemplateNames())
+	assert.Equal(t, "v1:<p>Hi</p>", tpl.stored["welcome_en"].Body.Html)
+	assert.Contains(t, tpl.stored, "welcome_ja")
+	assert.NotContains(t, tpl.stored, "draft-welcome_en")
+
+	// the change is read back from the store with its audit trail
+	change, err = wf.Get("welcome")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi", change.Input.Subject)
+	assert.Equal(t, "v1:<p>Hi</p>", change.Templates[0].Body.Html)
+	actions := []string{}
+	for _, h := range change.History {
+		actions = append(actions, h.Action+":"+h.User)
+	}
+	assert.Equal(t, []string{"draft:alice", "approve:bob", "publish:carol"}, actions)
+
+	// a new draft needs a new approval
+	change, err = wf.Draft("bob", newInput("Hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDraft, change.Status)
+	assert.Empty(t, change.ApprovedBy)
+	assert.Equal(t, "Hi", tpl.stored["welcome_en"].Subject)
+
+	changes, err := wf.List()
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Len(t, changes[0].History, 4)
+
+	// a draft with fewer languages removes the drafts of the languages it dropped
+	input := newInput("Hello")
+	input.Locales = map[string]*dao.Locale{"en": {}}
+	_, err = wf.Draft("bob", input)
+	assert.NoError(t, err)
+	assert.Contains(t, tpl.stored, "draft-welcome_en")
+	assert.NotContains(t, tpl.stored, "draft-welcome_ja")
+}
+
+func TestWorkflowDraftTemplates(t *testing.T) {
+	tpl := newFakeTemplate()
+	wf := newTestWorkflow(t, tpl)
+
+	copied := dao.NewTemplate("welcome", "en", "Hi {{NAME}}", "", "<p>Hi</p>")
+	_, err := wf.DraftTemplates("alice", "other", []*dao.Template{copied})
+	assert.EqualError(t, err, "template welcome_en is not of event other")
+
+	change, err := wf.DraftTemplates("alice", "welcome", []*dao.Template{copied})
+	assert.NoError(t, err)
+	assert.Nil(t, change.Input)
+	assert.Equal(t, []string{"draft-welcome_en"}, change.DraftNames())
+	assert.Equal(t, "<p>Hi</p>", tpl.stored["draft-welcome_en"].Body.Html)
+	assert.NotContains(t, tpl.stored, "welcome_en")
+	assert.True(t, change.Templates[0].Variables.Get("NAME").Required)
+
+	_, err = wf.Approve("bob", "welcome")
+	assert.NoError(t, err)
+	_, err = wf.Publish("bob", "welcome")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi {{NAME}}", tpl.stored["welcome_en"].Subject)
+	assert.NotContains(t, tpl.stored, "draft-welcome_en")
+}
+
+func TestWorkflowDraftDelete(t *testing.T) {
+	tpl := newFakeTemplate()
+	wf := newTestWorkflow(t, tpl)
+	assert.NoError(t, tpl.ApplyTemplate(newInput("Hi")))
+
+	_, err := wf.DraftDelete("alice", "welcome_zh")
+	assert.EqualError(t, err, "template welcome_zh does not exist")
+	_, err = wf.DraftDelete("alice", "welcome")
+	assert.EqualError(t, err, "invalid template name: welcome")
+
+	_, err = wf.DraftDelete("alice", "welcome_en")
+	assert.NoError(t, err)
+	change, err := wf.DraftDelete("alice", "welcome_ja")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"welcome_en", "welcome_ja"}, change.Deletes)
+	// the templates are only deleted once the deletion is approved and published
+	assert.Contains(t, tpl.stored, "welcome_en")
+
+	_, err = wf.Approve("bob", "welcome")
+	assert.NoError(t, err)
+	change, err = wf.Publish("bob", "welcome")
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPublished, change.Status)
+	assert.NotContains(t, tpl.stored, "welcome_en")
+	assert.NotContains(t, tpl.stored, "welcome_ja")
+}
+
+func TestWorkflowDraftErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		input    *dao.ApplyTemplateInput
+		applyErr error
+		wantErr  string
+	}{
+		{
+			name:    "no user",
+			input:   newInput("Hi"),
+			wantErr: "user is required",
+		},
+		{
+			name:    "invalid template",
+			user:    "alice",
+			input:   newInput(""),
+			wantErr: "locale en: subject is required",
+		},
+		{
+			name: "draft event",
+			user: "alice",
+			input: func() *dao.ApplyTemplateInput {
+				input := newInput("Hi")
+				input.Event = "draft-welcome"
+				return input
+			}(),
+			wantErr: "event can not start with draft-",
+		},
+		{
+			name:     "apply failed",
+			user:     "alice",
+			input:    newInput("Hi"),
+			applyErr: errors.New("lint failed"),
+			wantErr:  "lint failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := newFakeTemplate()
+			tpl.applyErr = tt.applyErr
+			wf := newTestWorkflow(t, tpl)
+			_, err := wf.Draft(tt.user, tt.input)
+			assert.EqualError(t, err, tt.wantErr)
+			changes, err := wf.List()
+			assert.NoError(t, err)
+			assert.Empty(t, changes)
+		})
+	}
+}
+
+func TestWorkflowNoDraft(t *testing.T) {
+	wf := newTestWorkflow(t, newFakeTemplate())
+	_, err := wf.Approve("bob", "welcome")
+	assert.EqualError(t, err, "template welcome has no draft")
+	_, err = wf.Publish("bob", "welcome")
+	assert.EqualError(t, err, "template welcome has no draft")
+}
+
+func TestIsDraftName(t *testing.T) {
+	assert.True(t, IsDraftName("draft-welcome_en"))
+	assert.False(t, IsDraftName("welcome_en"))
+}
+
+func TestUserOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    *service.Info
+		want    string
+		wantErr string
+	}{
+		{name: "email", info: &service.Info{Sub: "sub-1", Email: "alice@oosa.life", Enable: true}, want: "alice@oosa.life"},
+		{name: "sub", info: &service.Info{Sub: "sub-1", Enable: true}, want: "sub-1"},
+		{name: "inactive", info: &service.Info{Sub: "sub-1", Email: "alice@oosa.life"}, wantErr: "identity is not active"},
+		{name: "nil", wantErr: "identity is not active"},
+		{name: "no sub", info: &service.Info{Enable: true}, wantErr: "identity has no sub"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := UserOf(tt.info)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, user)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package mobilepush
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arwoosa/notifaction/service/jwt"
+)
+
+const (
+	DefaultApnsEndpoint = "https://api.push.apple.com"
+	SandboxApnsEndpoint = "https://api.sandbox.push.apple.com"
+
+	// apple rejects provider tokens older than an hour and throttles refreshing more often than every 20 minutes
+	apnsTokenLifetime = 50 * time.Minute
+	// apns-collapse-id is limited to 64 bytes
+	maxCollapseId = 64
+)
+
+// LoadApnsKey reads the .p8 signing key downloaded from the apple developer account.
+func LoadApnsKey(file string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apns key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid apns key %s", file)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apns key %s: %w", file, err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("apns key %s is not ecdsa", file)
+	}
+	return ecKey, nil
+}
+
+type apnsOpt func(*apns)
+
+// WithApnsEndpoint replaces https://api.push.apple.com, e.g. by SandboxApnsEndpoint or a local fake,
+// when endpoint is not empty.
+func WithApnsEndpoint(endpoint string) apnsOpt {
+	return func(a *apns) {
+		if endpoint != "" {
+			a.endpoint = strings.TrimRight(endpoint, "/")
+		}
+	}
+}
+
+// WithApnsHttpClient replaces the client, APNs only accepts HTTP/2 so its transport must support it.
+func WithApnsHttpClient(client *http.Client) apnsOpt {
+	return func(a *apns) {
+		a.client = client
+	}
+}
+
+func WithApnsNow(now func() time.Time) apnsOpt {
+	return func(a *apns) {
+		a.now = now
+	}
+}
+
+// NewAPNs returns the provider of APNs with token based authentication, the key of keyId
+// of the team signs the provider tokens and topic is the bundle id of the app.
+func NewAPNs(key *ecdsa.PrivateKey, keyId, teamId, topic string, opts ...apnsOpt) (Provider, error) {
+	if key == nil {
+		return nil, errors.New("apns key is required")
+	}
+	if keyId == "" || teamId == "" || topic == "" {
+		return nil, errors.New("apns needs key id, team id and topic")
+	}
+	a := &apns{
+		key:      key,
+		keyId:    keyId,
+		teamId:   teamId,
+		topic:    topic,
+		endpoint: DefaultApnsEndpoint,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.client == nil {
+		// the default transport negotiates HTTP/2 over tls
+		a.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return a, nil
+}
+
+type apns struct {
+	key      *ecdsa.PrivateKey
+	keyId    string
+	teamId   string
+	topic    string
+	endpoint string
+	client   *http.Client
+	now      func() time.Time
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+func (a *apns) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	if a.token != "" && now.Sub(a.issuedAt) < apnsTokenLifetime {
+		return a.token, nil
+	}
+	token, err := jwt.SignES256(a.key, map[string]any{"kid": a.keyId}, map[string]any{
+		"iss": a.teamId,
+		"iat": now.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	a.token = token
+	a.issuedAt = now
+	return token, nil
+}
+
+func (a *apns) resetToken() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+func (a *apns) Send(token string, msg *Message) (string, error) {
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return "", err
+	}
+	payload := map[string]any{
+		"aps": map[string]any{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound": "default",
+		},
+	}
+	if len(msg.Data) > 0 {
+		payload["data"] = msg.Data
+	}
+	header := http.Header{
+		"Authorization":  {"bearer " + providerToken},
+		"Apns-Topic":     {a.topic},
+		"Apns-Push-Type": {"alert"},
+		"Apns-Priority":  {"10"},
+	}
+	if collapseId := msg.CollapseKey; collapseId != "" {
+		if len(collapseId) > maxCollapseId {
+			collapseId = collapseId[:maxCollapseId]
+		}
+		header.Set("Apns-Collapse-Id", collapseId)
+	}
+	resp, err := postJSON(a.client, a.endpoint+"/3/device/"+token, header, payload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body := readBody(resp)
+	if resp.StatusCode == http.StatusOK {
+		return resp.Header.Get("apns-id"), nil
+	}
+	result := struct {
+		Reason string `json:"reason"`
+	}{}
+	_ = json.Unmarshal(body, &result)
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		result.Reason == "BadDeviceToken", result.Reason == "Unregistered", result.Reason == "DeviceTokenNotForTopic":
+		return "", ErrInvalidToken
+	case result.Reason == "ExpiredProviderToken" || result.Reason == "InvalidProviderToken":
+		a.resetToken()
+	}
+	return "", fmt.Errorf("apns send failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,144 @@
+package mobilepush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeApns is a local stand-in for APNs, it only speaks HTTP/2 like apple does.
+type fakeApns struct {
+	t       *testing.T
+	server  *httptest.Server
+	key     *ecdsa.PublicKey
+	mu      sync.Mutex
+	tokens  map[string]bool
+	headers []http.Header
+	bodies  []map[string]any
+}
+
+func newFakeApns(t *testing.T, key *ecdsa.PublicKey) *fakeApns {
+	f := &fakeApns{t: t, key: key, tokens: map[string]bool{}}
+	f.server = httptest.NewUnstartedServer(http.HandlerFunc(f.handle))
+	f.server.EnableHTTP2 = true
+	f.server.StartTLS()
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeApns) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	assert.Equal(f.t, 2, r.ProtoMajor)
+	bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "bearer ")
+	f.tokens[bearer] = true
+	claims := verifyES256(f.t, bearer, f.key)
+	assert.Equal(f.t, "TEAM1", claims["iss"])
+	f.headers = append(f.headers, r.Header.Clone())
+	body := map[string]any{}
+	assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+	f.bodies = append(f.bodies, body)
+	switch strings.TrimPrefix(r.URL.Path, "/3/device/") {
+	case "gone":
+		w.WriteHeader(http.StatusGone)
+		_, _ = io.WriteString(w, `{"reason":"Unregistered","timestamp":1700000000000}`)
+	case "bad":
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"reason":"BadDeviceToken"}`)
+	case "throttled":
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"reason":"TooManyRequests"}`)
+	default:
+		w.Header().Set("apns-id", "apns-"+strings.TrimPrefix(r.URL.Path, "/3/device/"))
+	}
+}
+
+func verifyES256(t *testing.T, token string, key *ecdsa.PublicKey) map[string]any {
+	parts := strings.Split(token, ".")
+	if !assert.Len(t, parts, 3) {
+		return nil
+	}
+	header := map[string]any{}
+	h, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	assert.NoError(t, json.Unmarshal(h, &header))
+	assert.Equal(t, "KEY1", header["kid"])
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.True(t, ecdsa.Verify(key, hash[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])))
+	claims := map[string]any{}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, json.Unmarshal(payload, &claims))
+	return claims
+}
+
+func writeApnsKey(t *testing.T) (string, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "AuthKey_KEY1.p8")
+	assert.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return file, key
+}
+
+func TestAPNs(t *testing.T) {
+	file, key := writeApnsKey(t)
+	fake := newFakeApns(t, &key.PublicKey)
+	loaded, err := LoadApnsKey(file)
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	provider, err := NewAPNs(loaded, "KEY1", "TEAM1", "com.example.app", WithApnsEndpoint(fake.server.URL),
+		WithApnsHttpClient(fake.server.Client()), WithApnsNow(func() time.Time { return now }))
+	assert.NoError(t, err)
+
+	msg := &Message{Title: "Alice joined", Body: "Say hi", Data: map[string]string{"FROM": "Alice"},
+		CollapseKey: strings.Repeat("k", 70)}
+	id, err := provider.Send("device-1", msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "apns-device-1", id)
+	header := fake.headers[0]
+	assert.Equal(t, "com.example.app", header.Get("apns-topic"))
+	assert.Equal(t, "alert", header.Get("apns-push-type"))
+	assert.Equal(t, "10", header.Get("apns-priority"))
+	assert.Equal(t, strings.Repeat("k", 64), header.Get("apns-collapse-id"))
+	assert.Equal(t, map[string]any{
+		"aps":  map[string]any{"alert": map[string]any{"title": "Alice joined", "body": "Say hi"}, "sound": "default"},
+		"data": map[string]any{"FROM": "Alice"},
+	}, fake.bodies[0])
+
+	_, err = provider.Send("gone", msg)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = provider.Send("bad", msg)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = provider.Send("throttled", msg)
+	assert.EqualError(t, err, `apns send failed with status 429: {"reason":"TooManyRequests"}`)
+	// the provider token is reused until it is 50 minutes old
+	assert.Len(t, fake.tokens, 1)
+	now = now.Add(51 * time.Minute)
+	_, err = provider.Send("device-1", msg)
+	assert.NoError(t, err)
+	assert.Len(t, fake.tokens, 2)
+
+	_, err = NewAPNs(loaded, "", "TEAM1", "com.example.app")
+	assert.EqualError(t, err, "apns needs key id, team id and topic")
+	_, err = LoadApnsKey(filepath.Join(t.TempDir(), "missing.p8"))
+	assert.Error(t, err)
+}
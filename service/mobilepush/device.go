@@ -0,0 +1,47 @@
+package mobilepush
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	Android = "android"
+	IOS     = "ios"
+)
+
+var ErrNotFound = errors.New("device not found")
+
+// Device is an app installation of the user sub, its token addresses the app on FCM or APNs.
+type Device struct {
+	Sub        string    `json:"-" bson:"sub"`
+	Platform   string    `json:"platform" bson:"platform"`
+	Token      string    `json:"token" bson:"token"`
+	AppVersion string    `json:"app_version" bson:"app_version"`
+	LastSeen   time.Time `json:"last_seen" bson:"last_seen"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+}
+
+func (d *Device) Validate() error {
+	if d.Platform != Android && d.Platform != IOS {
+		return fmt.Errorf("invalid platform: %s, must be %s or %s", d.Platform, Android, IOS)
+	}
+	if d.Token == "" {
+		return errors.New("empty token")
+	}
+	if len(d.Token) > 4096 {
+		return errors.New("token is too long")
+	}
+	return nil
+}
+
+// Store is the registry of devices, a token belongs to one sub at a time.
+type Store interface {
+	// Register adds d, or updates the sub, app version and last seen of its token.
+	Register(d *Device) error
+	List(sub string) ([]*Device, error)
+	Delete(sub, platform, token string) error
+	// Remove deletes a token the push service rejected as invalid.
+	Remove(platform, token string) error
+}
@@ -0,0 +1,51 @@
+package mobilepush
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		device *Device
+		err    string
+	}{
+		{name: "android", device: &Device{Platform: Android, Token: "t"}},
+		{name: "ios", device: &Device{Platform: IOS, Token: "t"}},
+		{name: "unknown platform", device: &Device{Platform: "web", Token: "t"}, err: "invalid platform: web, must be android or ios"},
+		{name: "empty token", device: &Device{Platform: IOS}, err: "empty token"},
+		{name: "long token", device: &Device{Platform: IOS, Token: strings.Repeat("t", 4097)}, err: "token is too long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.device.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMockStore(t *testing.T) {
+	store := NewMockStore()
+	seen := time.Unix(1700000000, 0)
+	assert.NoError(t, store.Register(&Device{Sub: "bob", Platform: IOS, Token: "t1", AppVersion: "1.0", LastSeen: seen}))
+	// the token moves to carol when she signs in on the same device
+	assert.NoError(t, store.Register(&Device{Sub: "carol", Platform: IOS, Token: "t1", AppVersion: "1.1", LastSeen: seen.Add(time.Hour)}))
+	devices, _ := store.List("bob")
+	assert.Empty(t, devices)
+	devices, _ = store.List("carol")
+	assert.Equal(t, []*Device{{Sub: "carol", Platform: IOS, Token: "t1", AppVersion: "1.1", LastSeen: seen.Add(time.Hour)}}, devices)
+
+	assert.ErrorIs(t, store.Delete("bob", IOS, "t1"), ErrNotFound)
+	assert.NoError(t, store.Delete("carol", IOS, "t1"))
+	assert.NoError(t, store.Remove(IOS, "t1"))
+	devices, _ = store.List("carol")
+	assert.Empty(t, devices)
+}
@@ -0,0 +1,238 @@
+package mobilepush
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arwoosa/notifaction/service/jwt"
+)
+
+const (
+	DefaultFcmEndpoint = "https://fcm.googleapis.com"
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// ServiceAccount is the json key of a google service account allowed to send with FCM.
+type ServiceAccount struct {
+	ProjectId    string `json:"project_id"`
+	PrivateKeyId string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenUri     string `json:"token_uri"`
+}
+
+func LoadServiceAccount(file string) (*ServiceAccount, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account: %w", err)
+	}
+	account := &ServiceAccount{}
+	if err := json.Unmarshal(data, account); err != nil {
+		return nil, fmt.Errorf("invalid service account %s: %w", file, err)
+	}
+	return account, nil
+}
+
+type fcmOpt func(*fcm)
+
+// WithFcmEndpoint replaces https://fcm.googleapis.com, e.g. by a local fake, when endpoint is not empty.
+func WithFcmEndpoint(endpoint string) fcmOpt {
+	return func(f *fcm) {
+		if endpoint != "" {
+			f.endpoint = strings.TrimRight(endpoint, "/")
+		}
+	}
+}
+
+// WithFcmTokenUrl replaces the token_uri of the service account when tokenUrl is not empty.
+func WithFcmTokenUrl(tokenUrl string) fcmOpt {
+	return func(f *fcm) {
+		if tokenUrl != "" {
+			f.tokenUrl = tokenUrl
+		}
+	}
+}
+
+func WithFcmHttpClient(client *http.Client) fcmOpt {
+	return func(f *fcm) {
+		f.client = client
+	}
+}
+
+func WithFcmNow(now func() time.Time) fcmOpt {
+	return func(f *fcm) {
+		f.now = now
+	}
+}
+
+// NewFCM returns the provider of the FCM HTTP v1 api of the project of account. It authenticates
+// with OAuth 2 access tokens granted for jwts signed by the key of account.
+func NewFCM(account *ServiceAccount, opts ...fcmOpt) (Provider, error) {
+	if account == nil || account.ProjectId == "" || account.ClientEmail == "" {
+		return nil, errors.New("service account needs project_id and client_email")
+	}
+	key, err := parseRsaKey(account.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	f := &fcm{
+		account:  account,
+		key:      key,
+		endpoint: DefaultFcmEndpoint,
+		tokenUrl: account.TokenUri,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.tokenUrl == "" {
+		return nil, errors.New("service account needs token_uri")
+	}
+	if f.client == nil {
+		f.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return f, nil
+}
+
+func parseRsaKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid service account private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account private key is not rsa")
+	}
+	return rsaKey, nil
+}
+
+type fcm struct {
+	account  *ServiceAccount
+	key      *rsa.PrivateKey
+	endpoint string
+	tokenUrl string
+	client   *http.Client
+	now      func() time.Time
+
+	mu          sync.Mutex
+	accessToken string
+	expires     time.Time
+}
+
+// token returns the cached access token, granting a new one a minute before it expires.
+func (f *fcm) token() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	if f.accessToken != "" && now.Before(f.expires.Add(-time.Minute)) {
+		return f.accessToken, nil
+	}
+	assertion, err := jwt.SignRS256(f.key, map[string]any{"kid": f.account.PrivateKeyId}, map[string]any{
+		"iss":   f.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.tokenUrl,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := f.client.PostForm(f.tokenUrl, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get fcm access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body := readBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get fcm access token, status %d: %s", resp.StatusCode, body)
+	}
+	grant := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := json.Unmarshal(body, &grant); err != nil || grant.AccessToken == "" {
+		return "", fmt.Errorf("invalid fcm access token response: %s", body)
+	}
+	f.accessToken = grant.AccessToken
+	f.expires = now.Add(time.Duration(grant.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
+
+func (f *fcm) resetToken() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.accessToken = ""
+}
+
+type fcmError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (f *fcm) Send(token string, msg *Message) (string, error) {
+	accessToken, err := f.token()
+	if err != nil {
+		return "", err
+	}
+	message := map[string]any{
+		"token":        token,
+		"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+	}
+	if len(msg.Data) > 0 {
+		message["data"] = msg.Data
+	}
+	if msg.CollapseKey != "" {
+		message["android"] = map[string]any{"collapse_key": msg.CollapseKey}
+	}
+	resp, err := postJSON(f.client, f.endpoint+"/v1/projects/"+f.account.ProjectId+"/messages:send",
+		http.Header{"Authorization": {"Bearer " + accessToken}}, map[string]any{"message": message})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body := readBody(resp)
+	if resp.StatusCode == http.StatusOK {
+		result := struct {
+			Name string `json:"name"`
+		}{}
+		_ = json.Unmarshal(body, &result)
+		return result.Name, nil
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		f.resetToken()
+	}
+	fcmErr := &fcmError{}
+	_ = json.Unmarshal(body, fcmErr)
+	if fcmErr.Error.Status == "NOT_FOUND" {
+		return "", ErrInvalidToken
+	}
+	for _, d := range fcmErr.Error.Details {
+		if d.ErrorCode == "UNREGISTERED" {
+			return "", ErrInvalidToken
+		}
+	}
+	return "", fmt.Errorf("fcm send failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
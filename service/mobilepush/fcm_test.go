@@ -0,0 +1,147 @@
+package mobilepush
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeFcm is a local stand-in for the google token endpoint and the FCM HTTP v1 api.
+type fakeFcm struct {
+	t        *testing.T
+	server   *httptest.Server
+	key      *rsa.PublicKey
+	mu       sync.Mutex
+	grants   int
+	messages []map[string]any
+	// invalid tokens are answered with UNREGISTERED
+	invalid map[string]bool
+}
+
+func newFakeFcm(t *testing.T, key *rsa.PublicKey) *fakeFcm {
+	f := &fakeFcm{t: t, key: key, invalid: map[string]bool{}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeFcm) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/token" {
+		assert.Equal(f.t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.FormValue("grant_type"))
+		claims := verifyRS256(f.t, r.FormValue("assertion"), f.key)
+		assert.Equal(f.t, "svc@demo.iam.gserviceaccount.com", claims["iss"])
+		assert.Equal(f.t, fcmScope, claims["scope"])
+		assert.Equal(f.t, f.server.URL+"/token", claims["aud"])
+		f.grants++
+		_, _ = io.WriteString(w, `{"access_token":"access-1","expires_in":3600,"token_type":"Bearer"}`)
+		return
+	}
+	assert.Equal(f.t, "/v1/projects/demo/messages:send", r.URL.Path)
+	assert.Equal(f.t, "Bearer access-1", r.Header.Get("Authorization"))
+	body := map[string]map[string]any{}
+	assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+	message := body["message"]
+	f.messages = append(f.messages, message)
+	token, _ := message["token"].(string)
+	if f.invalid[token] {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":{"code":404,"message":"Requested entity was not found.","status":"NOT_FOUND",
+			"details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`)
+		return
+	}
+	if token == "broken" {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, `{"error":{"code":500,"status":"INTERNAL"}}`)
+		return
+	}
+	_, _ = io.WriteString(w, `{"name":"projects/demo/messages/`+token+`"}`)
+}
+
+func verifyRS256(t *testing.T, token string, key *rsa.PublicKey) map[string]any {
+	parts := strings.Split(token, ".")
+	if !assert.Len(t, parts, 3) {
+		return nil
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig))
+	claims := map[string]any{}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, json.Unmarshal(payload, &claims))
+	return claims
+}
+
+func writeServiceAccount(t *testing.T, tokenUri string) (string, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	account, _ := json.Marshal(&ServiceAccount{
+		ProjectId:    "demo",
+		PrivateKeyId: "kid-1",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail:  "svc@demo.iam.gserviceaccount.com",
+		TokenUri:     tokenUri,
+	})
+	file := filepath.Join(t.TempDir(), "service-account.json")
+	assert.NoError(t, os.WriteFile(file, account, 0o600))
+	return file, key
+}
+
+func TestFCM(t *testing.T) {
+	file, key := writeServiceAccount(t, "https://oauth2.googleapis.com/token")
+	fake := newFakeFcm(t, &key.PublicKey)
+	fake.invalid["gone"] = true
+	account, err := LoadServiceAccount(file)
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	provider, err := NewFCM(account, WithFcmEndpoint(fake.server.URL+"/"), WithFcmTokenUrl(fake.server.URL+"/token"),
+		WithFcmNow(func() time.Time { return now }))
+	assert.NoError(t, err)
+
+	msg := &Message{Title: "Alice joined", Body: "Say hi", Data: map[string]string{"FROM": "Alice"}, CollapseKey: "EVENT_JOIN"}
+	id, err := provider.Send("device-1", msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "projects/demo/messages/device-1", id)
+	assert.Equal(t, map[string]any{
+		"token":        "device-1",
+		"notification": map[string]any{"title": "Alice joined", "body": "Say hi"},
+		"data":         map[string]any{"FROM": "Alice"},
+		"android":      map[string]any{"collapse_key": "EVENT_JOIN"},
+	}, fake.messages[0])
+
+	_, err = provider.Send("gone", msg)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = provider.Send("broken", msg)
+	assert.EqualError(t, err, `fcm send failed with status 500: {"error":{"code":500,"status":"INTERNAL"}}`)
+	// the access token is granted once until it is about to expire
+	assert.Equal(t, 1, fake.grants)
+	now = now.Add(time.Hour)
+	_, err = provider.Send("device-1", msg)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.grants)
+
+	_, err = NewFCM(&ServiceAccount{ProjectId: "demo", ClientEmail: "svc", PrivateKey: "bad"})
+	assert.EqualError(t, err, "invalid service account private key")
+	_, err = LoadServiceAccount(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
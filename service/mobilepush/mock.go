@@ -0,0 +1,69 @@
+package mobilepush
+
+import "sync"
+
+// NewMockStore returns a device registry in memory.
+func NewMockStore() Store {
+	return &mockStore{}
+}
+
+type mockStore struct {
+	mu      sync.Mutex
+	devices []*Device
+}
+
+func (m *mockStore) find(platform, token string) int {
+	for i, d := range m.devices {
+		if d.Platform == platform && d.Token == token {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *mockStore) Register(d *Device) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i := m.find(d.Platform, d.Token); i >= 0 {
+		m.devices[i].Sub = d.Sub
+		m.devices[i].AppVersion = d.AppVersion
+		m.devices[i].LastSeen = d.LastSeen
+		return nil
+	}
+	c := *d
+	m.devices = append(m.devices, &c)
+	return nil
+}
+
+func (m *mockStore) List(sub string) ([]*Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := []*Device{}
+	for _, d := range m.devices {
+		if d.Sub == sub {
+			c := *d
+			result = append(result, &c)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockStore) Delete(sub, platform, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.find(platform, token)
+	if i < 0 || m.devices[i].Sub != sub {
+		return ErrNotFound
+	}
+	m.devices = append(m.devices[:i], m.devices[i+1:]...)
+	return nil
+}
+
+func (m *mockStore) Remove(platform, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i := m.find(platform, token); i >= 0 {
+		m.devices = append(m.devices[:i], m.devices[i+1:]...)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package mobilepush
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoStore returns the device registry in collection, see EnsureIndexes.
+func NewMongoStore(collection *mongo.Collection) Store {
+	return &mongoStore{collection: collection, timeout: 5 * time.Second}
+}
+
+// EnsureIndexes creates the unique index of tokens and the index of subs.
+func EnsureIndexes(collection *mongo.Collection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "platform", Value: 1}, {Key: "token", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "sub", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create device indexes: %w", err)
+	}
+	return nil
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func (m *mongoStore) Register(d *Device) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.UpdateOne(ctx,
+		bson.M{"platform": d.Platform, "token": d.Token},
+		bson.M{
+			"$set":         bson.M{"sub": d.Sub, "app_version": d.AppVersion, "last_seen": d.LastSeen},
+			"$setOnInsert": bson.M{"created_at": d.CreatedAt},
+		},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+func (m *mongoStore) List(sub string) ([]*Device, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	cur, err := m.collection.Find(ctx, bson.M{"sub": sub})
+	if err != nil {
+		return nil, err
+	}
+	devices := []*Device{}
+	if err := cur.All(ctx, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (m *mongoStore) Delete(sub, platform, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	result, err := m.collection.DeleteOne(ctx, bson.M{"sub": sub, "platform": platform, "token": token})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *mongoStore) Remove(platform, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.DeleteOne(ctx, bson.M{"platform": platform, "token": token})
+	return err
+}
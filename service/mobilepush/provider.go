@@ -0,0 +1,52 @@
+package mobilepush
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidToken is returned by providers for tokens the push service no longer accepts.
+var ErrInvalidToken = errors.New("invalid device token")
+
+// Message is what a provider shows on a device.
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string
+	// CollapseKey lets a newer message of the same key replace an undelivered or shown one.
+	CollapseKey string
+}
+
+// Provider sends to the devices of a platform.
+type Provider interface {
+	Send(token string, msg *Message) (messageId string, err error)
+}
+
+func postJSON(client *http.Client, url string, header http.Header, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
+func readBody(resp *http.Response) []byte {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return body
+}
@@ -0,0 +1,122 @@
+package mobilepush
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+)
+
+// CollapseKeyData names the notification data that overrides the collapse key, the event by default.
+const CollapseKeyData = "COLLAPSE_KEY"
+
+type senderOpt func(*sender)
+
+// WithProvider sends to the devices of platform with p, a nil p leaves the platform out.
+func WithProvider(platform string, p Provider) senderOpt {
+	return func(s *sender) {
+		if p != nil {
+			s.providers[platform] = p
+		}
+	}
+}
+
+func WithLangResolver(resolver lang.Resolver) senderOpt {
+	return func(s *sender) {
+		s.langResolver = resolver
+	}
+}
+
+// NewSender returns the sender of the mobilepush channel, it pushes the <event>-mobilepush template
+// rendered in the lang of the recipient to every device of the recipient.
+func NewSender(store Store, tpl channel.TemplateReader, opts ...senderOpt) (service.Sender, error) {
+	if store == nil {
+		return nil, errors.New("device store is required")
+	}
+	if tpl == nil {
+		return nil, errors.New("template is required")
+	}
+	s := &sender{
+		store:     store,
+		tpl:       tpl,
+		providers: map[string]Provider{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.providers) == 0 {
+		return nil, errors.New("at least one push provider is required")
+	}
+	if s.langResolver == nil {
+		s.langResolver = lang.NewResolver()
+	}
+	return s, nil
+}
+
+type sender struct {
+	store        Store
+	tpl          channel.TemplateReader
+	providers    map[string]Provider
+	langResolver lang.Resolver
+}
+
+// Send returns the message ids of the push services. Recipients without devices, or whose
+// tokens are all invalid, are skipped.
+func (s *sender) Send(notify *service.Notification) (string, error) {
+	if len(notify.SendTo) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+	var devices []*Device
+	for _, to := range notify.SendTo {
+		toDevices, err := s.store.List(to.Sub)
+		if err != nil {
+			return "", fmt.Errorf("failed to list devices of %s: %w", to.Sub, err)
+		}
+		for _, d := range toDevices {
+			if s.providers[d.Platform] != nil {
+				devices = append(devices, d)
+			}
+		}
+	}
+	if len(devices) == 0 {
+		return "", channel.Skip("no push device")
+	}
+	detail, err := channel.Render(s.tpl, s.langResolver, channel.MobilePush, notify)
+	if err != nil {
+		return "", err
+	}
+	msg := &Message{
+		Title:       detail.Subject,
+		Body:        detail.Body.Plaint,
+		Data:        notify.Data,
+		CollapseKey: notify.Data[CollapseKeyData],
+	}
+	if msg.CollapseKey == "" {
+		msg.CollapseKey = notify.Event
+	}
+
+	var ids []string
+	var errs []error
+	invalid := 0
+	for _, d := range devices {
+		id, err := s.providers[d.Platform].Send(d.Token, msg)
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			invalid++
+			if err := s.store.Remove(d.Platform, d.Token); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove %s token: %w", d.Platform, err))
+			}
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			ids = append(ids, id)
+		}
+	}
+	if invalid == len(devices) && len(errs) == 0 {
+		return "", channel.Skip("push device tokens invalid")
+	}
+	return strings.Join(ids, ","), errors.Join(errs...)
+}
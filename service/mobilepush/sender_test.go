@@ -0,0 +1,129 @@
+package mobilepush
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeProvider struct {
+	mu       sync.Mutex
+	results  map[string]error
+	messages []*Message
+}
+
+func (f *fakeProvider) Send(token string, msg *Message) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, msg)
+	if err := f.results[token]; err != nil {
+		return "", err
+	}
+	return "id-" + token, nil
+}
+
+func TestSender(t *testing.T) {
+	tpl := mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) {
+			return name == "EVENT_JOIN-mobilepush_en", nil
+		}),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			detail := &dao.DetailTemplateResponse{Subject: "{{FROM}} joined"}
+			detail.Body.Plaint = "Say hi to {{FROM}}"
+			return detail, nil
+		}),
+	)
+	android := &fakeProvider{results: map[string]error{"a-gone": ErrInvalidToken, "a-broken": errors.New("fcm down")}}
+	ios := &fakeProvider{results: map[string]error{"i-gone": ErrInvalidToken}}
+	store := NewMockStore()
+	for _, d := range []*Device{
+		{Sub: "bob", Platform: Android, Token: "a-1"},
+		{Sub: "bob", Platform: Android, Token: "a-gone"},
+		{Sub: "bob", Platform: IOS, Token: "i-1"},
+		{Sub: "carol", Platform: IOS, Token: "i-gone"},
+		{Sub: "dave", Platform: Android, Token: "a-broken"},
+	} {
+		assert.NoError(t, store.Register(d))
+	}
+	sender, err := NewSender(store, tpl, WithProvider(Android, android), WithProvider(IOS, ios),
+		WithLangResolver(lang.NewResolver(lang.WithDefault("en"))))
+	assert.NoError(t, err)
+
+	notify := &service.Notification{
+		Event:  "EVENT_JOIN",
+		Lang:   "ja",
+		Data:   map[string]string{"FROM": "Alice"},
+		SendTo: []*service.Info{{Sub: "bob"}},
+	}
+	mid, err := sender.Send(notify)
+	assert.NoError(t, err)
+	assert.Equal(t, "id-a-1,id-i-1", mid)
+	assert.Equal(t, "EVENT_JOIN-mobilepush_en", notify.TemplateUsed)
+	assert.Equal(t, &Message{Title: "Alice joined", Body: "Say hi to Alice", Data: map[string]string{"FROM": "Alice"},
+		CollapseKey: "EVENT_JOIN"}, ios.messages[0])
+	// the invalid token is removed
+	devices, _ := store.List("bob")
+	assert.Len(t, devices, 2)
+
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "carol"}},
+		Data: map[string]string{CollapseKeyData: "join-alice"}})
+	assert.True(t, channel.IsSkip(err), err)
+	assert.EqualError(t, err, "skipped: push device tokens invalid")
+	assert.Equal(t, "join-alice", ios.messages[1].CollapseKey)
+	devices, _ = store.List("carol")
+	assert.Empty(t, devices)
+
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "erin"}}})
+	assert.EqualError(t, err, "skipped: no push device")
+
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "dave"}}})
+	assert.EqualError(t, err, "fcm down")
+	devices, _ = store.List("dave")
+	assert.Len(t, devices, 1)
+
+	// devices of a platform without provider are not addressed
+	androidOnly, err := NewSender(store, tpl, WithProvider(Android, android))
+	assert.NoError(t, err)
+	assert.NoError(t, store.Register(&Device{Sub: "frank", Platform: IOS, Token: "i-2"}))
+	_, err = androidOnly.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "frank"}}})
+	assert.EqualError(t, err, "skipped: no push device")
+
+	_, err = NewSender(store, tpl, WithProvider(IOS, nil))
+	assert.EqualError(t, err, "at least one push provider is required")
+}
+
+type removeErrStore struct {
+	Store
+}
+
+func (s *removeErrStore) Remove(platform, token string) error {
+	return errors.New("store down")
+}
+
+func TestSenderRemoveError(t *testing.T) {
+	tpl := mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) {
+			return true, nil
+		}),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			return &dao.DetailTemplateResponse{Subject: "joined"}, nil
+		}),
+	)
+	store := &removeErrStore{Store: NewMockStore()}
+	assert.NoError(t, store.Register(&Device{Sub: "carol", Platform: IOS, Token: "i-gone"}))
+	ios := &fakeProvider{results: map[string]error{"i-gone": ErrInvalidToken}}
+	sender, err := NewSender(store, tpl, WithProvider(IOS, ios))
+	assert.NoError(t, err)
+
+	// a token that could not be pruned is reported instead of skipped
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "carol"}}})
+	assert.False(t, channel.IsSkip(err), err)
+	assert.EqualError(t, err, "failed to remove ios token: store down")
+}
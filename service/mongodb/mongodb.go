@@ -0,0 +1,49 @@
+// Package mongodb connects the mongo blocks of the configuration.
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	mu      sync.Mutex
+	clients = map[string]*mongo.Client{}
+)
+
+// Collection returns the collection of the <prefix>.mongo block, clients are shared by uri.
+func Collection(prefix, defaultCollection string) (*mongo.Collection, error) {
+	uri := viper.GetString(prefix + ".mongo.uri")
+	if uri == "" {
+		return nil, errors.New(prefix + ".mongo.uri is empty")
+	}
+	database := viper.GetString(prefix + ".mongo.database")
+	if database == "" {
+		return nil, errors.New(prefix + ".mongo.database is empty")
+	}
+	collection := viper.GetString(prefix + ".mongo.collection")
+	if collection == "" {
+		collection = defaultCollection
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	client, ok := clients[uri]
+	if !ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var err error
+		client, err = mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect %s mongo: %w", prefix, err)
+		}
+		clients[uri] = client
+	}
+	return client.Database(database).Collection(collection), nil
+}
@@ -0,0 +1,58 @@
+package preference
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+)
+
+// NewFilter returns the channel filter of the preferences of store. It reads a preference once,
+// so a filter serves one dispatch.
+func NewFilter(store Store, categories *Categories) channel.Filter {
+	return &filter{store: store, categories: categories, cache: map[string]*Preference{}}
+}
+
+type filter struct {
+	store      Store
+	categories *Categories
+	mu         sync.Mutex
+	cache      map[string]*Preference
+}
+
+// Allow reports whether every recipient of notify allows channel, recipients without a sub get
+// the defaults of the category of the event.
+func (f *filter) Allow(ch string, notify *service.Notification) (bool, error) {
+	if !slices.Contains(Channels, ch) {
+		return true, nil
+	}
+	category := f.categories.Of(notify.Event)
+	for _, info := range notify.SendTo {
+		p, err := f.preference(info.Sub)
+		if err != nil {
+			return false, err
+		}
+		if !p.Allows(category, ch) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (f *filter) preference(sub string) (*Preference, error) {
+	if sub == "" {
+		return &Preference{}, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := f.cache[sub]; ok {
+		return p, nil
+	}
+	p, err := f.store.Get(sub)
+	if err != nil {
+		return nil, err
+	}
+	f.cache[sub] = p
+	return p, nil
+}
@@ -0,0 +1,48 @@
+package preference
+
+import (
+	"maps"
+	"sync"
+)
+
+// NewMockStore returns a store keeping the preferences in memory.
+func NewMockStore() Store {
+	return &mockStore{prefs: map[string]*Preference{}}
+}
+
+type mockStore struct {
+	mu    sync.Mutex
+	prefs map[string]*Preference
+}
+
+func (m *mockStore) Get(sub string) (*Preference, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.prefs[sub]
+	if !ok {
+		return &Preference{Sub: sub}, nil
+	}
+	c := *p
+	c.Channels = cloneChannels(p.Channels)
+	return &c, nil
+}
+
+func (m *mockStore) Save(p *Preference) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := *p
+	saved.Channels = cloneChannels(p.Channels)
+	m.prefs[p.Sub] = &saved
+	return nil
+}
+
+func cloneChannels(channels map[string]map[string]bool) map[string]map[string]bool {
+	if channels == nil {
+		return nil
+	}
+	c := make(map[string]map[string]bool, len(channels))
+	for category, choices := range channels {
+		c[category] = maps.Clone(choices)
+	}
+	return c
+}
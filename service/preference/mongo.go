@@ -0,0 +1,42 @@
+package preference
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoStore returns the store of the preferences in collection, keyed by sub.
+func NewMongoStore(collection *mongo.Collection) Store {
+	return &mongoStore{collection: collection, timeout: 5 * time.Second}
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func (m *mongoStore) Get(sub string) (*Preference, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	p := &Preference{}
+	err := m.collection.FindOne(ctx, bson.M{"_id": sub}).Decode(p)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &Preference{Sub: sub}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (m *mongoStore) Save(p *Preference) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.ReplaceOne(ctx, bson.M{"_id": p.Sub}, p, options.Replace().SetUpsert(true))
+	return err
+}
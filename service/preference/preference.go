@@ -0,0 +1,145 @@
+package preference
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/arwoosa/notifaction/service/channel"
+)
+
+// DefaultCategory holds the events of no configured category.
+const DefaultCategory = "general"
+
+// Channels are the channels preferences apply to, the ones reaching the recipients themselves.
+// Chatops and webhook post to teams and partner services.
+var Channels = []string{channel.Email, channel.Inbox, channel.WebPush, channel.MobilePush, channel.Line, channel.SMS}
+
+// Category groups the events users choose the channels of at once.
+type Category struct {
+	Name   string   `mapstructure:"name" json:"name"`
+	Events []string `mapstructure:"events" json:"events"`
+	// Defaults are the channels on or off for users who did not choose, channels left out are on.
+	Defaults map[string]bool `mapstructure:"defaults" json:"-"`
+}
+
+// Default reports whether users who did not choose receive the events of c through ch.
+func (c *Category) Default(ch string) bool {
+	on, ok := c.Defaults[ch]
+	return !ok || on
+}
+
+type Categories struct {
+	list    []*Category
+	byName  map[string]*Category
+	byEvent map[string]*Category
+}
+
+// NewCategories checks categories and adds the default category when it is not configured.
+// An event belongs to one category at most.
+func NewCategories(categories []*Category) (*Categories, error) {
+	c := &Categories{byName: map[string]*Category{}, byEvent: map[string]*Category{}}
+	for _, category := range categories {
+		if category.Name == "" {
+			return nil, errors.New("category name is empty")
+		}
+		if _, ok := c.byName[category.Name]; ok {
+			return nil, fmt.Errorf("category %s is repeated", category.Name)
+		}
+		for ch := range category.Defaults {
+			if !slices.Contains(Channels, ch) {
+				return nil, fmt.Errorf("category %s: invalid channel %s, must be one of %v", category.Name, ch, Channels)
+			}
+		}
+		for _, event := range category.Events {
+			if other, ok := c.byEvent[event]; ok {
+				return nil, fmt.Errorf("event %s is in categories %s and %s", event, other.Name, category.Name)
+			}
+			c.byEvent[event] = category
+		}
+		c.byName[category.Name] = category
+		c.list = append(c.list, category)
+	}
+	if _, ok := c.byName[DefaultCategory]; !ok {
+		general := &Category{Name: DefaultCategory}
+		c.byName[DefaultCategory] = general
+		c.list = append(c.list, general)
+	}
+	return c, nil
+}
+
+// Of returns the category of event.
+func (c *Categories) Of(event string) *Category {
+	if category, ok := c.byEvent[event]; ok {
+		return category
+	}
+	return c.byName[DefaultCategory]
+}
+
+// List returns the categories in the configured order, the default category last unless configured.
+func (c *Categories) List() []*Category {
+	return c.list
+}
+
+// Preference is what a user receives: the channels chosen per category, or nothing when muted.
+type Preference struct {
+	Sub   string `bson:"_id"`
+	Muted bool   `bson:"muted"`
+	// Channels are the choices by category and channel, the defaults of the category apply to the others.
+	Channels  map[string]map[string]bool `bson:"channels"`
+	UpdatedAt time.Time                  `bson:"updated_at"`
+}
+
+// Validate checks that the choices are of known categories and channels.
+func (p *Preference) Validate(categories *Categories) error {
+	for name, channels := range p.Channels {
+		if _, ok := categories.byName[name]; !ok {
+			return fmt.Errorf("unknown category: %s", name)
+		}
+		for ch := range channels {
+			if !slices.Contains(Channels, ch) {
+				return fmt.Errorf("category %s: invalid channel %s, must be one of %v", name, ch, Channels)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether the user receives the events of category through ch.
+func (p *Preference) Allows(category *Category, ch string) bool {
+	return !p.Muted && p.chosen(category, ch)
+}
+
+func (p *Preference) chosen(category *Category, ch string) bool {
+	if on, ok := p.Channels[category.Name][ch]; ok {
+		return on
+	}
+	return category.Default(ch)
+}
+
+// Choice is the channels a user receives the events of a category through, muted or not.
+type Choice struct {
+	*Category
+	Channels map[string]bool `json:"channels"`
+}
+
+// Choices returns the choice of every category with the defaults applied.
+func (p *Preference) Choices(categories *Categories) []*Choice {
+	choices := make([]*Choice, 0, len(categories.list))
+	for _, category := range categories.list {
+		choice := &Choice{Category: category, Channels: map[string]bool{}}
+		for _, ch := range Channels {
+			choice.Channels[ch] = p.chosen(category, ch)
+		}
+		choices = append(choices, choice)
+	}
+	return choices
+}
+
+// Store keeps the preferences of the users.
+type Store interface {
+	// Get returns the preference of sub, an empty one when the user never chose.
+	Get(sub string) (*Preference, error)
+	Save(p *Preference) error
+}
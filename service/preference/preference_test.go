@@ -0,0 +1,139 @@
+package preference
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCategories(t *testing.T) *Categories {
+	categories, err := NewCategories([]*Category{
+		{Name: "social", Events: []string{"EVENT_JOIN", "EVENT_CANCEL"}, Defaults: map[string]bool{channel.SMS: false}},
+		{Name: "payment", Events: []string{"EVENT_PAYMENT_FAILED"}},
+	})
+	assert.NoError(t, err)
+	return categories
+}
+
+func TestNewCategories(t *testing.T) {
+	categories := newTestCategories(t)
+	assert.Equal(t, "social", categories.Of("EVENT_CANCEL").Name)
+	assert.Equal(t, DefaultCategory, categories.Of("EVENT_REPORT").Name)
+	assert.Len(t, categories.List(), 3)
+
+	tests := []struct {
+		name       string
+		categories []*Category
+		err        string
+	}{
+		{
+			name:       "empty name",
+			categories: []*Category{{Events: []string{"EVENT_JOIN"}}},
+			err:        "category name is empty",
+		},
+		{
+			name:       "repeated category",
+			categories: []*Category{{Name: "social"}, {Name: "social"}},
+			err:        "category social is repeated",
+		},
+		{
+			name:       "event in two categories",
+			categories: []*Category{{Name: "social", Events: []string{"EVENT_JOIN"}}, {Name: "events", Events: []string{"EVENT_JOIN"}}},
+			err:        "event EVENT_JOIN is in categories social and events",
+		},
+		{
+			name:       "default of unknown channel",
+			categories: []*Category{{Name: "social", Defaults: map[string]bool{"fax": false}}},
+			err:        "category social: invalid channel fax, must be one of [email inbox webpush mobilepush line sms]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCategories(tt.categories)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestPreference(t *testing.T) {
+	categories := newTestCategories(t)
+	social := categories.Of("EVENT_JOIN")
+	p := &Preference{Sub: "bob", Channels: map[string]map[string]bool{
+		"social": {channel.Email: false, channel.SMS: true},
+	}}
+	assert.NoError(t, p.Validate(categories))
+	assert.False(t, p.Allows(social, channel.Email))
+	assert.True(t, p.Allows(social, channel.SMS))
+	assert.True(t, p.Allows(social, channel.Inbox))
+	// defaults apply to the users who did not choose
+	assert.False(t, (&Preference{}).Allows(social, channel.SMS))
+
+	choices := p.Choices(categories)
+	assert.Len(t, choices, 3)
+	assert.Equal(t, "social", choices[0].Name)
+	assert.Equal(t, map[string]bool{"email": false, "inbox": true, "webpush": true, "mobilepush": true, "line": true, "sms": true}, choices[0].Channels)
+	assert.Equal(t, DefaultCategory, choices[2].Name)
+
+	// muting keeps the choices
+	p.Muted = true
+	assert.False(t, p.Allows(social, channel.SMS))
+	assert.True(t, p.Choices(categories)[0].Channels[channel.SMS])
+
+	assert.EqualError(t, (&Preference{Channels: map[string]map[string]bool{"news": {channel.Email: false}}}).Validate(categories), "unknown category: news")
+	assert.EqualError(t, (&Preference{Channels: map[string]map[string]bool{"social": {channel.ChatOps: false}}}).Validate(categories),
+		"category social: invalid channel chatops, must be one of [email inbox webpush mobilepush line sms]")
+}
+
+type countingStore struct {
+	Store
+	gets int
+	err  error
+}
+
+func (s *countingStore) Get(sub string) (*Preference, error) {
+	s.gets++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.Store.Get(sub)
+}
+
+func TestFilter(t *testing.T) {
+	categories := newTestCategories(t)
+	store := &countingStore{Store: NewMockStore()}
+	assert.NoError(t, store.Save(&Preference{Sub: "bob", Channels: map[string]map[string]bool{"payment": {channel.Email: false}}}))
+	assert.NoError(t, store.Save(&Preference{Sub: "carol", Muted: true}))
+	f := NewFilter(store, categories)
+
+	notify := func(event, sub string) *service.Notification {
+		return &service.Notification{Event: event, SendTo: []*service.Info{{Sub: sub}}}
+	}
+	tests := []struct {
+		channel string
+		notify  *service.Notification
+		allowed bool
+	}{
+		{channel: channel.Email, notify: notify("EVENT_PAYMENT_FAILED", "bob"), allowed: false},
+		{channel: channel.Inbox, notify: notify("EVENT_PAYMENT_FAILED", "bob"), allowed: true},
+		{channel: channel.Email, notify: notify("EVENT_JOIN", "bob"), allowed: true},
+		{channel: channel.SMS, notify: notify("EVENT_JOIN", "bob"), allowed: false},
+		{channel: channel.Email, notify: notify("EVENT_REPORT", "carol"), allowed: false},
+		// preferences do not apply to the posts to teams and partners
+		{channel: channel.Webhook, notify: notify("EVENT_REPORT", "carol"), allowed: true},
+		{channel: channel.Email, notify: notify("EVENT_REPORT", ""), allowed: true},
+		{channel: channel.SMS, notify: notify("EVENT_CANCEL", ""), allowed: false},
+	}
+	for _, tt := range tests {
+		allowed, err := f.Allow(tt.channel, tt.notify)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.allowed, allowed, "%s of %s to %s", tt.channel, tt.notify.Event, tt.notify.SendTo[0].Sub)
+	}
+	assert.Equal(t, 2, store.gets, "preferences are read once per dispatch")
+
+	store.err = errors.New("store is down")
+	_, err := NewFilter(store, categories).Allow(channel.Email, notify("EVENT_JOIN", "bob"))
+	assert.EqualError(t, err, "store is down")
+}
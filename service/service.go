@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -11,6 +12,9 @@ type Notification struct {
 	Data   map[string]string
 	From   *Info
 	SendTo []*Info
+	// TemplateUsed is set by the sender to the name of the template actually sent,
+	// which differs from GetTemplateName when a fallback language is used.
+	TemplateUsed string
 }
 
 func (n *Notification) UpperKeyData() map[string]string {
@@ -29,11 +33,22 @@ func GetTemplateName(event, lang string) string {
 	return fmt.Sprintf("%s_%s", event, lang)
 }
 
+// ParseTemplateName splits a template name built by GetTemplateName back into event and lang.
+func ParseTemplateName(name string) (event, lang string, err error) {
+	splitIdx := strings.LastIndex(name, "_")
+	if splitIdx <= 0 || splitIdx == len(name)-1 {
+		return "", "", errors.New("invalid template name: " + name)
+	}
+	return name[:splitIdx], name[splitIdx+1:], nil
+}
+
 type Info struct {
-	Sub    string
-	Name   string
-	Email  string
-	Enable bool
+	Sub        string
+	Name       string
+	Email      string
+	Enable     bool
+	LineUserId string
+	Phone      string
 }
 
 type Sender interface {
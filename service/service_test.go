@@ -148,3 +148,48 @@ func TestUpperKeyData(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTemplateName(t *testing.T) {
+	tests := []struct {
+		name      string
+		tplName   string
+		wantEvent string
+		wantLang  string
+		wantErr   bool
+	}{
+		{
+			name:      "event with underscore",
+			tplName:   "EVENT_JOIN_DENIED_zh-TW",
+			wantEvent: "EVENT_JOIN_DENIED",
+			wantLang:  "zh-TW",
+		},
+		{
+			name:    "without separator",
+			tplName: "template",
+			wantErr: true,
+		},
+		{
+			name:    "empty lang",
+			tplName: "event_",
+			wantErr: true,
+		},
+		{
+			name:    "empty event",
+			tplName: "_en",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, lang, err := ParseTemplateName(tt.tplName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTemplateName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if event != tt.wantEvent || lang != tt.wantLang {
+				t.Errorf("ParseTemplateName() = %q, %q, want %q, %q", event, lang, tt.wantEvent, tt.wantLang)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Counter counts the sms sent to each sub per day, for the daily caps.
+type Counter interface {
+	// Incr counts an sms to sub on day, a YYYY-MM-DD date, and returns the count of the day.
+	Incr(sub, day string) (int, error)
+	// Decr takes back an sms counted by Incr that was not sent.
+	Decr(sub, day string) error
+}
+
+// NewMemoryCounter returns a counter of the current day in memory, every replica counts on its own.
+func NewMemoryCounter() Counter {
+	return &memoryCounter{counts: map[string]int{}}
+}
+
+type memoryCounter struct {
+	mu     sync.Mutex
+	day    string
+	counts map[string]int
+}
+
+func (m *memoryCounter) Incr(sub, day string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if day != m.day {
+		m.day = day
+		m.counts = map[string]int{}
+	}
+	m.counts[sub]++
+	return m.counts[sub], nil
+}
+
+func (m *memoryCounter) Decr(sub, day string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if day == m.day && m.counts[sub] > 0 {
+		m.counts[sub]--
+	}
+	return nil
+}
+
+// NewMongoCounter returns the counter in collection shared by every replica, see EnsureCounterIndexes.
+func NewMongoCounter(collection *mongo.Collection) Counter {
+	return &mongoCounter{collection: collection, timeout: 5 * time.Second, now: time.Now}
+}
+
+// EnsureCounterIndexes creates the unique index of the counts and the ttl index that drops them
+// after two days.
+func EnsureCounterIndexes(collection *mongo.Collection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sub", Value: 1}, {Key: "day", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create sms counter indexes: %w", err)
+	}
+	return nil
+}
+
+type mongoCounter struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+	now        func() time.Time
+}
+
+func (m *mongoCounter) Incr(sub, day string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	result := struct {
+		Count int `bson:"count"`
+	}{}
+	err := m.collection.FindOneAndUpdate(ctx,
+		bson.M{"sub": sub, "day": day},
+		bson.M{
+			"$inc":         bson.M{"count": 1},
+			"$setOnInsert": bson.M{"expires_at": m.now().Add(48 * time.Hour)},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&result)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count sms of %s: %w", sub, err)
+	}
+	return result.Count, nil
+}
+
+func (m *mongoCounter) Decr(sub, day string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.UpdateOne(ctx,
+		bson.M{"sub": sub, "day": day, "count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to uncount sms of %s: %w", sub, err)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package sms
+
+import "errors"
+
+// ErrInvalidNumber is returned by providers for numbers that cannot receive sms.
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// Provider sends sms through a gateway.
+type Provider interface {
+	Send(to, text string) (messageId string, err error)
+}
@@ -0,0 +1,97 @@
+package sms
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwilio(t *testing.T) {
+	var forms []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "AC1", user)
+		assert.Equal(t, "secret", pass)
+		assert.Equal(t, "/2010-04-01/Accounts/AC1/Messages.json", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		form := map[string]string{}
+		for k := range r.PostForm {
+			form[k] = r.PostForm.Get(k)
+		}
+		forms = append(forms, form)
+		switch r.PostForm.Get("To") {
+		case "+15005550001":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"code":21211,"message":"The 'To' number is not a valid phone number.","status":400}`)
+		case "+15005550002":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"code":20003,"message":"Authenticate","status":401}`)
+		default:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"sid":"SM1","status":"queued"}`)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewTwilio("AC1", "secret", "+15005550006", WithTwilioApiUrl(server.URL+"/"))
+	assert.NoError(t, err)
+	id, err := provider.Send("+886912345678", "活動取消")
+	assert.NoError(t, err)
+	assert.Equal(t, "SM1", id)
+	assert.Equal(t, map[string]string{"To": "+886912345678", "From": "+15005550006", "Body": "活動取消"}, forms[0])
+
+	_, err = provider.Send("+15005550001", "hi")
+	assert.ErrorIs(t, err, ErrInvalidNumber)
+	_, err = provider.Send("+15005550002", "hi")
+	assert.EqualError(t, err, `twilio send failed with status 401: {"code":20003,"message":"Authenticate","status":401}`)
+
+	provider, err = NewTwilio("AC1", "secret", "MG123", WithTwilioApiUrl(server.URL))
+	assert.NoError(t, err)
+	_, err = provider.Send("+886912345678", "hi")
+	assert.NoError(t, err)
+	assert.Equal(t, "MG123", forms[3]["MessagingServiceSid"])
+	assert.NotContains(t, forms[3], "From")
+
+	_, err = NewTwilio("AC1", "", "+15005550006")
+	assert.EqualError(t, err, "twilio needs account sid, auth token and from")
+}
+
+type fakePublisher struct {
+	inputs []*sns.PublishInput
+	err    error
+}
+
+func (f *fakePublisher) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	f.inputs = append(f.inputs, input)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sns.PublishOutput{MessageId: aws.String("sns-1")}, nil
+}
+
+func TestSNS(t *testing.T) {
+	publisher := &fakePublisher{}
+	provider, err := NewSNS(publisher, WithSenderId("OOSA"))
+	assert.NoError(t, err)
+	id, err := provider.Send("+886912345678", "hi")
+	assert.NoError(t, err)
+	assert.Equal(t, "sns-1", id)
+	input := publisher.inputs[0]
+	assert.Equal(t, "+886912345678", aws.StringValue(input.PhoneNumber))
+	assert.Equal(t, "Transactional", aws.StringValue(input.MessageAttributes["AWS.SNS.SMS.SMSType"].StringValue))
+	assert.Equal(t, "OOSA", aws.StringValue(input.MessageAttributes["AWS.SNS.SMS.SenderID"].StringValue))
+
+	publisher.err = awserr.New(sns.ErrCodeInvalidParameterException, "Invalid parameter: PhoneNumber", nil)
+	_, err = provider.Send("+1", "hi")
+	assert.ErrorIs(t, err, ErrInvalidNumber)
+	publisher.err = errors.New("throttled")
+	_, err = provider.Send("+886912345678", "hi")
+	assert.EqualError(t, err, "sns publish failed: throttled")
+}
@@ -0,0 +1,118 @@
+package sms
+
+import (
+	"regexp"
+	"strings"
+)
+
+type Encoding string
+
+const (
+	GSM7 Encoding = "GSM-7"
+	UCS2 Encoding = "UCS-2"
+)
+
+const (
+	gsm7Single = 160
+	gsm7Multi  = 153
+	ucs2Single = 70
+	ucs2Multi  = 67
+)
+
+// gsm7Basic is the GSM 03.38 basic character set, one septet each.
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extension are the characters of the extension table, an escape and a septet each.
+const gsm7Extension = "\f^{}\\[~]|€"
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// ValidE164 reports whether phone is a number in E.164 format, e.g. +886912345678.
+func ValidE164(phone string) bool {
+	return e164.MatchString(phone)
+}
+
+// Count returns the encoding text is sent in and the number of segments it takes. Texts of the
+// GSM-7 character set take 160 characters in one segment, 153 per segment when concatenated,
+// texts with other characters, e.g. Chinese, are sent in UCS-2 with 70 and 67.
+func Count(text string) (Encoding, int) {
+	if text == "" {
+		return GSM7, 0
+	}
+	if units, ok := gsm7Units(text); ok {
+		return GSM7, segments(units, gsm7Single, gsm7Multi)
+	}
+	return UCS2, segments(ucs2Units(text), ucs2Single, ucs2Multi)
+}
+
+func gsm7Units(text string) ([]int, bool) {
+	units := make([]int, 0, len(text))
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			units = append(units, 1)
+		case strings.ContainsRune(gsm7Extension, r):
+			units = append(units, 2)
+		default:
+			return nil, false
+		}
+	}
+	return units, true
+}
+
+func ucs2Units(text string) []int {
+	units := make([]int, 0, len(text))
+	for _, r := range text {
+		// characters outside the basic multilingual plane take a surrogate pair
+		if r > 0xFFFF {
+			units = append(units, 2)
+		} else {
+			units = append(units, 1)
+		}
+	}
+	return units
+}
+
+// segments counts the segments of units, an escaped septet or a surrogate pair is never split.
+func segments(units []int, single, multi int) int {
+	total := 0
+	for _, u := range units {
+		total += u
+	}
+	if total <= single {
+		return 1
+	}
+	n, used := 1, 0
+	for _, u := range units {
+		if used+u > multi {
+			n++
+			used = 0
+		}
+		used += u
+	}
+	return n
+}
+
+// Truncate cuts text to fit in maxSegments segments, marking the cut with an ellipsis.
+func Truncate(text string, maxSegments int) string {
+	encoding, n := Count(text)
+	if n <= maxSegments || maxSegments <= 0 {
+		return text
+	}
+	// … is not in GSM-7, it would turn the text into UCS-2
+	ellipsis := "..."
+	if encoding == UCS2 {
+		ellipsis = "…"
+	}
+	runes := []rune(text)
+	// no segment holds more than 160 characters, so longer cuts need not be tried
+	start := min(len(runes)-1, maxSegments*gsm7Single)
+	for i := start; i > 0; i-- {
+		cut := strings.TrimRightFunc(string(runes[:i]), func(r rune) bool { return r == ' ' || r == '\n' }) + ellipsis
+		if _, n := Count(cut); n <= maxSegments {
+			return cut
+		}
+	}
+	return ellipsis
+}
@@ -0,0 +1,69 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		encoding Encoding
+		segments int
+	}{
+		{name: "empty", text: "", encoding: GSM7, segments: 0},
+		{name: "ascii", text: "Hello {world}", encoding: GSM7, segments: 1},
+		{name: "160 gsm-7", text: strings.Repeat("a", 160), encoding: GSM7, segments: 1},
+		{name: "161 gsm-7", text: strings.Repeat("a", 161), encoding: GSM7, segments: 2},
+		{name: "306 gsm-7", text: strings.Repeat("a", 306), encoding: GSM7, segments: 2},
+		{name: "307 gsm-7", text: strings.Repeat("a", 307), encoding: GSM7, segments: 3},
+		{name: "extension takes two septets", text: strings.Repeat("€", 80), encoding: GSM7, segments: 1},
+		{name: "escape is not split", text: strings.Repeat("€", 81), encoding: GSM7, segments: 2},
+		{name: "chinese", text: "活動取消", encoding: UCS2, segments: 1},
+		{name: "70 ucs-2", text: strings.Repeat("中", 70), encoding: UCS2, segments: 1},
+		{name: "71 ucs-2", text: strings.Repeat("中", 71), encoding: UCS2, segments: 2},
+		{name: "134 ucs-2", text: strings.Repeat("中", 134), encoding: UCS2, segments: 2},
+		{name: "135 ucs-2", text: strings.Repeat("中", 135), encoding: UCS2, segments: 3},
+		{name: "one chinese character turns the text into ucs-2", text: strings.Repeat("a", 70) + "中", encoding: UCS2, segments: 2},
+		{name: "surrogate pairs", text: strings.Repeat("😀", 35), encoding: UCS2, segments: 1},
+		{name: "surrogate pair is not split", text: strings.Repeat("😀", 36), encoding: UCS2, segments: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoding, segments := Count(tt.text)
+			assert.Equal(t, tt.encoding, encoding)
+			assert.Equal(t, tt.segments, segments)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 1))
+	gsm := Truncate(strings.Repeat("a", 200), 1)
+	assert.Equal(t, strings.Repeat("a", 157)+"...", gsm)
+	ucs := Truncate(strings.Repeat("中", 100), 1)
+	assert.Equal(t, strings.Repeat("中", 69)+"…", ucs)
+	ucs = Truncate(strings.Repeat("中", 1000), 2)
+	_, segments := Count(ucs)
+	assert.Equal(t, 2, segments)
+	assert.Equal(t, 134, len([]rune(ucs)))
+	// spaces before the cut are dropped
+	assert.Equal(t, strings.Repeat("a", 156)+"...", Truncate(strings.Repeat("a", 156)+" "+strings.Repeat("b", 10), 1))
+}
+
+func TestValidE164(t *testing.T) {
+	for phone, valid := range map[string]bool{
+		"+886912345678":     true,
+		"+14155552671":      true,
+		"0912345678":        false,
+		"+0912345678":       false,
+		"+886 912 345":      false,
+		"+8869123456789012": false,
+		"":                  false,
+	} {
+		assert.Equal(t, valid, ValidE164(phone), phone)
+	}
+}
@@ -0,0 +1,161 @@
+package sms
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+)
+
+const defaultMaxSegments = 3
+
+type senderOpt func(*sender)
+
+// WithDailyCap limits the sms a sub receives per day, no limit when not positive.
+func WithDailyCap(dailyCap int, counter Counter) senderOpt {
+	return func(s *sender) {
+		s.dailyCap = dailyCap
+		s.counter = counter
+	}
+}
+
+// WithMaxSegments cuts texts longer than maxSegments segments, 3 when not positive.
+func WithMaxSegments(maxSegments int) senderOpt {
+	return func(s *sender) {
+		s.maxSegments = maxSegments
+	}
+}
+
+// WithLocation sets the time zone the days of the daily cap start in, UTC when nil.
+func WithLocation(loc *time.Location) senderOpt {
+	return func(s *sender) {
+		s.location = loc
+	}
+}
+
+func WithNow(now func() time.Time) senderOpt {
+	return func(s *sender) {
+		s.now = now
+	}
+}
+
+func WithLangResolver(resolver lang.Resolver) senderOpt {
+	return func(s *sender) {
+		s.langResolver = resolver
+	}
+}
+
+// NewSender returns the sender of the sms channel, it sends the plain text of the <event>-sms
+// template rendered in the lang of the recipient to the phone trait of the recipient.
+func NewSender(provider Provider, tpl channel.TemplateReader, opts ...senderOpt) (service.Sender, error) {
+	if provider == nil {
+		return nil, errors.New("sms provider is required")
+	}
+	if tpl == nil {
+		return nil, errors.New("template is required")
+	}
+	s := &sender{
+		provider: provider,
+		tpl:      tpl,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.maxSegments <= 0 {
+		s.maxSegments = defaultMaxSegments
+	}
+	if s.location == nil {
+		s.location = time.UTC
+	}
+	if s.dailyCap > 0 && s.counter == nil {
+		s.counter = NewMemoryCounter()
+	}
+	if s.langResolver == nil {
+		s.langResolver = lang.NewResolver()
+	}
+	return s, nil
+}
+
+type sender struct {
+	provider     Provider
+	tpl          channel.TemplateReader
+	dailyCap     int
+	counter      Counter
+	maxSegments  int
+	location     *time.Location
+	now          func() time.Time
+	langResolver lang.Resolver
+}
+
+// Send returns the message ids of the provider. Recipients without a phone number, or over
+// their daily cap, are skipped.
+func (s *sender) Send(notify *service.Notification) (string, error) {
+	if len(notify.SendTo) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+	var recipients []*service.Info
+	var errs []error
+	for _, to := range notify.SendTo {
+		switch {
+		case to.Phone == "":
+		case !ValidE164(to.Phone):
+			// the number itself is left out of the error, it ends up in responses and logs
+			errs = append(errs, fmt.Errorf("phone number of %s is not in E.164 format", to.Sub))
+		default:
+			recipients = append(recipients, to)
+		}
+	}
+	if len(recipients) == 0 {
+		if len(errs) > 0 {
+			return "", errors.Join(errs...)
+		}
+		return "", channel.Skip("no phone number")
+	}
+	detail, err := channel.Render(s.tpl, s.langResolver, channel.SMS, notify)
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(detail.Body.Plaint)
+	if text == "" {
+		text = detail.Subject
+	}
+	text = Truncate(text, s.maxSegments)
+
+	var ids []string
+	capped := 0
+	day := s.now().In(s.location).Format(time.DateOnly)
+	for _, to := range recipients {
+		if s.dailyCap > 0 {
+			count, err := s.counter.Incr(to.Sub, day)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if count > s.dailyCap {
+				capped++
+				continue
+			}
+		}
+		id, err := s.provider.Send(to.Phone, text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sms to %s: %w", to.Sub, err))
+			// a failed send does not use up the cap
+			if s.dailyCap > 0 {
+				if err := s.counter.Decr(to.Sub, day); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if capped == len(recipients) && len(errs) == 0 {
+		return "", channel.Skip("daily sms cap of %d reached", s.dailyCap)
+	}
+	return strings.Join(ids, ","), errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+package sms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeProvider struct {
+	sent []string
+}
+
+func (f *fakeProvider) Send(to, text string) (string, error) {
+	if to == "+15005550001" {
+		return "", ErrInvalidNumber
+	}
+	f.sent = append(f.sent, to+":"+text)
+	return "id" + to, nil
+}
+
+type flakyProvider struct {
+	failures int
+	sent     int
+}
+
+func (f *flakyProvider) Send(to, text string) (string, error) {
+	if f.failures > 0 {
+		f.failures--
+		return "", errors.New("provider is down")
+	}
+	f.sent++
+	return "id" + to, nil
+}
+
+func TestSender(t *testing.T) {
+	tpl := mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) {
+			return name == "EVENT_CANCEL-sms_zh-TW", nil
+		}),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			detail := &dao.DetailTemplateResponse{}
+			detail.Body.Plaint = "{{TITLE}} 已取消 " + strings.Repeat("。", 80)
+			return detail, nil
+		}),
+	)
+	provider := &fakeProvider{}
+	now := time.Date(2026, 1, 1, 15, 59, 0, 0, time.UTC)
+	taipei := time.FixedZone("Asia/Taipei", 8*60*60)
+	sender, err := NewSender(provider, tpl, WithDailyCap(2, NewMemoryCounter()), WithMaxSegments(1), WithLocation(taipei),
+		WithNow(func() time.Time { return now }), WithLangResolver(lang.NewResolver(lang.WithDefault("zh-TW"))))
+	assert.NoError(t, err)
+
+	notify := &service.Notification{
+		Event:  "EVENT_CANCEL",
+		Lang:   "zh-TW",
+		Data:   map[string]string{"TITLE": "登山"},
+		SendTo: []*service.Info{{Sub: "bob", Phone: "+886912345678"}, {Sub: "carol"}},
+	}
+	mid, err := sender.Send(notify)
+	assert.NoError(t, err)
+	assert.Equal(t, "id+886912345678", mid)
+	assert.Equal(t, "EVENT_CANCEL-sms_zh-TW", notify.TemplateUsed)
+	// the text is cut to one ucs-2 segment
+	text := strings.TrimPrefix(provider.sent[0], "+886912345678:")
+	assert.Equal(t, 70, len([]rune(text)))
+	assert.True(t, strings.HasPrefix(text, "登山 已取消"))
+
+	_, err = sender.Send(notify)
+	assert.NoError(t, err)
+	_, err = sender.Send(notify)
+	assert.True(t, channel.IsSkip(err), err)
+	assert.EqualError(t, err, "skipped: daily sms cap of 2 reached")
+	// the cap resets at midnight of the location
+	now = now.Add(time.Minute)
+	_, err = sender.Send(notify)
+	assert.NoError(t, err)
+
+	_, err = sender.Send(&service.Notification{Event: "EVENT_CANCEL", Lang: "zh-TW", SendTo: []*service.Info{{Sub: "carol"}}})
+	assert.EqualError(t, err, "skipped: no phone number")
+	_, err = sender.Send(&service.Notification{Event: "EVENT_CANCEL", Lang: "zh-TW", SendTo: []*service.Info{{Sub: "dave", Phone: "0912345678"}}})
+	assert.EqualError(t, err, "phone number of dave is not in E.164 format")
+	mid, err = sender.Send(&service.Notification{Event: "EVENT_CANCEL", Lang: "zh-TW",
+		SendTo: []*service.Info{{Sub: "erin", Phone: "+15005550001"}, {Sub: "frank", Phone: "+886922222222"}}})
+	assert.Equal(t, "id+886922222222", mid)
+	assert.ErrorIs(t, err, ErrInvalidNumber)
+	assert.EqualError(t, err, "sms to erin: invalid phone number")
+
+	_, err = NewSender(nil, tpl)
+	assert.EqualError(t, err, "sms provider is required")
+}
+
+func TestSenderFailedSendKeepsCap(t *testing.T) {
+	tpl := mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) {
+			return true, nil
+		}),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			return &dao.DetailTemplateResponse{Subject: "cancelled"}, nil
+		}),
+	)
+	provider := &flakyProvider{failures: 2}
+	sender, err := NewSender(provider, tpl, WithDailyCap(1, NewMemoryCounter()))
+	assert.NoError(t, err)
+
+	notify := &service.Notification{Event: "EVENT_CANCEL", Lang: "en", SendTo: []*service.Info{{Sub: "bob", Phone: "+886912345678"}}}
+	for i := 0; i < 2; i++ {
+		_, err = sender.Send(notify)
+		assert.EqualError(t, err, "sms to bob: provider is down")
+	}
+	// failed sends did not use up the cap
+	mid, err := sender.Send(notify)
+	assert.NoError(t, err)
+	assert.Equal(t, "id+886912345678", mid)
+	assert.Equal(t, 1, provider.sent)
+	_, err = sender.Send(notify)
+	assert.EqualError(t, err, "skipped: daily sms cap of 1 reached")
+}
@@ -0,0 +1,63 @@
+package sms
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+type snsPublisher interface {
+	Publish(*sns.PublishInput) (*sns.PublishOutput, error)
+}
+
+type snsOpt func(*snsProvider)
+
+// WithSenderId sets the alphanumeric sender id shown in the countries that support it.
+func WithSenderId(senderId string) snsOpt {
+	return func(s *snsProvider) {
+		s.senderId = senderId
+	}
+}
+
+// NewSNS returns the provider of AWS SNS, publisher is an sns client of the aws session.
+// Messages are transactional, which SNS delivers with the higher reliability.
+func NewSNS(publisher snsPublisher, opts ...snsOpt) (Provider, error) {
+	if publisher == nil {
+		return nil, errors.New("sns client is required")
+	}
+	s := &snsProvider{publisher: publisher}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+type snsProvider struct {
+	publisher snsPublisher
+	senderId  string
+}
+
+func (s *snsProvider) Send(to, text string) (string, error) {
+	attributes := map[string]*sns.MessageAttributeValue{
+		"AWS.SNS.SMS.SMSType": {DataType: aws.String("String"), StringValue: aws.String("Transactional")},
+	}
+	if s.senderId != "" {
+		attributes["AWS.SNS.SMS.SenderID"] = &sns.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(s.senderId)}
+	}
+	out, err := s.publisher.Publish(&sns.PublishInput{
+		PhoneNumber:       aws.String(to),
+		Message:           aws.String(text),
+		MessageAttributes: attributes,
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == sns.ErrCodeInvalidParameterException {
+		return "", fmt.Errorf("%w: %s", ErrInvalidNumber, awsErr.Message())
+	}
+	if err != nil {
+		return "", fmt.Errorf("sns publish failed: %w", err)
+	}
+	return aws.StringValue(out.MessageId), nil
+}
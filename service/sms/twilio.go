@@ -0,0 +1,104 @@
+package sms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultTwilioApiUrl = "https://api.twilio.com"
+
+// twilio error codes of numbers that cannot receive sms
+var twilioInvalidNumberCodes = map[int]bool{
+	21211: true, // invalid To number
+	21612: true, // To number is not reachable
+	21614: true, // To number is not a mobile number
+	21610: true, // the recipient replied STOP
+}
+
+type twilioOpt func(*twilio)
+
+// WithTwilioApiUrl replaces https://api.twilio.com, e.g. by a compatible gateway or a local fake,
+// when url is not empty.
+func WithTwilioApiUrl(url string) twilioOpt {
+	return func(t *twilio) {
+		if url != "" {
+			t.apiUrl = strings.TrimRight(url, "/")
+		}
+	}
+}
+
+func WithTwilioHttpClient(client *http.Client) twilioOpt {
+	return func(t *twilio) {
+		t.client = client
+	}
+}
+
+// NewTwilio returns the provider of the Twilio Messages api of the account, or of a gateway
+// compatible with it. From is a sender number, or a messaging service sid starting with MG.
+func NewTwilio(accountSid, authToken, from string, opts ...twilioOpt) (Provider, error) {
+	if accountSid == "" || authToken == "" || from == "" {
+		return nil, errors.New("twilio needs account sid, auth token and from")
+	}
+	t := &twilio{
+		accountSid: accountSid,
+		authToken:  authToken,
+		from:       from,
+		apiUrl:     DefaultTwilioApiUrl,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.client == nil {
+		t.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return t, nil
+}
+
+type twilio struct {
+	accountSid string
+	authToken  string
+	from       string
+	apiUrl     string
+	client     *http.Client
+}
+
+func (t *twilio) Send(to, text string) (string, error) {
+	form := url.Values{"To": {to}, "Body": {text}}
+	if strings.HasPrefix(t.from, "MG") {
+		form.Set("MessagingServiceSid", t.from)
+	} else {
+		form.Set("From", t.from)
+	}
+	req, err := http.NewRequest(http.MethodPost, t.apiUrl+"/2010-04-01/Accounts/"+t.accountSid+"/Messages.json",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(t.accountSid, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	result := struct {
+		Sid     string `json:"sid"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}{}
+	_ = json.Unmarshal(body, &result)
+	if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK {
+		return result.Sid, nil
+	}
+	if twilioInvalidNumberCodes[result.Code] {
+		return "", fmt.Errorf("%w: twilio error %d: %s", ErrInvalidNumber, result.Code, result.Message)
+	}
+	return "", fmt.Errorf("twilio send failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = 5 * time.Second
+	// UserAgent is sent with every post so that partners can tell the deliveries apart.
+	UserAgent = "notifaction-webhook/1"
+)
+
+type delivererOpt func(*Deliverer)
+
+func WithHttpClient(client *http.Client) delivererOpt {
+	return func(d *Deliverer) {
+		if client != nil {
+			d.client = client
+		}
+	}
+}
+
+// WithMaxAttempts sets how often a delivery is posted before it fails, 5 times when not positive.
+func WithMaxAttempts(maxAttempts int) delivererOpt {
+	return func(d *Deliverer) {
+		if maxAttempts > 0 {
+			d.maxAttempts = maxAttempts
+		}
+	}
+}
+
+// WithBackoff sets the wait before the first retry, it doubles with every retry. 5 seconds when not positive.
+func WithBackoff(backoff time.Duration) delivererOpt {
+	return func(d *Deliverer) {
+		if backoff > 0 {
+			d.backoff = backoff
+		}
+	}
+}
+
+func WithNow(now func() time.Time) delivererOpt {
+	return func(d *Deliverer) {
+		d.now = now
+	}
+}
+
+// Deliverer posts deliveries to the subscriptions, recording every attempt in the delivery store.
+type Deliverer struct {
+	deliveries  DeliveryStore
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	now         func() time.Time
+	sleep       func(time.Duration)
+}
+
+func NewDeliverer(deliveries DeliveryStore, opts ...delivererOpt) (*Deliverer, error) {
+	if deliveries == nil {
+		return nil, errors.New("delivery store is required")
+	}
+	d := &Deliverer{
+		deliveries:  deliveries,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultBackoff,
+		now:         time.Now,
+		sleep:       time.Sleep,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d, nil
+}
+
+// Deliver posts delivery until the subscription accepts it, it is rejected with a client error
+// or the attempts run out. It waits the backoff between attempts, so callers run it in the background.
+func (d *Deliverer) Deliver(sub *Subscription, delivery *Delivery) error {
+	wait := d.backoff
+	for {
+		err := d.Attempt(sub, delivery)
+		if err == nil || delivery.Status != StatusPending {
+			return err
+		}
+		d.sleep(wait)
+		wait *= 2
+	}
+}
+
+// Resume delivers the pending deliveries no process retries anymore, e.g. the ones of a replica that
+// stopped while it retried them, and returns how many it resumed. Each delivery is run by run, in a
+// goroutine when run is nil. Deliveries of deleted subscriptions fail. A delivery the stopped process
+// was posting may be received twice, partners tell it by the id of its payload.
+func (d *Deliverer) Resume(subs Store, run func(func())) (int, error) {
+	if run == nil {
+		run = func(deliver func()) { go deliver() }
+	}
+	now := d.now()
+	pending, err := d.deliveries.ListPending(now.Add(-d.staleAfter()), 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pending deliveries: %w", err)
+	}
+	resumed := 0
+	for _, delivery := range pending {
+		sub, err := subs.Get(delivery.SubscriptionId)
+		if errors.Is(err, ErrNotFound) {
+			delivery.Status = StatusFailed
+		} else if err != nil {
+			return resumed, err
+		}
+		// saving the delivery updates it, so that replicas starting meanwhile do not resume it too
+		delivery.UpdatedAt = now
+		if err := d.deliveries.Save(delivery); err != nil {
+			return resumed, fmt.Errorf("failed to save delivery %s: %w", delivery.Id, err)
+		}
+		if delivery.Status != StatusPending {
+			continue
+		}
+		resumed++
+		run(func() {
+			if err := d.Deliver(sub, delivery); err != nil {
+				log.Printf("webhook %s: delivery %s %s: %v", sub.Name, delivery.Id, delivery.Status, err)
+			}
+		})
+	}
+	return resumed, nil
+}
+
+// staleAfter is how long a pending delivery goes without attempts before it is resumed, longer
+// than any wait between two attempts so that the deliveries retried by a live process are left alone.
+func (d *Deliverer) staleAfter() time.Duration {
+	return d.backoff<<min(d.maxAttempts-1, 16) + d.client.Timeout
+}
+
+// Ping posts a test ping to sub once, it is not retried so that partners see the outcome at once.
+func (d *Deliverer) Ping(sub *Subscription) (*Delivery, error) {
+	delivery, err := NewPing(sub, d.now())
+	if err != nil {
+		return nil, err
+	}
+	once := *d
+	once.maxAttempts = 1
+	return delivery, once.Attempt(sub, delivery)
+}
+
+// Attempt posts delivery once and saves the attempt. The delivery stays pending when the attempt
+// failed and can be retried.
+func (d *Deliverer) Attempt(sub *Subscription, delivery *Delivery) error {
+	start := d.now()
+	status, err := d.post(sub, delivery, start)
+	attempt := &Attempt{
+		At:         start,
+		StatusCode: status,
+		DurationMs: d.now().Sub(start).Milliseconds(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.UpdatedAt = d.now()
+	switch {
+	case err == nil:
+		delivery.Status = StatusSucceeded
+	case !retryable(status) || len(delivery.Attempts) >= d.maxAttempts:
+		delivery.Status = StatusFailed
+	}
+	if saveErr := d.deliveries.Save(delivery); saveErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to save delivery %s: %w", delivery.Id, saveErr))
+	}
+	return err
+}
+
+func (d *Deliverer) post(sub *Subscription, delivery *Delivery, now time.Time) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, sub.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, errors.New("failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.Id)
+	// retries are signed again, receivers would reject the timestamp of the first attempt
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, now, delivery.Payload))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, fmt.Errorf("failed to post: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a post answered with status may succeed later, the client errors
+// other than timeouts and rate limits will not.
+func retryable(status int) bool {
+	if status >= 400 && status < 500 {
+		return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+	}
+	return true
+}
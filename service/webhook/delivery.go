@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// PingEvent is the event of the deliveries of test pings.
+const PingEvent = "PING"
+
+// Party is a sender or recipient of a notification.
+type Party struct {
+	Sub  string `json:"sub"`
+	Name string `json:"name"`
+}
+
+// Payload is the JSON body posted to the subscriptions, Id is the same for every subscription
+// so that partners can tell a notification they already received.
+type Payload struct {
+	Id        string            `json:"id"`
+	Event     string            `json:"event"`
+	Lang      string            `json:"lang,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	From      *Party            `json:"from,omitempty"`
+	To        []*Party          `json:"to,omitempty"`
+	Data      map[string]string `json:"data"`
+}
+
+// Attempt is one post of a delivery, StatusCode is 0 when no response was received.
+type Attempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms" bson:"duration_ms"`
+}
+
+// Delivery is the post of a payload to a subscription with every attempt made.
+type Delivery struct {
+	Id             string          `json:"id" bson:"_id"`
+	SubscriptionId string          `json:"subscription_id" bson:"subscription_id"`
+	Event          string          `json:"event" bson:"event"`
+	Payload        json.RawMessage `json:"payload" bson:"payload"`
+	Status         string          `json:"status" bson:"status"`
+	Attempts       []*Attempt      `json:"attempts" bson:"attempts"`
+	CreatedAt      time.Time       `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" bson:"updated_at"`
+}
+
+// NewDelivery returns the pending delivery of payload to sub.
+func NewDelivery(sub *Subscription, event string, payload []byte, now time.Time) *Delivery {
+	return &Delivery{
+		Id:             newId(),
+		SubscriptionId: sub.Id,
+		Event:          event,
+		Payload:        payload,
+		Status:         StatusPending,
+		Attempts:       []*Attempt{},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// NewPing returns the delivery of a test ping to sub.
+func NewPing(sub *Subscription, now time.Time) (*Delivery, error) {
+	payload, err := json.Marshal(&Payload{
+		Id:        newId(),
+		Event:     PingEvent,
+		CreatedAt: now,
+		Data:      map[string]string{"subscription_id": sub.Id},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewDelivery(sub, PingEvent, payload, now), nil
+}
+
+// DeliveryStore keeps the deliveries so that partners and operators can query them.
+type DeliveryStore interface {
+	// Save adds d or replaces it.
+	Save(d *Delivery) error
+	// List returns the latest deliveries of a subscription, newest first, only the ones of
+	// status when it is not empty.
+	List(subscriptionId, status string, limit int) ([]*Delivery, error)
+	// ListPending returns the pending deliveries of every subscription last updated before before,
+	// oldest first.
+	ListPending(before time.Time, limit int) ([]*Delivery, error)
+}
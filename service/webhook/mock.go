@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewMockStore returns a store keeping the subscriptions in memory.
+func NewMockStore() Store {
+	return &mockStore{subs: map[string]*Subscription{}}
+}
+
+type mockStore struct {
+	mu   sync.Mutex
+	subs map[string]*Subscription
+	// ids in the order they were created
+	order []string
+}
+
+func (m *mockStore) Create(s *Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s.Id == "" {
+		s.Id = newId()
+	}
+	created := *s
+	m.subs[s.Id] = &created
+	m.order = append(m.order, s.Id)
+	return nil
+}
+
+func (m *mockStore) Get(id string) (*Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.subs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *s
+	return &c, nil
+}
+
+func (m *mockStore) List() ([]*Subscription, error) {
+	return m.list(func(*Subscription) bool { return true }), nil
+}
+
+func (m *mockStore) ListByEvent(event string) ([]*Subscription, error) {
+	return m.list(func(s *Subscription) bool { return slices.Contains(s.Events, event) }), nil
+}
+
+func (m *mockStore) list(match func(*Subscription) bool) []*Subscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := []*Subscription{}
+	for _, id := range m.order {
+		if s, ok := m.subs[id]; ok && match(s) {
+			c := *s
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+func (m *mockStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.subs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.subs, id)
+	return nil
+}
+
+// NewMockDeliveryStore returns a delivery store keeping the deliveries in memory.
+func NewMockDeliveryStore() DeliveryStore {
+	return &mockDeliveryStore{deliveries: map[string]*Delivery{}}
+}
+
+type mockDeliveryStore struct {
+	mu         sync.Mutex
+	deliveries map[string]*Delivery
+}
+
+func (m *mockDeliveryStore) Save(d *Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := *d
+	saved.Attempts = slices.Clone(d.Attempts)
+	m.deliveries[d.Id] = &saved
+	return nil
+}
+
+func (m *mockDeliveryStore) List(subscriptionId, status string, limit int) ([]*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := []*Delivery{}
+	for _, d := range m.deliveries {
+		if d.SubscriptionId == subscriptionId && (status == "" || d.Status == status) {
+			c := *d
+			result = append(result, &c)
+		}
+	}
+	// ids are object ids, so they sort in the order the deliveries were created
+	sort.Slice(result, func(i, j int) bool { return result[i].Id > result[j].Id })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *mockDeliveryStore) ListPending(before time.Time, limit int) ([]*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := []*Delivery{}
+	for _, d := range m.deliveries {
+		if d.Status == StatusPending && d.UpdatedAt.Before(before) {
+			c := *d
+			c.Attempts = slices.Clone(d.Attempts)
+			result = append(result, &c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
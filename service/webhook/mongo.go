@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoStore returns the store of the subscriptions in collection, see EnsureIndexes.
+func NewMongoStore(collection *mongo.Collection) Store {
+	return &mongoStore{collection: collection, timeout: 5 * time.Second}
+}
+
+// EnsureIndexes creates the index of the events of the subscriptions.
+func EnsureIndexes(collection *mongo.Collection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "events", Value: 1}}})
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription indexes: %w", err)
+	}
+	return nil
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func (m *mongoStore) Create(s *Subscription) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	if s.Id == "" {
+		s.Id = newId()
+	}
+	_, err := m.collection.InsertOne(ctx, s)
+	return err
+}
+
+func (m *mongoStore) Get(id string) (*Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	s := &Subscription{}
+	err := m.collection.FindOne(ctx, bson.M{"_id": id}).Decode(s)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (m *mongoStore) List() ([]*Subscription, error) {
+	return m.find(bson.M{})
+}
+
+func (m *mongoStore) ListByEvent(event string) ([]*Subscription, error) {
+	return m.find(bson.M{"events": event})
+}
+
+func (m *mongoStore) find(filter bson.M) ([]*Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	cur, err := m.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	subs := []*Subscription{}
+	if err := cur.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (m *mongoStore) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	result, err := m.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// NewMongoDeliveryStore returns the store of the deliveries in collection, see EnsureDeliveryIndexes.
+func NewMongoDeliveryStore(collection *mongo.Collection) DeliveryStore {
+	return &mongoDeliveryStore{collection: collection, timeout: 5 * time.Second}
+}
+
+// EnsureDeliveryIndexes creates the indexes listing the deliveries of a subscription and the pending
+// ones, and the TTL index deleting them after retention when it is positive.
+func EnsureDeliveryIndexes(collection *mongo.Collection, retention time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "subscription_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}},
+	}
+	if retention > 0 {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		})
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create webhook delivery indexes: %w", err)
+	}
+	return nil
+}
+
+type mongoDeliveryStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func (m *mongoDeliveryStore) Save(d *Delivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.ReplaceOne(ctx, bson.M{"_id": d.Id}, d, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *mongoDeliveryStore) List(subscriptionId, status string, limit int) ([]*Delivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	filter := bson.M{"subscription_id": subscriptionId}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cur, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []*Delivery{}
+	if err := cur.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (m *mongoDeliveryStore) ListPending(before time.Time, limit int) ([]*Delivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	filter := bson.M{"status": StatusPending, "updated_at": bson.M{"$lt": before}}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cur, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []*Delivery{}
+	if err := cur.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+)
+
+type senderOpt func(*sender)
+
+func WithSenderNow(now func() time.Time) senderOpt {
+	return func(s *sender) {
+		s.now = now
+	}
+}
+
+// NewSender returns the sender of the webhook channel, it posts the notifications of an event to
+// the subscriptions of the event in the background and returns the ids of the deliveries.
+func NewSender(store Store, deliverer *Deliverer, opts ...senderOpt) (service.Sender, error) {
+	if store == nil {
+		return nil, errors.New("subscription store is required")
+	}
+	if deliverer == nil {
+		return nil, errors.New("deliverer is required")
+	}
+	s := &sender{
+		store:     store,
+		deliverer: deliverer,
+		now:       time.Now,
+		goDeliver: func(deliver func()) { go deliver() },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+type sender struct {
+	store     Store
+	deliverer *Deliverer
+	now       func() time.Time
+	// goDeliver runs the deliveries, tests run them in place
+	goDeliver func(func())
+}
+
+func (s *sender) Send(notify *service.Notification) (string, error) {
+	subs, err := s.store.ListByEvent(notify.Event)
+	if err != nil {
+		return "", err
+	}
+	if len(subs) == 0 {
+		return "", channel.Skip("no webhook subscription of %s", notify.Event)
+	}
+	now := s.now()
+	payload, err := json.Marshal(newPayload(notify, now))
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	var errs []error
+	for _, sub := range subs {
+		delivery := NewDelivery(sub, notify.Event, payload, now)
+		// the pending delivery is saved first so that it can be queried while it is retried
+		if err := s.deliverer.deliveries.Save(delivery); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: failed to save delivery: %w", sub.Name, err))
+			continue
+		}
+		ids = append(ids, delivery.Id)
+		s.goDeliver(func() {
+			if err := s.deliverer.Deliver(sub, delivery); err != nil {
+				log.Printf("webhook %s: delivery %s %s: %v", sub.Name, delivery.Id, delivery.Status, err)
+			}
+		})
+	}
+	return strings.Join(ids, ","), errors.Join(errs...)
+}
+
+func newPayload(notify *service.Notification, now time.Time) *Payload {
+	payload := &Payload{
+		Id:        newId(),
+		Event:     notify.Event,
+		Lang:      notify.Lang,
+		CreatedAt: now,
+		Data:      notify.Data,
+	}
+	if notify.From != nil {
+		payload.From = &Party{Sub: notify.From.Sub, Name: notify.From.Name}
+	}
+	for _, info := range notify.SendTo {
+		payload.To = append(payload.To, &Party{Sub: info.Sub, Name: info.Name})
+	}
+	if payload.Data == nil {
+		payload.Data = map[string]string{}
+	}
+	return payload
+}
@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePartner struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+// newFakePartner answers the posts with statuses in turn, and 200 once they run out.
+func newFakePartner(t *testing.T, statuses ...int) *fakePartner {
+	f := &fakePartner{statuses: statuses}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		f.requests = append(f.requests, r)
+		f.bodies = append(f.bodies, body)
+		status := http.StatusOK
+		if len(f.statuses) > 0 {
+			status, f.statuses = f.statuses[0], f.statuses[1:]
+		}
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func newTestDeliverer(t *testing.T, deliveries DeliveryStore, now time.Time) (*Deliverer, *[]time.Duration) {
+	d, err := NewDeliverer(deliveries, WithMaxAttempts(4), WithBackoff(time.Second), WithNow(func() time.Time { return now }))
+	assert.NoError(t, err)
+	waits := &[]time.Duration{}
+	d.sleep = func(wait time.Duration) {
+		*waits = append(*waits, wait)
+	}
+	return d, waits
+}
+
+func TestSender(t *testing.T) {
+	now := time.Unix(1700000000, 0).UTC()
+	partner := newFakePartner(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	other := newFakePartner(t, http.StatusGone)
+	store := NewMockStore()
+	subs := []*Subscription{
+		{Name: "partner", Url: partner.server.URL, Secret: "whsec_0123456789abcdef", Events: []string{"EVENT_JOIN", "EVENT_CANCEL"}},
+		{Name: "other", Url: other.server.URL, Secret: "whsec_fedcba9876543210", Events: []string{"EVENT_JOIN"}},
+	}
+	for _, sub := range subs {
+		assert.NoError(t, store.Create(sub))
+	}
+	deliveries := NewMockDeliveryStore()
+	deliverer, waits := newTestDeliverer(t, deliveries, now)
+	s, err := NewSender(store, deliverer, WithSenderNow(func() time.Time { return now }))
+	assert.NoError(t, err)
+	var pending []string
+	s.(*sender).goDeliver = func(deliver func()) {
+		ds, _ := deliveries.List(subs[0].Id, StatusPending, 0)
+		for _, d := range ds {
+			pending = append(pending, d.Id)
+		}
+		deliver()
+	}
+
+	mid, err := s.Send(&service.Notification{
+		Event:  "EVENT_JOIN",
+		Lang:   "en",
+		From:   &service.Info{Sub: "sub-1", Name: "Alice", Email: "alice@oosa.life"},
+		SendTo: []*service.Info{{Sub: "sub-2", Name: "Bob", Email: "bob@oosa.life"}},
+		Data:   map[string]string{"TITLE": "Hiking"},
+	})
+	assert.NoError(t, err)
+
+	// the partner is retried with backoff until it accepts the delivery
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *waits)
+	partnerDeliveries, _ := deliveries.List(subs[0].Id, "", 0)
+	assert.Len(t, partnerDeliveries, 1)
+	delivery := partnerDeliveries[0]
+	assert.Equal(t, []string{delivery.Id}, pending, "deliveries are saved before they are posted")
+	assert.Equal(t, StatusSucceeded, delivery.Status)
+	assert.Equal(t, []*Attempt{
+		{At: now, StatusCode: 503, Error: "status 503: Service Unavailable"},
+		{At: now, StatusCode: 429, Error: "status 429: Too Many Requests"},
+		{At: now, StatusCode: 200},
+	}, delivery.Attempts)
+	assert.Len(t, partner.requests, 3)
+	for i, r := range partner.requests {
+		assert.Equal(t, "EVENT_JOIN", r.Header.Get(EventHeader))
+		assert.Equal(t, delivery.Id, r.Header.Get(DeliveryHeader))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, Verify("whsec_0123456789abcdef", r.Header.Get(SignatureHeader), partner.bodies[i], 5*time.Minute, now))
+	}
+	payload := map[string]any{}
+	assert.NoError(t, json.Unmarshal(partner.bodies[0], &payload))
+	assert.NotEmpty(t, payload["id"])
+	delete(payload, "id")
+	assert.Equal(t, map[string]any{
+		"event":      "EVENT_JOIN",
+		"lang":       "en",
+		"created_at": "2023-11-14T22:13:20Z",
+		"from":       map[string]any{"sub": "sub-1", "name": "Alice"},
+		"to":         []any{map[string]any{"sub": "sub-2", "name": "Bob"}},
+		"data":       map[string]any{"TITLE": "Hiking"},
+	}, payload)
+
+	// client errors are not retried
+	otherDeliveries, _ := deliveries.List(subs[1].Id, "", 0)
+	assert.Len(t, otherDeliveries, 1)
+	assert.Equal(t, StatusFailed, otherDeliveries[0].Status)
+	assert.Len(t, otherDeliveries[0].Attempts, 1)
+	assert.Len(t, other.requests, 1)
+	// both subscriptions receive the same notification id
+	assert.JSONEq(t, string(partner.bodies[0]), string(other.bodies[0]))
+	assert.Equal(t, delivery.Id+","+otherDeliveries[0].Id, mid)
+
+	_, err = s.Send(&service.Notification{Event: "EVENT_REPORT"})
+	assert.True(t, channel.IsSkip(err), err)
+	assert.EqualError(t, err, "skipped: no webhook subscription of EVENT_REPORT")
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	partner := newFakePartner(t, 500, 500, 500, 500, 500)
+	down := &Subscription{Id: "down", Url: "http://127.0.0.1:1/hooks", Secret: "whsec_0123456789abcdef"}
+	sub := &Subscription{Id: "partner", Url: partner.server.URL, Secret: "whsec_0123456789abcdef"}
+	deliveries := NewMockDeliveryStore()
+	deliverer, waits := newTestDeliverer(t, deliveries, now)
+
+	delivery := NewDelivery(sub, "EVENT_JOIN", []byte(`{}`), now)
+	err := deliverer.Deliver(sub, delivery)
+	assert.EqualError(t, err, "status 500: Internal Server Error")
+	assert.Equal(t, StatusFailed, delivery.Status)
+	assert.Len(t, delivery.Attempts, 4)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, *waits)
+
+	// a single attempt leaves the delivery pending for the retries
+	retried := NewDelivery(down, "EVENT_JOIN", []byte(`{}`), now)
+	err = deliverer.Attempt(down, retried)
+	assert.ErrorContains(t, err, "failed to post: ")
+	assert.NotContains(t, err.Error(), "127.0.0.1:1/hooks")
+	assert.Equal(t, StatusPending, retried.Status)
+	assert.Equal(t, 0, retried.Attempts[0].StatusCode)
+
+	// pings are not retried
+	ping, err := deliverer.Ping(down)
+	assert.ErrorContains(t, err, "failed to post: ")
+	assert.Equal(t, StatusFailed, ping.Status)
+	saved, _ := deliveries.List("down", StatusFailed, 0)
+	assert.Len(t, saved, 1)
+	assert.Equal(t, PingEvent, saved[0].Event)
+	assert.JSONEq(t, `{"subscription_id":"down"}`, string(mustField(t, saved[0].Payload, "data")))
+}
+
+func TestDeliverResume(t *testing.T) {
+	now := time.Unix(1700000000, 0).UTC()
+	partner := newFakePartner(t, http.StatusServiceUnavailable)
+	store := NewMockStore()
+	sub := &Subscription{Name: "partner", Url: partner.server.URL, Secret: "whsec_0123456789abcdef", Events: []string{"EVENT_JOIN"}}
+	assert.NoError(t, store.Create(sub))
+	deleted := &Subscription{Id: "deleted"}
+	deliveries := NewMockDeliveryStore()
+
+	// deliveries saved by a stopped process: one it retried long ago, one it just attempted
+	// and one of a subscription deleted since
+	stale := NewDelivery(sub, "EVENT_JOIN", []byte(`{"id":"p1"}`), now.Add(-time.Hour))
+	stale.Attempts = []*Attempt{{At: now.Add(-time.Hour), StatusCode: http.StatusBadGateway}}
+	recent := NewDelivery(sub, "EVENT_JOIN", []byte(`{"id":"p2"}`), now.Add(-time.Second))
+	orphan := NewDelivery(deleted, "EVENT_JOIN", []byte(`{"id":"p3"}`), now.Add(-time.Hour))
+	done := NewDelivery(sub, "EVENT_JOIN", []byte(`{"id":"p4"}`), now.Add(-time.Hour))
+	done.Status = StatusSucceeded
+	for _, d := range []*Delivery{stale, recent, orphan, done} {
+		assert.NoError(t, deliveries.Save(d))
+	}
+
+	deliverer, waits := newTestDeliverer(t, deliveries, now)
+	var runs []func()
+	resumed, err := deliverer.Resume(store, func(deliver func()) {
+		runs = append(runs, deliver)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+
+	// a process starting while the delivery is resumed leaves it alone
+	resumed, err = deliverer.Resume(store, func(deliver func()) {
+		t.Error("delivery resumed twice")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, resumed)
+	for _, run := range runs {
+		run()
+	}
+
+	// the stale delivery goes on from its attempts, the partner gets the same payload
+	saved, _ := deliveries.List(sub.Id, StatusSucceeded, 0)
+	assert.Len(t, saved, 2)
+	var resumedDelivery *Delivery
+	for _, d := range saved {
+		if d.Id == stale.Id {
+			resumedDelivery = d
+		}
+	}
+	if assert.NotNil(t, resumedDelivery) {
+		assert.Len(t, resumedDelivery.Attempts, 3)
+	}
+	assert.Equal(t, []time.Duration{time.Second}, *waits)
+	assert.Len(t, partner.bodies, 2)
+	assert.JSONEq(t, `{"id":"p1"}`, string(partner.bodies[0]))
+
+	pending, _ := deliveries.List(sub.Id, StatusPending, 0)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, recent.Id, pending[0].Id)
+	failed, _ := deliveries.List("deleted", StatusFailed, 0)
+	assert.Len(t, failed, 1)
+}
+
+func mustField(t *testing.T, payload []byte, field string) json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	assert.NoError(t, json.Unmarshal(payload, &fields))
+	return fields[field]
+}
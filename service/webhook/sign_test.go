@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := "whsec_0123456789abcdef"
+	body := []byte(`{"event":"EVENT_JOIN"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign(secret, now, body)
+	assert.Equal(t, "t=1700000000,v1=", header[:16])
+	assert.Len(t, header, 16+64)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		err    error
+	}{
+		{name: "valid", secret: secret, header: header, body: body, now: now.Add(4 * time.Minute)},
+		{name: "rotated secret", secret: secret, header: "v1=00," + header, body: body, now: now},
+		{name: "other secret", secret: "whsec_fedcba9876543210", header: header, body: body, now: now, err: ErrInvalidSignature},
+		{name: "tampered body", secret: secret, header: header, body: []byte(`{"event":"EVENT_CANCEL"}`), now: now, err: ErrInvalidSignature},
+		{name: "tampered timestamp", secret: secret, header: "t=1700000100" + header[12:], body: body, now: now, err: ErrInvalidSignature},
+		{name: "replayed", secret: secret, header: header, body: body, now: now.Add(6 * time.Minute), err: ErrExpiredSignature},
+		{name: "malformed", secret: secret, header: "sha256=abc", body: body, now: now, err: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrNotFound = errors.New("webhook subscription not found")
+
+// minSecretLength keeps secrets long enough that signatures cannot be guessed.
+const minSecretLength = 16
+
+// Subscription is the endpoint of a partner service receiving the notifications of Events.
+type Subscription struct {
+	Id        string    `json:"id" bson:"_id"`
+	Name      string    `json:"name" bson:"name"`
+	Url       string    `json:"url" bson:"url"`
+	Secret    string    `json:"-" bson:"secret"`
+	Events    []string  `json:"events" bson:"events"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Validate checks that the url is https and the secret is at least 16 characters.
+func (s *Subscription) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is empty")
+	}
+	u, err := url.Parse(s.Url)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid url: %s", s.Url)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("url must be https: %s", s.Url)
+	}
+	if len(s.Secret) < minSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", minSecretLength)
+	}
+	if len(s.Events) == 0 {
+		return errors.New("events are empty")
+	}
+	for _, event := range s.Events {
+		if event == "" {
+			return errors.New("event is empty")
+		}
+	}
+	return nil
+}
+
+func newId() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// Store keeps the subscriptions of the partner services.
+type Store interface {
+	// Create adds s, setting its id.
+	Create(s *Subscription) error
+	Get(id string) (*Subscription, error)
+	List() ([]*Subscription, error)
+	ListByEvent(event string) ([]*Subscription, error)
+	Delete(id string) error
+}
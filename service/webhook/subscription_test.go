@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionValidate(t *testing.T) {
+	valid := func() *Subscription {
+		return &Subscription{
+			Name:   "partner",
+			Url:    "https://partner.example.com/hooks/notifaction",
+			Secret: "whsec_0123456789abcdef",
+			Events: []string{"EVENT_JOIN"},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(s *Subscription)
+		err    string
+	}{
+		{name: "valid", modify: func(s *Subscription) {}},
+		{name: "empty name", modify: func(s *Subscription) { s.Name = "" }, err: "name is empty"},
+		{name: "invalid url", modify: func(s *Subscription) { s.Url = "partner" }, err: "invalid url: partner"},
+		{name: "http url", modify: func(s *Subscription) { s.Url = "http://partner.example.com" }, err: "url must be https: http://partner.example.com"},
+		{name: "short secret", modify: func(s *Subscription) { s.Secret = "secret" }, err: "secret must be at least 16 characters"},
+		{name: "no event", modify: func(s *Subscription) { s.Events = nil }, err: "events are empty"},
+		{name: "empty event", modify: func(s *Subscription) { s.Events = []string{"EVENT_JOIN", ""} }, err: "event is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(s)
+			err := s.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// recordSize is the record size of the aes128gcm header, payloads fit in one record
+	recordSize = 4096
+	headerSize = 16 + 4 + 1 + 65
+	tagSize    = 16
+	// MaxPayload is the largest payload push services must accept in one message of 4096 bytes
+	MaxPayload = recordSize - headerSize - tagSize - 1
+)
+
+// DecodeKey decodes the base64url keys of push subscriptions and VAPID, padded or not.
+func DecodeKey(key string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(key, "="))
+}
+
+func EncodeKey(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+type encrypter struct {
+	// random source of the ephemeral key and the salt
+	rand io.Reader
+}
+
+// encrypt encrypts plaintext for the user agent key uaPublic and authSecret as the aes128gcm
+// content coding of RFC 8291, with an ephemeral application server key.
+func (e *encrypter) encrypt(uaPublic, authSecret, plaintext []byte) ([]byte, error) {
+	if len(plaintext) > MaxPayload {
+		return nil, fmt.Errorf("payload of %d bytes exceeds %d", len(plaintext), MaxPayload)
+	}
+	if len(authSecret) != 16 {
+		return nil, errors.New("auth secret must be 16 bytes")
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	asKey, err := ecdh.P256().GenerateKey(e.rand)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(e.rand, salt); err != nil {
+		return nil, err
+	}
+	return seal(asKey, uaKey, authSecret, salt, plaintext)
+}
+
+func seal(asKey *ecdh.PrivateKey, uaKey *ecdh.PublicKey, authSecret, salt, plaintext []byte) ([]byte, error) {
+	ecdhSecret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaKey.Bytes()...), asPublic...)
+	ikm, err := hkdfBytes(ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	body := bytes.NewBuffer(make([]byte, 0, headerSize+len(plaintext)+1+tagSize))
+	body.Write(salt)
+	_ = binary.Write(body, binary.BigEndian, uint32(recordSize))
+	body.WriteByte(byte(len(asPublic)))
+	body.Write(asPublic)
+	// 0x02 delimits the last and only record
+	record := append(append([]byte{}, plaintext...), 0x02)
+	body.Write(gcm.Seal(nil, nonce, record, nil))
+	return body.Bytes(), nil
+}
+
+func hkdfBytes(secret, salt, info []byte, size int) ([]byte, error) {
+	out := make([]byte, size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func newEncrypter() *encrypter {
+	return &encrypter{rand: rand.Reader}
+}
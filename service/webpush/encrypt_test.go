@@ -0,0 +1,104 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	b, err := DecodeKey(s)
+	assert.NoError(t, err)
+	return b
+}
+
+// decrypt is the user agent side of RFC 8291.
+func decrypt(uaKey *ecdh.PrivateKey, authSecret, body []byte) ([]byte, error) {
+	if len(body) < headerSize {
+		return nil, errors.New("short body")
+	}
+	salt := body[:16]
+	rs := binary.BigEndian.Uint32(body[16:20])
+	idLen := int(body[20])
+	asKey, err := ecdh.P256().NewPublicKey(body[21 : 21+idLen])
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := body[21+idLen:]
+	if uint32(len(ciphertext)) > rs {
+		return nil, errors.New("more than one record")
+	}
+	ecdhSecret, err := uaKey.ECDH(asKey)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaKey.PublicKey().Bytes()...), asKey.Bytes()...)
+	ikm, _ := hkdfBytes(ecdhSecret, authSecret, keyInfo, 32)
+	cek, _ := hkdfBytes(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce, _ := hkdfBytes(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	record = bytes.TrimRight(record, "\x00")
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		return nil, errors.New("invalid delimiter")
+	}
+	return record[:len(record)-1], nil
+}
+
+// the example of RFC 8291 appendix A
+func TestSealRFC8291(t *testing.T) {
+	asKey, err := ecdh.P256().NewPrivateKey(mustDecode(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	assert.NoError(t, err)
+	uaKey, err := ecdh.P256().NewPrivateKey(mustDecode(t, "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"))
+	assert.NoError(t, err)
+	assert.Equal(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4",
+		EncodeKey(uaKey.PublicKey().Bytes()))
+	authSecret := mustDecode(t, "BTBZMqHH6r4Tts7J_aSIgg")
+	salt := mustDecode(t, "DGv6ra1nlYgDCS1FRnbzlw")
+
+	body, err := seal(asKey, uaKey.PublicKey(), authSecret, salt, []byte("When I grow up, I want to be a watermelon"))
+	assert.NoError(t, err)
+	assert.Equal(t, "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN",
+		EncodeKey(body))
+
+	plaintext, err := decrypt(uaKey, authSecret, body)
+	assert.NoError(t, err)
+	assert.Equal(t, "When I grow up, I want to be a watermelon", string(plaintext))
+}
+
+func TestEncrypt(t *testing.T) {
+	uaKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	authSecret := make([]byte, 16)
+	_, _ = rand.Read(authSecret)
+	e := &encrypter{rand: rand.Reader}
+
+	body, err := e.encrypt(uaKey.PublicKey().Bytes(), authSecret, []byte(`{"title":"hi"}`))
+	assert.NoError(t, err)
+	plaintext, err := decrypt(uaKey, authSecret, body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"title":"hi"}`, string(plaintext))
+
+	body, err = e.encrypt(uaKey.PublicKey().Bytes(), authSecret, []byte(strings.Repeat("x", MaxPayload)))
+	assert.NoError(t, err)
+	assert.Len(t, body, recordSize)
+	_, err = e.encrypt(uaKey.PublicKey().Bytes(), authSecret, []byte(strings.Repeat("x", MaxPayload+1)))
+	assert.EqualError(t, err, "payload of 3994 bytes exceeds 3993")
+
+	_, err = e.encrypt([]byte("bad"), authSecret, nil)
+	assert.ErrorContains(t, err, "invalid p256dh key")
+	_, err = e.encrypt(uaKey.PublicKey().Bytes(), authSecret[:8], nil)
+	assert.EqualError(t, err, "auth secret must be 16 bytes")
+}
@@ -0,0 +1,56 @@
+package webpush
+
+import "sync"
+
+// NewMockStore returns a store keeping the subscriptions in memory.
+func NewMockStore() Store {
+	return &mockStore{subs: map[string]*Subscription{}}
+}
+
+type mockStore struct {
+	mu   sync.Mutex
+	subs map[string]*Subscription
+	// endpoints in the order they were saved
+	order []string
+}
+
+func (m *mockStore) Save(s *Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.subs[s.Endpoint]; !ok {
+		m.order = append(m.order, s.Endpoint)
+	}
+	saved := *s
+	m.subs[s.Endpoint] = &saved
+	return nil
+}
+
+func (m *mockStore) List(sub string) ([]*Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := []*Subscription{}
+	for _, endpoint := range m.order {
+		if s, ok := m.subs[endpoint]; ok && s.Sub == sub {
+			c := *s
+			result = append(result, &c)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockStore) Delete(sub, endpoint string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.subs[endpoint]; !ok || s.Sub != sub {
+		return ErrNotFound
+	}
+	delete(m.subs, endpoint)
+	return nil
+}
+
+func (m *mockStore) Prune(endpoint string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.subs, endpoint)
+	return nil
+}
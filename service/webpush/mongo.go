@@ -0,0 +1,76 @@
+package webpush
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoStore returns the store of the subscriptions in collection, see EnsureIndexes.
+func NewMongoStore(collection *mongo.Collection) Store {
+	return &mongoStore{collection: collection, timeout: 5 * time.Second}
+}
+
+// EnsureIndexes creates the unique index of endpoints and the index of subs.
+func EnsureIndexes(collection *mongo.Collection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "endpoint", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "sub", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create push subscription indexes: %w", err)
+	}
+	return nil
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+func (m *mongoStore) Save(s *Subscription) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.ReplaceOne(ctx, bson.M{"endpoint": s.Endpoint}, s, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *mongoStore) List(sub string) ([]*Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	cur, err := m.collection.Find(ctx, bson.M{"sub": sub})
+	if err != nil {
+		return nil, err
+	}
+	subs := []*Subscription{}
+	if err := cur.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (m *mongoStore) Delete(sub, endpoint string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	result, err := m.collection.DeleteOne(ctx, bson.M{"sub": sub, "endpoint": endpoint})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *mongoStore) Prune(endpoint string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	_, err := m.collection.DeleteOne(ctx, bson.M{"endpoint": endpoint})
+	return err
+}
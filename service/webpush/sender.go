@@ -0,0 +1,189 @@
+package webpush
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+)
+
+const defaultTTL = 24 * time.Hour
+
+// Payload is the json a service worker receives in its push event.
+type Payload struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Event string            `json:"event"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+type senderOpt func(*sender)
+
+func WithHttpClient(client *http.Client) senderOpt {
+	return func(s *sender) {
+		s.client = client
+	}
+}
+
+func WithLangResolver(resolver lang.Resolver) senderOpt {
+	return func(s *sender) {
+		s.langResolver = resolver
+	}
+}
+
+// WithTTL sets how long push services keep a message for an offline browser, 24 hours when not positive.
+func WithTTL(ttl time.Duration) senderOpt {
+	return func(s *sender) {
+		s.ttl = ttl
+	}
+}
+
+// NewSender returns the sender of the webpush channel, it pushes the <event>-webpush template
+// rendered in the lang of the recipient to every subscription of the recipient.
+func NewSender(store Store, vapid *Vapid, tpl channel.TemplateReader, opts ...senderOpt) (service.Sender, error) {
+	if store == nil {
+		return nil, errors.New("push subscription store is required")
+	}
+	if vapid == nil {
+		return nil, errors.New("vapid is required")
+	}
+	if tpl == nil {
+		return nil, errors.New("template is required")
+	}
+	s := &sender{
+		store:     store,
+		vapid:     vapid,
+		tpl:       tpl,
+		encrypter: newEncrypter(),
+		ttl:       defaultTTL,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.client == nil {
+		s.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if s.ttl <= 0 {
+		s.ttl = defaultTTL
+	}
+	if s.langResolver == nil {
+		s.langResolver = lang.NewResolver()
+	}
+	return s, nil
+}
+
+type sender struct {
+	store        Store
+	vapid        *Vapid
+	tpl          channel.TemplateReader
+	encrypter    *encrypter
+	client       *http.Client
+	langResolver lang.Resolver
+	ttl          time.Duration
+}
+
+var errGone = errors.New("subscription is gone")
+
+// Send returns the message locations of the push services. Recipients without subscriptions,
+// or whose subscriptions all expired, are skipped.
+func (s *sender) Send(notify *service.Notification) (string, error) {
+	if len(notify.SendTo) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+	var subs []*Subscription
+	for _, to := range notify.SendTo {
+		toSubs, err := s.store.List(to.Sub)
+		if err != nil {
+			return "", fmt.Errorf("failed to list push subscriptions of %s: %w", to.Sub, err)
+		}
+		subs = append(subs, toSubs...)
+	}
+	if len(subs) == 0 {
+		return "", channel.Skip("no push subscription")
+	}
+	detail, err := channel.Render(s.tpl, s.langResolver, channel.WebPush, notify)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(&Payload{
+		Title: detail.Subject,
+		Body:  detail.Body.Plaint,
+		Event: notify.Event,
+		Data:  notify.Data,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var ids []string
+	var errs []error
+	gone := 0
+	for _, sub := range subs {
+		id, err := s.push(sub, payload)
+		switch {
+		case errors.Is(err, errGone):
+			gone++
+			if err := s.store.Prune(sub.Endpoint); err != nil {
+				errs = append(errs, fmt.Errorf("failed to prune %s: %w", sub.Endpoint, err))
+			}
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			ids = append(ids, id)
+		}
+	}
+	if gone == len(subs) {
+		return "", channel.Skip("push subscriptions expired")
+	}
+	return strings.Join(ids, ","), errors.Join(errs...)
+}
+
+func (s *sender) push(sub *Subscription, payload []byte) (string, error) {
+	p256dh, err := DecodeKey(sub.Keys.P256dh)
+	if err != nil {
+		return "", fmt.Errorf("invalid p256dh key of %s: %w", sub.Endpoint, err)
+	}
+	auth, err := DecodeKey(sub.Keys.Auth)
+	if err != nil {
+		return "", fmt.Errorf("invalid auth secret of %s: %w", sub.Endpoint, err)
+	}
+	body, err := s.encrypter.encrypt(p256dh, auth, payload)
+	if err != nil {
+		return "", err
+	}
+	authorization, err := s.vapid.Authorization(sub.Endpoint)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(s.ttl.Seconds())))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to push to %s: %w", sub.Endpoint, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return "", errGone
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.Header.Get("Location"), nil
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("push to %s failed with status %d: %s", sub.Endpoint, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+}
@@ -0,0 +1,151 @@
+package webpush
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arwoosa/notifaction/service"
+	"github.com/arwoosa/notifaction/service/channel"
+	"github.com/arwoosa/notifaction/service/lang"
+	"github.com/arwoosa/notifaction/service/mail"
+	"github.com/arwoosa/notifaction/service/mail/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+// pushService is a local stand-in for a push service, it decrypts what it receives.
+type pushService struct {
+	t       *testing.T
+	server  *httptest.Server
+	vapid   string
+	mu      sync.Mutex
+	keys    map[string]*testSubscriber
+	payload []*Payload
+	ttl     []string
+}
+
+type testSubscriber struct {
+	sub    *Subscription
+	decode func(body []byte) ([]byte, error)
+	status int
+}
+
+func newPushService(t *testing.T, vapidPublic string) *pushService {
+	p := &pushService{t: t, vapid: vapidPublic, keys: map[string]*testSubscriber{}}
+	p.server = httptest.NewTLSServer(http.HandlerFunc(p.handle))
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *pushService) subscribe(sub, path string, status int) *Subscription {
+	key, keys := newTestKeys(p.t)
+	s := &Subscription{Sub: sub, Endpoint: p.server.URL + path, Keys: keys}
+	auth := mustDecode(p.t, keys.Auth)
+	p.keys[path] = &testSubscriber{sub: s, status: status, decode: func(body []byte) ([]byte, error) {
+		return decrypt(key, auth, body)
+	}}
+	return s
+}
+
+func (p *pushService) handle(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	claims := verifyVapid(p.t, r.Header.Get("Authorization"), p.vapid)
+	assert.Equal(p.t, p.server.URL, claims["aud"])
+	assert.Equal(p.t, "aes128gcm", r.Header.Get("Content-Encoding"))
+	p.ttl = append(p.ttl, r.Header.Get("TTL"))
+	s, ok := p.keys[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if s.status != http.StatusCreated {
+		w.WriteHeader(s.status)
+		_, _ = io.WriteString(w, "push service error")
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	plaintext, err := s.decode(body)
+	assert.NoError(p.t, err)
+	payload := &Payload{}
+	assert.NoError(p.t, json.Unmarshal(plaintext, payload))
+	p.payload = append(p.payload, payload)
+	w.Header().Set("Location", p.server.URL+"/message"+r.URL.Path)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func newTestSender(t *testing.T, store Store, push *pushService, vapid *Vapid) service.Sender {
+	tpl := mail.NewMockTemplateStore(
+		mail.WithIsTemplateExist(func(name string) (bool, error) {
+			return name == "EVENT_JOIN-webpush_en", nil
+		}),
+		mail.WithDetailTemplate(func(name string) (*dao.DetailTemplateResponse, error) {
+			detail := &dao.DetailTemplateResponse{Subject: "{{FROM}} joined"}
+			detail.Body.Plaint = "Say hi to {{FROM}}"
+			return detail, nil
+		}),
+	)
+	sender, err := NewSender(store, vapid, tpl, WithHttpClient(push.server.Client()), WithTTL(time.Hour),
+		WithLangResolver(lang.NewResolver(lang.WithDefault("en"))))
+	assert.NoError(t, err)
+	return sender
+}
+
+func TestSender(t *testing.T) {
+	private, public, err := GenerateVapidKeys()
+	assert.NoError(t, err)
+	vapid, err := NewVapid(private, "mailto:dev@example.com")
+	assert.NoError(t, err)
+	push := newPushService(t, public)
+	store := NewMockStore()
+	for _, s := range []*Subscription{
+		push.subscribe("bob", "/bob-phone", http.StatusCreated),
+		push.subscribe("bob", "/bob-laptop", http.StatusGone),
+		push.subscribe("bob", "/bob-tablet", http.StatusCreated),
+		push.subscribe("carol", "/carol", http.StatusGone),
+		push.subscribe("dave", "/dave", http.StatusInternalServerError),
+	} {
+		assert.NoError(t, store.Save(s))
+	}
+	// an endpoint the push service does not know
+	assert.NoError(t, store.Save(&Subscription{Sub: "carol", Endpoint: push.server.URL + "/unknown", Keys: push.keys["/carol"].sub.Keys}))
+	sender := newTestSender(t, store, push, vapid)
+
+	notify := &service.Notification{
+		Event:  "EVENT_JOIN",
+		Lang:   "ja",
+		Data:   map[string]string{"FROM": "Alice"},
+		SendTo: []*service.Info{{Sub: "bob"}},
+	}
+	mid, err := sender.Send(notify)
+	assert.NoError(t, err)
+	assert.Equal(t, push.server.URL+"/message/bob-phone,"+push.server.URL+"/message/bob-tablet", mid)
+	assert.Equal(t, "EVENT_JOIN-webpush_en", notify.TemplateUsed)
+	assert.Equal(t, []*Payload{
+		{Title: "Alice joined", Body: "Say hi to Alice", Event: "EVENT_JOIN", Data: map[string]string{"FROM": "Alice"}},
+		{Title: "Alice joined", Body: "Say hi to Alice", Event: "EVENT_JOIN", Data: map[string]string{"FROM": "Alice"}},
+	}, push.payload)
+	assert.Equal(t, []string{"3600", "3600", "3600"}, push.ttl)
+	// the gone subscription is pruned
+	subs, _ := store.List("bob")
+	assert.Len(t, subs, 2)
+
+	// every subscription of carol is gone or unknown
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "carol"}}})
+	assert.True(t, channel.IsSkip(err), err)
+	assert.EqualError(t, err, "skipped: push subscriptions expired")
+	subs, _ = store.List("carol")
+	assert.Empty(t, subs)
+
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "erin"}}})
+	assert.EqualError(t, err, "skipped: no push subscription")
+
+	_, err = sender.Send(&service.Notification{Event: "EVENT_JOIN", Lang: "en", SendTo: []*service.Info{{Sub: "dave"}}})
+	assert.EqualError(t, err, "push to "+push.server.URL+"/dave failed with status 500: push service error")
+	subs, _ = store.List("dave")
+	assert.Len(t, subs, 1)
+}
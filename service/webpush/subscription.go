@@ -0,0 +1,82 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("push subscription not found")
+
+// DefaultAllowedHosts are the push services of the major browsers, subdomains included.
+var DefaultAllowedHosts = []string{
+	"fcm.googleapis.com",
+	"android.googleapis.com",
+	"updates.push.services.mozilla.com",
+	"notify.windows.com",
+	"push.apple.com",
+}
+
+type Keys struct {
+	P256dh string `json:"p256dh" bson:"p256dh"`
+	Auth   string `json:"auth" bson:"auth"`
+}
+
+// Subscription is the PushSubscription of a browser of the user sub.
+type Subscription struct {
+	Sub       string    `json:"-" bson:"sub"`
+	Endpoint  string    `json:"endpoint" bson:"endpoint"`
+	Keys      Keys      `json:"keys" bson:"keys"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Validate checks the keys and that the endpoint is an https url of one of allowedHosts,
+// the service posts to endpoints so they must not reach anything but push services.
+func (s *Subscription) Validate(allowedHosts []string) error {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid endpoint: %s", s.Endpoint)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("endpoint must be https: %s", s.Endpoint)
+	}
+	if !isAllowedHost(u.Hostname(), allowedHosts) {
+		return fmt.Errorf("endpoint host is not a known push service: %s", u.Hostname())
+	}
+	p256dh, err := DecodeKey(s.Keys.P256dh)
+	if err != nil {
+		return fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	if _, err := ecdh.P256().NewPublicKey(p256dh); err != nil {
+		return fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	auth, err := DecodeKey(s.Keys.Auth)
+	if err != nil || len(auth) != 16 {
+		return errors.New("invalid auth secret, it must be 16 bytes")
+	}
+	return nil
+}
+
+func isAllowedHost(host string, allowedHosts []string) bool {
+	host = strings.ToLower(host)
+	for _, h := range allowedHosts {
+		h = strings.ToLower(h)
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store keeps the push subscriptions, an endpoint belongs to one sub at a time.
+type Store interface {
+	// Save adds s or moves its endpoint to s.Sub.
+	Save(s *Subscription) error
+	List(sub string) ([]*Subscription, error)
+	Delete(sub, endpoint string) error
+	// Prune deletes the subscription of an endpoint the push service no longer accepts.
+	Prune(endpoint string) error
+}
@@ -0,0 +1,105 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeys(t *testing.T) (*ecdh.PrivateKey, Keys) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	auth := make([]byte, 16)
+	_, _ = rand.Read(auth)
+	return key, Keys{P256dh: EncodeKey(key.PublicKey().Bytes()), Auth: EncodeKey(auth)}
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	_, keys := newTestKeys(t)
+	tests := []struct {
+		name     string
+		endpoint string
+		keys     Keys
+		expErr   string
+	}{
+		{
+			name:     "fcm",
+			endpoint: "https://fcm.googleapis.com/fcm/send/abc",
+			keys:     keys,
+		},
+		{
+			name:     "subdomain",
+			endpoint: "https://wns2-by3p.notify.windows.com/w/?token=abc",
+			keys:     keys,
+		},
+		{
+			name:     "padded keys",
+			endpoint: "https://web.push.apple.com/abc",
+			keys:     Keys{P256dh: keys.P256dh + "=", Auth: keys.Auth + "=="},
+		},
+		{
+			name:     "http",
+			endpoint: "http://fcm.googleapis.com/fcm/send/abc",
+			keys:     keys,
+			expErr:   "endpoint must be https: http://fcm.googleapis.com/fcm/send/abc",
+		},
+		{
+			name:     "unknown host",
+			endpoint: "https://169.254.169.254/latest",
+			keys:     keys,
+			expErr:   "endpoint host is not a known push service: 169.254.169.254",
+		},
+		{
+			name:     "lookalike host",
+			endpoint: "https://evilfcm.googleapis.com.example.com/abc",
+			keys:     keys,
+			expErr:   "endpoint host is not a known push service: evilfcm.googleapis.com.example.com",
+		},
+		{
+			name:     "invalid endpoint",
+			endpoint: "fcm",
+			keys:     keys,
+			expErr:   "invalid endpoint: fcm",
+		},
+		{
+			name:     "invalid p256dh",
+			endpoint: "https://fcm.googleapis.com/fcm/send/abc",
+			keys:     Keys{P256dh: EncodeKey([]byte("short")), Auth: keys.Auth},
+			expErr:   "invalid p256dh key: crypto/ecdh: invalid public key",
+		},
+		{
+			name:     "invalid auth",
+			endpoint: "https://fcm.googleapis.com/fcm/send/abc",
+			keys:     Keys{P256dh: keys.P256dh, Auth: EncodeKey([]byte("short"))},
+			expErr:   "invalid auth secret, it must be 16 bytes",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Subscription{Sub: "bob", Endpoint: test.endpoint, Keys: test.keys}
+			err := s.Validate(DefaultAllowedHosts)
+			if test.expErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, test.expErr)
+		})
+	}
+}
+
+func TestMockStore(t *testing.T) {
+	store := NewMockStore()
+	assert.NoError(t, store.Save(&Subscription{Sub: "bob", Endpoint: "https://a"}))
+	assert.NoError(t, store.Save(&Subscription{Sub: "bob", Endpoint: "https://b"}))
+	// the browser of endpoint b is now used by alice
+	assert.NoError(t, store.Save(&Subscription{Sub: "alice", Endpoint: "https://b"}))
+	subs, _ := store.List("bob")
+	assert.Len(t, subs, 1)
+	assert.ErrorIs(t, store.Delete("bob", "https://b"), ErrNotFound)
+	assert.NoError(t, store.Delete("alice", "https://b"))
+	assert.NoError(t, store.Prune("https://a"))
+	subs, _ = store.List("bob")
+	assert.Empty(t, subs)
+}
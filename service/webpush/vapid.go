@@ -0,0 +1,127 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arwoosa/notifaction/service/jwt"
+)
+
+const vapidExpiry = 12 * time.Hour
+
+// GenerateVapidKeys returns a new VAPID key pair, base64url encoded: the private key is the
+// scalar of the P-256 key and the public key its uncompressed point, the applicationServerKey.
+func GenerateVapidKeys() (privateKey, publicKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return EncodeKey(key.Bytes()), EncodeKey(key.PublicKey().Bytes()), nil
+}
+
+type vapidOpt func(*Vapid)
+
+func WithVapidNow(now func() time.Time) vapidOpt {
+	return func(v *Vapid) {
+		v.now = now
+	}
+}
+
+// NewVapid returns the signer of the push requests of the application server identified
+// by subject, a mailto: or https: contact of RFC 8292.
+func NewVapid(privateKey, subject string, opts ...vapidOpt) (*Vapid, error) {
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https:") {
+		return nil, fmt.Errorf("vapid subject must be a mailto: or https: uri: %s", subject)
+	}
+	d, err := DecodeKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	key, err := parsePrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	ecdhKey, err := key.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	v := &Vapid{
+		key:       key,
+		publicKey: EncodeKey(ecdhKey.PublicKey().Bytes()),
+		subject:   subject,
+		now:       time.Now,
+		tokens:    map[string]*vapidToken{},
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v, nil
+}
+
+// parsePrivateKey builds the ecdsa key of the scalar d through its SEC 1 encoding.
+func parsePrivateKey(d []byte) (*ecdsa.PrivateKey, error) {
+	if len(d) != 32 {
+		return nil, errors.New("key must be 32 bytes")
+	}
+	der, err := asn1.Marshal(struct {
+		Version       int
+		PrivateKey    []byte
+		NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	}{1, d, asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}})
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseECPrivateKey(der)
+}
+
+type vapidToken struct {
+	token   string
+	expires time.Time
+}
+
+type Vapid struct {
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+	now       func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]*vapidToken
+}
+
+// PublicKey returns the applicationServerKey browsers subscribe with.
+func (v *Vapid) PublicKey() string {
+	return v.publicKey
+}
+
+// Authorization returns the Authorization header of a push to endpoint. Tokens are signed per
+// push service and reused until they get close to their expiry.
+func (v *Vapid) Authorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid endpoint: %s", endpoint)
+	}
+	aud := u.Scheme + "://" + u.Host
+	now := v.now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if t, ok := v.tokens[aud]; ok && now.Before(t.expires.Add(-time.Hour)) {
+		return "vapid t=" + t.token + ", k=" + v.publicKey, nil
+	}
+	expires := now.Add(vapidExpiry)
+	token, err := jwt.SignES256(v.key, nil, map[string]any{"aud": aud, "exp": expires.Unix(), "sub": v.subject})
+	if err != nil {
+		return "", err
+	}
+	v.tokens[aud] = &vapidToken{token: token, expires: expires}
+	return "vapid t=" + token + ", k=" + v.publicKey, nil
+}
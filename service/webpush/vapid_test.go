@@ -0,0 +1,76 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// verifyVapid checks the signature of the Authorization header and returns its claims.
+func verifyVapid(t *testing.T, authorization, publicKey string) map[string]any {
+	rest, ok := strings.CutPrefix(authorization, "vapid t=")
+	assert.True(t, ok, authorization)
+	token, k, ok := strings.Cut(rest, ", k=")
+	assert.True(t, ok, authorization)
+	assert.Equal(t, publicKey, k)
+
+	parts := strings.Split(token, ".")
+	assert.Len(t, parts, 3)
+	pub, err := ecdh.P256().NewPublicKey(mustDecode(t, k))
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	assert.NoError(t, err)
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	assert.NoError(t, err)
+	sig := mustDecode(t, parts[2])
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.True(t, ecdsa.Verify(parsed.(*ecdsa.PublicKey), hash[:],
+		new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])), "invalid signature")
+
+	header := map[string]any{}
+	assert.NoError(t, json.Unmarshal(mustDecode(t, parts[0]), &header))
+	assert.Equal(t, map[string]any{"typ": "JWT", "alg": "ES256"}, header)
+	claims := map[string]any{}
+	assert.NoError(t, json.Unmarshal(mustDecode(t, parts[1]), &claims))
+	return claims
+}
+
+func TestVapid(t *testing.T) {
+	private, public, err := GenerateVapidKeys()
+	assert.NoError(t, err)
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	v, err := NewVapid(private, "mailto:dev@example.com", WithVapidNow(func() time.Time { return now }))
+	assert.NoError(t, err)
+	assert.Equal(t, public, v.PublicKey())
+
+	auth, err := v.Authorization("https://push.example.com/send/abc?x=1")
+	assert.NoError(t, err)
+	claims := verifyVapid(t, auth, public)
+	assert.Equal(t, map[string]any{
+		"aud": "https://push.example.com",
+		"exp": float64(now.Add(12 * time.Hour).Unix()),
+		"sub": "mailto:dev@example.com",
+	}, claims)
+
+	// tokens are reused per push service until an hour before they expire
+	again, _ := v.Authorization("https://push.example.com/send/def")
+	assert.Equal(t, auth, again)
+	now = now.Add(11*time.Hour + time.Minute)
+	again, _ = v.Authorization("https://push.example.com/send/def")
+	assert.NotEqual(t, auth, again)
+
+	_, err = v.Authorization("not a url")
+	assert.EqualError(t, err, "invalid endpoint: not a url")
+	_, err = NewVapid(private, "dev@example.com")
+	assert.EqualError(t, err, "vapid subject must be a mailto: or https: uri: dev@example.com")
+	_, err = NewVapid("c2hvcnQ", "mailto:dev@example.com")
+	assert.EqualError(t, err, "invalid vapid private key: key must be 32 bytes")
+}